@@ -1,37 +1,147 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 	"vk/ecom/internal/handler"
 
-	// "vk/ecom/internal/repository/memory"
 	"vk/ecom/internal/database"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/fingerprint"
+	"vk/ecom/internal/pkg/mail"
+	"vk/ecom/internal/pkg/money"
+	"vk/ecom/internal/pkg/notify"
+	"vk/ecom/internal/pkg/oidc"
+	"vk/ecom/internal/pkg/password"
+	"vk/ecom/internal/pkg/payment"
+	"vk/ecom/internal/pkg/ratelimit"
+	"vk/ecom/internal/pkg/screening"
+	"vk/ecom/internal/pkg/sms"
+	"vk/ecom/internal/repository/memory"
 	"vk/ecom/internal/repository/postgres"
 	"vk/ecom/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+const auctionSchedulerInterval = 10 * time.Second
+
 func setupRoutes(handler *handler.Handler) *gin.Engine {
 	router := gin.Default()
 
 	auth := router.Group("/api/auth")
+	auth.Use(handler.RateLimitMiddleware())
 	{
 		auth.POST("/login", handler.Login)
+		auth.POST("/login/2fa", handler.LoginTwoFactor)
 		auth.POST("/register", handler.Register)
+		auth.POST("/logout", handler.OptionalAuthMiddleware(), handler.Logout)
+		auth.POST("/password-reset", handler.RequestPasswordReset)
+		auth.POST("/password-reset/confirm", handler.ConfirmPasswordReset)
+		auth.POST("/verify-email", handler.VerifyEmail)
+		auth.GET("/oidc", handler.GetLoginProviders)
+		auth.GET("/oidc/:provider", handler.BeginExternalLogin)
+		auth.GET("/oidc/:provider/callback", handler.ExternalLoginCallback)
+	}
+
+	router.POST("/api/payments/webhook", handler.PaymentWebhook)
+
+	cart := router.Group("/api/cart")
+	cart.Use(handler.OptionalAuthMiddleware(), handler.RateLimitMiddleware())
+	{
+		cart.GET("", handler.GetCart)
+		cart.POST("/items", handler.AddCartItem)
+		cart.DELETE("/items/:listing_id", handler.RemoveCartItem)
+	}
+
+	router.GET("/api/categories/:category/attributes", handler.GetCategoryAttributes)
+
+	users := router.Group("/api/users")
+	users.Use(handler.RateLimitMiddleware())
+	{
+		users.GET("/:id", handler.GetUserProfile)
+		users.GET("/:id/reviews", handler.GetUserReviews)
 	}
 
 	listings := router.Group("/api/listings")
-	listings.Use(handler.OptionalAuthMiddleware())
+	listings.Use(handler.OptionalAuthMiddleware(), handler.RateLimitMiddleware())
 	{
-		listings.GET("/", handler.GetListings)
+		readListings := handler.APIKeyRoutes(listings, domain.ScopeListingsRead)
+		readListings.GET("/", handler.GetListings)
+		readListings.GET("/trending", handler.GetTrendingListings)
+		readListings.GET("/:id", handler.GetListing)
+		readListings.GET("/:id/bids", handler.GetBids)
+		readListings.GET("/:id/similar", handler.GetSimilarListings)
+		readListings.GET("/:id/price-history", handler.GetPriceHistory)
 	}
 
 	protected := router.Group("/api")
-	protected.Use(handler.AuthMiddleware())
+	protected.Use(handler.AuthMiddleware(), handler.RateLimitMiddleware())
+	{
+		writeListings := handler.APIKeyRoutes(protected, domain.ScopeListingsWrite)
+		writeListings.POST("/listings", handler.CreateListing)
+		writeListings.PUT("/listings/:id", handler.UpdateListing)
+		protected.POST("/listings/:id/bids", handler.PlaceBid)
+		protected.POST("/listings/:id/reviews", handler.CreateReview)
+		protected.POST("/reviews/:id/reply", handler.ReplyToReview)
+		protected.POST("/listings/:id/reports", handler.ReportListing)
+		protected.PUT("/listings/:id/favorite", handler.FavoriteListing)
+		protected.DELETE("/listings/:id/favorite", handler.UnfavoriteListing)
+		protected.POST("/listings/:id/events", handler.TrackListingEvent)
+
+		protected.POST("/orders", handler.CreateOrder)
+		protected.GET("/orders", handler.GetOrders)
+		protected.GET("/orders/:id", handler.GetOrder)
+		protected.POST("/orders/:id/pay", handler.PayOrder)
+		protected.POST("/orders/:id/ship", handler.ShipOrder)
+		protected.POST("/orders/:id/complete", handler.CompleteOrder)
+		protected.POST("/orders/:id/cancel", handler.CancelOrder)
+		protected.POST("/orders/:id/refund", handler.RefundOrder)
+
+		protected.GET("/me/cart", handler.GetCart)
+		protected.POST("/me/cart/items", handler.AddCartItem)
+		protected.DELETE("/me/cart/items/:listing_id", handler.RemoveCartItem)
+		protected.POST("/me/cart/checkout", handler.Checkout)
+		protected.POST("/me/listings/:id/purchaser", handler.ConfirmPurchaser)
+		handler.APIKeyRoutes(protected, domain.ScopeListingsRead).GET("/me/listings/:id/stats", handler.GetListingStats)
+		protected.POST("/me/password", handler.ChangePassword)
+		protected.GET("/me/sessions", handler.GetSessions)
+		protected.DELETE("/me/sessions", handler.RevokeOtherSessions)
+		protected.DELETE("/me/sessions/:id", handler.RevokeSession)
+		protected.GET("/me/contact", handler.GetContactDetails)
+		protected.PUT("/me/email", handler.UpdateEmail)
+		protected.PUT("/me/phone", handler.UpdatePhone)
+		protected.POST("/me/email/verification", handler.ResendEmailVerification)
+		protected.POST("/me/phone/verification", handler.ResendPhoneVerification)
+		protected.POST("/me/phone/verify", handler.VerifyPhone)
+		protected.GET("/me/2fa", handler.GetTwoFactorStatus)
+		protected.POST("/me/2fa/enroll", handler.EnrollTwoFactor)
+		protected.POST("/me/2fa/confirm", handler.ConfirmTwoFactor)
+		protected.POST("/me/2fa/disable", handler.DisableTwoFactor)
+		protected.GET("/me/identities", handler.GetIdentities)
+		protected.POST("/me/identities/:provider", handler.LinkIdentity)
+		protected.DELETE("/me/identities/:provider", handler.UnlinkIdentity)
+		protected.GET("/me/api-keys", handler.GetAPIKeys)
+		protected.POST("/me/api-keys", handler.CreateAPIKey)
+		protected.DELETE("/me/api-keys/:id", handler.RevokeAPIKey)
+	}
+
+	admin := router.Group("/api/admin")
+	admin.Use(handler.AuthMiddleware(), handler.StaffMiddleware())
 	{
-		protected.POST("/listings", handler.CreateListing)
+		moderate := handler.APIKeyRoutes(admin, domain.ScopeAdminModerate)
+		moderate.GET("/moderation/cases", handler.GetModerationQueue)
+		moderate.GET("/moderation/cases/:id", handler.GetModerationCase)
+		moderate.POST("/moderation/cases/:id/claim", handler.ClaimModerationCase)
+		moderate.POST("/moderation/cases/:id/resolve", handler.ResolveModerationCase)
+		moderate.GET("/listings/:id/duplicates", handler.GetListingDuplicates)
+		admin.GET("/lockouts", handler.GetLockouts)
+		admin.DELETE("/lockouts/:kind/:value", handler.ClearLockout)
 	}
 
 	return router
@@ -57,24 +167,240 @@ func main() {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	similarCache := service.NewSimilarListingsCache(10*time.Minute, 10000)
+
 	userRepo := postgres.NewUserRepository(db)
-	listingRepo := postgres.NewListingRepository(db)
+	listingRepo := similarCache.Watch(postgres.NewListingRepository(db))
+	auctionRepo := postgres.NewAuctionRepository(db)
+	orderRepo := postgres.NewOrderRepository(db)
+	cartRepo := postgres.NewCartRepository(db)
+	favoriteRepo := postgres.NewFavoriteRepository(db)
+	loginAttemptRepo := postgres.NewLoginAttemptRepository(db)
+	passwordResetRepo := postgres.NewPasswordResetRepository(db)
+	phoneCodeRepo := postgres.NewPhoneCodeRepository(db)
+	twoFactorRepo := postgres.NewTwoFactorRepository(db)
+	identityRepo := postgres.NewIdentityRepository(db)
+	apiKeyRepo := postgres.NewAPIKeyRepository(db)
+	sessionRepo := postgres.NewSessionRepository(db)
+	reviewRepo := postgres.NewReviewRepository(db)
+	moderationRepo := postgres.NewModerationRepository(db)
+	analyticsRepo := postgres.NewAnalyticsRepository(db)
 
 	// userRepo := memory.NewInMemoryUserRepository()
-	// listingRepo := memory.NewInMemoryListingRepository()
+	// listingRepo := similarCache.Watch(memory.NewInMemoryListingRepository())
+	// auctionRepo := memory.NewInMemoryAuctionRepository()
+	// orderRepo := memory.NewInMemoryOrderRepository()
+	// cartRepo := memory.NewInMemoryCartRepository()
+	// favoriteRepo := memory.NewInMemoryFavoriteRepository()
+	// loginAttemptRepo := memory.NewInMemoryLoginAttemptRepository()
+	// passwordResetRepo := memory.NewInMemoryPasswordResetRepository()
+	// phoneCodeRepo := memory.NewInMemoryPhoneCodeRepository()
+	// twoFactorRepo := memory.NewInMemoryTwoFactorRepository()
+	// identityRepo := memory.NewInMemoryIdentityRepository()
+	// apiKeyRepo := memory.NewInMemoryAPIKeyRepository()
+	// sessionRepo := memory.NewInMemorySessionRepository()
+	// reviewRepo := memory.NewInMemoryReviewRepository()
+	// moderationRepo := memory.NewInMemoryModerationRepository()
+	// analyticsRepo := memory.NewInMemoryAnalyticsRepository()
+
+	// The fake provider charges nothing, so it must never serve real traffic,
+	// and its webhook route is public, so it needs a secret of its own.
+	if appEnv := getEnv("APP_ENV", ""); appEnv != "development" && appEnv != "test" {
+		log.Fatal("The fake payment provider requires APP_ENV=development or APP_ENV=test, got: ", appEnv)
+	}
+	webhookSecret := getEnv("PAYMENT_WEBHOOK_SECRET", "")
+	if webhookSecret == "" {
+		log.Fatal("PAYMENT_WEBHOOK_SECRET is required")
+	}
+	paymentProvider := payment.NewFakeProvider(
+		webhookSecret,
+		getEnv("PAYMENT_FAKE_MODE", payment.FakeModeSuccess),
+		2*time.Second,
+	)
 
-	authService := service.NewAuthService(userRepo)
-	listingService := service.NewListingService(listingRepo, userRepo)
+	screeningConfig := screening.DefaultConfig()
+	if path := getEnv("SCREENING_CONFIG_FILE", ""); path != "" {
+		if screeningConfig, err = screening.LoadConfig(path); err != nil {
+			log.Fatal("Failed to load screening config:", err)
+		}
+	}
+	imageHasher := fingerprint.NewHTTPImageHasher(5*time.Second, 10<<20)
+	screener, err := service.NewListingScreener(screeningConfig, listingRepo, moderationRepo, imageHasher)
+	if err != nil {
+		log.Fatal("Failed to build content screening:", err)
+	}
+
+	attributeCatalog := service.NewAttributeCatalog(service.DefaultCategorySchemas()...)
+
+	var exchangeRates money.RateProvider
+	if path := getEnv("EXCHANGE_RATES_FILE", ""); path != "" {
+		if exchangeRates, err = money.LoadRatesFile(path); err != nil {
+			log.Fatal("Failed to load exchange rates:", err)
+		}
+	}
+
+	loginThrottle := service.NewLoginThrottle(loginAttemptRepo, service.DefaultLoginThrottleConfig())
+	// Reset emails go to the log unless MAIL_DIR collects them as files;
+	// SMS_DIR does the same for text messages.
+	var mailer mail.Mailer = mail.LogMailer{}
+	if dir := getEnv("MAIL_DIR", ""); dir != "" {
+		mailer = mail.FileMailer{Dir: dir}
+	}
+	var smsSender sms.Sender = sms.LogSender{}
+	if dir := getEnv("SMS_DIR", ""); dir != "" {
+		smsSender = sms.FileSender{Dir: dir}
+	}
+	passwordResetConfig := service.DefaultPasswordResetConfig()
+	passwordResetConfig.ResetURL = getEnv("PASSWORD_RESET_URL", "")
+	authOpts := []service.AuthServiceOption{
+		service.WithLoginThrottle(loginThrottle),
+		service.WithPasswordReset(passwordResetRepo, mailer, passwordResetConfig),
+		service.WithTwoFactor(twoFactorRepo, service.DefaultTwoFactorConfig()),
+		service.WithAPIKeys(apiKeyRepo, service.DefaultAPIKeyConfig()),
+		service.WithSessions(sessionRepo, service.DefaultSessionConfig()),
+		service.WithPasswordHasher(password.NewArgon2id(argon2idParams())),
+	}
+	// PASSWORD_BLOCKLIST is "common" for the bundled list or the path of a
+	// larger one, one password per line.
+	switch list := getEnv("PASSWORD_BLOCKLIST", ""); list {
+	case "":
+	case "common":
+		authOpts = append(authOpts, service.WithPasswordBlocklist(password.CommonPasswords()))
+	default:
+		blocklist, err := password.LoadBlocklist(list)
+		if err != nil {
+			log.Fatal("Failed to load PASSWORD_BLOCKLIST:", err)
+		}
+		authOpts = append(authOpts, service.WithPasswordBlocklist(blocklist))
+	}
+	// Verification links are signed, so they need a stable key.
+	if key := getEnv("CONTACT_VERIFICATION_KEY", ""); key != "" {
+		contactConfig := service.DefaultContactVerificationConfig([]byte(key))
+		contactConfig.VerifyURL = getEnv("EMAIL_VERIFY_URL", "")
+		authOpts = append(authOpts, service.WithContactVerification(phoneCodeRepo, mailer, smsSender, contactConfig))
+	}
+	if path := getEnv("OIDC_CONFIG_FILE", ""); path != "" {
+		providerConfigs, err := oidc.LoadConfig(path)
+		if err != nil {
+			log.Fatal("Failed to load OIDC config:", err)
+		}
+		httpClient := &http.Client{Timeout: 10 * time.Second}
+		providers := make([]*oidc.Provider, len(providerConfigs))
+		for i, config := range providerConfigs {
+			providers[i] = oidc.NewProvider(config, httpClient)
+		}
+		authOpts = append(authOpts, service.WithExternalLogin(identityRepo, service.DefaultExternalLoginConfig(), providers...))
+	}
+	authService := service.NewAuthService(userRepo, authOpts...)
+	listingOpts := []service.ListingServiceOption{
+		service.WithSellerRatings(reviewRepo),
+		service.WithScreener(screener),
+		service.WithSimilarListingsCache(similarCache),
+		service.WithAttributeCatalog(attributeCatalog),
+		service.WithExchangeRates(exchangeRates),
+		service.WithFavorites(favoriteRepo, notify.LogNotifier{}),
+	}
+	auctionOpts := []service.AuctionServiceOption{
+		service.WithAuctionScreener(screener),
+		service.WithAuctionAttributeCatalog(attributeCatalog),
+	}
+	if getEnv("REQUIRE_VERIFIED_EMAIL", "") == "true" {
+		listingOpts = append(listingOpts, service.WithVerifiedEmailRequired())
+		auctionOpts = append(auctionOpts, service.WithAuctionVerifiedEmailRequired())
+	}
+	listingService := service.NewListingService(listingRepo, userRepo, listingOpts...)
+	auctionService := service.NewAuctionService(auctionRepo, listingRepo, userRepo, service.DefaultAuctionConfig(), auctionOpts...)
+	orderService := service.NewOrderService(orderRepo, listingRepo, paymentProvider)
+	cartService := service.NewCartService(cartRepo, listingRepo, userRepo, orderService)
+	reviewService := service.NewReviewService(reviewRepo, listingRepo, userRepo)
+
+	moderationConfig := service.DefaultModerationConfig()
+	if threshold, err := strconv.Atoi(getEnv("MODERATION_REPORT_THRESHOLD", "")); err == nil && threshold > 0 {
+		moderationConfig.ReportThreshold = threshold
+	}
+	moderationService := service.NewModerationService(moderationRepo, listingRepo, userRepo, moderationConfig)
+	analyticsService := service.NewAnalyticsService(analyticsRepo, listingRepo, service.DefaultAnalyticsConfig())
+
+	paymentProvider.OnWebhook(func(payload []byte, signature string) {
+		if err := orderService.HandlePaymentWebhook(payload, signature); err != nil {
+			log.Println("Failed to handle payment webhook:", err)
+		}
+	})
 
 	// addTestData(authService, listingService)
 
-	handler := handler.NewHandler(authService, listingService)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go auctionService.RunScheduler(ctx, auctionSchedulerInterval)
+	go analyticsService.Run(ctx)
+
+	handlerOpts := []handler.Option{
+		handler.WithAuctionService(auctionService),
+		handler.WithOrderService(orderService),
+		handler.WithCartService(cartService),
+		handler.WithReviewService(reviewService),
+		handler.WithModerationService(moderationService),
+		handler.WithAnalyticsService(analyticsService),
+	}
+	// Rate limits are kept in memory unless several instances have to share
+	// them through postgres.
+	rateLimitConfig := ratelimit.DefaultConfig()
+	if path := getEnv("RATE_LIMIT_CONFIG_FILE", ""); path != "" {
+		if rateLimitConfig, err = ratelimit.LoadConfig(path); err != nil {
+			log.Fatal("Failed to load rate limit config:", err)
+		}
+	}
+	var rateLimitStore ratelimit.Store = memory.NewInMemoryRateLimitRepository()
+	if getEnv("RATE_LIMIT_STORE", "memory") == "postgres" {
+		rateLimitStore = postgres.NewRateLimitRepository(db)
+	}
+	rateLimiter, err := ratelimit.NewLimiter(rateLimitStore, rateLimitConfig)
+	if err != nil {
+		log.Fatal("Invalid rate limit config:", err)
+	}
+	go rateLimiter.RunPruner(ctx, time.Minute)
+	handlerOpts = append(handlerOpts, handler.WithRateLimiter(rateLimiter))
+
+	// Cookie sessions for the browser frontend need a CSRF signing key.
+	if key := getEnv("SESSION_CSRF_KEY", ""); key != "" {
+		sessionConfig := handler.DefaultCookieSessionConfig([]byte(key))
+		sessionConfig.Secure = getEnv("SESSION_COOKIE_INSECURE", "") != "true"
+		handlerOpts = append(handlerOpts, handler.WithCookieSessions(sessionConfig))
+	}
+
+	handler := handler.NewHandler(authService, listingService, handlerOpts...)
 
 	router := setupRoutes(handler)
+	// The login limiter keys on the client address, so forwarded headers
+	// are only trusted from the configured proxies.
+	var trustedProxies []string
+	if proxies := getEnv("TRUSTED_PROXIES", ""); proxies != "" {
+		trustedProxies = strings.Split(proxies, ",")
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 
 	router.Run(":8080")
 }
 
+// argon2idParams returns the default argon2id parameters with overrides
+// from ARGON2_MEMORY_KIB, ARGON2_ITERATIONS and ARGON2_PARALLELISM.
+// Changing them rehashes passwords on the next login.
+func argon2idParams() password.Argon2idParams {
+	params := password.DefaultArgon2idParams()
+	if memory, err := strconv.ParseUint(getEnv("ARGON2_MEMORY_KIB", ""), 10, 32); err == nil && memory > 0 {
+		params.Memory = uint32(memory)
+	}
+	if iterations, err := strconv.ParseUint(getEnv("ARGON2_ITERATIONS", ""), 10, 32); err == nil && iterations > 0 {
+		params.Iterations = uint32(iterations)
+	}
+	if parallelism, err := strconv.ParseUint(getEnv("ARGON2_PARALLELISM", ""), 10, 8); err == nil && parallelism > 0 {
+		params.Parallelism = uint8(parallelism)
+	}
+	return params
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -42,6 +42,33 @@ func MigrateDB(db *sql.DB) error {
 			password VARCHAR(255) NOT NULL
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_users_login ON users(login)`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user'`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS banned BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS session_version INT NOT NULL DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS phone VARCHAR(16) NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_verified BOOLEAN NOT NULL DEFAULT FALSE`,
+		// Addresses from before normalization are lowered. Verified addresses
+		// that then collide stop the migration: picking an owner would lock
+		// the other accounts out of password resets, so an operator must
+		// decide.
+		`UPDATE users SET email = LOWER(TRIM(email)) WHERE email <> LOWER(TRIM(email))`,
+		`DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM users WHERE email <> '' AND email_verified GROUP BY email HAVING COUNT(*) > 1) THEN
+				RAISE EXCEPTION 'users share a verified email address; resolve the duplicates before migrating';
+			END IF;
+		END $$`,
+		// Only verified addresses are unique, so nobody can reserve an
+		// address they do not own.
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_verified_email ON users(email) WHERE email <> '' AND email_verified`,
+		`DROP INDEX IF EXISTS idx_users_email`,
+		`CREATE INDEX IF NOT EXISTS idx_users_email_lookup ON users(email) WHERE email <> ''`,
+		// The same goes for phone numbers. They were unique before, so
+		// the new index cannot fail.
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_verified_phone ON users(phone) WHERE phone <> '' AND phone_verified`,
+		`DROP INDEX IF EXISTS idx_users_phone`,
 
 		`CREATE TABLE IF NOT EXISTS listings (
 			id BIGSERIAL PRIMARY KEY,
@@ -55,6 +82,270 @@ func MigrateDB(db *sql.DB) error {
 		`CREATE INDEX IF NOT EXISTS idx_listings_author_id ON listings(author_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_listings_price ON listings(price)`,
 		`CREATE INDEX IF NOT EXISTS idx_listings_created_at ON listings(created_at)`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS listing_type VARCHAR(20) NOT NULL DEFAULT 'fixed'`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active'`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_status ON listings(status)`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS text_hash BIGINT NOT NULL DEFAULT 0`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS image_hash BIGINT`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS text_band0 INT GENERATED ALWAYS AS ((text_hash >> 48) & 65535) STORED`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS text_band1 INT GENERATED ALWAYS AS ((text_hash >> 32) & 65535) STORED`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS text_band2 INT GENERATED ALWAYS AS ((text_hash >> 16) & 65535) STORED`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS text_band3 INT GENERATED ALWAYS AS ((text_hash >> 0) & 65535) STORED`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS image_band0 INT GENERATED ALWAYS AS ((image_hash >> 48) & 65535) STORED`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS image_band1 INT GENERATED ALWAYS AS ((image_hash >> 32) & 65535) STORED`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS image_band2 INT GENERATED ALWAYS AS ((image_hash >> 16) & 65535) STORED`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS image_band3 INT GENERATED ALWAYS AS ((image_hash >> 0) & 65535) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_text_band0 ON listings(text_band0)`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_text_band1 ON listings(text_band1)`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_text_band2 ON listings(text_band2)`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_text_band3 ON listings(text_band3)`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_image_band0 ON listings(image_band0)`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_image_band1 ON listings(image_band1)`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_image_band2 ON listings(image_band2)`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_image_band3 ON listings(image_band3)`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS category VARCHAR(50) NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_category ON listings(category) WHERE category <> ''`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', title || ' ' || coalesce(description, ''))) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_search_vector ON listings USING GIN (search_vector)`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS popularity DOUBLE PRECISION NOT NULL DEFAULT 0`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_status_popularity ON listings(status, popularity DESC)`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS city VARCHAR(100) NOT NULL DEFAULT ''`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS region VARCHAR(100) NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_location ON listings(latitude, longitude) WHERE latitude IS NOT NULL`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}'`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_attributes ON listings USING GIN (attributes jsonb_path_ops)`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'RUB'`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS previous_price BIGINT`,
+		`ALTER TABLE listings ADD COLUMN IF NOT EXISTS price_dropped_at TIMESTAMP`,
+		`CREATE INDEX IF NOT EXISTS idx_listings_price_dropped_at ON listings(price_dropped_at) WHERE previous_price IS NOT NULL`,
+		`CREATE TABLE IF NOT EXISTS listing_price_history (
+			id BIGSERIAL PRIMARY KEY,
+			listing_id BIGINT NOT NULL REFERENCES listings(id) ON DELETE CASCADE,
+			old_price BIGINT NOT NULL,
+			new_price BIGINT NOT NULL,
+			changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_listing_price_history_listing_id ON listing_price_history(listing_id, changed_at)`,
+		`CREATE TABLE IF NOT EXISTS listing_favorites (
+			user_id BIGINT NOT NULL,
+			listing_id BIGINT NOT NULL REFERENCES listings(id) ON DELETE CASCADE,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (user_id, listing_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_listing_favorites_listing_id ON listing_favorites(listing_id)`,
+
+		`CREATE TABLE IF NOT EXISTS auctions (
+			listing_id BIGINT PRIMARY KEY REFERENCES listings(id) ON DELETE CASCADE,
+			start_price BIGINT NOT NULL,
+			reserve_price BIGINT NOT NULL DEFAULT 0,
+			min_increment BIGINT NOT NULL,
+			ends_at TIMESTAMP NOT NULL,
+			current_price BIGINT NOT NULL DEFAULT 0,
+			leader_id BIGINT,
+			bid_count INT NOT NULL DEFAULT 0,
+			status VARCHAR(20) NOT NULL DEFAULT 'open',
+			winner_id BIGINT,
+			closed_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_auctions_status_ends_at ON auctions(status, ends_at)`,
+
+		`CREATE TABLE IF NOT EXISTS bids (
+			id BIGSERIAL PRIMARY KEY,
+			listing_id BIGINT NOT NULL REFERENCES auctions(listing_id) ON DELETE CASCADE,
+			bidder_id BIGINT NOT NULL,
+			amount BIGINT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_bids_listing_id ON bids(listing_id, created_at)`,
+
+		`CREATE TABLE IF NOT EXISTS orders (
+			id BIGSERIAL PRIMARY KEY,
+			listing_id BIGINT NOT NULL REFERENCES listings(id),
+			buyer_id BIGINT NOT NULL,
+			seller_id BIGINT NOT NULL,
+			amount BIGINT NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			payment_id VARCHAR(100) NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_orders_buyer_id ON orders(buyer_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_orders_seller_id ON orders(seller_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_orders_payment_id ON orders(payment_id) WHERE payment_id <> ''`,
+		`CREATE TABLE IF NOT EXISTS order_items (
+			order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+			listing_id BIGINT NOT NULL REFERENCES listings(id),
+			price BIGINT NOT NULL,
+			PRIMARY KEY (order_id, listing_id)
+		)`,
+		// Orders used to hold a single listing; move it to order_items.
+		`DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'orders' AND column_name = 'listing_id') THEN
+				INSERT INTO order_items (order_id, listing_id, price)
+				SELECT id, listing_id, amount FROM orders
+				ON CONFLICT DO NOTHING;
+			END IF;
+		END $$`,
+		`ALTER TABLE orders DROP COLUMN IF EXISTS listing_id`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'RUB'`,
+
+		`CREATE TABLE IF NOT EXISTS cart_items (
+			cart_key VARCHAR(100) NOT NULL,
+			listing_id BIGINT NOT NULL REFERENCES listings(id) ON DELETE CASCADE,
+			price_at_add BIGINT NOT NULL,
+			added_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (cart_key, listing_id)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS purchase_confirmations (
+			listing_id BIGINT PRIMARY KEY REFERENCES listings(id) ON DELETE CASCADE,
+			seller_id BIGINT NOT NULL,
+			buyer_id BIGINT NOT NULL,
+			confirmed_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE IF NOT EXISTS reviews (
+			id BIGSERIAL PRIMARY KEY,
+			listing_id BIGINT NOT NULL UNIQUE REFERENCES listings(id) ON DELETE CASCADE,
+			seller_id BIGINT NOT NULL,
+			author_id BIGINT NOT NULL,
+			rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+			text TEXT NOT NULL DEFAULT '',
+			reply TEXT NOT NULL DEFAULT '',
+			replied_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_reviews_seller_id ON reviews(seller_id, created_at)`,
+		`CREATE TABLE IF NOT EXISTS seller_ratings (
+			seller_id BIGINT PRIMARY KEY,
+			rating_sum BIGINT NOT NULL DEFAULT 0,
+			review_count INT NOT NULL DEFAULT 0
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS moderation_cases (
+			id BIGSERIAL PRIMARY KEY,
+			listing_id BIGINT NOT NULL REFERENCES listings(id) ON DELETE CASCADE,
+			status VARCHAR(20) NOT NULL DEFAULT 'open',
+			report_count INT NOT NULL DEFAULT 0,
+			auto_hidden BOOLEAN NOT NULL DEFAULT FALSE,
+			claimed_by BIGINT,
+			claimed_at TIMESTAMP,
+			resolution VARCHAR(20) NOT NULL DEFAULT '',
+			resolved_by BIGINT,
+			resolved_at TIMESTAMP,
+			note TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_moderation_cases_listing_open ON moderation_cases(listing_id) WHERE status <> 'resolved'`,
+		`CREATE INDEX IF NOT EXISTS idx_moderation_cases_status ON moderation_cases(status, report_count DESC)`,
+		`CREATE TABLE IF NOT EXISTS listing_reports (
+			id BIGSERIAL PRIMARY KEY,
+			case_id BIGINT NOT NULL REFERENCES moderation_cases(id) ON DELETE CASCADE,
+			listing_id BIGINT NOT NULL,
+			reporter_id BIGINT NOT NULL,
+			reason VARCHAR(30) NOT NULL,
+			comment TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			UNIQUE (case_id, reporter_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_listing_reports_reporter ON listing_reports(reporter_id, created_at)`,
+
+		`CREATE TABLE IF NOT EXISTS listing_daily_stats (
+			listing_id BIGINT NOT NULL REFERENCES listings(id) ON DELETE CASCADE,
+			day DATE NOT NULL,
+			views INT NOT NULL DEFAULT 0,
+			favorites INT NOT NULL DEFAULT 0,
+			messages INT NOT NULL DEFAULT 0,
+			PRIMARY KEY (listing_id, day)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS login_attempts (
+			attempt_key VARCHAR(100) PRIMARY KEY,
+			failures INT NOT NULL DEFAULT 0,
+			last_failure_at TIMESTAMP NOT NULL,
+			locked_until TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_login_attempts_locked_until ON login_attempts(locked_until) WHERE locked_until IS NOT NULL`,
+
+		`CREATE TABLE IF NOT EXISTS rate_limits (
+			limit_key VARCHAR(200) PRIMARY KEY,
+			tat TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_rate_limits_tat ON rate_limits(tat)`,
+
+		`CREATE TABLE IF NOT EXISTS password_reset_tokens (
+			id BIGSERIAL PRIMARY KEY,
+			user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id)`,
+
+		`CREATE TABLE IF NOT EXISTS user_two_factor (
+			user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			secret VARCHAR(64) NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT FALSE,
+			last_used_step BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE IF NOT EXISTS two_factor_recovery_codes (
+			user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			code_hash VARCHAR(64) NOT NULL,
+			PRIMARY KEY (user_id, code_hash)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS user_identities (
+			id BIGSERIAL PRIMARY KEY,
+			user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			provider VARCHAR(32) NOT NULL,
+			subject VARCHAR(255) NOT NULL,
+			email VARCHAR(255) NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			UNIQUE (provider, subject),
+			UNIQUE (user_id, provider)
+		)`,
+		`CREATE TABLE IF NOT EXISTS external_login_states (
+			state_hash VARCHAR(64) PRIMARY KEY,
+			provider VARCHAR(32) NOT NULL,
+			nonce VARCHAR(64) NOT NULL,
+			code_verifier VARCHAR(128) NOT NULL,
+			user_id INT,
+			expires_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS api_keys (
+			id BIGSERIAL PRIMARY KEY,
+			user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(64) NOT NULL,
+			prefix VARCHAR(16) NOT NULL,
+			key_hash VARCHAR(64) NOT NULL UNIQUE,
+			scopes TEXT[] NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			last_used_at TIMESTAMP,
+			revoked_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id)`,
+		`CREATE TABLE IF NOT EXISTS user_sessions (
+			id BIGSERIAL PRIMARY KEY,
+			user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			ip VARCHAR(64) NOT NULL DEFAULT '',
+			user_agent VARCHAR(255) NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			expires_at TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions(user_id)`,
+		`CREATE TABLE IF NOT EXISTS phone_verification_codes (
+			user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			phone VARCHAR(16) NOT NULL,
+			code_hash VARCHAR(64) NOT NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			expires_at TIMESTAMP NOT NULL
+		)`,
 	}
 
 	for _, query := range queries {
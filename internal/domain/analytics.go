@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// Listing events counted for seller analytics.
+const (
+	ListingEventView     = "view"
+	ListingEventFavorite = "favorite"
+	ListingEventMessage  = "message"
+)
+
+// ListingDailyStats holds the event counts of one listing on one UTC day.
+type ListingDailyStats struct {
+	ListingID int64     `json:"listing_id"`
+	Day       time.Time `json:"day"`
+	Views     int       `json:"views"`
+	Favorites int       `json:"favorites"`
+	Messages  int       `json:"messages"`
+}
+
+// Add increments the counter of the given event.
+func (s *ListingDailyStats) Add(event string, n int) {
+	switch event {
+	case ListingEventView:
+		s.Views += n
+	case ListingEventFavorite:
+		s.Favorites += n
+	case ListingEventMessage:
+		s.Messages += n
+	}
+}
+
+// Merge adds the counts of other to s.
+func (s *ListingDailyStats) Merge(other *ListingDailyStats) {
+	s.Views += other.Views
+	s.Favorites += other.Favorites
+	s.Messages += other.Messages
+}
@@ -0,0 +1,75 @@
+package domain
+
+const (
+	AttributeTypeEnum    = "enum"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+	AttributeTypeText    = "text"
+)
+
+// Attribute filter operators. Range operators only apply to numbers.
+const (
+	AttributeOpEq  = "eq"
+	AttributeOpGte = "gte"
+	AttributeOpLte = "lte"
+)
+
+// AttributeDefinition describes one typed attribute of a category.
+type AttributeDefinition struct {
+	Key      string `json:"key"`
+	Label    string `json:"label"`
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+	// Values lists the allowed values of an enum.
+	Values []string `json:"values,omitempty"`
+	// Unit, Min and Max apply to numbers; Min and Max are optional.
+	Unit string   `json:"unit,omitempty"`
+	Min  *float64 `json:"min,omitempty"`
+	Max  *float64 `json:"max,omitempty"`
+	// MaxLength applies to text; zero means the default limit.
+	MaxLength int `json:"max_length,omitempty"`
+}
+
+// CategorySchema lists the attributes listings of a category may carry.
+type CategorySchema struct {
+	Category   string                `json:"category"`
+	Attributes []AttributeDefinition `json:"attributes"`
+}
+
+func (s *CategorySchema) Attribute(key string) (*AttributeDefinition, bool) {
+	for i := range s.Attributes {
+		if s.Attributes[i].Key == key {
+			return &s.Attributes[i], true
+		}
+	}
+	return nil, false
+}
+
+// AttributeFilter keeps listings whose attribute Key compares to Value
+// with Op. Value is a string, float64 or bool as stored in
+// Listing.Attributes.
+type AttributeFilter struct {
+	Key   string
+	Op    string
+	Value interface{}
+}
+
+// Matches reports whether the attributes satisfy the filter.
+func (f AttributeFilter) Matches(attributes map[string]interface{}) bool {
+	value, ok := attributes[f.Key]
+	if !ok {
+		return false
+	}
+	if f.Op == AttributeOpEq {
+		return value == f.Value
+	}
+	number, ok := value.(float64)
+	bound, isNumber := f.Value.(float64)
+	if !ok || !isNumber {
+		return false
+	}
+	if f.Op == AttributeOpGte {
+		return number >= bound
+	}
+	return number <= bound
+}
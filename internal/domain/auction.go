@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"time"
+)
+
+const (
+	AuctionStatusOpen   = "open"
+	AuctionStatusClosed = "closed"
+)
+
+// Auction holds the bidding state of a listing with Type == ListingTypeAuction.
+// CurrentPrice and LeaderID describe the highest accepted bid so far.
+type Auction struct {
+	ListingID    int64      `json:"listing_id" db:"listing_id"`
+	StartPrice   int64      `json:"start_price" db:"start_price"`
+	ReservePrice int64      `json:"reserve_price" db:"reserve_price"`
+	MinIncrement int64      `json:"min_increment" db:"min_increment"`
+	EndsAt       time.Time  `json:"ends_at" db:"ends_at"`
+	CurrentPrice int64      `json:"current_price" db:"current_price"`
+	LeaderID     *int64     `json:"leader_id" db:"leader_id"`
+	BidCount     int        `json:"bid_count" db:"bid_count"`
+	Status       string     `json:"status" db:"status"`
+	WinnerID     *int64     `json:"winner_id" db:"winner_id"`
+	ClosedAt     *time.Time `json:"closed_at" db:"closed_at"`
+}
+
+type Bid struct {
+	ID        int64     `json:"id" db:"id"`
+	ListingID int64     `json:"listing_id" db:"listing_id"`
+	BidderID  int64     `json:"bidder_id" db:"bidder_id"`
+	Amount    int64     `json:"amount" db:"amount"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
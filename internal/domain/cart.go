@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"time"
+)
+
+// CartItem is a listing saved in a cart. CartKey identifies the cart: it is
+// derived from the user ID for signed-in users and from a random token for
+// anonymous visitors.
+type CartItem struct {
+	CartKey    string    `json:"cart_key" db:"cart_key"`
+	ListingID  int64     `json:"listing_id" db:"listing_id"`
+	PriceAtAdd int64     `json:"price_at_add" db:"price_at_add"`
+	AddedAt    time.Time `json:"added_at" db:"added_at"`
+}
@@ -2,6 +2,25 @@ package domain
 
 import (
 	"time"
+	"vk/ecom/internal/pkg/geo"
+	"vk/ecom/internal/pkg/money"
+)
+
+const (
+	ListingTypeFixed   = "fixed"
+	ListingTypeAuction = "auction"
+)
+
+// DefaultCurrency is the currency of listings created without one.
+const DefaultCurrency = "RUB"
+
+const (
+	ListingStatusActive   = "active"
+	ListingStatusReserved = "reserved"
+	ListingStatusSold     = "sold"
+	// ListingStatusHidden is set by moderation; hidden listings stay out of
+	// the feed until a moderator restores them.
+	ListingStatusHidden = "hidden"
 )
 
 type Listing struct {
@@ -12,4 +31,192 @@ type Listing struct {
 	Price       int64     `json:"price" db:"price"`
 	AuthorID    int64     `json:"author_id" db:"author_id"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	Type        string    `json:"type" db:"listing_type"`
+	Status      string    `json:"status" db:"status"`
+	// Currency is the ISO 4217 code of Price, which is in minor units.
+	Currency string `json:"currency" db:"currency"`
+	// Category is an optional slug such as "bicycles"; empty means
+	// uncategorized.
+	Category string `json:"category" db:"category"`
+	// Popularity is a time-decayed engagement score, recomputed
+	// periodically from the listing stats.
+	Popularity float64 `json:"-" db:"popularity"`
+	// TextHash is a SimHash of the title and description, ImageHash a
+	// perceptual hash of the image if it could be computed.
+	TextHash  uint64  `json:"-" db:"text_hash"`
+	ImageHash *uint64 `json:"-" db:"image_hash"`
+	// Latitude and Longitude are either both set or both nil.
+	Latitude  *float64 `json:"latitude" db:"latitude"`
+	Longitude *float64 `json:"longitude" db:"longitude"`
+	City      string   `json:"city" db:"city"`
+	Region    string   `json:"region" db:"region"`
+	// Attributes holds the values of the category's typed attributes,
+	// keyed by attribute key: strings, float64 numbers and bools.
+	Attributes map[string]interface{} `json:"attributes" db:"attributes"`
+	// PreviousPrice is the price before the latest run of drops and
+	// PriceDroppedAt the time of the last drop; both are nil unless the
+	// price is below what it was, see SetPrice.
+	PreviousPrice  *int64     `json:"previous_price,omitempty" db:"previous_price"`
+	PriceDroppedAt *time.Time `json:"-" db:"price_dropped_at"`
+	// DistanceKm is filled by searches around a point and not stored.
+	DistanceKm *float64 `json:"-" db:"-"`
+}
+
+// PriceDropWindow is how long a price drop is shown on a listing.
+const PriceDropWindow = 14 * 24 * time.Hour
+
+// PriceChange is one entry of a listing's price history.
+type PriceChange struct {
+	ListingID int64     `json:"listing_id"`
+	OldPrice  int64     `json:"old_price"`
+	NewPrice  int64     `json:"new_price"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+func (l *Listing) Money() money.Money {
+	return money.New(l.Price, l.Currency)
+}
+
+// SetPrice changes the price and tracks drops. Consecutive drops within
+// PriceDropWindow keep the price before the first one as PreviousPrice;
+// raising the price back to it clears the drop.
+func (l *Listing) SetPrice(price int64, now time.Time) {
+	switch {
+	case price < l.Price:
+		if _, ok := l.RecentPriceDrop(now); !ok {
+			previous := l.Price
+			l.PreviousPrice = &previous
+		}
+		l.PriceDroppedAt = &now
+	case price > l.Price && l.PreviousPrice != nil && price >= *l.PreviousPrice:
+		l.PreviousPrice = nil
+		l.PriceDroppedAt = nil
+	}
+	l.Price = price
+}
+
+// RecentPriceDrop returns the price before a drop within PriceDropWindow
+// of now.
+func (l *Listing) RecentPriceDrop(now time.Time) (int64, bool) {
+	if l.PreviousPrice == nil || l.PriceDroppedAt == nil || *l.PreviousPrice <= l.Price {
+		return 0, false
+	}
+	if now.Sub(*l.PriceDroppedAt) > PriceDropWindow {
+		return 0, false
+	}
+	return *l.PreviousPrice, true
+}
+
+// Location returns the listing's coordinates, if it has any.
+func (l *Listing) Location() (geo.Point, bool) {
+	if l.Latitude == nil || l.Longitude == nil {
+		return geo.Point{}, false
+	}
+	return geo.Point{Lat: *l.Latitude, Lng: *l.Longitude}, true
+}
+
+// ListingQuery selects a page of active listings for the feed.
+type ListingQuery struct {
+	// Category, when set, keeps only listings of that category, and
+	// Attributes only those whose attributes match every filter.
+	Category   string
+	Attributes []AttributeFilter
+	MinPrice   *int64
+	MaxPrice   *int64
+	// PriceRates, when set, converts prices before they are filtered,
+	// sorted and bucketed: a listing's price counts as Price times the rate
+	// of its currency, so MinPrice and MaxPrice are in the minor units of a
+	// display currency. Listings in currencies without a rate have no
+	// comparable price; they fail price filters and sort last.
+	PriceRates map[string]float64
+	// CreatedAfter, when set, keeps only listings created since then.
+	CreatedAfter *time.Time
+	// PriceDroppedSince, when set, keeps only listings whose price was
+	// lowered since then and is still below its previous price.
+	PriceDroppedSince *time.Time
+	// Near restricts the feed to listings within RadiusKm of the point.
+	Near     *geo.Point
+	RadiusKm float64
+	// SortBy is "date", "price", "popular" or "distance"; distance requires
+	// Near.
+	SortBy    string
+	SortOrder string
+	Page      int
+	PageSize  int
+}
+
+// FacetQuery selects the facets counted for a ListingQuery. Each facet
+// counts the listings matching every filter of the query except its own.
+type FacetQuery struct {
+	Categories bool
+	// PriceBounds are the ascending lower bounds of the price buckets; the
+	// last bucket has no upper bound. No bounds means no price facet.
+	PriceBounds []int64
+	// AgeBuckets count listings created since each bucket's time, so they
+	// overlap rather than partition the listings.
+	AgeBuckets []AgeBucket
+}
+
+type AgeBucket struct {
+	Name  string
+	Since time.Time
+}
+
+type FacetCount struct {
+	Value string
+	Count int
+}
+
+// PriceBucketCount counts listings priced from Min up to but excluding
+// Max; a nil Max means no upper bound.
+type PriceBucketCount struct {
+	Min   int64
+	Max   *int64
+	Count int
+}
+
+// NewPriceBuckets returns the empty buckets delimited by bounds.
+func NewPriceBuckets(bounds []int64) []PriceBucketCount {
+	buckets := make([]PriceBucketCount, len(bounds))
+	for i, bound := range bounds {
+		buckets[i].Min = bound
+		if i+1 < len(bounds) {
+			next := bounds[i+1]
+			buckets[i].Max = &next
+		}
+	}
+	return buckets
+}
+
+// ListingFacets holds the facets requested by a FacetQuery; the others are
+// nil. Categories come in no particular order and listings without a
+// category are not counted; the buckets come in the requested order.
+type ListingFacets struct {
+	Categories []FacetCount
+	Prices     []PriceBucketCount
+	Ages       []FacetCount
+}
+
+// NearDuplicate is a listing whose fingerprint is close to another one.
+// ImageDistance is nil unless both listings have an image hash.
+type NearDuplicate struct {
+	ListingID     int64 `json:"listing_id"`
+	AuthorID      int64 `json:"author_id"`
+	TextDistance  int   `json:"text_distance"`
+	ImageDistance *int  `json:"image_distance"`
+}
+
+// SimilarListing is a candidate for the "similar listings" block. TextScore
+// is the backend's text relevance, only comparable within one result set.
+type SimilarListing struct {
+	Listing   *Listing
+	TextScore float64
+}
+
+// Distance is the closer of the two fingerprint distances.
+func (d *NearDuplicate) Distance() int {
+	if d.ImageDistance != nil && *d.ImageDistance < d.TextDistance {
+		return *d.ImageDistance
+	}
+	return d.TextDistance
 }
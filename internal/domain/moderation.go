@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"time"
+)
+
+const (
+	ReportReasonScam          = "scam"
+	ReportReasonProhibited    = "prohibited"
+	ReportReasonCounterfeit   = "counterfeit"
+	ReportReasonOffensive     = "offensive"
+	ReportReasonSpam          = "spam"
+	ReportReasonWrongCategory = "wrong_category"
+	ReportReasonOther         = "other"
+)
+
+// ReportReasons lists the reason codes accepted from reporters.
+var ReportReasons = []string{
+	ReportReasonScam,
+	ReportReasonProhibited,
+	ReportReasonCounterfeit,
+	ReportReasonOffensive,
+	ReportReasonSpam,
+	ReportReasonWrongCategory,
+	ReportReasonOther,
+}
+
+// ReportReasonAutomated marks reports filed by content screening on behalf
+// of SystemReporterID. It cannot be chosen by users.
+const (
+	ReportReasonAutomated       = "automated"
+	SystemReporterID      int64 = 0
+)
+
+const (
+	CaseStatusOpen     = "open"
+	CaseStatusClaimed  = "claimed"
+	CaseStatusResolved = "resolved"
+)
+
+const (
+	ResolutionDismiss = "dismiss"
+	ResolutionHide    = "hide"
+	ResolutionBan     = "ban"
+)
+
+// ModerationCase groups the reports filed against a listing until a moderator
+// resolves them. A listing has at most one unresolved case at a time; reports
+// arriving after resolution open a new one.
+type ModerationCase struct {
+	ID          int64      `json:"id" db:"id"`
+	ListingID   int64      `json:"listing_id" db:"listing_id"`
+	Status      string     `json:"status" db:"status"`
+	ReportCount int        `json:"report_count" db:"report_count"`
+	AutoHidden  bool       `json:"auto_hidden" db:"auto_hidden"`
+	ClaimedBy   *int64     `json:"claimed_by" db:"claimed_by"`
+	ClaimedAt   *time.Time `json:"claimed_at" db:"claimed_at"`
+	Resolution  string     `json:"resolution" db:"resolution"`
+	ResolvedBy  *int64     `json:"resolved_by" db:"resolved_by"`
+	ResolvedAt  *time.Time `json:"resolved_at" db:"resolved_at"`
+	Note        string     `json:"note" db:"note"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+type Report struct {
+	ID         int64     `json:"id" db:"id"`
+	CaseID     int64     `json:"case_id" db:"case_id"`
+	ListingID  int64     `json:"listing_id" db:"listing_id"`
+	ReporterID int64     `json:"reporter_id" db:"reporter_id"`
+	Reason     string    `json:"reason" db:"reason"`
+	Comment    string    `json:"comment" db:"comment"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
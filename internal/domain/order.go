@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"time"
+)
+
+const (
+	OrderStatusCreated   = "created"
+	OrderStatusPaid      = "paid"
+	OrderStatusShipped   = "shipped"
+	OrderStatusCompleted = "completed"
+	OrderStatusCancelled = "cancelled"
+	OrderStatusRefunded  = "refunded"
+)
+
+// Order groups listings of a single seller bought together. Amount is the
+// sum of the item prices at the moment the order was created, in minor
+// units of Currency; all items share that currency.
+type Order struct {
+	ID        int64        `json:"id" db:"id"`
+	BuyerID   int64        `json:"buyer_id" db:"buyer_id"`
+	SellerID  int64        `json:"seller_id" db:"seller_id"`
+	Amount    int64        `json:"amount" db:"amount"`
+	Currency  string       `json:"currency" db:"currency"`
+	Status    string       `json:"status" db:"status"`
+	PaymentID string       `json:"payment_id" db:"payment_id"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at" db:"updated_at"`
+	Items     []*OrderItem `json:"items"`
+}
+
+type OrderItem struct {
+	OrderID   int64 `json:"order_id" db:"order_id"`
+	ListingID int64 `json:"listing_id" db:"listing_id"`
+	Price     int64 `json:"price" db:"price"`
+}
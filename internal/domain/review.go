@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"time"
+)
+
+// PurchaseConfirmation records that the seller of a listing confirmed who
+// bought it. Only the confirmed buyer may review the listing's seller.
+type PurchaseConfirmation struct {
+	ListingID   int64     `json:"listing_id" db:"listing_id"`
+	SellerID    int64     `json:"seller_id" db:"seller_id"`
+	BuyerID     int64     `json:"buyer_id" db:"buyer_id"`
+	ConfirmedAt time.Time `json:"confirmed_at" db:"confirmed_at"`
+}
+
+type Review struct {
+	ID        int64      `json:"id" db:"id"`
+	ListingID int64      `json:"listing_id" db:"listing_id"`
+	SellerID  int64      `json:"seller_id" db:"seller_id"`
+	AuthorID  int64      `json:"author_id" db:"author_id"`
+	Rating    int        `json:"rating" db:"rating"`
+	Text      string     `json:"text" db:"text"`
+	Reply     string     `json:"reply" db:"reply"`
+	RepliedAt *time.Time `json:"replied_at" db:"replied_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// SellerRating is the running aggregate of all reviews of a seller.
+type SellerRating struct {
+	SellerID    int64 `json:"seller_id" db:"seller_id"`
+	RatingSum   int64 `json:"rating_sum" db:"rating_sum"`
+	ReviewCount int   `json:"review_count" db:"review_count"`
+}
+
+func (r *SellerRating) Average() float64 {
+	if r == nil || r.ReviewCount == 0 {
+		return 0
+	}
+	return float64(r.RatingSum) / float64(r.ReviewCount)
+}
@@ -1,7 +1,142 @@
 package domain
 
+import "time"
+
+const (
+	UserRoleUser  = "user"
+	UserRoleStaff = "staff"
+)
+
 type User struct {
 	ID       int    `json:"id" db:"id"`
 	Login    string `json:"login" db:"login"`
 	Password string `json:"password" db:"password"`
+	// Email and Phone are stored normalized, lower case and E.164, and
+	// are unique among users when set.
+	Email         string `json:"email" db:"email"`
+	EmailVerified bool   `json:"email_verified" db:"email_verified"`
+	Phone         string `json:"phone" db:"phone"`
+	PhoneVerified bool   `json:"phone_verified" db:"phone_verified"`
+	Role          string `json:"role" db:"role"`
+	Banned        bool   `json:"banned" db:"banned"`
+	// SessionVersion is embedded in issued tokens; bumping it revokes
+	// all of the user's sessions.
+	SessionVersion int `json:"-" db:"session_version"`
+	// SessionID is the session of the token the user was loaded from, if
+	// sessions are tracked. It is not stored with the user.
+	SessionID int64 `json:"-" db:"-"`
+}
+
+// ClientInfo describes the client a login comes from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// Session is a login: the tokens issued for it carry its ID and stop
+// working once it is revoked.
+type Session struct {
+	ID         int64      `json:"id"`
+	UserID     int        `json:"user_id"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// PasswordResetToken is an issued reset token. Only the SHA-256 hash of
+// the token is stored.
+type PasswordResetToken struct {
+	ID        int64      `json:"id"`
+	UserID    int        `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// PhoneCode is a texted verification code waiting to be entered, of which
+// only a hash is kept. Attempts counts the guesses made against it.
+type PhoneCode struct {
+	UserID    int       `json:"user_id"`
+	Phone     string    `json:"phone"`
+	CodeHash  string    `json:"-"`
+	Attempts  int       `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LoginAttempts counts recent failed logins for a key: "login:<login>" for
+// an account or "ip:<address>" for a client.
+type LoginAttempts struct {
+	Key           string     `json:"key"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
+
+// TwoFactor is a user's TOTP setup. It is pending until the user confirms
+// a code from the authenticator app. LastUsedStep is the time step of the
+// last accepted code, which cannot be used again.
+type TwoFactor struct {
+	UserID       int       `json:"user_id"`
+	Secret       string    `json:"-"`
+	Enabled      bool      `json:"enabled"`
+	LastUsedStep int64     `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Identity links a user to an account at an external OpenID provider,
+// identified by the provider's subject.
+type Identity struct {
+	ID        int64     `json:"id"`
+	UserID    int       `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"-"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExternalLoginState is kept between redirecting to a provider and its
+// callback. UserID is set when a signed-in user links an identity rather
+// than logging in. Only the SHA-256 hash of the state is stored.
+type ExternalLoginState struct {
+	StateHash    string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	UserID       int
+	ExpiresAt    time.Time
+}
+
+// API key scopes. A key may only be used on routes that declare one of
+// its scopes; admin:moderate is only granted to staff.
+const (
+	ScopeListingsRead  = "listings:read"
+	ScopeListingsWrite = "listings:write"
+	ScopeAdminModerate = "admin:moderate"
+)
+
+// APIKey is a non-interactive credential owned by a user. Only the SHA-256
+// hash of the key is stored; Prefix identifies it in listings.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
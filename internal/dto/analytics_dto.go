@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+type ListingEventRequest struct {
+	Type string `json:"type"`
+}
+
+type ListingStatsPointDTO struct {
+	Date      string `json:"date"`
+	Views     int    `json:"views"`
+	Favorites int    `json:"favorites"`
+	Messages  int    `json:"messages"`
+}
+
+type ListingStatsDTO struct {
+	ListingID int64                   `json:"listing_id"`
+	Views     int                     `json:"views"`
+	Favorites int                     `json:"favorites"`
+	Messages  int                     `json:"messages"`
+	Series    []*ListingStatsPointDTO `json:"series"`
+}
+
+// ToListingStatsDTO sums up a daily series, which must be in date order.
+func ToListingStatsDTO(listingID int64, series []*domain.ListingDailyStats) *ListingStatsDTO {
+	result := &ListingStatsDTO{ListingID: listingID, Series: make([]*ListingStatsPointDTO, 0, len(series))}
+	for _, day := range series {
+		result.Views += day.Views
+		result.Favorites += day.Favorites
+		result.Messages += day.Messages
+		result.Series = append(result.Series, &ListingStatsPointDTO{
+			Date:      day.Day.Format(time.DateOnly),
+			Views:     day.Views,
+			Favorites: day.Favorites,
+			Messages:  day.Messages,
+		})
+	}
+	return result
+}
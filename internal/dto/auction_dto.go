@@ -0,0 +1,103 @@
+package dto
+
+import (
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+type AuctionRequest struct {
+	StartPrice   int64     `json:"start_price"`
+	ReservePrice int64     `json:"reserve_price"`
+	MinIncrement int64     `json:"min_increment"`
+	EndsAt       time.Time `json:"ends_at"`
+}
+
+type BidRequest struct {
+	Amount int64 `json:"amount"`
+}
+
+type AuctionDTO struct {
+	ListingID    int64      `json:"listing_id"`
+	StartPrice   int64      `json:"start_price"`
+	MinIncrement int64      `json:"min_increment"`
+	ReserveMet   bool       `json:"reserve_met"`
+	EndsAt       time.Time  `json:"ends_at"`
+	CurrentPrice int64      `json:"current_price"`
+	MinNextBid   int64      `json:"min_next_bid"`
+	BidCount     int        `json:"bid_count"`
+	Status       string     `json:"status"`
+	WinnerID     *int64     `json:"winner_id,omitempty"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+}
+
+type BidDTO struct {
+	ID          int64     `json:"id"`
+	Amount      int64     `json:"amount"`
+	BidderLogin string    `json:"bidder_login"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type BidHistoryResponse struct {
+	Auction *AuctionDTO `json:"auction"`
+	Bids    []*BidDTO   `json:"bids"`
+}
+
+// ToAuctionDTO deliberately leaves out the reserve price and the leader:
+// bidders only learn whether the reserve has been reached. The winner is
+// shown only to the seller and to the winner.
+func ToAuctionDTO(auction *domain.Auction, sellerID int64, currentUserID *int64) *AuctionDTO {
+	if auction == nil {
+		return nil
+	}
+
+	minNextBid := auction.StartPrice
+	if auction.BidCount > 0 {
+		minNextBid = auction.CurrentPrice + auction.MinIncrement
+	}
+
+	var winnerID *int64
+	if auction.WinnerID != nil && currentUserID != nil &&
+		(*currentUserID == sellerID || *currentUserID == *auction.WinnerID) {
+		winnerID = auction.WinnerID
+	}
+
+	return &AuctionDTO{
+		ListingID:    auction.ListingID,
+		StartPrice:   auction.StartPrice,
+		MinIncrement: auction.MinIncrement,
+		ReserveMet:   auction.BidCount > 0 && auction.CurrentPrice >= auction.ReservePrice,
+		EndsAt:       auction.EndsAt,
+		CurrentPrice: auction.CurrentPrice,
+		MinNextBid:   minNextBid,
+		BidCount:     auction.BidCount,
+		Status:       auction.Status,
+		WinnerID:     winnerID,
+		ClosedAt:     auction.ClosedAt,
+	}
+}
+
+func ToBidDTO(bid *domain.Bid, bidderLogin string) *BidDTO {
+	if bid == nil {
+		return nil
+	}
+	return &BidDTO{
+		ID:          bid.ID,
+		Amount:      bid.Amount,
+		BidderLogin: MaskLogin(bidderLogin),
+		CreatedAt:   bid.CreatedAt,
+	}
+}
+
+// MaskLogin keeps the first and the last character of a login, e.g. "alice"
+// becomes "a***e". Short logins keep only the first character.
+func MaskLogin(login string) string {
+	runes := []rune(login)
+	switch {
+	case len(runes) == 0:
+		return "***"
+	case len(runes) <= 2:
+		return string(runes[0]) + "***"
+	default:
+		return string(runes[0]) + "***" + string(runes[len(runes)-1])
+	}
+}
@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"time"
 	"vk/ecom/internal/domain"
 )
 
@@ -18,3 +19,138 @@ func ToUserDTO(user *domain.User) *UserDTO {
 		Login: user.Login,
 	}
 }
+
+// UserProfileDTO is the public view of a user shown to other users.
+type UserProfileDTO struct {
+	ID          int      `json:"id"`
+	Login       string   `json:"login"`
+	Rating      *float64 `json:"rating"`
+	ReviewCount int      `json:"review_count"`
+}
+
+// LoginRequest is the login body. Session "cookie" asks for a cookie
+// session instead of a bearer token in the response.
+type LoginRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+	Session  string `json:"session,omitempty"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type PasswordResetRequest struct {
+	Login string `json:"login"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type TwoFactorEnrollmentDTO struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TwoFactorStatusDTO struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TwoFactorCodeRequest carries a TOTP code or a recovery code.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// TwoFactorLoginRequest is the second login step. Session works as in
+// LoginRequest.
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	Session        string `json:"session,omitempty"`
+}
+
+// ExternalLoginResult is the outcome of a provider callback: a token for a
+// login, or just the identity when a signed-in user linked one. Created is
+// set when the login created the user.
+type ExternalLoginResult struct {
+	Token    string
+	User     *domain.User
+	Identity *domain.Identity
+	Created  bool
+}
+
+// ContactDTO is the user's own view of their contact details, which the
+// public UserDTO leaves out.
+type ContactDTO struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Phone         string `json:"phone"`
+	PhoneVerified bool   `json:"phone_verified"`
+}
+
+func ToContactDTO(user *domain.User) *ContactDTO {
+	return &ContactDTO{
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Phone:         user.Phone,
+		PhoneVerified: user.PhoneVerified,
+	}
+}
+
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+type PhoneRequest struct {
+	Phone string `json:"phone"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type VerifyPhoneRequest struct {
+	Code string `json:"code"`
+}
+
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIKeyCreatedDTO carries a new key. Key is only ever shown once.
+type APIKeyCreatedDTO struct {
+	Key    string         `json:"key"`
+	APIKey *domain.APIKey `json:"api_key"`
+}
+
+// SessionDTO is a session as its owner sees it. Current marks the session
+// of the request.
+type SessionDTO struct {
+	ID         int64     `json:"id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+func ToSessionDTO(session *domain.Session, current bool) *SessionDTO {
+	return &SessionDTO{
+		ID:         session.ID,
+		IP:         session.IP,
+		UserAgent:  session.UserAgent,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		Current:    current,
+	}
+}
@@ -0,0 +1,52 @@
+package dto
+
+import (
+	"time"
+)
+
+type CartItemRequest struct {
+	ListingID int64 `json:"listing_id"`
+}
+
+type CheckoutRequest struct {
+	AcceptPriceChanges bool `json:"accept_price_changes"`
+}
+
+type CartItemDTO struct {
+	ListingID    int64     `json:"listing_id"`
+	Title        string    `json:"title"`
+	ImageURL     string    `json:"image_url"`
+	Price        int64     `json:"price"`
+	Currency     string    `json:"currency,omitempty"`
+	PriceAtAdd   int64     `json:"price_at_add"`
+	PriceChanged bool      `json:"price_changed"`
+	Available    bool      `json:"available"`
+	AddedAt      time.Time `json:"added_at"`
+}
+
+// CartSellerGroupDTO lists the cart items of one seller priced in one
+// currency; each group becomes a separate order on checkout.
+type CartSellerGroupDTO struct {
+	SellerID    int64          `json:"seller_id"`
+	SellerLogin string         `json:"seller_login"`
+	Currency    string         `json:"currency"`
+	Items       []*CartItemDTO `json:"items"`
+	Subtotal    int64          `json:"subtotal"`
+}
+
+type CartDTO struct {
+	Groups      []*CartSellerGroupDTO `json:"groups"`
+	Unavailable []*CartItemDTO        `json:"unavailable"`
+	ItemCount   int                   `json:"item_count"`
+	// Total sums the groups when they share one currency and is zero
+	// otherwise; Totals always has the sum per currency.
+	Total               int64            `json:"total"`
+	Totals              map[string]int64 `json:"totals"`
+	HasPriceChanges     bool             `json:"has_price_changes"`
+	HasUnavailableItems bool             `json:"has_unavailable_items"`
+	CartToken           string           `json:"cart_token,omitempty"`
+}
+
+type CheckoutResponse struct {
+	Orders []*OrderDTO `json:"orders"`
+}
@@ -1,50 +1,161 @@
 package dto
 
 import (
+	"sort"
 	"time"
 	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/geo"
+	"vk/ecom/internal/pkg/money"
 )
 
 type ListingRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	ImageURL    string `json:"image_url"`
-	Price       int64  `json:"price"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	ImageURL    string   `json:"image_url"`
+	Price       int64    `json:"price"`
+	Currency    string   `json:"currency,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	Latitude    *float64 `json:"latitude,omitempty"`
+	Longitude   *float64 `json:"longitude,omitempty"`
+	City        string   `json:"city,omitempty"`
+	Region      string   `json:"region,omitempty"`
+	// Attributes are validated against the category's attribute schema.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Type       string                 `json:"type,omitempty"`
+	Auction    *AuctionRequest        `json:"auction,omitempty"`
 }
 
 type ListingDTO struct {
-	ID           int64     `json:"id"`
-	Title        string    `json:"title"`
-	Description  string    `json:"description"`
-	ImageURL     string    `json:"image_url"`
-	Price        int64     `json:"price"`
-	AuthorID     int64     `json:"author_id"`
-	AuthorLogin  string    `json:"author_login"`
-	CreatedAt    time.Time `json:"created_at"`
-	IsOwnListing *bool     `json:"is_own_listing,omitempty"`
+	ID            int64                  `json:"id"`
+	Title         string                 `json:"title"`
+	Description   string                 `json:"description"`
+	ImageURL      string                 `json:"image_url"`
+	Price         int64                  `json:"price"`
+	Currency      string                 `json:"currency"`
+	DisplayPrice  *money.Money           `json:"display_price,omitempty"`
+	PreviousPrice *int64                 `json:"previous_price,omitempty"`
+	Category      string                 `json:"category,omitempty"`
+	Latitude      *float64               `json:"latitude,omitempty"`
+	Longitude     *float64               `json:"longitude,omitempty"`
+	City          string                 `json:"city,omitempty"`
+	Region        string                 `json:"region,omitempty"`
+	DistanceKm    *float64               `json:"distance_km,omitempty"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	AuthorID      int64                  `json:"author_id"`
+	AuthorLogin   string                 `json:"author_login"`
+	AuthorRating  *float64               `json:"author_rating,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	Type          string                 `json:"type"`
+	IsOwnListing  *bool                  `json:"is_own_listing,omitempty"`
+}
+
+// ListingSearchRequest holds the feed query parameters. Near is nil unless
+// the request asks for listings around a point.
+type ListingSearchRequest struct {
+	SortBy    string
+	SortOrder string
+	// Currency is the display currency: MinPrice, MaxPrice, price sorting
+	// and price facets use it, and listings get a DisplayPrice in it.
+	Currency string
+	Category string
+	MinPrice *int64
+	MaxPrice *int64
+	// Age is "today", "week" or "month" to keep only recent listings.
+	Age      string
+	Near     *geo.Point
+	RadiusKm float64
+	Page     int
+	PageSize int
+	// PriceDropped keeps only listings with a recent price drop.
+	PriceDropped bool
+	// Attributes maps "key" or "key.op" filters to their raw values, see
+	// domain.AttributeFilter.
+	Attributes map[string]string
+	// Facets names the facets to count: "category", "price" and "age".
+	// PriceBuckets optionally replaces the default price bucket bounds.
+	Facets       []string
+	PriceBuckets []int64
 }
 
 type ListingsResponse struct {
-	Listings   []*ListingDTO `json:"listings"`
-	Count      int           `json:"count"`
-	TotalCount int           `json:"total_count"`
-	Page       int           `json:"page"`
-	PageSize   int           `json:"page_size"`
-	TotalPages int           `json:"total_pages"`
+	Listings   []*ListingDTO     `json:"listings"`
+	Count      int               `json:"count"`
+	TotalCount int               `json:"total_count"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"page_size"`
+	TotalPages int               `json:"total_pages"`
+	Facets     *ListingFacetsDTO `json:"facets,omitempty"`
+}
+
+type ListingFacetsDTO struct {
+	Categories []FacetCountDTO  `json:"category,omitempty"`
+	Prices     []PriceBucketDTO `json:"price,omitempty"`
+	Ages       []FacetCountDTO  `json:"age,omitempty"`
+}
+
+type FacetCountDTO struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type PriceBucketDTO struct {
+	Min   int64  `json:"min"`
+	Max   *int64 `json:"max,omitempty"`
+	Count int    `json:"count"`
+}
+
+// ToListingFacetsDTO converts the facets, ordering categories by count and
+// then by name.
+func ToListingFacetsDTO(facets *domain.ListingFacets) *ListingFacetsDTO {
+	result := &ListingFacetsDTO{}
+	for _, c := range facets.Categories {
+		result.Categories = append(result.Categories, FacetCountDTO{Value: c.Value, Count: c.Count})
+	}
+	sort.Slice(result.Categories, func(i, j int) bool {
+		if result.Categories[i].Count != result.Categories[j].Count {
+			return result.Categories[i].Count > result.Categories[j].Count
+		}
+		return result.Categories[i].Value < result.Categories[j].Value
+	})
+	for _, p := range facets.Prices {
+		result.Prices = append(result.Prices, PriceBucketDTO{Min: p.Min, Max: p.Max, Count: p.Count})
+	}
+	for _, a := range facets.Ages {
+		result.Ages = append(result.Ages, FacetCountDTO{Value: a.Value, Count: a.Count})
+	}
+	return result
 }
 
 func ToListingDTO(listing *domain.Listing) *ListingDTO {
 	if listing == nil {
 		return nil
 	}
-	return &ListingDTO{
+	result := &ListingDTO{
 		ID:          listing.ID,
 		Title:       listing.Title,
 		Description: listing.Description,
 		ImageURL:    listing.ImageURL,
 		Price:       listing.Price,
+		Currency:    listing.Currency,
+		Category:    listing.Category,
+		Latitude:    listing.Latitude,
+		Longitude:   listing.Longitude,
+		City:        listing.City,
+		Region:      listing.Region,
+		DistanceKm:  listing.DistanceKm,
+		Attributes:  listing.Attributes,
 		AuthorID:    listing.AuthorID,
 		CreatedAt:   listing.CreatedAt,
+		Type:        listing.Type,
+	}
+	setPreviousPrice(result, listing)
+	return result
+}
+
+// setPreviousPrice shows the price before a recent drop.
+func setPreviousPrice(dto *ListingDTO, listing *domain.Listing) {
+	if previous, ok := listing.RecentPriceDrop(time.Now()); ok {
+		dto.PreviousPrice = &previous
 	}
 }
 
@@ -59,10 +170,20 @@ func ToListingDTOWithAuthor(listing *domain.Listing, authorLogin string, current
 		Description: listing.Description,
 		ImageURL:    listing.ImageURL,
 		Price:       listing.Price,
+		Currency:    listing.Currency,
+		Category:    listing.Category,
+		Latitude:    listing.Latitude,
+		Longitude:   listing.Longitude,
+		City:        listing.City,
+		Region:      listing.Region,
+		DistanceKm:  listing.DistanceKm,
+		Attributes:  listing.Attributes,
 		AuthorID:    listing.AuthorID,
 		AuthorLogin: authorLogin,
 		CreatedAt:   listing.CreatedAt,
+		Type:        listing.Type,
 	}
+	setPreviousPrice(dto, listing)
 
 	if currentUserID != nil {
 		isOwn := listing.AuthorID == *currentUserID
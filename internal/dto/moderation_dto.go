@@ -0,0 +1,115 @@
+package dto
+
+import (
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/fingerprint"
+)
+
+type ReportRequest struct {
+	Reason  string `json:"reason"`
+	Comment string `json:"comment"`
+}
+
+type ResolveCaseRequest struct {
+	Resolution string `json:"resolution"`
+	Note       string `json:"note"`
+}
+
+type ReportDTO struct {
+	ID         int64     `json:"id"`
+	ListingID  int64     `json:"listing_id"`
+	ReporterID int64     `json:"reporter_id,omitempty"`
+	Reason     string    `json:"reason"`
+	Comment    string    `json:"comment,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ModerationCaseDTO struct {
+	ID            int64        `json:"id"`
+	ListingID     int64        `json:"listing_id"`
+	ListingTitle  string       `json:"listing_title"`
+	ListingStatus string       `json:"listing_status"`
+	AuthorID      int64        `json:"author_id"`
+	Status        string       `json:"status"`
+	ReportCount   int          `json:"report_count"`
+	AutoHidden    bool         `json:"auto_hidden"`
+	ClaimedBy     *int64       `json:"claimed_by,omitempty"`
+	ClaimedAt     *time.Time   `json:"claimed_at,omitempty"`
+	Resolution    string       `json:"resolution,omitempty"`
+	ResolvedBy    *int64       `json:"resolved_by,omitempty"`
+	ResolvedAt    *time.Time   `json:"resolved_at,omitempty"`
+	Note          string       `json:"note,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+	Reports       []*ReportDTO `json:"reports,omitempty"`
+}
+
+func ToReportDTO(report *domain.Report) *ReportDTO {
+	if report == nil {
+		return nil
+	}
+	return &ReportDTO{
+		ID:         report.ID,
+		ListingID:  report.ListingID,
+		ReporterID: report.ReporterID,
+		Reason:     report.Reason,
+		Comment:    report.Comment,
+		CreatedAt:  report.CreatedAt,
+	}
+}
+
+// ToModerationCaseDTO converts a case; listing may be nil if it was deleted.
+func ToModerationCaseDTO(c *domain.ModerationCase, listing *domain.Listing) *ModerationCaseDTO {
+	if c == nil {
+		return nil
+	}
+	result := &ModerationCaseDTO{
+		ID:          c.ID,
+		ListingID:   c.ListingID,
+		Status:      c.Status,
+		ReportCount: c.ReportCount,
+		AutoHidden:  c.AutoHidden,
+		ClaimedBy:   c.ClaimedBy,
+		ClaimedAt:   c.ClaimedAt,
+		Resolution:  c.Resolution,
+		ResolvedBy:  c.ResolvedBy,
+		ResolvedAt:  c.ResolvedAt,
+		Note:        c.Note,
+		CreatedAt:   c.CreatedAt,
+	}
+	if listing != nil {
+		result.ListingTitle = listing.Title
+		result.ListingStatus = listing.Status
+		result.AuthorID = listing.AuthorID
+	}
+	return result
+}
+
+type NearDuplicateDTO struct {
+	ListingID       int64     `json:"listing_id"`
+	ListingTitle    string    `json:"listing_title"`
+	ListingStatus   string    `json:"listing_status"`
+	AuthorID        int64     `json:"author_id"`
+	TextDistance    int       `json:"text_distance"`
+	TextSimilarity  float64   `json:"text_similarity"`
+	ImageDistance   *int      `json:"image_distance,omitempty"`
+	ImageSimilarity float64   `json:"image_similarity,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func ToNearDuplicateDTO(duplicate *domain.NearDuplicate, listing *domain.Listing) *NearDuplicateDTO {
+	result := &NearDuplicateDTO{
+		ListingID:      duplicate.ListingID,
+		ListingTitle:   listing.Title,
+		ListingStatus:  listing.Status,
+		AuthorID:       duplicate.AuthorID,
+		TextDistance:   duplicate.TextDistance,
+		TextSimilarity: fingerprint.Similarity(duplicate.TextDistance),
+		ImageDistance:  duplicate.ImageDistance,
+		CreatedAt:      listing.CreatedAt,
+	}
+	if duplicate.ImageDistance != nil {
+		result.ImageSimilarity = fingerprint.Similarity(*duplicate.ImageDistance)
+	}
+	return result
+}
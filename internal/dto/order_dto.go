@@ -0,0 +1,58 @@
+package dto
+
+import (
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+// OrderRequest accepts either a single listing_id or several listing_ids of
+// the same seller.
+type OrderRequest struct {
+	ListingID  int64   `json:"listing_id"`
+	ListingIDs []int64 `json:"listing_ids"`
+}
+
+type OrderItemDTO struct {
+	ListingID    int64  `json:"listing_id"`
+	ListingTitle string `json:"listing_title"`
+	Price        int64  `json:"price"`
+}
+
+type OrderDTO struct {
+	ID        int64           `json:"id"`
+	BuyerID   int64           `json:"buyer_id"`
+	SellerID  int64           `json:"seller_id"`
+	Amount    int64           `json:"amount"`
+	Currency  string          `json:"currency"`
+	Status    string          `json:"status"`
+	Items     []*OrderItemDTO `json:"items"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+func ToOrderDTO(order *domain.Order, listingTitles map[int64]string) *OrderDTO {
+	if order == nil {
+		return nil
+	}
+
+	items := make([]*OrderItemDTO, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, &OrderItemDTO{
+			ListingID:    item.ListingID,
+			ListingTitle: listingTitles[item.ListingID],
+			Price:        item.Price,
+		})
+	}
+
+	return &OrderDTO{
+		ID:        order.ID,
+		BuyerID:   order.BuyerID,
+		SellerID:  order.SellerID,
+		Amount:    order.Amount,
+		Currency:  order.Currency,
+		Status:    order.Status,
+		Items:     items,
+		CreatedAt: order.CreatedAt,
+		UpdatedAt: order.UpdatedAt,
+	}
+}
@@ -0,0 +1,57 @@
+package dto
+
+import (
+	"math"
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+type ConfirmPurchaserRequest struct {
+	BuyerID int64 `json:"buyer_id"`
+}
+
+type ReviewRequest struct {
+	Rating int    `json:"rating"`
+	Text   string `json:"text"`
+}
+
+type ReviewReplyRequest struct {
+	Text string `json:"text"`
+}
+
+type ReviewDTO struct {
+	ID          int64      `json:"id"`
+	ListingID   int64      `json:"listing_id"`
+	Rating      int        `json:"rating"`
+	Text        string     `json:"text"`
+	AuthorLogin string     `json:"author_login"`
+	Reply       string     `json:"reply,omitempty"`
+	RepliedAt   *time.Time `json:"replied_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func ToReviewDTO(review *domain.Review, authorLogin string) *ReviewDTO {
+	if review == nil {
+		return nil
+	}
+	return &ReviewDTO{
+		ID:          review.ID,
+		ListingID:   review.ListingID,
+		Rating:      review.Rating,
+		Text:        review.Text,
+		AuthorLogin: authorLogin,
+		Reply:       review.Reply,
+		RepliedAt:   review.RepliedAt,
+		CreatedAt:   review.CreatedAt,
+	}
+}
+
+// RoundedRating returns the average rating rounded to two decimals, or nil
+// when the seller has not been reviewed yet.
+func RoundedRating(rating *domain.SellerRating) *float64 {
+	if rating == nil || rating.ReviewCount == 0 {
+		return nil
+	}
+	avg := math.Round(rating.Average()*100) / 100
+	return &avg
+}
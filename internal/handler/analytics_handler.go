@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrackListingEvent records message starts reported by the signed-in
+// client. Views are counted by GetListing and favorites by FavoriteListing.
+func (h *Handler) TrackListingEvent(c *gin.Context) {
+	if h.analyticsService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Analytics are not enabled"})
+		return
+	}
+
+	listingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	var req dto.ListingEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if req.Type != domain.ListingEventMessage {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be message"})
+		return
+	}
+
+	if err := h.analyticsService.TrackEvent(listingID, c.GetInt64("user_id"), req.Type); err != nil {
+		respondAnalyticsError(c, err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+func (h *Handler) GetListingStats(c *gin.Context) {
+	if h.analyticsService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Analytics are not enabled"})
+		return
+	}
+
+	listingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	days := 0
+	if daysStr := c.Query("days"); daysStr != "" {
+		if days, err = strconv.Atoi(daysStr); err != nil || days < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a positive number"})
+			return
+		}
+	}
+
+	stats, err := h.analyticsService.GetListingStats(listingID, c.GetInt64("user_id"), days)
+	if err != nil {
+		respondAnalyticsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+func visitorKey(c *gin.Context) string {
+	return service.VisitorKey(currentUserID(c), c.ClientIP(), c.Request.UserAgent())
+}
+
+func respondAnalyticsError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrListingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotListingOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidListingEvent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve listing stats"})
+	}
+}
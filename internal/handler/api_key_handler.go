@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyRoutes registers routes on group that also accept API keys
+// with the given scopes. Keys are refused on routes registered any other
+// way, so account settings stay reachable only with an interactive login.
+func (h *Handler) APIKeyRoutes(group *gin.RouterGroup, scopes ...string) *APIKeyRouter {
+	return &APIKeyRouter{handler: h, group: group, scopes: scopes}
+}
+
+// APIKeyRouter registers routes that accept API keys, see
+// Handler.APIKeyRoutes.
+type APIKeyRouter struct {
+	handler *Handler
+	group   *gin.RouterGroup
+	scopes  []string
+}
+
+func (r *APIKeyRouter) GET(relativePath string, handlers ...gin.HandlerFunc) {
+	r.handle(http.MethodGet, relativePath, handlers)
+}
+
+func (r *APIKeyRouter) POST(relativePath string, handlers ...gin.HandlerFunc) {
+	r.handle(http.MethodPost, relativePath, handlers)
+}
+
+func (r *APIKeyRouter) PUT(relativePath string, handlers ...gin.HandlerFunc) {
+	r.handle(http.MethodPut, relativePath, handlers)
+}
+
+// handle keys the scopes by method and full path, the way gin reports
+// the matched route in FullPath.
+func (r *APIKeyRouter) handle(method, relativePath string, handlers []gin.HandlerFunc) {
+	r.group.Handle(method, relativePath, handlers...)
+
+	fullPath := r.group.BasePath()
+	if relativePath != "" {
+		fullPath = path.Join(fullPath, relativePath)
+		if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(fullPath, "/") {
+			fullPath += "/"
+		}
+	}
+	r.handler.apiKeyScopes[method+" "+fullPath] = r.scopes
+}
+
+// authenticateAPIKey validates an API key sent as a bearer token and
+// checks its scopes against the matched route.
+func (h *Handler) authenticateAPIKey(c *gin.Context, token string) (*domain.User, *authError) {
+	user, key, err := h.authService.ValidateAPIKey(token)
+	if err != nil {
+		return nil, &authError{status: http.StatusUnauthorized, code: "invalid_token", description: "The API key is invalid or revoked"}
+	}
+
+	required, ok := h.apiKeyScopes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		return nil, &authError{status: http.StatusForbidden, code: "insufficient_scope", description: "This endpoint does not accept API keys"}
+	}
+	for _, scope := range required {
+		if !key.HasScope(scope) {
+			return nil, &authError{status: http.StatusForbidden, code: "insufficient_scope", description: "The API key lacks the required scope", scope: strings.Join(required, " ")}
+		}
+	}
+	return user, nil
+}
+
+func (h *Handler) GetAPIKeys(c *gin.Context) {
+	keys, err := h.authService.GetAPIKeys(int(c.GetInt64("user_id")))
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// CreateAPIKey responds with the new key, which cannot be retrieved later.
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var req dto.APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	created, err := h.authService.CreateAPIKey(int(c.GetInt64("user_id")), req.Name, req.Scopes)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	keyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid api key id"})
+		return
+	}
+
+	if err := h.authService.RevokeAPIKey(int(c.GetInt64("user_id")), keyID); err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAPIKeysDisabled):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidScope), errors.Is(err, service.ErrInvalidAPIKeyName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrScopeNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTooManyAPIKeys):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to manage api keys"})
+	}
+}
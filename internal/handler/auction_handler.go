@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) PlaceBid(c *gin.Context) {
+	if h.auctionService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Auctions are not enabled"})
+		return
+	}
+
+	listingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	var req dto.BidRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	auction, err := h.auctionService.PlaceBid(listingID, c.GetInt64("user_id"), req.Amount)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrBidTooLow):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrAuctionEnded), errors.Is(err, service.ErrAuctionUnavailable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrOwnAuctionBid):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNotAnAuction):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrListingNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to place bid"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"auction": auction})
+}
+
+func (h *Handler) GetBids(c *gin.Context) {
+	if h.auctionService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Auctions are not enabled"})
+		return
+	}
+
+	listingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	history, err := h.auctionService.GetBidHistory(listingID, currentUserID(c))
+	if err != nil {
+		if errors.Is(err, service.ErrNotAnAuction) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bids"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"vk/ecom/internal/domain"
 	"vk/ecom/internal/dto"
+	"vk/ecom/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,7 +22,7 @@ func (h *Handler) Register(c *gin.Context) {
 
 	fmt.Printf("User data: %v\n", user)
 
-	if user, err := h.authService.RegisterUser(user.Login, user.Password); err != nil {
+	if user, err := h.authService.RegisterUserWithEmail(user.Login, user.Password, user.Email); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	} else {
 		c.JSON(http.StatusCreated, gin.H{
@@ -26,17 +30,163 @@ func (h *Handler) Register(c *gin.Context) {
 	}
 }
 func (h *Handler) Login(c *gin.Context) {
-	var user domain.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var req dto.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
+	cookieSession := req.Session == loginSessionCookie
+	if cookieSession && h.sessions == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cookie sessions are not enabled"})
+		return
+	}
 
-	token, u, err := h.authService.LoginUser(user.Login, user.Password)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid login or password"})
+	token, u, err := h.authService.LoginUserFrom(req.Login, req.Password, clientInfo(c))
+	var challenge *service.TwoFactorRequiredError
+	if errors.As(err, &challenge) {
+		c.JSON(http.StatusOK, gin.H{"two_factor_required": true, "challenge_token": challenge.ChallengeToken})
+		return
+	}
+	if respondLoginError(c, err, "Invalid login or password") {
+		return
+	}
+
+	h.completeLogin(c, token, u, cookieSession)
+}
+
+// respondLoginError writes the response for a failed login step and
+// reports whether there was one.
+func respondLoginError(c *gin.Context, err error, invalid string) bool {
+	var throttled *service.LoginThrottledError
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &throttled):
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(throttled.RetryAfter)))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many login attempts, try again later"})
+	case errors.Is(err, service.ErrUserBanned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is banned"})
+	default:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": invalid})
+	}
+	return true
+}
+
+// completeLogin hands out the token of a finished login, as a bearer
+// token or a cookie session.
+func (h *Handler) completeLogin(c *gin.Context, token string, u *domain.User, cookieSession bool) {
+	if token := c.GetHeader(cartTokenHeader); token != "" && h.cartService != nil {
+		if err := h.cartService.MergeAnonymousCart(token, int64(u.ID)); err != nil {
+			log.Println("Failed to merge anonymous cart:", err)
+		}
+	}
+
+	if cookieSession {
+		csrf, err := h.startSession(c, token)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"csrf_token": csrf, "user_id": u.ID, "login": u.Login})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"token": token, "user_id": u.ID, "login": u.Login})
 }
+
+// GetLockouts lists the accounts and client addresses locked out after
+// failed logins.
+func (h *Handler) GetLockouts(c *gin.Context) {
+	lockouts, err := h.authService.GetLockouts()
+	if err != nil {
+		respondLockoutError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lockouts": lockouts})
+}
+
+// ClearLockout lifts a lockout; kind is "login" or "ip".
+func (h *Handler) ClearLockout(c *gin.Context) {
+	if err := h.authService.ClearLockout(c.Param("kind"), c.Param("value")); err != nil {
+		respondLockoutError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondLockoutError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrLoginThrottleDisabled):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidLockoutKind):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update lockouts"})
+	}
+}
+
+// ChangePassword sets a new password for the signed-in user, who has to
+// confirm the current one.
+func (h *Handler) ChangePassword(c *gin.Context) {
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	err := h.authService.ChangePassword(int(c.GetInt64("user_id")), req.CurrentPassword, req.NewPassword)
+	switch {
+	case err == nil:
+		c.Status(http.StatusNoContent)
+	case errors.Is(err, service.ErrWrongPassword):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrWeakPassword), errors.Is(err, service.ErrCommonPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+	}
+}
+
+// RequestPasswordReset always answers 202 for a well-formed request, so it
+// cannot be used to find out which logins exist.
+func (h *Handler) RequestPasswordReset(c *gin.Context) {
+	var req dto.PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Login == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(req.Login); err != nil {
+		if errors.Is(err, service.ErrPasswordResetDisabled) {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+			return
+		}
+		log.Println("Failed to send password reset:", err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account has an email address, a reset link was sent to it"})
+}
+
+// ConfirmPasswordReset sets a new password with a mailed reset token.
+func (h *Handler) ConfirmPasswordReset(c *gin.Context) {
+	var req dto.PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	err := h.authService.ResetPassword(req.Token, req.NewPassword)
+	switch {
+	case err == nil:
+		c.Status(http.StatusNoContent)
+	case errors.Is(err, service.ErrPasswordResetDisabled):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidResetToken), errors.Is(err, service.ErrWeakPassword),
+		errors.Is(err, service.ErrCommonPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+	}
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const cartTokenHeader = "X-Cart-Token"
+
+func (h *Handler) GetCart(c *gin.Context) {
+	if h.cartService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Cart is not enabled"})
+		return
+	}
+
+	cartKey, token, ok := h.resolveCart(c, false)
+	if !ok {
+		return
+	}
+	if cartKey == "" {
+		c.JSON(http.StatusOK, &dto.CartDTO{Groups: []*dto.CartSellerGroupDTO{}, Unavailable: []*dto.CartItemDTO{}})
+		return
+	}
+
+	cart, err := h.cartService.GetCart(cartKey, currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve cart"})
+		return
+	}
+	cart.CartToken = token
+
+	c.JSON(http.StatusOK, cart)
+}
+
+func (h *Handler) AddCartItem(c *gin.Context) {
+	if h.cartService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Cart is not enabled"})
+		return
+	}
+
+	var req dto.CartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ListingID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	cartKey, token, ok := h.resolveCart(c, true)
+	if !ok {
+		return
+	}
+
+	cart, err := h.cartService.AddItem(cartKey, req.ListingID, currentUserID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrListingNotAddable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrCartFull):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cart"})
+		}
+		return
+	}
+	cart.CartToken = token
+
+	c.JSON(http.StatusOK, cart)
+}
+
+func (h *Handler) RemoveCartItem(c *gin.Context) {
+	if h.cartService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Cart is not enabled"})
+		return
+	}
+
+	listingID, err := strconv.ParseInt(c.Param("listing_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	cartKey, token, ok := h.resolveCart(c, false)
+	if !ok {
+		return
+	}
+	if cartKey == "" {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	cart, err := h.cartService.RemoveItem(cartKey, listingID, currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cart"})
+		return
+	}
+	cart.CartToken = token
+
+	c.JSON(http.StatusOK, cart)
+}
+
+func (h *Handler) Checkout(c *gin.Context) {
+	if h.cartService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Cart is not enabled"})
+		return
+	}
+
+	var req dto.CheckoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+	}
+
+	response, err := h.cartService.Checkout(c.GetInt64("user_id"), req.AcceptPriceChanges)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrCartEmpty):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrCartItemUnavailable), errors.Is(err, service.ErrCartPriceChanged):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			respondOrderError(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// resolveCart picks the signed-in user's cart or the anonymous cart named by
+// the X-Cart-Token header. With create set, a new anonymous token is issued
+// when the request carries none.
+func (h *Handler) resolveCart(c *gin.Context, create bool) (cartKey, token string, ok bool) {
+	if userID := currentUserID(c); userID != nil {
+		return service.UserCartKey(*userID), "", true
+	}
+
+	token = c.GetHeader(cartTokenHeader)
+	if token == "" {
+		if !create {
+			return "", "", true
+		}
+		var err error
+		if token, err = service.NewCartToken(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create cart"})
+			return "", "", false
+		}
+	}
+
+	cartKey, err := service.AnonymousCartKey(token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", "", false
+	}
+	c.Header(cartTokenHeader, token)
+	return cartKey, token, true
+}
+
+func currentUserID(c *gin.Context) *int64 {
+	if userID, exists := c.Get("user_id"); exists {
+		if id, ok := userID.(int64); ok {
+			return &id
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) GetContactDetails(c *gin.Context) {
+	contact, err := h.authService.GetContactDetails(int(c.GetInt64("user_id")))
+	if err != nil {
+		respondContactError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, contact)
+}
+
+// UpdateEmail sets an unverified address and mails a verification link.
+func (h *Handler) UpdateEmail(c *gin.Context) {
+	var req dto.EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.authService.UpdateEmail(int(c.GetInt64("user_id")), req.Email); err != nil {
+		respondContactError(c, err)
+		return
+	}
+
+	h.GetContactDetails(c)
+}
+
+// UpdatePhone sets an unverified number and texts a verification code.
+func (h *Handler) UpdatePhone(c *gin.Context) {
+	var req dto.PhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.authService.UpdatePhone(int(c.GetInt64("user_id")), req.Phone); err != nil {
+		respondContactError(c, err)
+		return
+	}
+
+	h.GetContactDetails(c)
+}
+
+func (h *Handler) ResendEmailVerification(c *gin.Context) {
+	if err := h.authService.SendEmailVerification(int(c.GetInt64("user_id"))); err != nil {
+		respondContactError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
+
+func (h *Handler) ResendPhoneVerification(c *gin.Context) {
+	if err := h.authService.SendPhoneVerification(int(c.GetInt64("user_id"))); err != nil {
+		respondContactError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification code sent"})
+}
+
+// VerifyEmail takes the token from a verification link; it needs no
+// session.
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.authService.VerifyEmail(req.Token); err != nil {
+		respondContactError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) VerifyPhone(c *gin.Context) {
+	var req dto.VerifyPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.authService.VerifyPhone(int(c.GetInt64("user_id")), req.Code); err != nil {
+		respondContactError(c, err)
+		return
+	}
+
+	h.GetContactDetails(c)
+}
+
+func respondContactError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrContactVerificationDisabled):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidEmail), errors.Is(err, service.ErrInvalidPhone),
+		errors.Is(err, service.ErrInvalidVerification):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEmailTaken), errors.Is(err, service.ErrPhoneTaken),
+		errors.Is(err, service.ErrNoEmail), errors.Is(err, service.ErrNoPhone),
+		errors.Is(err, service.ErrAlreadyVerified):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact details"})
+	}
+}
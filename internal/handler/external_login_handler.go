@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+	"vk/ecom/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// loginStateCookie binds a login or link to the browser that started
+	// it, so a callback URL cannot be replayed in a victim's browser.
+	loginStateCookie = "oidc_state"
+	loginStatePath   = "/api/auth/oidc"
+	loginStateMaxAge = 10 * time.Minute
+)
+
+func (h *Handler) GetLoginProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.authService.ExternalLoginProviders()})
+}
+
+// BeginExternalLogin redirects the browser to the provider's login page.
+func (h *Handler) BeginExternalLogin(c *gin.Context) {
+	authURL, err := h.authService.BeginExternalLogin(c.Param("provider"), 0)
+	if err != nil {
+		respondExternalLoginError(c, err)
+		return
+	}
+	if err := h.bindLoginState(c, authURL); err != nil {
+		respondExternalLoginError(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// ExternalLoginCallback finishes a login or an identity link when the
+// provider redirects back. Logins respond like Login.
+func (h *Handler) ExternalLoginCallback(c *gin.Context) {
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Login was not completed: %s", providerErr)})
+		return
+	}
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing code or state"})
+		return
+	}
+	bound, _ := c.Cookie(loginStateCookie)
+	h.setLoginStateCookie(c, "", -1)
+	if subtle.ConstantTimeCompare([]byte(bound), []byte(state)) != 1 {
+		respondExternalLoginError(c, service.ErrInvalidLoginState)
+		return
+	}
+
+	result, err := h.authService.CompleteExternalLogin(c.Param("provider"), code, state, clientInfo(c))
+	var challenge *service.TwoFactorRequiredError
+	if errors.As(err, &challenge) {
+		c.JSON(http.StatusOK, gin.H{"two_factor_required": true, "challenge_token": challenge.ChallengeToken})
+		return
+	}
+	if errors.Is(err, service.ErrUserBanned) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is banned"})
+		return
+	}
+	if err != nil {
+		respondExternalLoginError(c, err)
+		return
+	}
+
+	if result.Token == "" {
+		c.JSON(http.StatusCreated, gin.H{"identity": result.Identity})
+		return
+	}
+	h.completeLogin(c, result.Token, result.User, false)
+}
+
+func (h *Handler) GetIdentities(c *gin.Context) {
+	identities, err := h.authService.GetIdentities(int(c.GetInt64("user_id")))
+	if err != nil {
+		respondExternalLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// LinkIdentity returns the provider URL at which the signed-in user links
+// an identity; the callback completes the link.
+func (h *Handler) LinkIdentity(c *gin.Context) {
+	authURL, err := h.authService.BeginExternalLogin(c.Param("provider"), int(c.GetInt64("user_id")))
+	if err != nil {
+		respondExternalLoginError(c, err)
+		return
+	}
+	if err := h.bindLoginState(c, authURL); err != nil {
+		respondExternalLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+func (h *Handler) UnlinkIdentity(c *gin.Context) {
+	if err := h.authService.UnlinkIdentity(int(c.GetInt64("user_id")), c.Param("provider")); err != nil {
+		respondExternalLoginError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// bindLoginState stores the state of authURL in an HttpOnly cookie that the
+// callback must present.
+func (h *Handler) bindLoginState(c *gin.Context, authURL string) error {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		return err
+	}
+	state := parsed.Query().Get("state")
+	if state == "" {
+		return errors.New("authorization URL has no state")
+	}
+	h.setLoginStateCookie(c, state, int(loginStateMaxAge/time.Second))
+	return nil
+}
+
+// setLoginStateCookie uses SameSite=Lax, as the callback is a cross-site
+// top-level redirect from the provider.
+func (h *Handler) setLoginStateCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     loginStateCookie,
+		Value:    value,
+		Path:     loginStatePath,
+		MaxAge:   maxAge,
+		Secure:   h.sessions == nil || h.sessions.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func respondExternalLoginError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrExternalLoginUnavailable):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUnknownProvider):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidLoginState):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrExternalLoginFailed):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrIdentityLinked), errors.Is(err, service.ErrLastLoginMethod):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrLoginProviderFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Login provider is unavailable"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+	}
+}
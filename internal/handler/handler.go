@@ -2,16 +2,75 @@ package handler
 
 import (
 	"vk/ecom/internal/interfaces"
+	"vk/ecom/internal/pkg/ratelimit"
 )
 
 type Handler struct {
-	authService    interfaces.AuthServiceInterface
-	listingService interfaces.ListingServiceInterface
+	authService       interfaces.AuthServiceInterface
+	listingService    interfaces.ListingServiceInterface
+	auctionService    interfaces.AuctionServiceInterface
+	orderService      interfaces.OrderServiceInterface
+	cartService       interfaces.CartServiceInterface
+	reviewService     interfaces.ReviewServiceInterface
+	moderationService interfaces.ModerationServiceInterface
+	analyticsService  interfaces.AnalyticsServiceInterface
+	// sessions is optional, see WithCookieSessions.
+	sessions *CookieSessionConfig
+	// rateLimiter is optional, see WithRateLimiter.
+	rateLimiter *ratelimit.Limiter
+	// apiKeyScopes maps "METHOD /full/path" to the scopes an API key needs
+	// there. It is filled by APIKeyRoutes.
+	apiKeyScopes map[string][]string
 }
 
-func NewHandler(authService interfaces.AuthServiceInterface, listingService interfaces.ListingServiceInterface) *Handler {
-	return &Handler{
+// Option wires an optional service into the Handler. Routes backed by a
+// service that was not provided respond with 501 Not Implemented.
+type Option func(*Handler)
+
+func WithAuctionService(auctionService interfaces.AuctionServiceInterface) Option {
+	return func(h *Handler) {
+		h.auctionService = auctionService
+	}
+}
+
+func WithOrderService(orderService interfaces.OrderServiceInterface) Option {
+	return func(h *Handler) {
+		h.orderService = orderService
+	}
+}
+
+func WithCartService(cartService interfaces.CartServiceInterface) Option {
+	return func(h *Handler) {
+		h.cartService = cartService
+	}
+}
+
+func WithReviewService(reviewService interfaces.ReviewServiceInterface) Option {
+	return func(h *Handler) {
+		h.reviewService = reviewService
+	}
+}
+
+func WithModerationService(moderationService interfaces.ModerationServiceInterface) Option {
+	return func(h *Handler) {
+		h.moderationService = moderationService
+	}
+}
+
+func WithAnalyticsService(analyticsService interfaces.AnalyticsServiceInterface) Option {
+	return func(h *Handler) {
+		h.analyticsService = analyticsService
+	}
+}
+
+func NewHandler(authService interfaces.AuthServiceInterface, listingService interfaces.ListingServiceInterface, opts ...Option) *Handler {
+	h := &Handler{
 		authService:    authService,
 		listingService: listingService,
+		apiKeyScopes:   make(map[string][]string),
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
 }
@@ -1,10 +1,18 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"vk/ecom/internal/domain"
 	"vk/ecom/internal/dto"
+	"vk/ecom/internal/pkg/geo"
+	"vk/ecom/internal/pkg/money"
+	"vk/ecom/internal/pkg/screening"
+	"vk/ecom/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,7 +24,33 @@ func (h *Handler) CreateListing(c *gin.Context) {
 		return
 	}
 
+	if req.Type == domain.ListingTypeAuction {
+		if h.auctionService == nil {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "Auctions are not enabled"})
+			return
+		}
+		listing, err := h.auctionService.CreateAuction(&req, c.GetInt64("user_id"))
+		if respondScreeningRejection(c, err) || respondEmailNotVerified(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidAuctionArg) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to create auction"})
+			return
+		}
+		c.JSON(201, gin.H{
+			"listing": listing,
+		})
+		return
+	}
+
 	listing, err := h.listingService.CreateListing(&req, c.GetInt64("user_id"))
+	if respondScreeningRejection(c, err) || respondEmailNotVerified(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
@@ -27,9 +61,205 @@ func (h *Handler) CreateListing(c *gin.Context) {
 	})
 }
 
+func (h *Handler) UpdateListing(c *gin.Context) {
+	listingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	var req dto.ListingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	listing, err := h.listingService.UpdateListing(listingID, c.GetInt64("user_id"), &req)
+	if respondScreeningRejection(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrListingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotListingOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrListingNotEditable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"listing": listing})
+	}
+}
+
+// respondScreeningRejection writes a 422 with the screening findings if err
+// is a content screening rejection and reports whether it did.
+func respondScreeningRejection(c *gin.Context, err error) bool {
+	var rejected *screening.RejectedError
+	if !errors.As(err, &rejected) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"error": rejected.Error(), "violations": rejected.Findings})
+	return true
+}
+
+func respondEmailNotVerified(c *gin.Context, err error) bool {
+	if !errors.Is(err, service.ErrEmailNotVerified) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	return true
+}
+
+func (h *Handler) GetTrendingListings(c *gin.Context) {
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if val, err := strconv.Atoi(limitStr); err == nil && val > 0 {
+			limit = val
+		}
+	}
+
+	listings, err := h.listingService.GetTrendingListings(limit, c.Query("currency"), currentUserID(c))
+	if errors.Is(err, money.ErrUnsupportedCurrency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve trending listings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"listings": listings})
+}
+
+// GetListing serves the listing detail page and counts the view for the
+// seller's stats. Authors viewing their own listing are not counted.
+func (h *Handler) GetListing(c *gin.Context) {
+	listingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	userID := currentUserID(c)
+	listing, err := h.listingService.GetListing(listingID, c.Query("currency"), userID)
+	switch {
+	case errors.Is(err, service.ErrListingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, money.ErrUnsupportedCurrency):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve listing"})
+		return
+	}
+
+	if h.analyticsService != nil && (userID == nil || *userID != listing.AuthorID) {
+		h.analyticsService.TrackView(listingID, visitorKey(c))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"listing": listing})
+}
+
+func (h *Handler) GetSimilarListings(c *gin.Context) {
+	listingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	var currentUserID *int64
+	if userID, exists := c.Get("user_id"); exists {
+		if id, ok := userID.(int64); ok {
+			currentUserID = &id
+		}
+	}
+
+	listings, err := h.listingService.GetSimilarListings(listingID, c.Query("currency"), currentUserID)
+	switch {
+	case errors.Is(err, service.ErrListingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, money.ErrUnsupportedCurrency):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve similar listings"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"listings": listings})
+	}
+}
+
+// GetPriceHistory returns every price change of a listing, oldest first.
+func (h *Handler) GetPriceHistory(c *gin.Context) {
+	listingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	history, err := h.listingService.GetPriceHistory(listingID, currentUserID(c))
+	switch {
+	case errors.Is(err, service.ErrListingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve price history"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"price_history": history})
+	}
+}
+
+// FavoriteListing saves the listing to the user's favorites, which
+// subscribes them to its price drops.
+func (h *Handler) FavoriteListing(c *gin.Context) {
+	listingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	if err := h.listingService.AddFavorite(listingID, c.GetInt64("user_id")); err != nil {
+		respondFavoriteError(c, err)
+		return
+	}
+	// The only place favorites are counted; clients cannot report them.
+	if h.analyticsService != nil {
+		_ = h.analyticsService.TrackEvent(listingID, c.GetInt64("user_id"), domain.ListingEventFavorite)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) UnfavoriteListing(c *gin.Context) {
+	listingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	if err := h.listingService.RemoveFavorite(listingID, c.GetInt64("user_id")); err != nil {
+		respondFavoriteError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondFavoriteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrListingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrFavoritesDisabled):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update favorites"})
+	}
+}
+
 func (h *Handler) GetListings(c *gin.Context) {
 	sortBy := c.DefaultQuery("sort", "date")
-	sortOrder := c.DefaultQuery("order", "desc")
+	// An empty order lets the service pick: ascending for distance,
+	// descending otherwise.
+	sortOrder := c.Query("order")
 
 	page := 1
 	if pageStr := c.Query("page"); pageStr != "" {
@@ -62,6 +292,60 @@ func (h *Handler) GetListings(c *gin.Context) {
 		return
 	}
 
+	var near *geo.Point
+	if nearStr := c.Query("near"); nearStr != "" {
+		point, ok := parsePoint(nearStr)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrInvalidLocation.Error()})
+			return
+		}
+		near = &point
+	}
+	var radiusKm float64
+	if radiusStr := c.Query("radius_km"); radiusStr != "" {
+		val, err := strconv.ParseFloat(radiusStr, 64)
+		if err != nil || !(val > 0) || math.IsInf(val, 1) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "radius_km must be a positive number"})
+			return
+		}
+		radiusKm = val
+	}
+
+	var priceDropped bool
+	if droppedStr := c.Query("price_dropped"); droppedStr != "" {
+		val, err := strconv.ParseBool(droppedStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "price_dropped must be true or false"})
+			return
+		}
+		priceDropped = val
+	}
+
+	var facets []string
+	if facetsStr := c.Query("facets"); facetsStr != "" {
+		for _, facet := range strings.Split(facetsStr, ",") {
+			facets = append(facets, strings.TrimSpace(facet))
+		}
+	}
+	var priceBuckets []int64
+	if bucketsStr := c.Query("price_buckets"); bucketsStr != "" {
+		for _, bound := range strings.Split(bucketsStr, ",") {
+			val, err := strconv.ParseInt(strings.TrimSpace(bound), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrInvalidPriceBuckets.Error()})
+				return
+			}
+			priceBuckets = append(priceBuckets, val)
+		}
+	}
+
+	attributes := make(map[string]string)
+	for name, values := range c.Request.URL.Query() {
+		if key, ok := strings.CutPrefix(name, "attr."); ok && len(values) > 0 {
+			attributes[key] = values[0]
+		}
+	}
+
 	var currentUserID *int64
 	if userID, exists := c.Get("user_id"); exists {
 		if id, ok := userID.(int64); ok {
@@ -71,11 +355,69 @@ func (h *Handler) GetListings(c *gin.Context) {
 
 	fmt.Println("Current User ID:", currentUserID)
 
-	response, err := h.listingService.GetListingsWithPagination(sortBy, sortOrder, minPrice, maxPrice, page, pageSize, currentUserID)
+	response, err := h.listingService.SearchListings(&dto.ListingSearchRequest{
+		SortBy:       sortBy,
+		SortOrder:    sortOrder,
+		Category:     c.Query("category"),
+		Attributes:   attributes,
+		MinPrice:     minPrice,
+		MaxPrice:     maxPrice,
+		Currency:     c.Query("currency"),
+		Age:          c.Query("age"),
+		Near:         near,
+		RadiusKm:     radiusKm,
+		Page:         page,
+		PageSize:     pageSize,
+		PriceDropped: priceDropped,
+		Facets:       facets,
+		PriceBuckets: priceBuckets,
+	}, currentUserID)
 	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidLocation), errors.Is(err, service.ErrDistanceSortWithoutLocation),
+			errors.Is(err, service.ErrInvalidAgeFilter), errors.Is(err, service.ErrInvalidFacet),
+			errors.Is(err, service.ErrInvalidPriceBuckets), errors.Is(err, service.ErrInvalidAttributeFilter),
+			errors.Is(err, service.ErrAttributeFilterCategory), errors.Is(err, money.ErrUnsupportedCurrency):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve listings"})
 		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
+
+// GetCategoryAttributes returns the attribute schema of a category so
+// clients can render listing forms and filters.
+func (h *Handler) GetCategoryAttributes(c *gin.Context) {
+	schema, err := h.listingService.GetCategorySchema(c.Param("category"))
+	if err != nil {
+		if errors.Is(err, service.ErrCategoryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve category attributes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schema)
+}
+
+// parsePoint parses "lat,lng".
+func parsePoint(value string) (geo.Point, bool) {
+	latStr, lngStr, ok := strings.Cut(value, ",")
+	if !ok {
+		return geo.Point{}, false
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	if err != nil {
+		return geo.Point{}, false
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(lngStr), 64)
+	if err != nil {
+		return geo.Point{}, false
+	}
+	point := geo.Point{Lat: lat, Lng: lng}
+	return point, point.Valid()
+}
@@ -2,21 +2,22 @@ package handler
 
 import (
 	"net/http"
+	"vk/ecom/internal/domain"
 
 	"github.com/gin-gonic/gin"
 )
 
+// AuthMiddleware requires a bearer token or, with cookie sessions, a
+// session cookie. Failures carry an RFC 6750 WWW-Authenticate challenge.
 func (h *Handler) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+		user, fromCookie, authErr := h.authenticate(c)
+		if authErr != nil {
+			abortWithChallenge(c, authErr)
 			return
 		}
-
-		user, err := h.authService.ValidateToken(authHeader)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		if fromCookie && !h.checkCSRF(c) {
+			abortCSRF(c)
 			return
 		}
 		c.Set("user", user)
@@ -25,18 +26,18 @@ func (h *Handler) AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// OptionalAuthMiddleware treats requests with missing or invalid
+// credentials as anonymous. A valid cookie session still needs its CSRF
+// token on state-changing requests.
 func (h *Handler) OptionalAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-
-		if authHeader == "" {
+		user, fromCookie, authErr := h.authenticate(c)
+		if authErr != nil {
 			c.Next()
 			return
 		}
-
-		user, err := h.authService.ValidateToken(authHeader)
-		if err != nil {
-			c.Next()
+		if fromCookie && !h.checkCSRF(c) {
+			abortCSRF(c)
 			return
 		}
 
@@ -45,3 +46,18 @@ func (h *Handler) OptionalAuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// StaffMiddleware must run after AuthMiddleware. The role comes from the
+// user record loaded during token validation, so demoting a user takes
+// effect immediately.
+func (h *Handler) StaffMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("user")
+		user, ok := value.(*domain.User)
+		if !ok || user.Role != domain.UserRoleStaff {
+			abortWithChallenge(c, &authError{status: http.StatusForbidden, code: "insufficient_scope", description: "Staff access required"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) ReportListing(c *gin.Context) {
+	if h.moderationService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Moderation is not enabled"})
+		return
+	}
+
+	listingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	var req dto.ReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	report, err := h.moderationService.ReportListing(listingID, c.GetInt64("user_id"), &req)
+	if err != nil {
+		respondModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"report": report})
+}
+
+func (h *Handler) GetModerationQueue(c *gin.Context) {
+	if h.moderationService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Moderation is not enabled"})
+		return
+	}
+
+	cases, err := h.moderationService.GetQueue(c.Query("status"))
+	if err != nil {
+		respondModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cases": cases})
+}
+
+func (h *Handler) GetModerationCase(c *gin.Context) {
+	h.moderationCaseAction(c, func(caseID, staffID int64) (*dto.ModerationCaseDTO, error) {
+		return h.moderationService.GetCase(caseID)
+	})
+}
+
+func (h *Handler) ClaimModerationCase(c *gin.Context) {
+	h.moderationCaseAction(c, func(caseID, staffID int64) (*dto.ModerationCaseDTO, error) {
+		return h.moderationService.ClaimCase(caseID, staffID)
+	})
+}
+
+func (h *Handler) ResolveModerationCase(c *gin.Context) {
+	var req dto.ResolveCaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	h.moderationCaseAction(c, func(caseID, staffID int64) (*dto.ModerationCaseDTO, error) {
+		return h.moderationService.ResolveCase(caseID, staffID, &req)
+	})
+}
+
+func (h *Handler) GetListingDuplicates(c *gin.Context) {
+	if h.moderationService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Moderation is not enabled"})
+		return
+	}
+
+	listingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	duplicates, err := h.moderationService.FindDuplicates(listingID)
+	if err != nil {
+		respondModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"duplicates": duplicates})
+}
+
+func (h *Handler) moderationCaseAction(c *gin.Context, action func(caseID, staffID int64) (*dto.ModerationCaseDTO, error)) {
+	if h.moderationService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Moderation is not enabled"})
+		return
+	}
+
+	caseID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid case id"})
+		return
+	}
+
+	result, err := action(caseID, c.GetInt64("user_id"))
+	if err != nil {
+		respondModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"case": result})
+}
+
+func respondModerationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrListingNotFound), errors.Is(err, service.ErrCaseNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrReportRateLimited):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAlreadyReported), errors.Is(err, service.ErrCaseClaimed),
+		errors.Is(err, service.ErrCaseNotClaimed), errors.Is(err, service.ErrCaseResolved):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidReportReason), errors.Is(err, service.ErrReportTooLong),
+		errors.Is(err, service.ErrOwnListingReport), errors.Is(err, service.ErrInvalidCaseStatus),
+		errors.Is(err, service.ErrInvalidResolution):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process moderation request"})
+	}
+}
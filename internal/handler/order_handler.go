@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/pkg/payment"
+	"vk/ecom/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) CreateOrder(c *gin.Context) {
+	if h.orderService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Orders are not enabled"})
+		return
+	}
+
+	var req dto.OrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	listingIDs := req.ListingIDs
+	if req.ListingID > 0 {
+		listingIDs = append(listingIDs, req.ListingID)
+	}
+	if len(listingIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "listing_id is required"})
+		return
+	}
+
+	order, err := h.orderService.CreateOrder(listingIDs, c.GetInt64("user_id"))
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"order": order})
+}
+
+func (h *Handler) GetOrders(c *gin.Context) {
+	if h.orderService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Orders are not enabled"})
+		return
+	}
+
+	role := c.DefaultQuery("role", service.OrderRoleBuyer)
+	if role != service.OrderRoleBuyer && role != service.OrderRoleSeller {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be buyer or seller"})
+		return
+	}
+
+	orders, err := h.orderService.GetOrders(c.GetInt64("user_id"), role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve orders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"orders": orders})
+}
+
+func (h *Handler) GetOrder(c *gin.Context) {
+	h.orderAction(c, h.orderService.GetOrder)
+}
+
+func (h *Handler) PayOrder(c *gin.Context) {
+	h.orderAction(c, h.orderService.PayOrder)
+}
+
+func (h *Handler) ShipOrder(c *gin.Context) {
+	h.orderAction(c, h.orderService.ShipOrder)
+}
+
+func (h *Handler) CompleteOrder(c *gin.Context) {
+	h.orderAction(c, h.orderService.CompleteOrder)
+}
+
+func (h *Handler) CancelOrder(c *gin.Context) {
+	h.orderAction(c, h.orderService.CancelOrder)
+}
+
+func (h *Handler) RefundOrder(c *gin.Context) {
+	h.orderAction(c, h.orderService.RefundOrder)
+}
+
+// PaymentWebhook accepts settlement notifications from the payment provider.
+func (h *Handler) PaymentWebhook(c *gin.Context) {
+	if h.orderService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Orders are not enabled"})
+		return
+	}
+
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.orderService.HandlePaymentWebhook(payload, c.GetHeader("X-Signature")); err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		respondOrderError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) orderAction(c *gin.Context, action func(orderID, userID int64) (*dto.OrderDTO, error)) {
+	if h.orderService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Orders are not enabled"})
+		return
+	}
+
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+		return
+	}
+
+	order, err := action(orderID, c.GetInt64("user_id"))
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order": order})
+}
+
+func respondOrderError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOrderForbidden), errors.Is(err, service.ErrOwnListingOrder):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrMixedSellers), errors.Is(err, service.ErrMixedCurrencies):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrListingUnavailable), errors.Is(err, service.ErrInvalidOrderState),
+		errors.Is(err, service.ErrPaymentPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPaymentFailed):
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process order"})
+	}
+}
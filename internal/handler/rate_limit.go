@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+	"vk/ecom/internal/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WithRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(h *Handler) {
+		h.rateLimiter = limiter
+	}
+}
+
+// RateLimitMiddleware applies the limiter's policy for the matched route.
+// It must run after AuthMiddleware or OptionalAuthMiddleware for policies
+// keyed by user. Responses carry RateLimit-* headers; rejected ones get
+// 429 with Retry-After. If the store fails the request is let through.
+func (h *Handler) RateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.rateLimiter == nil {
+			c.Next()
+			return
+		}
+		policy, ok := h.rateLimiter.Policy(c.Request.Method, c.FullPath())
+		if !ok {
+			c.Next()
+			return
+		}
+
+		key := "ip:" + c.ClientIP()
+		if userID := c.GetInt64("user_id"); userID != 0 && policy.KeyBy == ratelimit.KeyByUser {
+			key = "user:" + strconv.FormatInt(userID, 10)
+		}
+
+		result, err := h.rateLimiter.Allow(policy, key, time.Now().UTC())
+		if err != nil {
+			log.Println("Failed to apply rate limit:", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", strconv.Itoa(policy.Limit)+";w="+strconv.Itoa(ceilSeconds(policy.Period)))
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, try again later"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds d up to whole seconds for headers such as Retry-After.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
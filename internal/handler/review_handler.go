@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) ConfirmPurchaser(c *gin.Context) {
+	if h.reviewService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Reviews are not enabled"})
+		return
+	}
+
+	listingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	var req dto.ConfirmPurchaserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.reviewService.ConfirmPurchaser(listingID, c.GetInt64("user_id"), req.BuyerID); err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) CreateReview(c *gin.Context) {
+	if h.reviewService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Reviews are not enabled"})
+		return
+	}
+
+	listingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing id"})
+		return
+	}
+
+	var req dto.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	review, err := h.reviewService.CreateReview(listingID, c.GetInt64("user_id"), &req)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"review": review})
+}
+
+func (h *Handler) ReplyToReview(c *gin.Context) {
+	if h.reviewService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Reviews are not enabled"})
+		return
+	}
+
+	reviewID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review id"})
+		return
+	}
+
+	var req dto.ReviewReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	review, err := h.reviewService.ReplyToReview(reviewID, c.GetInt64("user_id"), req.Text)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"review": review})
+}
+
+func (h *Handler) GetUserProfile(c *gin.Context) {
+	if h.reviewService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Reviews are not enabled"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	profile, err := h.reviewService.GetUserProfile(userID)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": profile})
+}
+
+func (h *Handler) GetUserReviews(c *gin.Context) {
+	if h.reviewService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Reviews are not enabled"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	reviews, err := h.reviewService.GetSellerReviews(userID)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews})
+}
+
+func respondReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrListingNotFound), errors.Is(err, service.ErrReviewNotFound),
+		errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotListingOwner), errors.Is(err, service.ErrReviewNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrReviewExists), errors.Is(err, service.ErrReplyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidReview), errors.Is(err, service.ErrReviewTooLong),
+		errors.Is(err, service.ErrInvalidReply), errors.Is(err, service.ErrInvalidPurchaser):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process review"})
+	}
+}
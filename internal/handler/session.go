@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	authRealm     = "ecom"
+	sessionCookie = "session"
+	csrfCookie    = "csrf_token"
+	csrfHeader    = "X-CSRF-Token"
+	sessionPath   = "/api"
+
+	loginSessionCookie = "cookie"
+)
+
+var errInvalidAuthHeader = errors.New("the Authorization header must use the Bearer scheme")
+
+// CookieSessionConfig enables cookie sessions for the browser frontend.
+// The session token is kept in an HttpOnly cookie and state-changing
+// requests must echo the CSRF cookie in the X-CSRF-Token header.
+type CookieSessionConfig struct {
+	// CSRFKey signs CSRF tokens to the session; keep it secret.
+	CSRFKey []byte
+	// Secure should only be disabled for local development over HTTP.
+	Secure   bool
+	SameSite http.SameSite
+	MaxAge   time.Duration
+}
+
+func DefaultCookieSessionConfig(csrfKey []byte) CookieSessionConfig {
+	return CookieSessionConfig{
+		CSRFKey:  csrfKey,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   24 * time.Hour,
+	}
+}
+
+func WithCookieSessions(config CookieSessionConfig) Option {
+	return func(h *Handler) {
+		h.sessions = &config
+	}
+}
+
+// authError is a failed authentication, reported with an RFC 6750
+// challenge. A missing code means no credentials were sent.
+type authError struct {
+	status      int
+	code        string
+	description string
+	// scope lists the scopes needed, for insufficient_scope errors.
+	scope string
+}
+
+func abortWithChallenge(c *gin.Context, err *authError) {
+	challenge := fmt.Sprintf(`Bearer realm=%q`, authRealm)
+	message := "Authorization header is required"
+	if err.code != "" {
+		challenge += fmt.Sprintf(`, error=%q, error_description=%q`, err.code, err.description)
+		if err.scope != "" {
+			challenge += fmt.Sprintf(`, scope=%q`, err.scope)
+		}
+		message = err.description
+	}
+	c.Header("WWW-Authenticate", challenge)
+	c.AbortWithStatusJSON(err.status, gin.H{"error": message})
+}
+
+// bearerToken returns the token of an RFC 6750 "Bearer" credential. The
+// scheme is case-insensitive and the token must be token68.
+func bearerToken(header string) (string, error) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", errInvalidAuthHeader
+	}
+	token = strings.TrimLeft(token, " ")
+	if !isToken68(token) {
+		return "", errInvalidAuthHeader
+	}
+	return token, nil
+}
+
+func isToken68(s string) bool {
+	body := strings.TrimRight(s, "=")
+	if body == "" {
+		return false
+	}
+	for _, r := range body {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("-._~+/", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// authenticate validates the bearer token, which may be a JWT or an API
+// key, or, with cookie sessions and no Authorization header, the session
+// cookie. fromCookie reports the latter.
+func (h *Handler) authenticate(c *gin.Context) (user *domain.User, fromCookie bool, authErr *authError) {
+	if header := c.GetHeader("Authorization"); header != "" {
+		token, err := bearerToken(header)
+		if err != nil {
+			return nil, false, &authError{status: http.StatusBadRequest, code: "invalid_request", description: err.Error()}
+		}
+		if strings.HasPrefix(token, service.APIKeyPrefix) {
+			user, authErr := h.authenticateAPIKey(c, token)
+			return user, false, authErr
+		}
+		user, err := h.authService.ValidateToken(token)
+		if err != nil {
+			return nil, false, &authError{status: http.StatusUnauthorized, code: "invalid_token", description: "The access token is invalid or expired"}
+		}
+		return user, false, nil
+	}
+
+	if h.sessions != nil {
+		if token, err := c.Cookie(sessionCookie); err == nil && token != "" {
+			user, err := h.authService.ValidateToken(token)
+			if err != nil {
+				return nil, true, &authError{status: http.StatusUnauthorized, code: "invalid_token", description: "The session is invalid or expired"}
+			}
+			return user, true, nil
+		}
+	}
+
+	return nil, false, &authError{status: http.StatusUnauthorized}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// checkCSRF reports whether a cookie-authenticated request may proceed:
+// safe methods always can, others must send the CSRF cookie's value in
+// the X-CSRF-Token header, signed for the current session.
+func (h *Handler) checkCSRF(c *gin.Context) bool {
+	if isSafeMethod(c.Request.Method) {
+		return true
+	}
+	session, _ := c.Cookie(sessionCookie)
+	cookie, err := c.Cookie(csrfCookie)
+	header := c.GetHeader(csrfHeader)
+	if err != nil || header == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) != 1 {
+		return false
+	}
+	return h.sessions.validCSRFToken(session, header)
+}
+
+func abortCSRF(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Missing or invalid CSRF token"})
+}
+
+// csrfToken returns a random nonce with its HMAC over the session, so a
+// token planted in the cookie by a sibling domain does not validate
+// without the session it was issued for.
+func (cfg *CookieSessionConfig) csrfToken(session string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(nonce) + "." + hex.EncodeToString(cfg.csrfMAC(nonce, session)), nil
+}
+
+func (cfg *CookieSessionConfig) validCSRFToken(session, token string) bool {
+	nonceHex, macHex, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	nonce, err := hex.DecodeString(nonceHex)
+	if err != nil {
+		return false
+	}
+	mac, err := hex.DecodeString(macHex)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(mac, cfg.csrfMAC(nonce, session))
+}
+
+func (cfg *CookieSessionConfig) csrfMAC(nonce []byte, session string) []byte {
+	mac := hmac.New(sha256.New, cfg.CSRFKey)
+	mac.Write(nonce)
+	mac.Write([]byte(session))
+	return mac.Sum(nil)
+}
+
+// startSession sets the session and CSRF cookies and returns the CSRF
+// token for the client to echo.
+func (h *Handler) startSession(c *gin.Context, token string) (string, error) {
+	csrf, err := h.sessions.csrfToken(token)
+	if err != nil {
+		return "", err
+	}
+	maxAge := int(h.sessions.MaxAge / time.Second)
+	h.setCookie(c, sessionCookie, token, maxAge, true)
+	h.setCookie(c, csrfCookie, csrf, maxAge, false)
+	return csrf, nil
+}
+
+func (h *Handler) setCookie(c *gin.Context, name, value string, maxAge int, httpOnly bool) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     sessionPath,
+		MaxAge:   maxAge,
+		Secure:   h.sessions.Secure,
+		HttpOnly: httpOnly,
+		SameSite: h.sessions.SameSite,
+	})
+}
+
+// Logout ends a cookie session by expiring its cookies. Bearer tokens are
+// dropped by the client. A tracked session is revoked either way.
+func (h *Handler) Logout(c *gin.Context) {
+	if sessionID := currentSessionID(c); sessionID != 0 {
+		err := h.authService.RevokeSession(int(c.GetInt64("user_id")), sessionID)
+		if err != nil && !errors.Is(err, service.ErrSessionNotFound) {
+			log.Println("Failed to revoke session:", err)
+		}
+	}
+	if h.sessions != nil {
+		h.setCookie(c, sessionCookie, "", -1, true)
+		h.setCookie(c, csrfCookie, "", -1, false)
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginTwoFactor is the second login step for users with two-factor auth.
+func (h *Handler) LoginTwoFactor(c *gin.Context) {
+	var req dto.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ChallengeToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	cookieSession := req.Session == loginSessionCookie
+	if cookieSession && h.sessions == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cookie sessions are not enabled"})
+		return
+	}
+
+	token, u, err := h.authService.CompleteTwoFactorLogin(req.ChallengeToken, req.Code, clientInfo(c))
+	if errors.Is(err, service.ErrTwoFactorUnavailable) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidLoginChallenge) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login challenge is invalid or expired, log in again"})
+		return
+	}
+	if respondLoginError(c, err, "Invalid two-factor code") {
+		return
+	}
+
+	h.completeLogin(c, token, u, cookieSession)
+}
+
+func (h *Handler) GetTwoFactorStatus(c *gin.Context) {
+	status, err := h.authService.GetTwoFactorStatus(int(c.GetInt64("user_id")))
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// EnrollTwoFactor returns a new secret and its otpauth URI; two-factor
+// auth is only on after ConfirmTwoFactor.
+func (h *Handler) EnrollTwoFactor(c *gin.Context) {
+	enrollment, err := h.authService.EnrollTwoFactor(int(c.GetInt64("user_id")))
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (h *Handler) ConfirmTwoFactor(c *gin.Context) {
+	var req dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	codes, err := h.authService.ConfirmTwoFactor(int(c.GetInt64("user_id")), req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	var req dto.TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(int(c.GetInt64("user_id")), req.Password, req.Code); err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTwoFactorUnavailable):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled), errors.Is(err, service.ErrTwoFactorNotEnrolled),
+		errors.Is(err, service.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrWrongPassword):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update two-factor authentication"})
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func clientInfo(c *gin.Context) domain.ClientInfo {
+	return domain.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// currentSessionID is the session of the request's token, 0 for API keys
+// and when sessions are not tracked.
+func currentSessionID(c *gin.Context) int64 {
+	value, _ := c.Get("user")
+	if user, ok := value.(*domain.User); ok {
+		return user.SessionID
+	}
+	return 0
+}
+
+// GetSessions lists where the user is logged in.
+func (h *Handler) GetSessions(c *gin.Context) {
+	sessions, err := h.authService.GetSessions(int(c.GetInt64("user_id")), currentSessionID(c))
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+func (h *Handler) RevokeSession(c *gin.Context) {
+	sessionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session id"})
+		return
+	}
+
+	if err := h.authService.RevokeSession(int(c.GetInt64("user_id")), sessionID); err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeOtherSessions logs out everywhere but the current session.
+func (h *Handler) RevokeOtherSessions(c *gin.Context) {
+	revoked, err := h.authService.RevokeOtherSessions(int(c.GetInt64("user_id")), currentSessionID(c))
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
+
+func respondSessionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSessionsDisabled):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to manage sessions"})
+	}
+}
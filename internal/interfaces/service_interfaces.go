@@ -7,12 +7,101 @@ import (
 
 type AuthServiceInterface interface {
 	RegisterUser(login, password string) (*domain.User, error)
+	RegisterUserWithEmail(login, password, email string) (*domain.User, error)
 	LoginUser(login, password string) (string, *domain.User, error)
+	LoginUserFrom(login, password string, client domain.ClientInfo) (string, *domain.User, error)
 	ValidateToken(tokenString string) (*domain.User, error)
+	GetLockouts() ([]*domain.LoginAttempts, error)
+	ClearLockout(kind, value string) error
+	ChangePassword(userID int, currentPassword, newPassword string) error
+	RequestPasswordReset(login string) error
+	ResetPassword(token, newPassword string) error
+	EnrollTwoFactor(userID int) (*dto.TwoFactorEnrollmentDTO, error)
+	ConfirmTwoFactor(userID int, code string) ([]string, error)
+	DisableTwoFactor(userID int, password, code string) error
+	GetTwoFactorStatus(userID int) (*dto.TwoFactorStatusDTO, error)
+	CompleteTwoFactorLogin(challengeToken, code string, client domain.ClientInfo) (string, *domain.User, error)
+	ExternalLoginProviders() []string
+	BeginExternalLogin(provider string, userID int) (string, error)
+	CompleteExternalLogin(provider, code, state string, client domain.ClientInfo) (*dto.ExternalLoginResult, error)
+	GetIdentities(userID int) ([]*domain.Identity, error)
+	UnlinkIdentity(userID int, provider string) error
+	CreateAPIKey(userID int, name string, scopes []string) (*dto.APIKeyCreatedDTO, error)
+	GetAPIKeys(userID int) ([]*domain.APIKey, error)
+	RevokeAPIKey(userID int, keyID int64) error
+	ValidateAPIKey(token string) (*domain.User, *domain.APIKey, error)
+	GetContactDetails(userID int) (*dto.ContactDTO, error)
+	UpdateEmail(userID int, email string) error
+	UpdatePhone(userID int, phone string) error
+	SendEmailVerification(userID int) error
+	SendPhoneVerification(userID int) error
+	VerifyEmail(token string) error
+	VerifyPhone(userID int, code string) error
+	GetSessions(userID int, currentID int64) ([]*dto.SessionDTO, error)
+	RevokeSession(userID int, sessionID int64) error
+	RevokeOtherSessions(userID int, currentID int64) (int, error)
 }
 
 type ListingServiceInterface interface {
 	CreateListing(req *dto.ListingRequest, authorID int64) (*dto.ListingDTO, error)
+	UpdateListing(listingID, authorID int64, req *dto.ListingRequest) (*dto.ListingDTO, error)
 	GetListings(sortBy, sortOrder string, minPrice, maxPrice *int64, currentUserID *int64) ([]*dto.ListingDTO, error)
 	GetListingsWithPagination(sortBy, sortOrder string, minPrice, maxPrice *int64, page, pageSize int, currentUserID *int64) (*dto.ListingsResponse, error)
+	SearchListings(req *dto.ListingSearchRequest, currentUserID *int64) (*dto.ListingsResponse, error)
+	GetCategorySchema(category string) (*domain.CategorySchema, error)
+	GetListing(listingID int64, currency string, currentUserID *int64) (*dto.ListingDTO, error)
+	GetTrendingListings(limit int, currency string, currentUserID *int64) ([]*dto.ListingDTO, error)
+	GetSimilarListings(listingID int64, currency string, currentUserID *int64) ([]*dto.ListingDTO, error)
+	GetPriceHistory(listingID int64, currentUserID *int64) ([]*domain.PriceChange, error)
+	AddFavorite(listingID, userID int64) error
+	RemoveFavorite(listingID, userID int64) error
+}
+
+type AuctionServiceInterface interface {
+	CreateAuction(req *dto.ListingRequest, authorID int64) (*dto.ListingDTO, error)
+	PlaceBid(listingID, bidderID, amount int64) (*dto.AuctionDTO, error)
+	GetBidHistory(listingID int64, currentUserID *int64) (*dto.BidHistoryResponse, error)
+}
+
+type OrderServiceInterface interface {
+	CreateOrder(listingIDs []int64, buyerID int64) (*dto.OrderDTO, error)
+	GetOrder(orderID, userID int64) (*dto.OrderDTO, error)
+	GetOrders(userID int64, role string) ([]*dto.OrderDTO, error)
+	PayOrder(orderID, buyerID int64) (*dto.OrderDTO, error)
+	ShipOrder(orderID, sellerID int64) (*dto.OrderDTO, error)
+	CompleteOrder(orderID, buyerID int64) (*dto.OrderDTO, error)
+	CancelOrder(orderID, userID int64) (*dto.OrderDTO, error)
+	RefundOrder(orderID, sellerID int64) (*dto.OrderDTO, error)
+	HandlePaymentWebhook(payload []byte, signature string) error
+}
+
+type CartServiceInterface interface {
+	GetCart(cartKey string, viewerID *int64) (*dto.CartDTO, error)
+	AddItem(cartKey string, listingID int64, viewerID *int64) (*dto.CartDTO, error)
+	RemoveItem(cartKey string, listingID int64, viewerID *int64) (*dto.CartDTO, error)
+	MergeAnonymousCart(token string, userID int64) error
+	Checkout(userID int64, acceptPriceChanges bool) (*dto.CheckoutResponse, error)
+}
+
+type ReviewServiceInterface interface {
+	ConfirmPurchaser(listingID, sellerID, buyerID int64) error
+	CreateReview(listingID, authorID int64, req *dto.ReviewRequest) (*dto.ReviewDTO, error)
+	ReplyToReview(reviewID, sellerID int64, text string) (*dto.ReviewDTO, error)
+	GetSellerReviews(sellerID int64) ([]*dto.ReviewDTO, error)
+	GetUserProfile(userID int64) (*dto.UserProfileDTO, error)
+}
+
+type ModerationServiceInterface interface {
+	ReportListing(listingID, reporterID int64, req *dto.ReportRequest) (*dto.ReportDTO, error)
+	GetQueue(status string) ([]*dto.ModerationCaseDTO, error)
+	GetCase(caseID int64) (*dto.ModerationCaseDTO, error)
+	ClaimCase(caseID, staffID int64) (*dto.ModerationCaseDTO, error)
+	ResolveCase(caseID, staffID int64, req *dto.ResolveCaseRequest) (*dto.ModerationCaseDTO, error)
+	FindDuplicates(listingID int64) ([]*dto.NearDuplicateDTO, error)
+}
+
+type AnalyticsServiceInterface interface {
+	TrackView(listingID int64, visitor string)
+	TrackEvent(listingID, userID int64, event string) error
+	GetListingStats(listingID, sellerID int64, days int) (*dto.ListingStatsDTO, error)
 }
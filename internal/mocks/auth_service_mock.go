@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
 	"vk/ecom/internal/interfaces"
 
 	"github.com/stretchr/testify/mock"
@@ -21,6 +22,14 @@ func (m *MockAuthService) RegisterUser(login, password string) (*domain.User, er
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
+func (m *MockAuthService) RegisterUserWithEmail(login, password, email string) (*domain.User, error) {
+	args := m.Called(login, password, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
 func (m *MockAuthService) LoginUser(login, password string) (string, *domain.User, error) {
 	args := m.Called(login, password)
 	if args.Get(1) == nil {
@@ -29,6 +38,14 @@ func (m *MockAuthService) LoginUser(login, password string) (string, *domain.Use
 	return args.String(0), args.Get(1).(*domain.User), args.Error(2)
 }
 
+func (m *MockAuthService) LoginUserFrom(login, password string, client domain.ClientInfo) (string, *domain.User, error) {
+	args := m.Called(login, password, client)
+	if args.Get(1) == nil {
+		return "", nil, args.Error(2)
+	}
+	return args.String(0), args.Get(1).(*domain.User), args.Error(2)
+}
+
 func (m *MockAuthService) ValidateToken(tokenString string) (*domain.User, error) {
 	args := m.Called(tokenString)
 	if args.Get(0) == nil {
@@ -36,3 +53,184 @@ func (m *MockAuthService) ValidateToken(tokenString string) (*domain.User, error
 	}
 	return args.Get(0).(*domain.User), args.Error(1)
 }
+
+func (m *MockAuthService) GetLockouts() ([]*domain.LoginAttempts, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.LoginAttempts), args.Error(1)
+}
+
+func (m *MockAuthService) ClearLockout(kind, value string) error {
+	args := m.Called(kind, value)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ChangePassword(userID int, currentPassword, newPassword string) error {
+	args := m.Called(userID, currentPassword, newPassword)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) RequestPasswordReset(login string) error {
+	args := m.Called(login)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ResetPassword(token, newPassword string) error {
+	args := m.Called(token, newPassword)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) EnrollTwoFactor(userID int) (*dto.TwoFactorEnrollmentDTO, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.TwoFactorEnrollmentDTO), args.Error(1)
+}
+
+func (m *MockAuthService) ConfirmTwoFactor(userID int, code string) ([]string, error) {
+	args := m.Called(userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockAuthService) DisableTwoFactor(userID int, password, code string) error {
+	args := m.Called(userID, password, code)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) GetTwoFactorStatus(userID int) (*dto.TwoFactorStatusDTO, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.TwoFactorStatusDTO), args.Error(1)
+}
+
+func (m *MockAuthService) CompleteTwoFactorLogin(challengeToken, code string, client domain.ClientInfo) (string, *domain.User, error) {
+	args := m.Called(challengeToken, code, client)
+	if args.Get(1) == nil {
+		return "", nil, args.Error(2)
+	}
+	return args.String(0), args.Get(1).(*domain.User), args.Error(2)
+}
+
+func (m *MockAuthService) ExternalLoginProviders() []string {
+	args := m.Called()
+	return args.Get(0).([]string)
+}
+
+func (m *MockAuthService) BeginExternalLogin(provider string, userID int) (string, error) {
+	args := m.Called(provider, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthService) CompleteExternalLogin(provider, code, state string, client domain.ClientInfo) (*dto.ExternalLoginResult, error) {
+	args := m.Called(provider, code, state, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ExternalLoginResult), args.Error(1)
+}
+
+func (m *MockAuthService) GetIdentities(userID int) ([]*domain.Identity, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Identity), args.Error(1)
+}
+
+func (m *MockAuthService) UnlinkIdentity(userID int, provider string) error {
+	args := m.Called(userID, provider)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) CreateAPIKey(userID int, name string, scopes []string) (*dto.APIKeyCreatedDTO, error) {
+	args := m.Called(userID, name, scopes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.APIKeyCreatedDTO), args.Error(1)
+}
+
+func (m *MockAuthService) GetAPIKeys(userID int) ([]*domain.APIKey, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.APIKey), args.Error(1)
+}
+
+func (m *MockAuthService) RevokeAPIKey(userID int, keyID int64) error {
+	args := m.Called(userID, keyID)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ValidateAPIKey(token string) (*domain.User, *domain.APIKey, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*domain.User), args.Get(1).(*domain.APIKey), args.Error(2)
+}
+
+func (m *MockAuthService) GetContactDetails(userID int) (*dto.ContactDTO, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ContactDTO), args.Error(1)
+}
+
+func (m *MockAuthService) UpdateEmail(userID int, email string) error {
+	args := m.Called(userID, email)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) UpdatePhone(userID int, phone string) error {
+	args := m.Called(userID, phone)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) SendEmailVerification(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) SendPhoneVerification(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) VerifyEmail(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) VerifyPhone(userID int, code string) error {
+	args := m.Called(userID, code)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) GetSessions(userID int, currentID int64) ([]*dto.SessionDTO, error) {
+	args := m.Called(userID, currentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*dto.SessionDTO), args.Error(1)
+}
+
+func (m *MockAuthService) RevokeSession(userID int, sessionID int64) error {
+	args := m.Called(userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) RevokeOtherSessions(userID int, currentID int64) (int, error) {
+	args := m.Called(userID, currentID)
+	return args.Int(0), args.Error(1)
+}
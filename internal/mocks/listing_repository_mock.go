@@ -46,3 +46,63 @@ func (m *MockListingRepository) GetByAuthorID(authorID int64) ([]*domain.Listing
 	}
 	return args.Get(0).([]*domain.Listing), args.Error(1)
 }
+
+func (m *MockListingRepository) UpdateStatus(id int64, from, to string) error {
+	args := m.Called(id, from, to)
+	return args.Error(0)
+}
+
+func (m *MockListingRepository) Delete(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockListingRepository) Update(listing *domain.Listing) error {
+	args := m.Called(listing)
+	return args.Error(0)
+}
+
+func (m *MockListingRepository) GetPriceHistory(listingID int64) ([]*domain.PriceChange, error) {
+	args := m.Called(listingID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.PriceChange), args.Error(1)
+}
+
+func (m *MockListingRepository) FindNearDuplicates(listing *domain.Listing, maxDistance int) ([]*domain.NearDuplicate, error) {
+	args := m.Called(listing, maxDistance)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.NearDuplicate), args.Error(1)
+}
+
+func (m *MockListingRepository) FindSimilar(listing *domain.Listing, limit int) ([]*domain.SimilarListing, error) {
+	args := m.Called(listing, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.SimilarListing), args.Error(1)
+}
+
+func (m *MockListingRepository) SetPopularity(scores map[int64]float64) error {
+	args := m.Called(scores)
+	return args.Error(0)
+}
+
+func (m *MockListingRepository) Search(query *domain.ListingQuery) ([]*domain.Listing, int, error) {
+	args := m.Called(query)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*domain.Listing), args.Int(1), args.Error(2)
+}
+
+func (m *MockListingRepository) Facets(query *domain.ListingQuery, facets *domain.FacetQuery) (*domain.ListingFacets, error) {
+	args := m.Called(query, facets)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ListingFacets), args.Error(1)
+}
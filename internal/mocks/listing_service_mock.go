@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"vk/ecom/internal/domain"
 	"vk/ecom/internal/dto"
 	"vk/ecom/internal/interfaces"
 
@@ -22,6 +23,14 @@ func (m *MockListingService) CreateListing(req *dto.ListingRequest, authorID int
 	return args.Get(0).(*dto.ListingDTO), args.Error(1)
 }
 
+func (m *MockListingService) UpdateListing(listingID, authorID int64, req *dto.ListingRequest) (*dto.ListingDTO, error) {
+	args := m.Called(listingID, authorID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ListingDTO), args.Error(1)
+}
+
 func (m *MockListingService) GetListings(sortBy, sortOrder string, minPrice, maxPrice *int64, currentUserID *int64) ([]*dto.ListingDTO, error) {
 	args := m.Called(sortBy, sortOrder, minPrice, maxPrice, currentUserID)
 	if args.Get(0) == nil {
@@ -37,3 +46,61 @@ func (m *MockListingService) GetListingsWithPagination(sortBy, sortOrder string,
 	}
 	return args.Get(0).(*dto.ListingsResponse), args.Error(1)
 }
+
+func (m *MockListingService) SearchListings(req *dto.ListingSearchRequest, currentUserID *int64) (*dto.ListingsResponse, error) {
+	args := m.Called(req, currentUserID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ListingsResponse), args.Error(1)
+}
+
+func (m *MockListingService) GetCategorySchema(category string) (*domain.CategorySchema, error) {
+	args := m.Called(category)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CategorySchema), args.Error(1)
+}
+
+func (m *MockListingService) GetSimilarListings(listingID int64, currency string, currentUserID *int64) ([]*dto.ListingDTO, error) {
+	args := m.Called(listingID, currency, currentUserID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*dto.ListingDTO), args.Error(1)
+}
+
+func (m *MockListingService) GetPriceHistory(listingID int64, currentUserID *int64) ([]*domain.PriceChange, error) {
+	args := m.Called(listingID, currentUserID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.PriceChange), args.Error(1)
+}
+
+func (m *MockListingService) AddFavorite(listingID, userID int64) error {
+	args := m.Called(listingID, userID)
+	return args.Error(0)
+}
+
+func (m *MockListingService) RemoveFavorite(listingID, userID int64) error {
+	args := m.Called(listingID, userID)
+	return args.Error(0)
+}
+
+func (m *MockListingService) GetListing(listingID int64, currency string, currentUserID *int64) (*dto.ListingDTO, error) {
+	args := m.Called(listingID, currency, currentUserID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ListingDTO), args.Error(1)
+}
+
+func (m *MockListingService) GetTrendingListings(limit int, currency string, currentUserID *int64) ([]*dto.ListingDTO, error) {
+	args := m.Called(limit, currency, currentUserID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*dto.ListingDTO), args.Error(1)
+}
@@ -30,3 +30,43 @@ func (m *MockUserRepository) GetByLogin(login string) (*domain.User, error) {
 	}
 	return args.Get(0).(*domain.User), args.Error(1)
 }
+
+func (m *MockUserRepository) Delete(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetBanned(id int, banned bool) error {
+	args := m.Called(id, banned)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetPassword(id int, passwordHash string, revokeSessions bool) error {
+	args := m.Called(id, passwordHash, revokeSessions)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetEmail(id int, email string) error {
+	args := m.Called(id, email)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetPhone(id int, phone string) error {
+	args := m.Called(id, phone)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RehashPassword(id int, oldHash, newHash string) error {
+	args := m.Called(id, oldHash, newHash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) MarkEmailVerified(id int, email string) error {
+	args := m.Called(id, email)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) MarkPhoneVerified(id int, phone string) error {
+	args := m.Called(id, phone)
+	return args.Error(0)
+}
@@ -0,0 +1,96 @@
+package fingerprint
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/screening"
+)
+
+// MaxBandDistance is the largest Hamming distance for which band lookups
+// are exact: with 64-bit hashes split into four 16-bit bands, two hashes
+// that differ in at most three bits share at least one band.
+const (
+	MaxBandDistance = 3
+	bandCount       = 4
+)
+
+// Text returns a 64-bit SimHash of the normalized title and description.
+// Features are word bigrams, so small edits change only a few bits while
+// reordering the text changes many.
+func Text(title, description string) uint64 {
+	words := strings.Fields(screening.Normalize(title + "\n" + description))
+	if len(words) == 0 {
+		return 0
+	}
+
+	features := words
+	if len(words) > 1 {
+		features = make([]string, 0, len(words)-1)
+		for i := 0; i+1 < len(words); i++ {
+			features = append(features, words[i]+" "+words[i+1])
+		}
+	}
+
+	var weights [64]int
+	for _, feature := range features {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var hash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Similarity maps a Hamming distance to [0, 1], 1 meaning identical hashes.
+func Similarity(distance int) float64 {
+	return 1 - float64(distance)/64
+}
+
+// Bands splits a hash into the four 16-bit values used for candidate lookup.
+func Bands(hash uint64) [bandCount]int {
+	var bands [bandCount]int
+	for i := range bands {
+		bands[i] = int(hash >> (16 * (bandCount - 1 - i)) & 0xffff)
+	}
+	return bands
+}
+
+// Compare reports whether candidate is a near duplicate of target: their
+// text hashes or, when both have one, their image hashes are at most
+// maxDistance bits apart. A zero text hash means the text was empty and is
+// never matched.
+func Compare(target, candidate *domain.Listing, maxDistance int) (*domain.NearDuplicate, bool) {
+	result := &domain.NearDuplicate{
+		ListingID:    candidate.ID,
+		AuthorID:     candidate.AuthorID,
+		TextDistance: Distance(target.TextHash, candidate.TextHash),
+	}
+	matched := target.TextHash != 0 && candidate.TextHash != 0 && result.TextDistance <= maxDistance
+
+	if target.ImageHash != nil && candidate.ImageHash != nil {
+		distance := Distance(*target.ImageHash, *candidate.ImageHash)
+		result.ImageDistance = &distance
+		matched = matched || distance <= maxDistance
+	}
+
+	return result, matched
+}
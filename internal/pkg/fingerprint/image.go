@@ -0,0 +1,163 @@
+package fingerprint
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrUnsupportedScheme = errors.New("image URL must use http or https")
+	ErrForbiddenAddress  = errors.New("image host is not a public address")
+)
+
+// sharedAddressSpace is the carrier-grade NAT range, which netip does not
+// count as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// ImageHasher computes a perceptual hash of the image behind a URL.
+type ImageHasher interface {
+	Hash(url string) (uint64, error)
+}
+
+// HTTPImageHasher downloads images and hashes them with DHash. Formats
+// without a standard library decoder, such as WebP, return an error.
+//
+// Image URLs come from sellers, so only http and https are fetched and
+// connections to loopback, private and link-local addresses are refused.
+// The check runs on the dialed address, which covers redirects and DNS
+// names that resolve to internal hosts.
+type HTTPImageHasher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+func NewHTTPImageHasher(timeout time.Duration, maxBytes int64) *HTTPImageHasher {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialPublicOnly}
+	return &HTTPImageHasher{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 5 {
+					return errors.New("too many redirects")
+				}
+				return checkScheme(req.URL)
+			},
+		},
+		maxBytes: maxBytes,
+	}
+}
+
+func (h *HTTPImageHasher) Hash(rawURL string) (uint64, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid image URL: %w", err)
+	}
+	if err := checkScheme(parsed); err != nil {
+		return 0, err
+	}
+
+	resp, err := h.client.Get(parsed.String())
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to fetch image: status %d", resp.StatusCode)
+	}
+
+	img, _, err := image.Decode(io.LimitReader(resp.Body, h.maxBytes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	return DHash(img)
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrUnsupportedScheme
+	}
+	return nil
+}
+
+// dialPublicOnly is a net.Dialer Control function that refuses connections
+// to addresses that are not publicly routable.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() ||
+		sharedAddressSpace.Contains(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// DHash is a difference hash: the image is scaled down to 9x8 grayscale
+// cells and each bit records whether a cell is brighter than its right
+// neighbour. It survives resizing, recompression and small color changes.
+func DHash(img image.Image) (uint64, error) {
+	bounds := img.Bounds()
+	if bounds.Dx() < 9 || bounds.Dy() < 8 {
+		return 0, errors.New("image is too small to hash")
+	}
+
+	var cells [8][9]float64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 9; x++ {
+			cells[y][x] = averageGray(img,
+				bounds.Min.X+x*bounds.Dx()/9, bounds.Min.Y+y*bounds.Dy()/8,
+				bounds.Min.X+(x+1)*bounds.Dx()/9, bounds.Min.Y+(y+1)*bounds.Dy()/8)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if cells[y][x] > cells[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash, nil
+}
+
+// averageGray samples at most 8x8 pixels of the cell, which is plenty for a
+// 64-bit hash and keeps large images cheap.
+func averageGray(img image.Image, x0, y0, x1, y1 int) float64 {
+	stepX := max(1, (x1-x0)/8)
+	stepY := max(1, (y1-y0)/8)
+
+	var sum float64
+	var n int
+	for y := y0; y < y1; y += stepY {
+		for x := x0; x < x1; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			n++
+		}
+	}
+	return sum / float64(n)
+}
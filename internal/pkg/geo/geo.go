@@ -0,0 +1,89 @@
+package geo
+
+import (
+	"math"
+)
+
+const earthRadiusKm = 6371.0
+
+type Point struct {
+	Lat float64
+	Lng float64
+}
+
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180
+}
+
+// DistanceKm is the great-circle distance between two points (haversine).
+func DistanceKm(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat := lat2 - lat1
+	dLng := radians(b.Lng - a.Lng)
+
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Box is a latitude/longitude rectangle. When it crosses the antimeridian
+// MinLng is greater than MaxLng.
+type Box struct {
+	MinLat, MaxLat float64
+	MinLng, MaxLng float64
+}
+
+// WrapsLng reports whether the box crosses the antimeridian.
+func (b Box) WrapsLng() bool {
+	return b.MinLng > b.MaxLng
+}
+
+func (b Box) Contains(p Point) bool {
+	if p.Lat < b.MinLat || p.Lat > b.MaxLat {
+		return false
+	}
+	if b.WrapsLng() {
+		return p.Lng >= b.MinLng || p.Lng <= b.MaxLng
+	}
+	return p.Lng >= b.MinLng && p.Lng <= b.MaxLng
+}
+
+// BoundingBox returns a box containing every point within radiusKm of
+// center. Near the poles it spans all longitudes.
+func BoundingBox(center Point, radiusKm float64) Box {
+	dLat := degrees(radiusKm / earthRadiusKm)
+	box := Box{
+		MinLat: math.Max(center.Lat-dLat, -90),
+		MaxLat: math.Min(center.Lat+dLat, 90),
+		MinLng: -180,
+		MaxLng: 180,
+	}
+	if box.MinLat == -90 || box.MaxLat == 90 {
+		return box
+	}
+
+	dLng := degrees(math.Asin(math.Min(1, math.Sin(radiusKm/earthRadiusKm)/math.Cos(radians(center.Lat)))))
+	if dLng >= 180 {
+		return box
+	}
+	box.MinLng = normalizeLng(center.Lng - dLng)
+	box.MaxLng = normalizeLng(center.Lng + dLng)
+	return box
+}
+
+func normalizeLng(lng float64) float64 {
+	if lng < -180 {
+		return lng + 360
+	}
+	if lng > 180 {
+		return lng - 360
+	}
+	return lng
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
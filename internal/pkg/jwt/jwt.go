@@ -9,18 +9,30 @@ import (
 type JWTClaim struct {
 	UserID int `json:"user_id"`
 	Login  string `json:"login"`
+	SessionVersion int `json:"session_version"`
+	// SessionID is the tracked session the token belongs to, if any.
+	SessionID int64 `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
 var jwtKey = []byte("your_secret_key")
 
+// Access tokens and two-factor login challenges share the key and are told
+// apart by subject, so a challenge cannot be used as an access token.
+const (
+	accessSubject    = "user_token"
+	challengeSubject = "login_challenge"
+)
+
 func GenerateToken(user *domain.User) (string, error) {
 	claims := &JWTClaim{
 		UserID: user.ID,
 		Login:  user.Login,
+		SessionVersion: user.SessionVersion,
+		SessionID: user.SessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "ecom",
-			Subject:   "user_token",
+			Subject:   accessSubject,
 			Audience:  []string{"ecom_users"},
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -31,10 +43,37 @@ func GenerateToken(user *domain.User) (string, error) {
 	return token.SignedString(jwtKey)
 }
 
+// GenerateChallengeToken issues the proof of a correct password that the
+// second login step exchanges, together with a code, for an access token.
+func GenerateChallengeToken(user *domain.User, ttl time.Duration) (string, error) {
+	claims := &JWTClaim{
+		UserID: user.ID,
+		Login:  user.Login,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "ecom",
+			Subject:   challengeSubject,
+			Audience:  []string{"ecom_users"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtKey)
+}
+
 func ParseToken(tokenString string) (*domain.User, error) {
+	return parse(tokenString, accessSubject)
+}
+
+func ParseChallengeToken(tokenString string) (*domain.User, error) {
+	return parse(tokenString, challengeSubject)
+}
+
+func parse(tokenString, subject string) (*domain.User, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaim{}, func(token *jwt.Token) (interface{}, error) {
 		return jwtKey, nil
-	})
+	}, jwt.WithSubject(subject))
 
 	if err != nil || !token.Valid {
 		return nil, err
@@ -48,5 +87,7 @@ func ParseToken(tokenString string) (*domain.User, error) {
 	return &domain.User{
 		ID:    claims.UserID,
 		Login: claims.Login,
+		SessionVersion: claims.SessionVersion,
+		SessionID: claims.SessionID,
 	}, nil
 }
\ No newline at end of file
@@ -0,0 +1,58 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email, e.g. through an SMTP relay or a provider API.
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// LogMailer writes messages to the log, for local use.
+type LogMailer struct{}
+
+func (LogMailer) Send(msg *Message) error {
+	log.Printf("mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer writes each message to its own .eml file in Dir, for local
+// use and tests that need to read what was sent.
+type FileMailer struct {
+	Dir string
+}
+
+func (m FileMailer) Send(msg *Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	file, err := os.CreateTemp(m.Dir, time.Now().UTC().Format("20060102T150405")+"-*.eml")
+	if err != nil {
+		return fmt.Errorf("failed to create mail file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(b.String()); err != nil {
+		return fmt.Errorf("failed to write mail file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrNoRate              = errors.New("no exchange rate")
+)
+
+// exponents maps the supported ISO 4217 codes to the number of minor units
+// in a major unit, as a power of ten.
+var exponents = map[string]int{
+	"AED": 2,
+	"AMD": 2,
+	"BYN": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"GEL": 2,
+	"JPY": 0,
+	"KZT": 2,
+	"RUB": 2,
+	"TRY": 2,
+	"UAH": 2,
+	"USD": 2,
+	"UZS": 2,
+}
+
+// Money is an amount in minor units of an ISO 4217 currency.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+func (m Money) String() string {
+	exp, ok := exponents[m.Currency]
+	if !ok || exp == 0 {
+		return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+	}
+	unit := int64(math.Pow10(exp))
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	return fmt.Sprintf("%s%d.%0*d %s", sign, amount/unit, exp, amount%unit, m.Currency)
+}
+
+// Supported reports whether code is a supported currency.
+func Supported(code string) bool {
+	_, ok := exponents[code]
+	return ok
+}
+
+// Currencies returns the supported currency codes in order.
+func Currencies() []string {
+	codes := make([]string, 0, len(exponents))
+	for code := range exponents {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Exponent returns the number of decimal digits of the currency's minor
+// unit.
+func Exponent(code string) (int, error) {
+	exp, ok := exponents[code]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, code)
+	}
+	return exp, nil
+}
+
+// Normalize upper-cases and checks a currency code.
+func Normalize(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !Supported(code) {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedCurrency, code)
+	}
+	return code, nil
+}
+
+// RateProvider supplies exchange rates. Rate returns the value of one major
+// unit of from in major units of to, or ErrNoRate.
+type RateProvider interface {
+	Rate(from, to string) (float64, error)
+}
+
+// Factor returns the multiplier that converts minor units of from into
+// minor units of to.
+func Factor(rates RateProvider, from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	fromExp, err := Exponent(from)
+	if err != nil {
+		return 0, err
+	}
+	toExp, err := Exponent(to)
+	if err != nil {
+		return 0, err
+	}
+	rate, err := rates.Rate(from, to)
+	if err != nil {
+		return 0, err
+	}
+	return rate * math.Pow10(toExp-fromExp), nil
+}
+
+// Convert converts m into the currency to, rounding to the nearest minor
+// unit.
+func Convert(m Money, to string, rates RateProvider) (Money, error) {
+	factor, err := Factor(rates, m.Currency, to)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: int64(math.Round(float64(m.Amount) * factor)), Currency: to}, nil
+}
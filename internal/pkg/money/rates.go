@@ -0,0 +1,70 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// StaticRates is a fixed table of rates against a base currency, as
+// published by most rate feeds: Rates["USD"] is the value of one base unit
+// in dollars.
+type StaticRates struct {
+	base  string
+	rates map[string]float64
+}
+
+var _ RateProvider = (*StaticRates)(nil)
+
+type ratesFile struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+func NewStaticRates(base string, rates map[string]float64) (*StaticRates, error) {
+	if !Supported(base) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, base)
+	}
+	r := &StaticRates{base: base, rates: map[string]float64{base: 1}}
+	for code, rate := range rates {
+		if !Supported(code) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, code)
+		}
+		if !(rate > 0) {
+			return nil, fmt.Errorf("invalid rate %v for %s", rate, code)
+		}
+		r.rates[code] = rate
+	}
+	return r, nil
+}
+
+// LoadRatesFile reads rates from a JSON file of the form
+// {"base": "RUB", "rates": {"USD": 0.011, "EUR": 0.0102}}.
+func LoadRatesFile(path string) (*StaticRates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates: %w", err)
+	}
+	var file ratesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse exchange rates: %w", err)
+	}
+	return NewStaticRates(file.Base, file.Rates)
+}
+
+// Base returns the currency the rates are quoted against.
+func (r *StaticRates) Base() string {
+	return r.base
+}
+
+func (r *StaticRates) Rate(from, to string) (float64, error) {
+	fromRate, ok := r.rates[from]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrNoRate, from)
+	}
+	toRate, ok := r.rates[to]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrNoRate, to)
+	}
+	return toRate / fromRate, nil
+}
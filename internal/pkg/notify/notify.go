@@ -0,0 +1,26 @@
+package notify
+
+import "log"
+
+const KindPriceDrop = "price_drop"
+
+// Notification is a message to one user about a listing.
+type Notification struct {
+	UserID    int64
+	Kind      string
+	ListingID int64
+	Text      string
+}
+
+// Notifier delivers notifications, e.g. by push or email.
+type Notifier interface {
+	Notify(n *Notification) error
+}
+
+// LogNotifier writes notifications to the log, for local use.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(n *Notification) error {
+	log.Printf("notify user %d about listing %d (%s): %s", n.UserID, n.ListingID, n.Kind, n.Text)
+	return nil
+}
@@ -0,0 +1,59 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// Config registers the application with an OpenID provider. The provider
+// is found through discovery at Issuer.
+type Config struct {
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURL  string   `json:"redirect_url"`
+	Scopes       []string `json:"scopes"`
+}
+
+func (c Config) Validate() error {
+	if !providerNamePattern.MatchString(c.Name) {
+		return fmt.Errorf("invalid provider name %q", c.Name)
+	}
+	if c.Issuer == "" || c.ClientID == "" || c.RedirectURL == "" {
+		return fmt.Errorf("provider %s needs an issuer, client_id and redirect_url", c.Name)
+	}
+	return nil
+}
+
+// LoadConfig reads the providers from a JSON file of the form
+// {"providers": [...]}.
+func LoadConfig(path string) ([]Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OIDC config: %w", err)
+	}
+
+	var file struct {
+		Providers []Config `json:"providers"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse OIDC config: %w", err)
+	}
+
+	seen := make(map[string]bool, len(file.Providers))
+	for _, config := range file.Providers {
+		if err := config.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[config.Name] {
+			return nil, fmt.Errorf("duplicate provider %s", config.Name)
+		}
+		seen[config.Name] = true
+	}
+	return file.Providers, nil
+}
@@ -0,0 +1,71 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+//go:embed common.txt
+var commonPasswords string
+
+// Blocklist is a set of passwords that must not be used, compared case
+// insensitively.
+type Blocklist struct {
+	passwords map[string]struct{}
+}
+
+// NewBlocklist reads one password per line. Blank lines and lines
+// starting with # are skipped.
+func NewBlocklist(r io.Reader) (*Blocklist, error) {
+	b := &Blocklist{passwords: make(map[string]struct{})}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		b.passwords[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// LoadBlocklist reads a blocklist file, such as a larger list of breached
+// passwords.
+func LoadBlocklist(path string) (*Blocklist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewBlocklist(f)
+}
+
+var (
+	commonOnce      sync.Once
+	commonBlocklist *Blocklist
+)
+
+// CommonPasswords returns the bundled list of the most common passwords
+// from public breach corpora.
+func CommonPasswords() *Blocklist {
+	commonOnce.Do(func() {
+		commonBlocklist, _ = NewBlocklist(strings.NewReader(commonPasswords))
+	})
+	return commonBlocklist
+}
+
+func (b *Blocklist) Contains(password string) bool {
+	_, ok := b.passwords[strings.ToLower(password)]
+	return ok
+}
+
+func (b *Blocklist) Len() int {
+	return len(b.passwords)
+}
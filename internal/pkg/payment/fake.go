@@ -0,0 +1,136 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	FakeModeSuccess = "success"
+	FakeModeFailure = "failure"
+	FakeModeAsync   = "async"
+)
+
+// WebhookFunc receives a signed webhook payload, the same bytes an HTTP
+// webhook endpoint would receive.
+type WebhookFunc func(payload []byte, signature string)
+
+// FakeProvider is an in-process Provider for local development and tests.
+// In async mode Charge returns a pending payment and delivers the result to
+// the registered WebhookFunc after Delay.
+type FakeProvider struct {
+	secret  []byte
+	mode    string
+	delay   time.Duration
+	webhook WebhookFunc
+	nextID  atomic.Int64
+	mu      sync.RWMutex
+	charges map[string]int64
+	// keys maps idempotency keys to the payments charged for them.
+	keys map[string]*Payment
+}
+
+func NewFakeProvider(secret string, mode string, delay time.Duration) *FakeProvider {
+	return &FakeProvider{
+		secret:  []byte(secret),
+		mode:    mode,
+		delay:   delay,
+		charges: make(map[string]int64),
+		keys:    make(map[string]*Payment),
+	}
+}
+
+func (p *FakeProvider) OnWebhook(fn WebhookFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.webhook = fn
+}
+
+func (p *FakeProvider) SetMode(mode string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mode = mode
+}
+
+func (p *FakeProvider) Charge(req ChargeRequest) (*Payment, error) {
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+
+	p.mu.Lock()
+	if existing, ok := p.keys[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		p.mu.Unlock()
+		result := *existing
+		return &result, nil
+	}
+	mode := p.mode
+	webhook := p.webhook
+	id := fmt.Sprintf("fake_%d", p.nextID.Add(1))
+	result := &Payment{ID: id, Status: StatusSucceeded}
+	switch mode {
+	case FakeModeFailure:
+		result.Status = StatusFailed
+	case FakeModeAsync:
+		result.Status = StatusPending
+	}
+	// Declined charges may be retried with the same key.
+	if mode != FakeModeFailure {
+		p.charges[id] = req.Amount
+		if req.IdempotencyKey != "" {
+			p.keys[req.IdempotencyKey] = result
+		}
+	}
+	p.mu.Unlock()
+
+	if mode == FakeModeAsync && webhook != nil {
+		event := Event{PaymentID: id, OrderID: req.OrderID, Status: StatusSucceeded}
+		go func() {
+			time.Sleep(p.delay)
+			payload, _ := json.Marshal(event)
+			webhook(payload, p.Sign(payload))
+		}()
+	}
+	copied := *result
+	return &copied, nil
+}
+
+func (p *FakeProvider) Refund(paymentID string, amount int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	charged, exists := p.charges[paymentID]
+	if !exists {
+		return errors.New("payment not found")
+	}
+	if amount > charged {
+		return errors.New("refund exceeds charged amount")
+	}
+	p.charges[paymentID] = charged - amount
+	return nil
+}
+
+func (p *FakeProvider) ParseWebhook(payload []byte, signature string) (*Event, error) {
+	if !hmac.Equal([]byte(p.Sign(payload)), []byte(signature)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	return &event, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of payload.
+func (p *FakeProvider) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
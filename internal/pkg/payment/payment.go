@@ -0,0 +1,44 @@
+package payment
+
+import (
+	"errors"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// ChargeRequest asks for Amount minor units of Currency. Providers return
+// the existing payment for a repeated IdempotencyKey instead of charging
+// again.
+type ChargeRequest struct {
+	OrderID        int64
+	Amount         int64
+	Currency       string
+	IdempotencyKey string
+}
+
+type Payment struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// Event is the payload a provider sends to the webhook endpoint when an
+// asynchronous payment settles.
+type Event struct {
+	PaymentID string `json:"payment_id"`
+	OrderID   int64  `json:"order_id"`
+	Status    string `json:"status"`
+}
+
+// Provider is implemented by payment gateways. Charge may settle immediately
+// or return a pending payment that is later confirmed through a webhook.
+type Provider interface {
+	Charge(req ChargeRequest) (*Payment, error)
+	Refund(paymentID string, amount int64) error
+	ParseWebhook(payload []byte, signature string) (*Event, error)
+}
@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// KeyByUser limits signed-in users by ID and anonymous ones by address.
+	KeyByUser = "user"
+	// KeyByIP limits by client address only.
+	KeyByIP = "ip"
+)
+
+// Policy allows Limit requests per Period, at most Burst of them at once.
+// Burst defaults to Limit.
+type Policy struct {
+	Name   string        `json:"-"`
+	Limit  int           `json:"limit"`
+	Period time.Duration `json:"-"`
+	Burst  int           `json:"burst"`
+	KeyBy  string        `json:"key_by"`
+}
+
+// interval is the time it takes to earn back one request. It is only
+// defined for policies that pass validate.
+func (p Policy) interval() time.Duration {
+	return p.Period / time.Duration(p.Limit)
+}
+
+// validate rejects policies that would make interval zero or divide by
+// zero, such as a limit above one per nanosecond.
+func (p Policy) validate() error {
+	if p.Limit <= 0 || p.Period <= 0 || p.Burst < 0 {
+		return fmt.Errorf("rate limit policy %s needs a positive limit and period", p.Name)
+	}
+	if p.interval() <= 0 {
+		return fmt.Errorf("rate limit policy %s allows more than one request per nanosecond", p.Name)
+	}
+	if p.KeyBy != KeyByUser && p.KeyBy != KeyByIP {
+		return fmt.Errorf("rate limit policy %s: key_by must be %s or %s", p.Name, KeyByUser, KeyByIP)
+	}
+	return nil
+}
+
+func (p Policy) burst() int {
+	if p.Burst > 0 {
+		return p.Burst
+	}
+	return p.Limit
+}
+
+// Config maps routes, written as "METHOD /full/path" the way gin registers
+// them, to named policies. Routes not listed use Default, if set.
+type Config struct {
+	Policies map[string]Policy `json:"policies"`
+	Routes   map[string]string `json:"routes"`
+	Default  string            `json:"default"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Policies: map[string]Policy{
+			"register": {Limit: 5, Period: time.Hour, KeyBy: KeyByIP},
+			"login":    {Limit: 30, Period: time.Minute, Burst: 10, KeyBy: KeyByIP},
+			"reset":    {Limit: 5, Period: time.Hour, KeyBy: KeyByIP},
+			"write":    {Limit: 20, Period: time.Minute, Burst: 10, KeyBy: KeyByUser},
+			"contact":  {Limit: 5, Period: time.Hour, KeyBy: KeyByUser},
+			"verify":   {Limit: 10, Period: time.Hour, KeyBy: KeyByUser},
+		},
+		Routes: map[string]string{
+			"POST /api/auth/register":               "register",
+			"POST /api/auth/login":                  "login",
+			"POST /api/auth/login/2fa":              "login",
+			"POST /api/auth/password-reset":         "reset",
+			"POST /api/auth/password-reset/confirm": "reset",
+			"POST /api/listings":                    "write",
+			"PUT /api/listings/:id":                 "write",
+			"PUT /api/me/email":                     "contact",
+			"PUT /api/me/phone":                     "contact",
+			"POST /api/me/email/verification":       "contact",
+			"POST /api/me/phone/verification":       "contact",
+			"POST /api/me/phone/verify":             "verify",
+			"POST /api/auth/verify-email":           "verify",
+		},
+	}
+}
+
+func (c Config) Validate() error {
+	for name, policy := range c.Policies {
+		policy.Name = name
+		if err := policy.validate(); err != nil {
+			return err
+		}
+	}
+	for route, name := range c.Routes {
+		if _, ok := c.Policies[name]; !ok {
+			return fmt.Errorf("route %s uses unknown rate limit policy %s", route, name)
+		}
+	}
+	if _, ok := c.Policies[c.Default]; c.Default != "" && !ok {
+		return fmt.Errorf("unknown default rate limit policy %s", c.Default)
+	}
+	return nil
+}
+
+// LoadConfig reads a JSON config file on top of DefaultConfig. Policies
+// and routes in the file replace the default ones of the same name;
+// periods are written as Go durations, e.g. "1m".
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to read rate limit config: %w", err)
+	}
+
+	var file struct {
+		Policies map[string]struct {
+			Policy
+			Period string `json:"period"`
+		} `json:"policies"`
+		Routes  map[string]string `json:"routes"`
+		Default string            `json:"default"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return config, fmt.Errorf("failed to parse rate limit config: %w", err)
+	}
+
+	for name, p := range file.Policies {
+		policy := p.Policy
+		if policy.Period, err = time.ParseDuration(p.Period); err != nil {
+			return config, fmt.Errorf("invalid period for rate limit policy %s: %w", name, err)
+		}
+		if policy.KeyBy == "" {
+			policy.KeyBy = KeyByUser
+		}
+		config.Policies[name] = policy
+	}
+	for route, name := range file.Routes {
+		config.Routes[route] = name
+	}
+	if file.Default != "" {
+		config.Default = file.Default
+	}
+
+	return config, config.Validate()
+}
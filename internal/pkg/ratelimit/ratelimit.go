@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Store keeps the theoretical arrival time (TAT) of each key. Update must
+// run fn atomically for key: fn receives the stored TAT, zero if there is
+// none, and returns the TAT to store and whether to store it.
+type Store interface {
+	Update(key string, fn func(tat time.Time) (time.Time, bool)) error
+	// DeleteExpired forgets keys whose TAT is before the given time; such
+	// keys have a full bucket anyway.
+	DeleteExpired(before time.Time) error
+}
+
+// Result describes the state of a key after a request.
+type Result struct {
+	Allowed bool
+	// Limit is the burst size, the most requests allowed at once.
+	Limit     int
+	Remaining int
+	// ResetAfter is the time until the bucket is full again.
+	ResetAfter time.Duration
+	// RetryAfter is the time until the next request is allowed. It is
+	// only set for rejected requests.
+	RetryAfter time.Duration
+}
+
+// Limiter applies the GCRA, the sliding token bucket, to requests keyed by
+// policy and client.
+type Limiter struct {
+	store  Store
+	config Config
+}
+
+func NewLimiter(store Store, config Config) (*Limiter, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &Limiter{store: store, config: config}, nil
+}
+
+// Policy returns the policy for a route, given as gin's method and full
+// path, falling back to the default policy.
+func (l *Limiter) Policy(method, route string) (Policy, bool) {
+	name, ok := l.config.Routes[method+" "+route]
+	if !ok {
+		name = l.config.Default
+	}
+	if name == "" {
+		return Policy{}, false
+	}
+	policy, ok := l.config.Policies[name]
+	policy.Name = name
+	return policy, ok
+}
+
+// Allow counts a request by key against policy at now.
+func (l *Limiter) Allow(policy Policy, key string, now time.Time) (Result, error) {
+	if err := policy.validate(); err != nil {
+		return Result{}, err
+	}
+	interval := policy.interval()
+	burst := policy.burst()
+	tolerance := interval * time.Duration(burst)
+
+	result := Result{Limit: burst}
+	err := l.store.Update(policy.Name+":"+key, func(tat time.Time) (time.Time, bool) {
+		if tat.Before(now) {
+			tat = now
+		}
+		next := tat.Add(interval)
+		allowAt := next.Add(-tolerance)
+		if now.Before(allowAt) {
+			result.RetryAfter = allowAt.Sub(now)
+			result.ResetAfter = tat.Sub(now)
+			return time.Time{}, false
+		}
+		result.Allowed = true
+		result.Remaining = int(now.Sub(allowAt) / interval)
+		result.ResetAfter = next.Sub(now)
+		return next, true
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	return result, nil
+}
+
+// RunPruner deletes expired keys from the store every interval until ctx
+// is cancelled.
+func (l *Limiter) RunPruner(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.store.DeleteExpired(time.Now().UTC()); err != nil {
+				log.Println("Failed to prune rate limits:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package screening
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Config describes a screening pipeline. Actions maps rule names to the
+// action taken when the rule matches; rules missing from the map are
+// allowed.
+type Config struct {
+	BannedWords        []string      `json:"banned_words"`
+	BannedPatterns     []string      `json:"banned_patterns"`
+	AllowedDomains     []string      `json:"allowed_domains"`
+	DuplicateWindow    time.Duration `json:"-"`
+	DuplicateThreshold float64       `json:"duplicate_threshold"`
+	// NearDuplicateMaxDistance is the largest fingerprint distance, in bits,
+	// at which RuleNearDuplicate matches.
+	NearDuplicateMaxDistance int               `json:"near_duplicate_max_distance"`
+	Actions                  map[string]Action `json:"actions"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		DuplicateWindow:          7 * 24 * time.Hour,
+		DuplicateThreshold:       0.9,
+		NearDuplicateMaxDistance: 3,
+		Actions: map[string]Action{
+			RuleBannedWords: ActionReject,
+			RulePhone:       ActionFlag,
+			RuleEmail:       ActionFlag,
+			RuleLink:        ActionFlag,
+			RuleDuplicate:   ActionReject,
+		},
+	}
+}
+
+// LoadConfig reads a JSON config file on top of DefaultConfig. Actions in
+// the file override the default action of the rules they name.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to read screening config: %w", err)
+	}
+
+	var file Config
+	if err := json.Unmarshal(data, &file); err != nil {
+		return config, fmt.Errorf("failed to parse screening config: %w", err)
+	}
+
+	config.BannedWords = file.BannedWords
+	config.BannedPatterns = file.BannedPatterns
+	config.AllowedDomains = file.AllowedDomains
+	if file.DuplicateThreshold > 0 {
+		config.DuplicateThreshold = file.DuplicateThreshold
+	}
+	if file.NearDuplicateMaxDistance > 0 {
+		config.NearDuplicateMaxDistance = file.NearDuplicateMaxDistance
+	}
+	for rule, action := range file.Actions {
+		if action != ActionAllow && action != ActionFlag && action != ActionReject {
+			return config, fmt.Errorf("invalid action %q for rule %s", action, rule)
+		}
+		config.Actions[rule] = action
+	}
+
+	return config, nil
+}
+
+// New builds the standard pipeline from config. recent is used by the
+// duplicate check to look up the author's recent listings.
+func New(config Config, recent RecentFunc) (*Pipeline, error) {
+	bannedWords, err := NewBannedWordsRule(config.BannedWords, config.BannedPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPipeline().
+		Add(bannedWords, config.Actions[RuleBannedWords]).
+		Add(PhoneRule{}, config.Actions[RulePhone]).
+		Add(EmailRule{}, config.Actions[RuleEmail]).
+		Add(NewLinkRule(config.AllowedDomains), config.Actions[RuleLink]).
+		Add(NewDuplicateRule(recent, config.DuplicateWindow, config.DuplicateThreshold), config.Actions[RuleDuplicate]), nil
+}
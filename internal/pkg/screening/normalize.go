@@ -0,0 +1,72 @@
+package screening
+
+import (
+	"strings"
+	"unicode"
+)
+
+// skeleton maps characters that are commonly swapped to dodge filters onto
+// one representative: leetspeak digits, and Cyrillic/Greek letters that look
+// like Latin ones. The mapping is lossy on purpose; it is applied to both the
+// banned terms and the text, so only the two skeletons have to agree.
+var skeleton = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '6': 'b', '7': 't', '8': 'b', '9': 'g',
+
+	'l': 'i',
+
+	'а': 'a', 'в': 'b', 'б': 'b', 'е': 'e', 'ё': 'e', 'з': 'e', 'і': 'i', 'ї': 'i',
+	'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y',
+	'х': 'x', 'ѕ': 's', 'ј': 'j', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w',
+
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'y', 'χ': 'x',
+}
+
+// symbolSkeleton is applied only to symbols followed by a letter or digit,
+// so "v!agra" is folded but a trailing "!" is not.
+var symbolSkeleton = map[rune]rune{
+	'@': 'a', '$': 's', '!': 'i', '|': 'i', '€': 'e',
+}
+
+// Normalize lowercases text, folds look-alike characters, drops separators
+// placed inside words ("f.r.e.e", "ф-р-и") and squeezes repeated letters, so
+// that "FRЕЕЕ" and "fr33" compare equal. Words are separated by single
+// spaces in the result.
+func Normalize(text string) string {
+	runes := []rune(strings.ToLower(text))
+
+	var b strings.Builder
+	var prev rune
+	for i, r := range runes {
+		if unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		followedByWord := i+1 < len(runes) && isWordRune(runes[i+1])
+		if isInnerSeparator(r) && i > 0 && isWordRune(runes[i-1]) && followedByWord {
+			continue
+		}
+		if mapped, ok := skeleton[r]; ok {
+			r = mapped
+		} else if mapped, ok := symbolSkeleton[r]; ok && followedByWord {
+			r = mapped
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			r = ' '
+		}
+		if r == prev {
+			continue
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func isInnerSeparator(r rune) bool {
+	return r == '.' || r == '-' || r == '_' || r == '*'
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
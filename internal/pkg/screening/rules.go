@@ -0,0 +1,244 @@
+package screening
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	RuleBannedWords = "banned_words"
+	RulePhone       = "phone"
+	RuleEmail       = "email"
+	RuleLink        = "link"
+	RuleDuplicate   = "duplicate"
+	// RuleNearDuplicate compares fingerprints against listings of all
+	// sellers. It is provided by the caller, see NewRule.
+	RuleNearDuplicate = "near_duplicate"
+)
+
+// BannedWordsRule matches whole words or phrases after normalization, plus
+// regular expressions evaluated against both the lowercased and the
+// normalized text.
+type BannedWordsRule struct {
+	terms    map[string]string
+	patterns []*regexp.Regexp
+}
+
+func NewBannedWordsRule(words, patterns []string) (*BannedWordsRule, error) {
+	rule := &BannedWordsRule{terms: make(map[string]string, len(words))}
+	for _, word := range words {
+		if term := Normalize(word); term != "" {
+			rule.terms[term] = word
+		}
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid banned pattern %q: %w", pattern, err)
+		}
+		rule.patterns = append(rule.patterns, re)
+	}
+	return rule, nil
+}
+
+func (r *BannedWordsRule) Name() string {
+	return RuleBannedWords
+}
+
+func (r *BannedWordsRule) Check(content *Content) ([]string, error) {
+	raw := strings.ToLower(content.text())
+	normalized := Normalize(raw)
+	padded := " " + normalized + " "
+
+	var matches []string
+	for term, original := range r.terms {
+		if strings.Contains(padded, " "+term+" ") {
+			matches = append(matches, original)
+		}
+	}
+	sort.Strings(matches)
+	for _, re := range r.patterns {
+		if m := re.FindString(raw); m != "" {
+			matches = append(matches, m)
+		} else if m := re.FindString(normalized); m != "" {
+			matches = append(matches, m)
+		}
+	}
+	return matches, nil
+}
+
+var phoneCandidate = regexp.MustCompile(`\+?\d[\d\s().\-]{8,}\d`)
+
+// PhoneRule finds phone numbers: runs of 10 to 15 digits that may be split
+// by spaces, dashes, dots or parentheses.
+type PhoneRule struct{}
+
+func (PhoneRule) Name() string {
+	return RulePhone
+}
+
+func (PhoneRule) Check(content *Content) ([]string, error) {
+	var matches []string
+	for _, candidate := range phoneCandidate.FindAllString(content.Description, -1) {
+		digits := 0
+		for _, r := range candidate {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		if digits >= 10 && digits <= 15 {
+			matches = append(matches, strings.TrimSpace(candidate))
+		}
+	}
+	return matches, nil
+}
+
+var emailPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}`),
+	regexp.MustCompile(`(?i)[a-z0-9._%+\-]+\s*(?:\(at\)|\[at\]|\sat\s|собака)\s*[a-z0-9\-]+\s*(?:\(dot\)|\[dot\]|\sdot\s|\sточка\s)\s*[a-z]{2,}`),
+}
+
+// EmailRule finds email addresses, including the "name (at) host (dot) com"
+// spelling used to get past naive filters.
+type EmailRule struct{}
+
+func (EmailRule) Name() string {
+	return RuleEmail
+}
+
+func (EmailRule) Check(content *Content) ([]string, error) {
+	var matches []string
+	for _, re := range emailPatterns {
+		matches = append(matches, re.FindAllString(content.Description, -1)...)
+	}
+	return matches, nil
+}
+
+var (
+	urlPattern    = regexp.MustCompile(`(?i)(?:https?://|www\.)[^\s<>"]+`)
+	domainPattern = regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}@.\-])((?:[\p{L}\p{N}\-]+\.)+(?:com|net|org|info|biz|io|me|ru|su|рф|ua|by|kz|ly|to|cc|xyz|site|online|shop|store))(?:$|[^\p{L}\p{N}])`)
+)
+
+// LinkRule finds external links and bare domain names. Hosts listed as
+// allowed, and their subdomains, are ignored.
+type LinkRule struct {
+	allowed []string
+}
+
+func NewLinkRule(allowedDomains []string) *LinkRule {
+	rule := &LinkRule{}
+	for _, domain := range allowedDomains {
+		rule.allowed = append(rule.allowed, strings.ToLower(strings.TrimPrefix(domain, "www.")))
+	}
+	return rule
+}
+
+func (r *LinkRule) Name() string {
+	return RuleLink
+}
+
+func (r *LinkRule) Check(content *Content) ([]string, error) {
+	text := content.Description
+	for _, re := range emailPatterns {
+		text = re.ReplaceAllString(text, " ")
+	}
+
+	var matches []string
+	for _, link := range urlPattern.FindAllString(text, -1) {
+		if !r.isAllowed(hostOf(link)) {
+			matches = append(matches, link)
+		}
+	}
+	text = urlPattern.ReplaceAllString(text, " ")
+	for _, m := range domainPattern.FindAllStringSubmatch(text, -1) {
+		if !r.isAllowed(m[1]) {
+			matches = append(matches, m[1])
+		}
+	}
+	return matches, nil
+}
+
+func (r *LinkRule) isAllowed(host string) bool {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	for _, allowed := range r.allowed {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func hostOf(link string) string {
+	link = strings.ToLower(link)
+	for _, prefix := range []string{"https://", "http://"} {
+		link = strings.TrimPrefix(link, prefix)
+	}
+	if i := strings.IndexAny(link, "/?#:"); i >= 0 {
+		link = link[:i]
+	}
+	return link
+}
+
+// RecentFunc returns the author's listings created since the given time.
+type RecentFunc func(authorID int64, since time.Time) ([]*Content, error)
+
+// DuplicateRule catches reposts of the author's own recent listings: two
+// listings are duplicates when the word sets of their normalized text
+// overlap by at least Threshold (Jaccard index).
+type DuplicateRule struct {
+	recent    RecentFunc
+	window    time.Duration
+	threshold float64
+}
+
+func NewDuplicateRule(recent RecentFunc, window time.Duration, threshold float64) *DuplicateRule {
+	return &DuplicateRule{recent: recent, window: window, threshold: threshold}
+}
+
+func (r *DuplicateRule) Name() string {
+	return RuleDuplicate
+}
+
+func (r *DuplicateRule) Check(content *Content) ([]string, error) {
+	others, err := r.recent(content.AuthorID, time.Now().Add(-r.window))
+	if err != nil {
+		return nil, err
+	}
+
+	words := wordSet(content.text())
+	var matches []string
+	for _, other := range others {
+		if other.ListingID == content.ListingID {
+			continue
+		}
+		if jaccard(words, wordSet(other.text())) >= r.threshold {
+			matches = append(matches, fmt.Sprintf("listing %d", other.ListingID))
+		}
+	}
+	return matches, nil
+}
+
+func wordSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(Normalize(text)) {
+		set[word] = true
+	}
+	return set
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
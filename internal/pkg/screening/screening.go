@@ -0,0 +1,148 @@
+package screening
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Action string
+
+const (
+	ActionAllow  Action = "allow"
+	ActionFlag   Action = "flag"
+	ActionReject Action = "reject"
+)
+
+func (a Action) severity() int {
+	switch a {
+	case ActionReject:
+		return 2
+	case ActionFlag:
+		return 1
+	}
+	return 0
+}
+
+// Content is the part of a listing that is screened. ListingID is zero for
+// listings that are being created; ImageHash is set when the image could be
+// fingerprinted.
+type Content struct {
+	ListingID   int64
+	AuthorID    int64
+	Title       string
+	Description string
+	ImageHash   *uint64
+}
+
+func (c *Content) text() string {
+	return c.Title + "\n" + c.Description
+}
+
+// Rule inspects content and returns what it matched, or nothing.
+type Rule interface {
+	Name() string
+	Check(content *Content) ([]string, error)
+}
+
+type ruleFunc struct {
+	name  string
+	check func(content *Content) ([]string, error)
+}
+
+// NewRule adapts a function to the Rule interface, for checks that need
+// data this package has no access to.
+func NewRule(name string, check func(content *Content) ([]string, error)) Rule {
+	return &ruleFunc{name: name, check: check}
+}
+
+func (r *ruleFunc) Name() string {
+	return r.name
+}
+
+func (r *ruleFunc) Check(content *Content) ([]string, error) {
+	return r.check(content)
+}
+
+type Finding struct {
+	Rule    string   `json:"rule"`
+	Action  Action   `json:"action"`
+	Matches []string `json:"matches,omitempty"`
+}
+
+type Result struct {
+	Action   Action
+	Findings []Finding
+}
+
+func (r *Result) Rejected() bool {
+	return r != nil && r.Action == ActionReject
+}
+
+func (r *Result) Flagged() bool {
+	return r != nil && r.Action == ActionFlag
+}
+
+// Summary renders the findings for moderators, e.g. "phone: +7 900 123-45-67".
+func (r *Result) Summary() string {
+	parts := make([]string, 0, len(r.Findings))
+	for _, f := range r.Findings {
+		parts = append(parts, f.Rule+": "+strings.Join(f.Matches, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// RejectedError is returned for content that matched a rejecting rule.
+type RejectedError struct {
+	Findings []Finding
+}
+
+func (e *RejectedError) Error() string {
+	rules := make([]string, 0, len(e.Findings))
+	for _, f := range e.Findings {
+		if f.Action == ActionReject {
+			rules = append(rules, f.Rule)
+		}
+	}
+	return fmt.Sprintf("listing rejected by content screening: %s", strings.Join(rules, ", "))
+}
+
+type step struct {
+	rule   Rule
+	action Action
+}
+
+// Pipeline runs rules in the order they were added. The overall action is
+// the most severe action among the rules that matched.
+type Pipeline struct {
+	steps []step
+}
+
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Add registers a rule. Rules set to ActionAllow are skipped entirely.
+func (p *Pipeline) Add(rule Rule, action Action) *Pipeline {
+	if action == ActionFlag || action == ActionReject {
+		p.steps = append(p.steps, step{rule: rule, action: action})
+	}
+	return p
+}
+
+func (p *Pipeline) Screen(content *Content) (*Result, error) {
+	result := &Result{Action: ActionAllow}
+	for _, s := range p.steps {
+		matches, err := s.rule.Check(content)
+		if err != nil {
+			return nil, fmt.Errorf("screening rule %s: %w", s.rule.Name(), err)
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		result.Findings = append(result.Findings, Finding{Rule: s.rule.Name(), Action: s.action, Matches: matches})
+		if s.action.severity() > result.Action.severity() {
+			result.Action = s.action
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package sms
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Message is a text message to a phone number in E.164 form.
+type Message struct {
+	To   string
+	Body string
+}
+
+// Sender delivers text messages, e.g. through an SMS gateway API.
+type Sender interface {
+	Send(msg *Message) error
+}
+
+// LogSender writes messages to the log, for local use.
+type LogSender struct{}
+
+func (LogSender) Send(msg *Message) error {
+	log.Printf("sms to %s: %s", msg.To, msg.Body)
+	return nil
+}
+
+// FileSender writes each message to its own .txt file in Dir, for local
+// use and tests that need to read what was sent.
+type FileSender struct {
+	Dir string
+}
+
+func (s FileSender) Send(msg *Message) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create sms directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(s.Dir, time.Now().UTC().Format("20060102T150405")+"-*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to create sms file: %w", err)
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintf(file, "To: %s\n\n%s", msg.To, msg.Body); err != nil {
+		return fmt.Errorf("failed to write sms file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "errors"
+
+// ErrConflict is returned by compare-and-set updates when the stored row no
+// longer has the expected state.
+var ErrConflict = errors.New("record was modified concurrently")
+
+// ErrDuplicate is returned when a uniqueness rule would be violated.
+var ErrDuplicate = errors.New("record already exists")
@@ -1,11 +1,135 @@
 package repository
 
-import "vk/ecom/internal/domain"
+import (
+	"time"
+	"vk/ecom/internal/domain"
+)
 
 type UserRepository interface {
 	Create(user *domain.User) error
 	GetByID(id int) (*domain.User, error)
 	GetByLogin(login string) (*domain.User, error)
+	SetBanned(id int, banned bool) error
+	// SetPassword stores a new password hash. With revokeSessions the
+	// user's session version is bumped, invalidating issued tokens.
+	SetPassword(id int, passwordHash string, revokeSessions bool) error
+	// RehashPassword replaces the password hash only if it still equals
+	// oldHash, otherwise it returns ErrConflict.
+	RehashPassword(id int, oldHash, newHash string) error
+	// SetEmail and SetPhone store a new address or number, "" to remove
+	// it, and mark it unverified. Only verified addresses and numbers are
+	// unique: Create returns ErrDuplicate for a verified address or number
+	// another user has verified.
+	SetEmail(id int, email string) error
+	SetPhone(id int, phone string) error
+	// MarkEmailVerified and MarkPhoneVerified verify the stored value only
+	// if it still equals the given one, otherwise they return ErrConflict.
+	// They return ErrDuplicate if another user has already verified the
+	// address or number.
+	MarkEmailVerified(id int, email string) error
+	MarkPhoneVerified(id int, phone string) error
+	Delete(id int) error
+}
+
+// TwoFactorRepository stores TOTP setups and their recovery codes, of
+// which only hashes are kept. Get returns nil for users without a setup.
+type TwoFactorRepository interface {
+	Get(userID int) (*domain.TwoFactor, error)
+	// SetSecret starts enrollment, replacing a pending secret. It returns
+	// ErrConflict if two-factor auth is already enabled.
+	SetSecret(userID int, secret string) error
+	// Enable turns a pending setup on, recording step as used, and replaces
+	// the recovery codes. It returns ErrConflict if already enabled.
+	Enable(userID int, step int64, recoveryCodeHashes []string) error
+	// UseStep records step as used if it is after the last used one and
+	// reports whether it was.
+	UseStep(userID int, step int64) (bool, error)
+	// UseRecoveryCode removes the code and reports whether it existed.
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+	CountRecoveryCodes(userID int) (int, error)
+	// Delete removes the setup and the recovery codes.
+	Delete(userID int) error
+}
+
+// IdentityRepository stores external identities. Get returns nil for an
+// unknown identity; Create returns ErrDuplicate if the identity is linked
+// already or the user has one at the provider.
+type IdentityRepository interface {
+	Get(provider, subject string) (*domain.Identity, error)
+	GetByUserID(userID int) ([]*domain.Identity, error)
+	Create(identity *domain.Identity) error
+	Delete(userID int, provider string) error
+	// CreateState stores the state of a started login; ConsumeState
+	// removes and returns it, or nil if it is unknown or expired.
+	CreateState(state *domain.ExternalLoginState) error
+	ConsumeState(stateHash string, now time.Time) (*domain.ExternalLoginState, error)
+}
+
+// SessionRepository stores login sessions. Get returns nil for an unknown
+// session.
+type SessionRepository interface {
+	Create(session *domain.Session) error
+	Get(id int64) (*domain.Session, error)
+	// GetActive returns the user's sessions that are neither revoked nor
+	// expired at now, most recently seen first.
+	GetActive(userID int, now time.Time) ([]*domain.Session, error)
+	SetLastSeen(id int64, at time.Time) error
+	// Revoke revokes the user's session and returns false if it has no
+	// such active session.
+	Revoke(userID int, id int64, now time.Time) (bool, error)
+	// RevokeAll revokes all active sessions of the user except exceptID and
+	// returns how many it revoked.
+	RevokeAll(userID int, exceptID int64, now time.Time) (int, error)
+}
+
+// APIKeyRepository stores API keys. Revoked keys are kept for the owner's
+// list; GetByHash returns them too, or nil for an unknown hash.
+type APIKeyRepository interface {
+	Create(key *domain.APIKey) error
+	GetByHash(keyHash string) (*domain.APIKey, error)
+	GetByUserID(userID int) ([]*domain.APIKey, error)
+	// Revoke marks the user's key as revoked at now. It returns false if
+	// the user has no such active key.
+	Revoke(userID int, id int64, now time.Time) (bool, error)
+	SetLastUsed(id int64, at time.Time) error
+}
+
+type PasswordResetRepository interface {
+	Create(token *domain.PasswordResetToken) error
+	// Consume marks the unused, unexpired token with the hash as used and
+	// returns it, or nil if there is none.
+	Consume(tokenHash string, now time.Time) (*domain.PasswordResetToken, error)
+	// DeleteByUserID removes all of the user's tokens.
+	DeleteByUserID(userID int) error
+}
+
+// PhoneCodeRepository stores each user's pending phone verification code.
+type PhoneCodeRepository interface {
+	// Set replaces the user's pending code.
+	Set(code *domain.PhoneCode) error
+	// Attempt counts a guess against the user's code if it is unexpired at
+	// now and returns the updated code, or nil if there is none.
+	Attempt(userID int, now time.Time) (*domain.PhoneCode, error)
+	Delete(userID int) error
+}
+
+// LoginAttemptRepository tracks failed logins per key, see
+// domain.LoginAttempts.
+type LoginAttemptRepository interface {
+	// Update runs fn on the key's record, or on an empty one, and stores
+	// the record if fn returns true. Updates of a key are serialized across
+	// instances.
+	Update(key string, fn func(attempts *domain.LoginAttempts) bool) error
+	Clear(key string) error
+	// GetLocked returns the records locked beyond now.
+	GetLocked(now time.Time) ([]*domain.LoginAttempts, error)
+}
+
+// RateLimitRepository stores rate limit state and satisfies
+// ratelimit.Store. Update runs fn atomically for key across instances.
+type RateLimitRepository interface {
+	Update(key string, fn func(tat time.Time) (time.Time, bool)) error
+	DeleteExpired(before time.Time) error
 }
 
 type ListingRepository interface {
@@ -13,5 +137,114 @@ type ListingRepository interface {
 	GetByID(id int64) (*domain.Listing, error)
 	GetAll(sortBy, sortOrder string, minPrice, maxPrice *int64) ([]*domain.Listing, error)
 	GetAllWithPagination(sortBy, sortOrder string, minPrice, maxPrice *int64, page, pageSize int) ([]*domain.Listing, int, error)
+	// Search returns a page of active listings matching the query and the
+	// total number of matches.
+	Search(query *domain.ListingQuery) ([]*domain.Listing, int, error)
+	// Facets counts the requested facets over the listings matching query,
+	// each ignoring the query's filter on its own dimension.
+	Facets(query *domain.ListingQuery, facets *domain.FacetQuery) (*domain.ListingFacets, error)
 	GetByAuthorID(authorID int64) ([]*domain.Listing, error)
+	UpdateStatus(id int64, from, to string) error
+	// Update stores the editable fields: title, description, image and price
+	// with its drop markers. A changed price is recorded in the history.
+	Update(listing *domain.Listing) error
+	// Delete removes a listing together with its price history.
+	Delete(id int64) error
+	// GetPriceHistory returns the listing's price changes, oldest first.
+	GetPriceHistory(listingID int64) ([]*domain.PriceChange, error)
+	// FindNearDuplicates returns other listings whose text or image hash is
+	// within maxDistance bits of the listing's. maxDistance must not exceed
+	// fingerprint.MaxBandDistance.
+	FindNearDuplicates(listing *domain.Listing, maxDistance int) ([]*domain.NearDuplicate, error)
+	// FindSimilar returns up to limit active listings of other authors that
+	// share words or the category with the listing, best matches first.
+	FindSimilar(listing *domain.Listing, limit int) ([]*domain.SimilarListing, error)
+	// SetPopularity stores the scores and resets all other listings to zero.
+	SetPopularity(scores map[int64]float64) error
+}
+
+// AuctionRepository stores auction state and bids. Update must serialize
+// concurrent callers per listing: fn sees the latest persisted auction, may
+// mutate it and may return a bid to be stored together with the new state.
+// FavoriteRepository stores which users favorited which listings. Adding
+// an existing favorite or removing a missing one is not an error.
+type FavoriteRepository interface {
+	Add(userID, listingID int64) error
+	Remove(userID, listingID int64) error
+	GetUserIDs(listingID int64) ([]int64, error)
+}
+
+type AuctionRepository interface {
+	Create(auction *domain.Auction) error
+	GetByListingID(listingID int64) (*domain.Auction, error)
+	Update(listingID int64, fn func(auction *domain.Auction) (*domain.Bid, error)) (*domain.Bid, error)
+	GetBids(listingID int64) ([]*domain.Bid, error)
+	GetExpired(now time.Time) ([]*domain.Auction, error)
+}
+
+type OrderRepository interface {
+	Create(order *domain.Order) error
+	GetByID(id int64) (*domain.Order, error)
+	GetByBuyerID(buyerID int64) ([]*domain.Order, error)
+	GetBySellerID(sellerID int64) ([]*domain.Order, error)
+	GetByPaymentID(paymentID string) (*domain.Order, error)
+	// Update persists order only if the stored status still equals
+	// expectedStatus, otherwise it returns ErrConflict.
+	Update(order *domain.Order, expectedStatus string) error
+	// SetPaymentID replaces the payment ID of a created order only if it
+	// still equals expected, otherwise it returns ErrConflict.
+	SetPaymentID(id int64, expected, paymentID string) error
+}
+
+type CartRepository interface {
+	GetItems(cartKey string) ([]*domain.CartItem, error)
+	// AddItem inserts the item or leaves an existing one for the same listing
+	// untouched, so the originally seen price is kept.
+	AddItem(item *domain.CartItem) error
+	RemoveItems(cartKey string, listingIDs []int64) error
+	// Merge moves all items of fromKey into toKey, skipping listings that are
+	// already in the destination cart, and deletes the source cart.
+	Merge(fromKey, toKey string) error
+}
+
+type ReviewRepository interface {
+	ConfirmPurchase(confirmation *domain.PurchaseConfirmation) error
+	GetPurchaseConfirmation(listingID int64) (*domain.PurchaseConfirmation, error)
+	// Create stores the review and updates the seller's aggregate rating in
+	// one step. A second review for the same listing returns ErrDuplicate.
+	Create(review *domain.Review) error
+	GetByID(id int64) (*domain.Review, error)
+	GetBySellerID(sellerID int64) ([]*domain.Review, error)
+	// SetReply stores the seller reply only if the review has none yet,
+	// otherwise it returns ErrConflict.
+	SetReply(reviewID int64, reply string, repliedAt time.Time) error
+	GetSellerRatings(sellerIDs []int64) (map[int64]*domain.SellerRating, error)
+}
+
+type ModerationRepository interface {
+	// AddReport files the report under the listing's unresolved case, opening
+	// one if needed, and returns the case with the updated report count. A
+	// second report by the same user on the same case returns ErrDuplicate.
+	AddReport(report *domain.Report) (*domain.ModerationCase, error)
+	CountReportsSince(reporterID int64, since time.Time) (int, error)
+	GetCase(id int64) (*domain.ModerationCase, error)
+	// GetCases returns cases with the given status, most reported first.
+	GetCases(status string, limit int) ([]*domain.ModerationCase, error)
+	GetReports(caseID int64) ([]*domain.Report, error)
+	SetAutoHidden(caseID int64) error
+	// UpdateCase persists the case only if the stored status and claimant
+	// still match expectedStatus and expectedClaimant, otherwise it returns
+	// ErrConflict.
+	UpdateCase(c *domain.ModerationCase, expectedStatus string, expectedClaimant *int64) error
+}
+
+type AnalyticsRepository interface {
+	// AddDailyStats adds the counts to the stored daily aggregates in a
+	// single batch. Each listing and day appears at most once.
+	AddDailyStats(stats []*domain.ListingDailyStats) error
+	// GetDailyStats returns the stored days between from and to inclusive,
+	// oldest first. Days without events are omitted.
+	GetDailyStats(listingID int64, from, to time.Time) ([]*domain.ListingDailyStats, error)
+	// GetDailyStatsSince returns the stored days of all listings from since on.
+	GetDailyStatsSince(since time.Time) ([]*domain.ListingDailyStats, error)
 }
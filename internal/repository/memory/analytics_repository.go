@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+type statsKey struct {
+	listingID int64
+	day       string
+}
+
+type InMemoryAnalyticsRepository struct {
+	stats map[statsKey]*domain.ListingDailyStats
+	mu    sync.RWMutex
+}
+
+func NewInMemoryAnalyticsRepository() *InMemoryAnalyticsRepository {
+	return &InMemoryAnalyticsRepository{
+		stats: make(map[statsKey]*domain.ListingDailyStats),
+	}
+}
+
+func truncateDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+func (r *InMemoryAnalyticsRepository) AddDailyStats(stats []*domain.ListingDailyStats) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range stats {
+		day := truncateDay(s.Day)
+		key := statsKey{listingID: s.ListingID, day: day.Format(time.DateOnly)}
+		stored, ok := r.stats[key]
+		if !ok {
+			stored = &domain.ListingDailyStats{ListingID: s.ListingID, Day: day}
+			r.stats[key] = stored
+		}
+		stored.Merge(s)
+	}
+	return nil
+}
+
+func (r *InMemoryAnalyticsRepository) GetDailyStats(listingID int64, from, to time.Time) ([]*domain.ListingDailyStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	from, to = truncateDay(from), truncateDay(to)
+	result := []*domain.ListingDailyStats{}
+	for _, s := range r.stats {
+		if s.ListingID == listingID && !s.Day.Before(from) && !s.Day.After(to) {
+			stored := *s
+			result = append(result, &stored)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Day.Before(result[j].Day)
+	})
+	return result, nil
+}
+
+func (r *InMemoryAnalyticsRepository) GetDailyStatsSince(since time.Time) ([]*domain.ListingDailyStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	since = truncateDay(since)
+	result := []*domain.ListingDailyStats{}
+	for _, s := range r.stats {
+		if !s.Day.Before(since) {
+			stored := *s
+			result = append(result, &stored)
+		}
+	}
+	return result, nil
+}
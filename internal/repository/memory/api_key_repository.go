@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+type InMemoryAPIKeyRepository struct {
+	keys   []*domain.APIKey
+	nextID int64
+	mu     sync.RWMutex
+}
+
+func NewInMemoryAPIKeyRepository() *InMemoryAPIKeyRepository {
+	return &InMemoryAPIKeyRepository{
+		nextID: 1,
+	}
+}
+
+func copyAPIKey(key *domain.APIKey) *domain.APIKey {
+	k := *key
+	k.Scopes = append([]string(nil), key.Scopes...)
+	return &k
+}
+
+func (r *InMemoryAPIKeyRepository) Create(key *domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key.ID = r.nextID
+	r.nextID++
+	key.CreatedAt = time.Now()
+	r.keys = append(r.keys, copyAPIKey(key))
+	return nil
+}
+
+func (r *InMemoryAPIKeyRepository) GetByHash(keyHash string) (*domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash {
+			return copyAPIKey(key), nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *InMemoryAPIKeyRepository) GetByUserID(userID int) ([]*domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var keys []*domain.APIKey
+	for _, key := range r.keys {
+		if key.UserID == userID {
+			keys = append(keys, copyAPIKey(key))
+		}
+	}
+	return keys, nil
+}
+
+func (r *InMemoryAPIKeyRepository) Revoke(userID int, id int64, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range r.keys {
+		if key.ID == id && key.UserID == userID && key.RevokedAt == nil {
+			key.RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *InMemoryAPIKeyRepository) SetLastUsed(id int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range r.keys {
+		if key.ID == id {
+			key.LastUsedAt = &at
+		}
+	}
+	return nil
+}
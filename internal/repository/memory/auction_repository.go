@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+type InMemoryAuctionRepository struct {
+	auctions  map[int64]*domain.Auction
+	bids      map[int64][]*domain.Bid
+	nextBidID int64
+	mu        sync.RWMutex
+}
+
+func NewInMemoryAuctionRepository() *InMemoryAuctionRepository {
+	return &InMemoryAuctionRepository{
+		auctions:  make(map[int64]*domain.Auction),
+		bids:      make(map[int64][]*domain.Bid),
+		nextBidID: 1,
+	}
+}
+
+func (r *InMemoryAuctionRepository) Create(auction *domain.Auction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.auctions[auction.ListingID]; exists {
+		return errors.New("auction already exists")
+	}
+	if auction.Status == "" {
+		auction.Status = domain.AuctionStatusOpen
+	}
+	stored := *auction
+	r.auctions[auction.ListingID] = &stored
+	return nil
+}
+
+func (r *InMemoryAuctionRepository) GetByListingID(listingID int64) (*domain.Auction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	auction, exists := r.auctions[listingID]
+	if !exists {
+		return nil, errors.New("auction not found")
+	}
+	result := *auction
+	return &result, nil
+}
+
+// Update holds the write lock for the whole callback, so bids on the same
+// auction are accepted strictly one after another.
+func (r *InMemoryAuctionRepository) Update(listingID int64, fn func(auction *domain.Auction) (*domain.Bid, error)) (*domain.Bid, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.auctions[listingID]
+	if !exists {
+		return nil, errors.New("auction not found")
+	}
+
+	auction := *stored
+	bid, err := fn(&auction)
+	if err != nil {
+		return nil, err
+	}
+
+	if bid != nil {
+		bid.ID = r.nextBidID
+		bid.ListingID = listingID
+		r.nextBidID++
+		stored := *bid
+		r.bids[listingID] = append(r.bids[listingID], &stored)
+	}
+	r.auctions[listingID] = &auction
+
+	return bid, nil
+}
+
+func (r *InMemoryAuctionRepository) GetBids(listingID int64) ([]*domain.Bid, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*domain.Bid, 0, len(r.bids[listingID]))
+	for _, bid := range r.bids[listingID] {
+		b := *bid
+		result = append(result, &b)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID > result[j].ID
+	})
+	return result, nil
+}
+
+func (r *InMemoryAuctionRepository) GetExpired(now time.Time) ([]*domain.Auction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []*domain.Auction
+	for _, auction := range r.auctions {
+		if auction.Status == domain.AuctionStatusOpen && !auction.EndsAt.After(now) {
+			a := *auction
+			result = append(result, &a)
+		}
+	}
+	return result, nil
+}
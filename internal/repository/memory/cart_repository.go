@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+type InMemoryCartRepository struct {
+	carts map[string]map[int64]*domain.CartItem
+	mu    sync.RWMutex
+}
+
+func NewInMemoryCartRepository() *InMemoryCartRepository {
+	return &InMemoryCartRepository{
+		carts: make(map[string]map[int64]*domain.CartItem),
+	}
+}
+
+func (r *InMemoryCartRepository) GetItems(cartKey string) ([]*domain.CartItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	items := make([]*domain.CartItem, 0, len(r.carts[cartKey]))
+	for _, item := range r.carts[cartKey] {
+		i := *item
+		items = append(items, &i)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].AddedAt.Equal(items[j].AddedAt) {
+			return items[i].ListingID < items[j].ListingID
+		}
+		return items[i].AddedAt.Before(items[j].AddedAt)
+	})
+	return items, nil
+}
+
+func (r *InMemoryCartRepository) AddItem(item *domain.CartItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cart, exists := r.carts[item.CartKey]
+	if !exists {
+		cart = make(map[int64]*domain.CartItem)
+		r.carts[item.CartKey] = cart
+	}
+	if _, exists := cart[item.ListingID]; exists {
+		return nil
+	}
+	if item.AddedAt.IsZero() {
+		item.AddedAt = time.Now()
+	}
+	stored := *item
+	cart[item.ListingID] = &stored
+	return nil
+}
+
+func (r *InMemoryCartRepository) RemoveItems(cartKey string, listingIDs []int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range listingIDs {
+		delete(r.carts[cartKey], id)
+	}
+	return nil
+}
+
+func (r *InMemoryCartRepository) Merge(fromKey, toKey string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	source := r.carts[fromKey]
+	if len(source) == 0 {
+		delete(r.carts, fromKey)
+		return nil
+	}
+
+	target, exists := r.carts[toKey]
+	if !exists {
+		target = make(map[int64]*domain.CartItem)
+		r.carts[toKey] = target
+	}
+	for id, item := range source {
+		if _, exists := target[id]; !exists {
+			item.CartKey = toKey
+			target[id] = item
+		}
+	}
+	delete(r.carts, fromKey)
+	return nil
+}
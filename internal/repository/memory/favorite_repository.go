@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+)
+
+type InMemoryFavoriteRepository struct {
+	byListing map[int64]map[int64]struct{}
+	mu        sync.RWMutex
+}
+
+func NewInMemoryFavoriteRepository() *InMemoryFavoriteRepository {
+	return &InMemoryFavoriteRepository{
+		byListing: make(map[int64]map[int64]struct{}),
+	}
+}
+
+func (r *InMemoryFavoriteRepository) Add(userID, listingID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.byListing[listingID] == nil {
+		r.byListing[listingID] = make(map[int64]struct{})
+	}
+	r.byListing[listingID][userID] = struct{}{}
+	return nil
+}
+
+func (r *InMemoryFavoriteRepository) Remove(userID, listingID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.byListing[listingID], userID)
+	if len(r.byListing[listingID]) == 0 {
+		delete(r.byListing, listingID)
+	}
+	return nil
+}
+
+func (r *InMemoryFavoriteRepository) GetUserIDs(listingID int64) ([]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	userIDs := make([]int64, 0, len(r.byListing[listingID]))
+	for userID := range r.byListing[listingID] {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+	return userIDs, nil
+}
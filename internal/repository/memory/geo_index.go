@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"math"
+	"vk/ecom/internal/pkg/geo"
+)
+
+// geoCellDegrees is the side of a grid cell, about 55 km of latitude.
+const geoCellDegrees = 0.5
+
+type geoCell struct {
+	lat int
+	lng int
+}
+
+func cellOf(lat, lng float64) geoCell {
+	return geoCell{lat: int(math.Floor(lat / geoCellDegrees)), lng: int(math.Floor(lng / geoCellDegrees))}
+}
+
+// geoIndex buckets listing locations into a fixed grid so radius searches
+// only look at the cells overlapping the search box. Like tfidfIndex it
+// relies on the repository lock.
+type geoIndex struct {
+	cells map[geoCell]map[int64]struct{}
+	byID  map[int64]geoCell
+}
+
+func newGeoIndex() *geoIndex {
+	return &geoIndex{
+		cells: make(map[geoCell]map[int64]struct{}),
+		byID:  make(map[int64]geoCell),
+	}
+}
+
+// set moves the listing to the cell of point, or drops it if point is nil.
+func (idx *geoIndex) set(id int64, point *geo.Point) {
+	if cell, ok := idx.byID[id]; ok {
+		delete(idx.cells[cell], id)
+		if len(idx.cells[cell]) == 0 {
+			delete(idx.cells, cell)
+		}
+		delete(idx.byID, id)
+	}
+	if point == nil {
+		return
+	}
+
+	cell := cellOf(point.Lat, point.Lng)
+	if idx.cells[cell] == nil {
+		idx.cells[cell] = make(map[int64]struct{})
+	}
+	idx.cells[cell][id] = struct{}{}
+	idx.byID[id] = cell
+}
+
+// within returns the listings in cells overlapping the box. The caller
+// still has to check the exact distance.
+func (idx *geoIndex) within(box geo.Box) []int64 {
+	minCell := cellOf(box.MinLat, box.MinLng)
+	maxCell := cellOf(box.MaxLat, box.MaxLng)
+	inLng := func(lng int) bool {
+		if box.WrapsLng() {
+			return lng >= minCell.lng || lng <= maxCell.lng
+		}
+		return lng >= minCell.lng && lng <= maxCell.lng
+	}
+
+	var ids []int64
+	for cell, listings := range idx.cells {
+		if cell.lat < minCell.lat || cell.lat > maxCell.lat || !inLng(cell.lng) {
+			continue
+		}
+		for id := range listings {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
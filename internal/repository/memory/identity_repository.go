@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository"
+)
+
+type InMemoryIdentityRepository struct {
+	identities []*domain.Identity
+	states     map[string]*domain.ExternalLoginState
+	nextID     int64
+	mu         sync.RWMutex
+}
+
+func NewInMemoryIdentityRepository() *InMemoryIdentityRepository {
+	return &InMemoryIdentityRepository{
+		states: make(map[string]*domain.ExternalLoginState),
+		nextID: 1,
+	}
+}
+
+func (r *InMemoryIdentityRepository) Get(provider, subject string) (*domain.Identity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			i := *identity
+			return &i, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *InMemoryIdentityRepository) GetByUserID(userID int) ([]*domain.Identity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var identities []*domain.Identity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			i := *identity
+			identities = append(identities, &i)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool { return identities[i].Provider < identities[j].Provider })
+	return identities, nil
+}
+
+func (r *InMemoryIdentityRepository) Create(identity *domain.Identity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.identities {
+		if existing.Provider == identity.Provider && (existing.Subject == identity.Subject || existing.UserID == identity.UserID) {
+			return repository.ErrDuplicate
+		}
+	}
+	identity.ID = r.nextID
+	r.nextID++
+	identity.CreatedAt = time.Now()
+	i := *identity
+	r.identities = append(r.identities, &i)
+	return nil
+}
+
+func (r *InMemoryIdentityRepository) Delete(userID int, provider string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.identities[:0]
+	for _, identity := range r.identities {
+		if identity.UserID != userID || identity.Provider != provider {
+			kept = append(kept, identity)
+		}
+	}
+	r.identities = kept
+	return nil
+}
+
+func (r *InMemoryIdentityRepository) CreateState(state *domain.ExternalLoginState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := *state
+	r.states[state.StateHash] = &s
+	return nil
+}
+
+func (r *InMemoryIdentityRepository) ConsumeState(stateHash string, now time.Time) (*domain.ExternalLoginState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.states[stateHash]
+	if !ok {
+		return nil, nil
+	}
+	delete(r.states, stateHash)
+	if !state.ExpiresAt.After(now) {
+		return nil, nil
+	}
+	return state, nil
+}
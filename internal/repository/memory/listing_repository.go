@@ -6,10 +6,16 @@ import (
 	"sync"
 	"time"
 	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/fingerprint"
+	"vk/ecom/internal/pkg/geo"
+	"vk/ecom/internal/repository"
 )
 
 type InMemoryListingRepository struct {
 	listings map[int64]*domain.Listing
+	index    *tfidfIndex
+	geo      *geoIndex
+	history  map[int64][]*domain.PriceChange
 	nextID   int64
 	mu       sync.RWMutex
 }
@@ -17,6 +23,9 @@ type InMemoryListingRepository struct {
 func NewInMemoryListingRepository() *InMemoryListingRepository {
 	return &InMemoryListingRepository{
 		listings: make(map[int64]*domain.Listing),
+		index:    newTFIDFIndex(),
+		geo:      newGeoIndex(),
+		history:  make(map[int64][]*domain.PriceChange),
 		nextID:   1,
 	}
 }
@@ -27,7 +36,19 @@ func (r *InMemoryListingRepository) Create(listing *domain.Listing) error {
 
 	listing.ID = r.nextID
 	listing.CreatedAt = time.Now()
+	listing.TextHash = fingerprint.Text(listing.Title, listing.Description)
+	if listing.Type == "" {
+		listing.Type = domain.ListingTypeFixed
+	}
+	if listing.Status == "" {
+		listing.Status = domain.ListingStatusActive
+	}
+	if listing.Currency == "" {
+		listing.Currency = domain.DefaultCurrency
+	}
 	r.listings[r.nextID] = listing
+	r.index.add(listing.ID, listing.Title+" "+listing.Description)
+	r.indexLocation(listing)
 	r.nextID++
 	return nil
 }
@@ -48,6 +69,9 @@ func (r *InMemoryListingRepository) GetAll(sortBy, sortOrder string, minPrice, m
 
 	var result []*domain.Listing
 	for _, listing := range r.listings {
+		if listing.Status != domain.ListingStatusActive {
+			continue
+		}
 		if minPrice != nil && listing.Price < *minPrice {
 			continue
 		}
@@ -72,50 +96,52 @@ func (r *InMemoryListingRepository) GetAll(sortBy, sortOrder string, minPrice, m
 			}
 			return result[i].CreatedAt.Before(result[j].CreatedAt)
 		})
+	case "popular":
+		sort.SliceStable(result, func(i, j int) bool {
+			if result[i].Popularity == result[j].Popularity {
+				return result[i].CreatedAt.After(result[j].CreatedAt)
+			}
+			if sortOrder == "asc" {
+				return result[i].Popularity < result[j].Popularity
+			}
+			return result[i].Popularity > result[j].Popularity
+		})
 	}
 
 	return result, nil
 }
 
 func (r *InMemoryListingRepository) GetAllWithPagination(sortBy, sortOrder string, minPrice, maxPrice *int64, page, pageSize int) ([]*domain.Listing, int, error) {
+	return r.Search(&domain.ListingQuery{
+		SortBy:    sortBy,
+		SortOrder: sortOrder,
+		MinPrice:  minPrice,
+		MaxPrice:  maxPrice,
+		Page:      page,
+		PageSize:  pageSize,
+	})
+}
+
+// Search returns a page of the listings matching q, see searchBase.
+func (r *InMemoryListingRepository) Search(q *domain.ListingQuery) ([]*domain.Listing, int, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	var filteredListings []*domain.Listing
-	for _, listing := range r.listings {
-		if minPrice != nil && listing.Price < *minPrice {
-			continue
-		}
-		if maxPrice != nil && listing.Price > *maxPrice {
-			continue
+	for _, listing := range r.searchBase(q) {
+		if matchesCategory(q, listing) && matchesPrice(q, listing) && matchesAge(q, listing) {
+			filteredListings = append(filteredListings, listing)
 		}
-		filteredListings = append(filteredListings, listing)
 	}
 
-	switch sortBy {
-	case "price":
-		sort.Slice(filteredListings, func(i, j int) bool {
-			if sortOrder == "desc" {
-				return filteredListings[i].Price > filteredListings[j].Price
-			}
-			return filteredListings[i].Price < filteredListings[j].Price
-		})
-	case "date":
-		sort.Slice(filteredListings, func(i, j int) bool {
-			if sortOrder == "desc" {
-				return filteredListings[i].CreatedAt.After(filteredListings[j].CreatedAt)
-			}
-			return filteredListings[i].CreatedAt.Before(filteredListings[j].CreatedAt)
-		})
-	default:
-		sort.Slice(filteredListings, func(i, j int) bool {
-			return filteredListings[i].CreatedAt.After(filteredListings[j].CreatedAt)
-		})
-	}
+	sortListings(filteredListings, q)
 
 	totalCount := len(filteredListings)
+	if q.PageSize <= 0 {
+		return filteredListings, totalCount, nil
+	}
 
-	start := (page - 1) * pageSize
+	start := (q.Page - 1) * q.PageSize
 	if start < 0 {
 		start = 0
 	}
@@ -123,7 +149,7 @@ func (r *InMemoryListingRepository) GetAllWithPagination(sortBy, sortOrder strin
 		return []*domain.Listing{}, totalCount, nil
 	}
 
-	end := start + pageSize
+	end := start + q.PageSize
 	if end > totalCount {
 		end = totalCount
 	}
@@ -131,6 +157,177 @@ func (r *InMemoryListingRepository) GetAllWithPagination(sortBy, sortOrder strin
 	return filteredListings[start:end], totalCount, nil
 }
 
+// Facets counts the requested facets over the listings Search would
+// consider.
+func (r *InMemoryListingRepository) Facets(q *domain.ListingQuery, facets *domain.FacetQuery) (*domain.ListingFacets, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := &domain.ListingFacets{}
+	categories := make(map[string]int)
+	if len(facets.PriceBounds) > 0 {
+		result.Prices = domain.NewPriceBuckets(facets.PriceBounds)
+	}
+	for _, bucket := range facets.AgeBuckets {
+		result.Ages = append(result.Ages, domain.FacetCount{Value: bucket.Name})
+	}
+
+	for _, listing := range r.searchBase(q) {
+		category, price, age := matchesCategory(q, listing), matchesPrice(q, listing), matchesAge(q, listing)
+		if facets.Categories && price && age && listing.Category != "" {
+			categories[listing.Category]++
+		}
+		if amount, ok := displayPrice(q, listing); ok && result.Prices != nil && category && age {
+			for i := len(result.Prices) - 1; i >= 0; i-- {
+				if amount >= float64(result.Prices[i].Min) {
+					result.Prices[i].Count++
+					break
+				}
+			}
+		}
+		if category && price {
+			for i, bucket := range facets.AgeBuckets {
+				if !listing.CreatedAt.Before(bucket.Since) {
+					result.Ages[i].Count++
+				}
+			}
+		}
+	}
+
+	if facets.Categories {
+		result.Categories = make([]domain.FacetCount, 0, len(categories))
+		for category, count := range categories {
+			result.Categories = append(result.Categories, domain.FacetCount{Value: category, Count: count})
+		}
+	}
+	return result, nil
+}
+
+// searchBase returns the active listings, limited to the radius for
+// searches around a point. Those are looked up in the grid index and
+// returned as copies carrying their distance. The caller holds the lock.
+func (r *InMemoryListingRepository) searchBase(q *domain.ListingQuery) []*domain.Listing {
+	candidates := r.listings
+	if q.Near != nil {
+		candidates = make(map[int64]*domain.Listing)
+		for _, id := range r.geo.within(geo.BoundingBox(*q.Near, q.RadiusKm)) {
+			candidates[id] = r.listings[id]
+		}
+	}
+
+	var listings []*domain.Listing
+	for _, listing := range candidates {
+		if listing.Status != domain.ListingStatusActive {
+			continue
+		}
+		if q.PriceDroppedSince != nil && !droppedSince(listing, *q.PriceDroppedSince) {
+			continue
+		}
+		if q.Near != nil {
+			location, _ := listing.Location()
+			distance := geo.DistanceKm(*q.Near, location)
+			if distance > q.RadiusKm {
+				continue
+			}
+			found := *listing
+			found.DistanceKm = &distance
+			listing = &found
+		}
+		listings = append(listings, listing)
+	}
+	return listings
+}
+
+func droppedSince(listing *domain.Listing, since time.Time) bool {
+	return listing.PreviousPrice != nil && *listing.PreviousPrice > listing.Price &&
+		listing.PriceDroppedAt != nil && !listing.PriceDroppedAt.Before(since)
+}
+
+// matchesCategory also applies the attribute filters, which depend on the
+// category.
+func matchesCategory(q *domain.ListingQuery, listing *domain.Listing) bool {
+	if q.Category != "" && listing.Category != q.Category {
+		return false
+	}
+	for _, filter := range q.Attributes {
+		if !filter.Matches(listing.Attributes) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesPrice(q *domain.ListingQuery, listing *domain.Listing) bool {
+	if q.MinPrice == nil && q.MaxPrice == nil {
+		return true
+	}
+	price, ok := displayPrice(q, listing)
+	return ok && (q.MinPrice == nil || price >= float64(*q.MinPrice)) && (q.MaxPrice == nil || price <= float64(*q.MaxPrice))
+}
+
+// displayPrice converts the listing's price with q.PriceRates, if any.
+func displayPrice(q *domain.ListingQuery, listing *domain.Listing) (float64, bool) {
+	if q.PriceRates == nil {
+		return float64(listing.Price), true
+	}
+	rate, ok := q.PriceRates[listing.Currency]
+	return float64(listing.Price) * rate, ok
+}
+
+func matchesAge(q *domain.ListingQuery, listing *domain.Listing) bool {
+	return q.CreatedAfter == nil || !listing.CreatedAt.Before(*q.CreatedAfter)
+}
+
+func sortListings(listings []*domain.Listing, q *domain.ListingQuery) {
+	sortBy, sortOrder := q.SortBy, q.SortOrder
+	switch sortBy {
+	case "price":
+		sort.Slice(listings, func(i, j int) bool {
+			pi, iok := displayPrice(q, listings[i])
+			pj, jok := displayPrice(q, listings[j])
+			if !iok || !jok {
+				return iok
+			}
+			if sortOrder == "desc" {
+				return pi > pj
+			}
+			return pi < pj
+		})
+	case "date":
+		sort.Slice(listings, func(i, j int) bool {
+			if sortOrder == "desc" {
+				return listings[i].CreatedAt.After(listings[j].CreatedAt)
+			}
+			return listings[i].CreatedAt.Before(listings[j].CreatedAt)
+		})
+	case "popular":
+		sort.SliceStable(listings, func(i, j int) bool {
+			if listings[i].Popularity == listings[j].Popularity {
+				return listings[i].CreatedAt.After(listings[j].CreatedAt)
+			}
+			if sortOrder == "asc" {
+				return listings[i].Popularity < listings[j].Popularity
+			}
+			return listings[i].Popularity > listings[j].Popularity
+		})
+	case "distance":
+		if len(listings) > 0 && listings[0].DistanceKm == nil {
+			sortListings(listings, &domain.ListingQuery{SortBy: "date", SortOrder: "desc"})
+			return
+		}
+		sort.Slice(listings, func(i, j int) bool {
+			if sortOrder == "desc" {
+				return *listings[i].DistanceKm > *listings[j].DistanceKm
+			}
+			return *listings[i].DistanceKm < *listings[j].DistanceKm
+		})
+	default:
+		sort.Slice(listings, func(i, j int) bool {
+			return listings[i].CreatedAt.After(listings[j].CreatedAt)
+		})
+	}
+}
+
 func (r *InMemoryListingRepository) GetByAuthorID(authorID int64) ([]*domain.Listing, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -146,3 +343,153 @@ func (r *InMemoryListingRepository) GetByAuthorID(authorID int64) ([]*domain.Lis
 	}
 	return authorListings, nil
 }
+
+func (r *InMemoryListingRepository) UpdateStatus(id int64, from, to string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	listing, exists := r.listings[id]
+	if !exists {
+		return errors.New("listing not found")
+	}
+	if listing.Status != from {
+		return repository.ErrConflict
+	}
+	listing.Status = to
+	return nil
+}
+
+func (r *InMemoryListingRepository) Update(listing *domain.Listing) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.listings[listing.ID]
+	if !exists {
+		return errors.New("listing not found")
+	}
+	if stored.Price != listing.Price {
+		r.history[stored.ID] = append(r.history[stored.ID], &domain.PriceChange{
+			ListingID: stored.ID,
+			OldPrice:  stored.Price,
+			NewPrice:  listing.Price,
+			ChangedAt: time.Now(),
+		})
+	}
+	stored.Title = listing.Title
+	stored.Description = listing.Description
+	stored.ImageURL = listing.ImageURL
+	stored.Price = listing.Price
+	stored.PreviousPrice = listing.PreviousPrice
+	stored.PriceDroppedAt = listing.PriceDroppedAt
+	stored.Category = listing.Category
+	stored.Latitude = listing.Latitude
+	stored.Longitude = listing.Longitude
+	stored.City = listing.City
+	stored.Region = listing.Region
+	stored.Attributes = listing.Attributes
+	stored.TextHash = fingerprint.Text(listing.Title, listing.Description)
+	stored.ImageHash = listing.ImageHash
+	listing.TextHash = stored.TextHash
+	r.index.add(stored.ID, stored.Title+" "+stored.Description)
+	r.indexLocation(stored)
+	return nil
+}
+
+func (r *InMemoryListingRepository) Delete(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.listings[id]; !exists {
+		return errors.New("listing not found")
+	}
+	delete(r.listings, id)
+	delete(r.history, id)
+	r.index.remove(id)
+	r.geo.set(id, nil)
+	return nil
+}
+
+func (r *InMemoryListingRepository) GetPriceHistory(listingID int64) ([]*domain.PriceChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history := make([]*domain.PriceChange, 0, len(r.history[listingID]))
+	for _, change := range r.history[listingID] {
+		c := *change
+		history = append(history, &c)
+	}
+	return history, nil
+}
+
+func (r *InMemoryListingRepository) indexLocation(listing *domain.Listing) {
+	if location, ok := listing.Location(); ok {
+		r.geo.set(listing.ID, &location)
+	} else {
+		r.geo.set(listing.ID, nil)
+	}
+}
+
+func (r *InMemoryListingRepository) FindNearDuplicates(listing *domain.Listing, maxDistance int) ([]*domain.NearDuplicate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	duplicates := []*domain.NearDuplicate{}
+	for _, candidate := range r.listings {
+		if candidate.ID == listing.ID {
+			continue
+		}
+		if duplicate, ok := fingerprint.Compare(listing, candidate, maxDistance); ok {
+			duplicates = append(duplicates, duplicate)
+		}
+	}
+	return duplicates, nil
+}
+
+func (r *InMemoryListingRepository) FindSimilar(listing *domain.Listing, limit int) ([]*domain.SimilarListing, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	scores := r.index.query(listing.Title + " " + listing.Description)
+	sameCategory := func(candidate *domain.Listing) bool {
+		return listing.Category != "" && candidate.Category == listing.Category
+	}
+
+	similar := []*domain.SimilarListing{}
+	for _, candidate := range r.listings {
+		if candidate.ID == listing.ID || candidate.AuthorID == listing.AuthorID || candidate.Status != domain.ListingStatusActive {
+			continue
+		}
+		score, matched := scores[candidate.ID]
+		if !matched && !sameCategory(candidate) {
+			continue
+		}
+		similar = append(similar, &domain.SimilarListing{Listing: candidate, TextScore: score})
+	}
+
+	rank := func(s *domain.SimilarListing) float64 {
+		if sameCategory(s.Listing) {
+			return s.TextScore + 1
+		}
+		return s.TextScore
+	}
+	sort.Slice(similar, func(i, j int) bool {
+		if ri, rj := rank(similar[i]), rank(similar[j]); ri != rj {
+			return ri > rj
+		}
+		return similar[i].Listing.CreatedAt.After(similar[j].Listing.CreatedAt)
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+	return similar, nil
+}
+
+func (r *InMemoryListingRepository) SetPopularity(scores map[int64]float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, listing := range r.listings {
+		listing.Popularity = scores[id]
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+type InMemoryLoginAttemptRepository struct {
+	attempts map[string]*domain.LoginAttempts
+	mu       sync.RWMutex
+}
+
+func NewInMemoryLoginAttemptRepository() *InMemoryLoginAttemptRepository {
+	return &InMemoryLoginAttemptRepository{
+		attempts: make(map[string]*domain.LoginAttempts),
+	}
+}
+
+func (r *InMemoryLoginAttemptRepository) Update(key string, fn func(attempts *domain.LoginAttempts) bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts := &domain.LoginAttempts{Key: key}
+	if stored, ok := r.attempts[key]; ok {
+		a := *stored
+		attempts = &a
+	}
+	if fn(attempts) {
+		r.attempts[key] = attempts
+	}
+	return nil
+}
+
+func (r *InMemoryLoginAttemptRepository) Clear(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+func (r *InMemoryLoginAttemptRepository) GetLocked(now time.Time) ([]*domain.LoginAttempts, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var locked []*domain.LoginAttempts
+	for _, attempts := range r.attempts {
+		if attempts.LockedUntil != nil && attempts.LockedUntil.After(now) {
+			a := *attempts
+			locked = append(locked, &a)
+		}
+	}
+	sort.Slice(locked, func(i, j int) bool { return locked[i].Key < locked[j].Key })
+	return locked, nil
+}
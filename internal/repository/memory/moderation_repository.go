@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository"
+)
+
+type InMemoryModerationRepository struct {
+	cases         map[int64]*domain.ModerationCase
+	reports       map[int64][]*domain.Report
+	openByListing map[int64]int64
+	nextCaseID    int64
+	nextReportID  int64
+	mu            sync.RWMutex
+}
+
+func NewInMemoryModerationRepository() *InMemoryModerationRepository {
+	return &InMemoryModerationRepository{
+		cases:         make(map[int64]*domain.ModerationCase),
+		reports:       make(map[int64][]*domain.Report),
+		openByListing: make(map[int64]int64),
+		nextCaseID:    1,
+		nextReportID:  1,
+	}
+}
+
+func (r *InMemoryModerationRepository) AddReport(report *domain.Report) (*domain.ModerationCase, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	caseID, exists := r.openByListing[report.ListingID]
+	if !exists {
+		caseID = r.nextCaseID
+		r.nextCaseID++
+		r.cases[caseID] = &domain.ModerationCase{
+			ID:        caseID,
+			ListingID: report.ListingID,
+			Status:    domain.CaseStatusOpen,
+			CreatedAt: now,
+		}
+		r.openByListing[report.ListingID] = caseID
+	}
+
+	for _, existing := range r.reports[caseID] {
+		if existing.ReporterID == report.ReporterID {
+			return nil, repository.ErrDuplicate
+		}
+	}
+
+	report.ID = r.nextReportID
+	r.nextReportID++
+	report.CaseID = caseID
+	report.CreatedAt = now
+	stored := *report
+	r.reports[caseID] = append(r.reports[caseID], &stored)
+
+	c := r.cases[caseID]
+	c.ReportCount++
+	return copyCase(c), nil
+}
+
+func (r *InMemoryModerationRepository) CountReportsSince(reporterID int64, since time.Time) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, reports := range r.reports {
+		for _, report := range reports {
+			if report.ReporterID == reporterID && !report.CreatedAt.Before(since) {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryModerationRepository) GetCase(id int64) (*domain.ModerationCase, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, exists := r.cases[id]
+	if !exists {
+		return nil, errors.New("moderation case not found")
+	}
+	return copyCase(c), nil
+}
+
+func (r *InMemoryModerationRepository) GetCases(status string, limit int) ([]*domain.ModerationCase, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []*domain.ModerationCase
+	for _, c := range r.cases {
+		if c.Status == status {
+			result = append(result, copyCase(c))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ReportCount != result[j].ReportCount {
+			return result[i].ReportCount > result[j].ReportCount
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (r *InMemoryModerationRepository) GetReports(caseID int64) ([]*domain.Report, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*domain.Report, 0, len(r.reports[caseID]))
+	for _, report := range r.reports[caseID] {
+		copied := *report
+		result = append(result, &copied)
+	}
+	return result, nil
+}
+
+func (r *InMemoryModerationRepository) SetAutoHidden(caseID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, exists := r.cases[caseID]
+	if !exists {
+		return errors.New("moderation case not found")
+	}
+	c.AutoHidden = true
+	return nil
+}
+
+func (r *InMemoryModerationRepository) UpdateCase(c *domain.ModerationCase, expectedStatus string, expectedClaimant *int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.cases[c.ID]
+	if !exists {
+		return errors.New("moderation case not found")
+	}
+	if stored.Status != expectedStatus || !sameClaimant(stored.ClaimedBy, expectedClaimant) {
+		return repository.ErrConflict
+	}
+
+	updated := copyCase(c)
+	updated.ReportCount = stored.ReportCount
+	updated.AutoHidden = stored.AutoHidden
+	r.cases[c.ID] = updated
+	if updated.Status == domain.CaseStatusResolved {
+		delete(r.openByListing, updated.ListingID)
+	}
+	return nil
+}
+
+func sameClaimant(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func copyCase(c *domain.ModerationCase) *domain.ModerationCase {
+	copied := *c
+	return &copied
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository"
+)
+
+type InMemoryOrderRepository struct {
+	orders map[int64]*domain.Order
+	nextID int64
+	mu     sync.RWMutex
+}
+
+func NewInMemoryOrderRepository() *InMemoryOrderRepository {
+	return &InMemoryOrderRepository{
+		orders: make(map[int64]*domain.Order),
+		nextID: 1,
+	}
+}
+
+func (r *InMemoryOrderRepository) Create(order *domain.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order.ID = r.nextID
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+	for _, item := range order.Items {
+		item.OrderID = order.ID
+	}
+	r.orders[r.nextID] = copyOrder(order)
+	r.nextID++
+	return nil
+}
+
+func copyOrder(order *domain.Order) *domain.Order {
+	result := *order
+	result.Items = make([]*domain.OrderItem, 0, len(order.Items))
+	for _, item := range order.Items {
+		i := *item
+		result.Items = append(result.Items, &i)
+	}
+	return &result
+}
+
+func (r *InMemoryOrderRepository) GetByID(id int64) (*domain.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	order, exists := r.orders[id]
+	if !exists {
+		return nil, errors.New("order not found")
+	}
+	return copyOrder(order), nil
+}
+
+func (r *InMemoryOrderRepository) GetByPaymentID(paymentID string) (*domain.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, order := range r.orders {
+		if paymentID != "" && order.PaymentID == paymentID {
+			return copyOrder(order), nil
+		}
+	}
+	return nil, errors.New("order not found")
+}
+
+func (r *InMemoryOrderRepository) GetByBuyerID(buyerID int64) ([]*domain.Order, error) {
+	return r.filter(func(o *domain.Order) bool { return o.BuyerID == buyerID }), nil
+}
+
+func (r *InMemoryOrderRepository) GetBySellerID(sellerID int64) ([]*domain.Order, error) {
+	return r.filter(func(o *domain.Order) bool { return o.SellerID == sellerID }), nil
+}
+
+func (r *InMemoryOrderRepository) Update(order *domain.Order, expectedStatus string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.orders[order.ID]
+	if !exists {
+		return errors.New("order not found")
+	}
+	if stored.Status != expectedStatus {
+		return repository.ErrConflict
+	}
+
+	order.UpdatedAt = time.Now()
+	updated := copyOrder(order)
+	updated.Items = stored.Items
+	r.orders[order.ID] = updated
+	return nil
+}
+
+func (r *InMemoryOrderRepository) SetPaymentID(id int64, expected, paymentID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.orders[id]
+	if !exists {
+		return errors.New("order not found")
+	}
+	if stored.Status != domain.OrderStatusCreated || stored.PaymentID != expected {
+		return repository.ErrConflict
+	}
+
+	stored.PaymentID = paymentID
+	stored.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *InMemoryOrderRepository) filter(match func(*domain.Order) bool) []*domain.Order {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []*domain.Order{}
+	for _, order := range r.orders {
+		if match(order) {
+			result = append(result, copyOrder(order))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID > result[j].ID
+	})
+	return result
+}
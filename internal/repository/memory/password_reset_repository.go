@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+type InMemoryPasswordResetRepository struct {
+	tokens map[string]*domain.PasswordResetToken
+	nextID int64
+	mu     sync.Mutex
+}
+
+func NewInMemoryPasswordResetRepository() *InMemoryPasswordResetRepository {
+	return &InMemoryPasswordResetRepository{
+		tokens: make(map[string]*domain.PasswordResetToken),
+		nextID: 1,
+	}
+}
+
+func (r *InMemoryPasswordResetRepository) Create(token *domain.PasswordResetToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token.ID = r.nextID
+	r.nextID++
+	token.CreatedAt = time.Now()
+	t := *token
+	r.tokens[token.TokenHash] = &t
+	return nil
+}
+
+func (r *InMemoryPasswordResetRepository) Consume(tokenHash string, now time.Time) (*domain.PasswordResetToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[tokenHash]
+	if !ok || token.UsedAt != nil || !token.ExpiresAt.After(now) {
+		return nil, nil
+	}
+	token.UsedAt = &now
+	t := *token
+	return &t, nil
+}
+
+func (r *InMemoryPasswordResetRepository) DeleteByUserID(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, token := range r.tokens {
+		if token.UserID == userID {
+			delete(r.tokens, hash)
+		}
+	}
+	return nil
+}
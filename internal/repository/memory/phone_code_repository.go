@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+type InMemoryPhoneCodeRepository struct {
+	codes map[int]*domain.PhoneCode
+	mu    sync.Mutex
+}
+
+func NewInMemoryPhoneCodeRepository() *InMemoryPhoneCodeRepository {
+	return &InMemoryPhoneCodeRepository{
+		codes: make(map[int]*domain.PhoneCode),
+	}
+}
+
+func (r *InMemoryPhoneCodeRepository) Set(code *domain.PhoneCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := *code
+	r.codes[code.UserID] = &c
+	return nil
+}
+
+func (r *InMemoryPhoneCodeRepository) Attempt(userID int, now time.Time) (*domain.PhoneCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	code, ok := r.codes[userID]
+	if !ok || !code.ExpiresAt.After(now) {
+		return nil, nil
+	}
+	code.Attempts++
+	c := *code
+	return &c, nil
+}
+
+func (r *InMemoryPhoneCodeRepository) Delete(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.codes, userID)
+	return nil
+}
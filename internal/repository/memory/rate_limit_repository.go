@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"sync"
+	"time"
+)
+
+type InMemoryRateLimitRepository struct {
+	tats map[string]time.Time
+	mu   sync.Mutex
+}
+
+func NewInMemoryRateLimitRepository() *InMemoryRateLimitRepository {
+	return &InMemoryRateLimitRepository{
+		tats: make(map[string]time.Time),
+	}
+}
+
+func (r *InMemoryRateLimitRepository) Update(key string, fn func(tat time.Time) (time.Time, bool)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tat, ok := fn(r.tats[key]); ok {
+		r.tats[key] = tat
+	}
+	return nil
+}
+
+func (r *InMemoryRateLimitRepository) DeleteExpired(before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, tat := range r.tats {
+		if tat.Before(before) {
+			delete(r.tats, key)
+		}
+	}
+	return nil
+}
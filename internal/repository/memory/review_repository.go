@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository"
+)
+
+type InMemoryReviewRepository struct {
+	confirmations map[int64]*domain.PurchaseConfirmation
+	reviews       map[int64]*domain.Review
+	byListing     map[int64]int64
+	ratings       map[int64]*domain.SellerRating
+	nextID        int64
+	mu            sync.RWMutex
+}
+
+func NewInMemoryReviewRepository() *InMemoryReviewRepository {
+	return &InMemoryReviewRepository{
+		confirmations: make(map[int64]*domain.PurchaseConfirmation),
+		reviews:       make(map[int64]*domain.Review),
+		byListing:     make(map[int64]int64),
+		ratings:       make(map[int64]*domain.SellerRating),
+		nextID:        1,
+	}
+}
+
+func (r *InMemoryReviewRepository) ConfirmPurchase(confirmation *domain.PurchaseConfirmation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	confirmation.ConfirmedAt = time.Now()
+	stored := *confirmation
+	r.confirmations[confirmation.ListingID] = &stored
+	return nil
+}
+
+func (r *InMemoryReviewRepository) GetPurchaseConfirmation(listingID int64) (*domain.PurchaseConfirmation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	confirmation, exists := r.confirmations[listingID]
+	if !exists {
+		return nil, errors.New("purchase confirmation not found")
+	}
+	result := *confirmation
+	return &result, nil
+}
+
+func (r *InMemoryReviewRepository) Create(review *domain.Review) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.byListing[review.ListingID]; exists {
+		return repository.ErrDuplicate
+	}
+
+	review.ID = r.nextID
+	review.CreatedAt = time.Now()
+	stored := *review
+	r.reviews[review.ID] = &stored
+	r.byListing[review.ListingID] = review.ID
+	r.nextID++
+
+	rating, exists := r.ratings[review.SellerID]
+	if !exists {
+		rating = &domain.SellerRating{SellerID: review.SellerID}
+		r.ratings[review.SellerID] = rating
+	}
+	rating.RatingSum += int64(review.Rating)
+	rating.ReviewCount++
+	return nil
+}
+
+func (r *InMemoryReviewRepository) GetByID(id int64) (*domain.Review, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	review, exists := r.reviews[id]
+	if !exists {
+		return nil, errors.New("review not found")
+	}
+	result := *review
+	return &result, nil
+}
+
+func (r *InMemoryReviewRepository) GetBySellerID(sellerID int64) ([]*domain.Review, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []*domain.Review{}
+	for _, review := range r.reviews {
+		if review.SellerID == sellerID {
+			rv := *review
+			result = append(result, &rv)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID > result[j].ID
+	})
+	return result, nil
+}
+
+func (r *InMemoryReviewRepository) SetReply(reviewID int64, reply string, repliedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	review, exists := r.reviews[reviewID]
+	if !exists {
+		return errors.New("review not found")
+	}
+	if review.RepliedAt != nil {
+		return repository.ErrConflict
+	}
+	review.Reply = reply
+	review.RepliedAt = &repliedAt
+	return nil
+}
+
+func (r *InMemoryReviewRepository) GetSellerRatings(sellerIDs []int64) (map[int64]*domain.SellerRating, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[int64]*domain.SellerRating, len(sellerIDs))
+	for _, id := range sellerIDs {
+		if rating, exists := r.ratings[id]; exists {
+			rt := *rating
+			result[id] = &rt
+		}
+	}
+	return result, nil
+}
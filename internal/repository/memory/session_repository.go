@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+type InMemorySessionRepository struct {
+	sessions map[int64]*domain.Session
+	nextID   int64
+	mu       sync.RWMutex
+}
+
+func NewInMemorySessionRepository() *InMemorySessionRepository {
+	return &InMemorySessionRepository{
+		sessions: make(map[int64]*domain.Session),
+		nextID:   1,
+	}
+}
+
+func (r *InMemorySessionRepository) Create(session *domain.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, s := range r.sessions {
+		if s.UserID == session.UserID && s.ExpiresAt.Before(now) {
+			delete(r.sessions, id)
+		}
+	}
+	session.ID = r.nextID
+	r.nextID++
+	session.CreatedAt = now
+	session.LastSeenAt = now
+	s := *session
+	r.sessions[session.ID] = &s
+	return nil
+}
+
+func (r *InMemorySessionRepository) Get(id int64) (*domain.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	s := *session
+	return &s, nil
+}
+
+func (r *InMemorySessionRepository) GetActive(userID int, now time.Time) ([]*domain.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sessions []*domain.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			s := *session
+			sessions = append(sessions, &s)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastSeenAt.Equal(sessions[j].LastSeenAt) {
+			return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+	return sessions, nil
+}
+
+func (r *InMemorySessionRepository) SetLastSeen(id int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session, ok := r.sessions[id]; ok {
+		session.LastSeenAt = at
+	}
+	return nil
+}
+
+func (r *InMemorySessionRepository) Revoke(userID int, id int64, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return false, nil
+	}
+	session.RevokedAt = &now
+	return true, nil
+}
+
+func (r *InMemorySessionRepository) RevokeAll(userID int, exceptID int64, now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revoked := 0
+	for id, session := range r.sessions {
+		if session.UserID == userID && id != exceptID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			revoked++
+		}
+	}
+	return revoked, nil
+}
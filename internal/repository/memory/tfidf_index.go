@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"math"
+	"strings"
+	"vk/ecom/internal/pkg/screening"
+)
+
+// tfidfIndex is an inverted index of listing texts scored with TF-IDF
+// cosine similarity. It is not safe for concurrent use; the owning
+// repository guards it with its own lock.
+type tfidfIndex struct {
+	docs     map[int64]map[string]int
+	postings map[string]map[int64]struct{}
+}
+
+func newTFIDFIndex() *tfidfIndex {
+	return &tfidfIndex{
+		docs:     make(map[int64]map[string]int),
+		postings: make(map[string]map[int64]struct{}),
+	}
+}
+
+func terms(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range strings.Fields(screening.Normalize(text)) {
+		if len([]rune(word)) >= 2 {
+			counts[word]++
+		}
+	}
+	return counts
+}
+
+func (idx *tfidfIndex) add(id int64, text string) {
+	idx.remove(id)
+	counts := terms(text)
+	idx.docs[id] = counts
+	for term := range counts {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[int64]struct{})
+		}
+		idx.postings[term][id] = struct{}{}
+	}
+}
+
+func (idx *tfidfIndex) remove(id int64) {
+	for term := range idx.docs[id] {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.docs, id)
+}
+
+func (idx *tfidfIndex) weight(term string, count int) float64 {
+	idf := math.Log(float64(len(idx.docs)+1)/float64(len(idx.postings[term])+1)) + 1
+	return (1 + math.Log(float64(count))) * idf
+}
+
+func (idx *tfidfIndex) vector(counts map[string]int) (map[string]float64, float64) {
+	vector := make(map[string]float64, len(counts))
+	var norm float64
+	for term, count := range counts {
+		w := idx.weight(term, count)
+		vector[term] = w
+		norm += w * w
+	}
+	return vector, math.Sqrt(norm)
+}
+
+// query returns the cosine similarity of text to every indexed document
+// sharing at least one term with it.
+func (idx *tfidfIndex) query(text string) map[int64]float64 {
+	queryVector, queryNorm := idx.vector(terms(text))
+	scores := make(map[int64]float64)
+	if queryNorm == 0 {
+		return scores
+	}
+
+	for term := range queryVector {
+		for id := range idx.postings[term] {
+			scores[id] = 0
+		}
+	}
+	for id := range scores {
+		docVector, docNorm := idx.vector(idx.docs[id])
+		var dot float64
+		for term, w := range queryVector {
+			dot += w * docVector[term]
+		}
+		scores[id] = dot / (queryNorm * docNorm)
+	}
+	return scores
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository"
+)
+
+type InMemoryTwoFactorRepository struct {
+	setups        map[int]*domain.TwoFactor
+	recoveryCodes map[int]map[string]bool
+	mu            sync.RWMutex
+}
+
+func NewInMemoryTwoFactorRepository() *InMemoryTwoFactorRepository {
+	return &InMemoryTwoFactorRepository{
+		setups:        make(map[int]*domain.TwoFactor),
+		recoveryCodes: make(map[int]map[string]bool),
+	}
+}
+
+func (r *InMemoryTwoFactorRepository) Get(userID int) (*domain.TwoFactor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tf, ok := r.setups[userID]
+	if !ok {
+		return nil, nil
+	}
+	t := *tf
+	return &t, nil
+}
+
+func (r *InMemoryTwoFactorRepository) SetSecret(userID int, secret string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tf, ok := r.setups[userID]; ok && tf.Enabled {
+		return repository.ErrConflict
+	}
+	r.setups[userID] = &domain.TwoFactor{UserID: userID, Secret: secret, CreatedAt: time.Now()}
+	return nil
+}
+
+func (r *InMemoryTwoFactorRepository) Enable(userID int, step int64, recoveryCodeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tf, ok := r.setups[userID]
+	if !ok || tf.Enabled {
+		return repository.ErrConflict
+	}
+	tf.Enabled = true
+	tf.LastUsedStep = step
+	codes := make(map[string]bool, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		codes[hash] = true
+	}
+	r.recoveryCodes[userID] = codes
+	return nil
+}
+
+func (r *InMemoryTwoFactorRepository) UseStep(userID int, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tf, ok := r.setups[userID]
+	if !ok || tf.LastUsedStep >= step {
+		return false, nil
+	}
+	tf.LastUsedStep = step
+	return true, nil
+}
+
+func (r *InMemoryTwoFactorRepository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.recoveryCodes[userID][codeHash] {
+		return false, nil
+	}
+	delete(r.recoveryCodes[userID], codeHash)
+	return true, nil
+}
+
+func (r *InMemoryTwoFactorRepository) CountRecoveryCodes(userID int) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.recoveryCodes[userID]), nil
+}
+
+func (r *InMemoryTwoFactorRepository) Delete(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.setups, userID)
+	delete(r.recoveryCodes, userID)
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"sync"
 	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository"
 )
 
 type InMemoryUserRepository struct {
@@ -23,7 +24,18 @@ func (r *InMemoryUserRepository) Create(user *domain.User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if r.taken(0, user.Email, user.EmailVerified, user.Phone, user.PhoneVerified) {
+		return repository.ErrDuplicate
+	}
+	for _, existing := range r.users {
+		if existing.Login == user.Login {
+			return repository.ErrDuplicate
+		}
+	}
 	user.ID = r.nextID
+	if user.Role == "" {
+		user.Role = domain.UserRoleUser
+	}
 	r.users[r.nextID] = user
 	r.nextID++
 	return nil
@@ -51,3 +63,123 @@ func (r *InMemoryUserRepository) GetByLogin(login string) (*domain.User, error)
 	}
 	return nil, errors.New("user not found")
 }
+
+func (r *InMemoryUserRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[id]; !exists {
+		return errors.New("user not found")
+	}
+	delete(r.users, id)
+	return nil
+}
+
+func (r *InMemoryUserRepository) SetBanned(id int, banned bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return errors.New("user not found")
+	}
+	user.Banned = banned
+	return nil
+}
+
+func (r *InMemoryUserRepository) SetPassword(id int, passwordHash string, revokeSessions bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return errors.New("user not found")
+	}
+	user.Password = passwordHash
+	if revokeSessions {
+		user.SessionVersion++
+	}
+	return nil
+}
+
+func (r *InMemoryUserRepository) RehashPassword(id int, oldHash, newHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[id]
+	if !exists || user.Password != oldHash {
+		return repository.ErrConflict
+	}
+	user.Password = newHash
+	return nil
+}
+
+// taken reports whether a user other than id has verified the email or
+// phone, for values the caller marks as verified.
+func (r *InMemoryUserRepository) taken(id int, email string, emailVerified bool, phone string, phoneVerified bool) bool {
+	for _, user := range r.users {
+		if user.ID == id {
+			continue
+		}
+		if (emailVerified && email != "" && user.EmailVerified && user.Email == email) || (phoneVerified && phone != "" && user.PhoneVerified && user.Phone == phone) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *InMemoryUserRepository) SetEmail(id int, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return errors.New("user not found")
+	}
+	user.Email = email
+	user.EmailVerified = false
+	return nil
+}
+
+func (r *InMemoryUserRepository) SetPhone(id int, phone string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return errors.New("user not found")
+	}
+	user.Phone = phone
+	user.PhoneVerified = false
+	return nil
+}
+
+func (r *InMemoryUserRepository) MarkEmailVerified(id int, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[id]
+	if !exists || email == "" || user.Email != email {
+		return repository.ErrConflict
+	}
+	if r.taken(id, email, true, "", false) {
+		return repository.ErrDuplicate
+	}
+	user.EmailVerified = true
+	return nil
+}
+
+func (r *InMemoryUserRepository) MarkPhoneVerified(id int, phone string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[id]
+	if !exists || phone == "" || user.Phone != phone {
+		return repository.ErrConflict
+	}
+	if r.taken(id, "", false, phone, true) {
+		return repository.ErrDuplicate
+	}
+	user.PhoneVerified = true
+	return nil
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"vk/ecom/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+type AnalyticsRepository struct {
+	db *sql.DB
+}
+
+func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// AddDailyStats upserts the whole batch with one statement by passing the
+// columns as arrays.
+func (r *AnalyticsRepository) AddDailyStats(stats []*domain.ListingDailyStats) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	listingIDs := make([]int64, 0, len(stats))
+	days := make([]string, 0, len(stats))
+	views := make([]int64, 0, len(stats))
+	favorites := make([]int64, 0, len(stats))
+	messages := make([]int64, 0, len(stats))
+	for _, s := range stats {
+		listingIDs = append(listingIDs, s.ListingID)
+		days = append(days, s.Day.UTC().Format(time.DateOnly))
+		views = append(views, int64(s.Views))
+		favorites = append(favorites, int64(s.Favorites))
+		messages = append(messages, int64(s.Messages))
+	}
+
+	query := `
+		INSERT INTO listing_daily_stats (listing_id, day, views, favorites, messages)
+		SELECT * FROM unnest($1::BIGINT[], $2::DATE[], $3::INT[], $4::INT[], $5::INT[])
+		ON CONFLICT (listing_id, day) DO UPDATE SET
+			views = listing_daily_stats.views + EXCLUDED.views,
+			favorites = listing_daily_stats.favorites + EXCLUDED.favorites,
+			messages = listing_daily_stats.messages + EXCLUDED.messages`
+
+	_, err := r.db.Exec(query, pq.Array(listingIDs), pq.Array(days), pq.Array(views), pq.Array(favorites), pq.Array(messages))
+	if err != nil {
+		return fmt.Errorf("failed to store listing stats: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AnalyticsRepository) GetDailyStats(listingID int64, from, to time.Time) ([]*domain.ListingDailyStats, error) {
+	query := `
+		SELECT listing_id, day, views, favorites, messages
+		FROM listing_daily_stats
+		WHERE listing_id = $1 AND day BETWEEN $2 AND $3
+		ORDER BY day`
+
+	rows, err := r.db.Query(query, listingID, from.UTC().Format(time.DateOnly), to.UTC().Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listing stats: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDailyStats(rows)
+}
+
+func (r *AnalyticsRepository) GetDailyStatsSince(since time.Time) ([]*domain.ListingDailyStats, error) {
+	query := `
+		SELECT listing_id, day, views, favorites, messages
+		FROM listing_daily_stats
+		WHERE day >= $1`
+
+	rows, err := r.db.Query(query, since.UTC().Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listing stats: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDailyStats(rows)
+}
+
+func scanDailyStats(rows *sql.Rows) ([]*domain.ListingDailyStats, error) {
+	stats := []*domain.ListingDailyStats{}
+	for rows.Next() {
+		s := &domain.ListingDailyStats{}
+		if err := rows.Scan(&s.ListingID, &s.Day, &s.Views, &s.Favorites, &s.Messages); err != nil {
+			return nil, fmt.Errorf("failed to scan listing stats: %w", err)
+		}
+		s.Day = s.Day.UTC()
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+const auctionColumns = "listing_id, start_price, reserve_price, min_increment, ends_at, current_price, leader_id, bid_count, status, winner_id, closed_at"
+
+type AuctionRepository struct {
+	db *sql.DB
+}
+
+func NewAuctionRepository(db *sql.DB) *AuctionRepository {
+	return &AuctionRepository{db: db}
+}
+
+func scanAuction(row rowScanner) (*domain.Auction, error) {
+	auction := &domain.Auction{}
+	var leaderID, winnerID sql.NullInt64
+	var closedAt sql.NullTime
+	err := row.Scan(&auction.ListingID, &auction.StartPrice, &auction.ReservePrice, &auction.MinIncrement, &auction.EndsAt,
+		&auction.CurrentPrice, &leaderID, &auction.BidCount, &auction.Status, &winnerID, &closedAt)
+	if err != nil {
+		return nil, err
+	}
+	if leaderID.Valid {
+		auction.LeaderID = &leaderID.Int64
+	}
+	if winnerID.Valid {
+		auction.WinnerID = &winnerID.Int64
+	}
+	if closedAt.Valid {
+		auction.ClosedAt = &closedAt.Time
+	}
+	return auction, nil
+}
+
+func (r *AuctionRepository) Create(auction *domain.Auction) error {
+	query := `
+		INSERT INTO auctions (listing_id, start_price, reserve_price, min_increment, ends_at, status)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	if auction.Status == "" {
+		auction.Status = domain.AuctionStatusOpen
+	}
+
+	_, err := r.db.Exec(query, auction.ListingID, auction.StartPrice, auction.ReservePrice, auction.MinIncrement, auction.EndsAt, auction.Status)
+	if err != nil {
+		return fmt.Errorf("failed to create auction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AuctionRepository) GetByListingID(listingID int64) (*domain.Auction, error) {
+	query := `SELECT ` + auctionColumns + ` FROM auctions WHERE listing_id = $1`
+
+	auction, err := scanAuction(r.db.QueryRow(query, listingID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("auction not found")
+		}
+		return nil, fmt.Errorf("failed to get auction: %w", err)
+	}
+
+	return auction, nil
+}
+
+// Update locks the auction row with SELECT ... FOR UPDATE for the duration of
+// fn, so concurrent bids on the same listing are applied one at a time.
+func (r *AuctionRepository) Update(listingID int64, fn func(auction *domain.Auction) (*domain.Bid, error)) (*domain.Bid, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + auctionColumns + ` FROM auctions WHERE listing_id = $1 FOR UPDATE`
+	auction, err := scanAuction(tx.QueryRow(query, listingID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("auction not found")
+		}
+		return nil, fmt.Errorf("failed to lock auction: %w", err)
+	}
+
+	bid, err := fn(auction)
+	if err != nil {
+		return nil, err
+	}
+
+	if bid != nil {
+		bid.ListingID = listingID
+		err = tx.QueryRow(`INSERT INTO bids (listing_id, bidder_id, amount, created_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+			bid.ListingID, bid.BidderID, bid.Amount, bid.CreatedAt).Scan(&bid.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create bid: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE auctions
+		SET ends_at = $2, current_price = $3, leader_id = $4, bid_count = $5, status = $6, winner_id = $7, closed_at = $8
+		WHERE listing_id = $1`,
+		listingID, auction.EndsAt, auction.CurrentPrice, auction.LeaderID, auction.BidCount, auction.Status, auction.WinnerID, auction.ClosedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update auction: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit auction update: %w", err)
+	}
+
+	return bid, nil
+}
+
+func (r *AuctionRepository) GetBids(listingID int64) ([]*domain.Bid, error) {
+	query := `SELECT id, listing_id, bidder_id, amount, created_at FROM bids WHERE listing_id = $1 ORDER BY id DESC`
+
+	rows, err := r.db.Query(query, listingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bids: %w", err)
+	}
+	defer rows.Close()
+
+	bids := []*domain.Bid{}
+	for rows.Next() {
+		bid := &domain.Bid{}
+		if err := rows.Scan(&bid.ID, &bid.ListingID, &bid.BidderID, &bid.Amount, &bid.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan bid: %w", err)
+		}
+		bids = append(bids, bid)
+	}
+
+	return bids, nil
+}
+
+func (r *AuctionRepository) GetExpired(now time.Time) ([]*domain.Auction, error) {
+	query := `SELECT ` + auctionColumns + ` FROM auctions WHERE status = $1 AND ends_at <= $2`
+
+	rows, err := r.db.Query(query, domain.AuctionStatusOpen, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired auctions: %w", err)
+	}
+	defer rows.Close()
+
+	var auctions []*domain.Auction
+	for rows.Next() {
+		auction, err := scanAuction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan auction: %w", err)
+		}
+		auctions = append(auctions, auction)
+	}
+
+	return auctions, nil
+}
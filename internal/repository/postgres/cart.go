@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"vk/ecom/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+type CartRepository struct {
+	db *sql.DB
+}
+
+func NewCartRepository(db *sql.DB) *CartRepository {
+	return &CartRepository{db: db}
+}
+
+func (r *CartRepository) GetItems(cartKey string) ([]*domain.CartItem, error) {
+	query := `SELECT cart_key, listing_id, price_at_add, added_at FROM cart_items WHERE cart_key = $1 ORDER BY added_at`
+
+	rows, err := r.db.Query(query, cartKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*domain.CartItem{}
+	for rows.Next() {
+		item := &domain.CartItem{}
+		if err := rows.Scan(&item.CartKey, &item.ListingID, &item.PriceAtAdd, &item.AddedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cart item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func (r *CartRepository) AddItem(item *domain.CartItem) error {
+	query := `
+		INSERT INTO cart_items (cart_key, listing_id, price_at_add, added_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (cart_key, listing_id) DO NOTHING`
+
+	if item.AddedAt.IsZero() {
+		item.AddedAt = time.Now()
+	}
+
+	if _, err := r.db.Exec(query, item.CartKey, item.ListingID, item.PriceAtAdd, item.AddedAt); err != nil {
+		return fmt.Errorf("failed to add cart item: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CartRepository) RemoveItems(cartKey string, listingIDs []int64) error {
+	query := `DELETE FROM cart_items WHERE cart_key = $1 AND listing_id = ANY($2)`
+
+	if _, err := r.db.Exec(query, cartKey, pq.Array(listingIDs)); err != nil {
+		return fmt.Errorf("failed to remove cart items: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CartRepository) Merge(fromKey, toKey string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO cart_items (cart_key, listing_id, price_at_add, added_at)
+		SELECT $2, listing_id, price_at_add, added_at FROM cart_items WHERE cart_key = $1
+		ON CONFLICT (cart_key, listing_id) DO NOTHING`, fromKey, toKey)
+	if err != nil {
+		return fmt.Errorf("failed to merge carts: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM cart_items WHERE cart_key = $1`, fromKey); err != nil {
+		return fmt.Errorf("failed to merge carts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to merge carts: %w", err)
+	}
+
+	return nil
+}
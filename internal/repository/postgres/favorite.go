@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type FavoriteRepository struct {
+	db *sql.DB
+}
+
+func NewFavoriteRepository(db *sql.DB) *FavoriteRepository {
+	return &FavoriteRepository{db: db}
+}
+
+func (r *FavoriteRepository) Add(userID, listingID int64) error {
+	query := `
+		INSERT INTO listing_favorites (user_id, listing_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, listing_id) DO NOTHING`
+
+	if _, err := r.db.Exec(query, userID, listingID); err != nil {
+		return fmt.Errorf("failed to add favorite: %w", err)
+	}
+
+	return nil
+}
+
+func (r *FavoriteRepository) Remove(userID, listingID int64) error {
+	query := `DELETE FROM listing_favorites WHERE user_id = $1 AND listing_id = $2`
+
+	if _, err := r.db.Exec(query, userID, listingID); err != nil {
+		return fmt.Errorf("failed to remove favorite: %w", err)
+	}
+
+	return nil
+}
+
+func (r *FavoriteRepository) GetUserIDs(listingID int64) ([]int64, error) {
+	query := `SELECT user_id FROM listing_favorites WHERE listing_id = $1 ORDER BY user_id`
+
+	rows, err := r.db.Query(query, listingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get favorites: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan favorite: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get favorites: %w", err)
+	}
+
+	return userIDs, nil
+}
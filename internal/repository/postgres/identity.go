@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository"
+)
+
+const identityColumns = "id, user_id, provider, subject, email, created_at"
+
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+func NewIdentityRepository(db *sql.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+func scanIdentity(row rowScanner) (*domain.Identity, error) {
+	identity := &domain.Identity{}
+	err := row.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt)
+	return identity, err
+}
+
+func (r *IdentityRepository) Get(provider, subject string) (*domain.Identity, error) {
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE provider = $1 AND subject = $2`
+
+	identity, err := scanIdentity(r.db.QueryRow(query, provider, subject))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	return identity, nil
+}
+
+func (r *IdentityRepository) GetByUserID(userID int) ([]*domain.Identity, error) {
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE user_id = $1 ORDER BY provider`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identities: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []*domain.Identity
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+func (r *IdentityRepository) Create(identity *domain.Identity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(query, identity.UserID, identity.Provider, identity.Subject, identity.Email).Scan(&identity.ID, &identity.CreatedAt)
+	if err == sql.ErrNoRows {
+		return repository.ErrDuplicate
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IdentityRepository) Delete(userID int, provider string) error {
+	if _, err := r.db.Exec(`DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`, userID, provider); err != nil {
+		return fmt.Errorf("failed to delete identity: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IdentityRepository) CreateState(state *domain.ExternalLoginState) error {
+	// Abandoned logins are cleaned up here rather than by a scheduler.
+	if _, err := r.db.Exec(`DELETE FROM external_login_states WHERE expires_at < $1`, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to delete expired login states: %w", err)
+	}
+
+	var userID sql.NullInt64
+	if state.UserID != 0 {
+		userID = sql.NullInt64{Int64: int64(state.UserID), Valid: true}
+	}
+	query := `
+		INSERT INTO external_login_states (state_hash, provider, nonce, code_verifier, user_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	if _, err := r.db.Exec(query, state.StateHash, state.Provider, state.Nonce, state.CodeVerifier, userID, state.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to create login state: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeState deletes the state in the same statement that reads it, so
+// a state is only ever used once.
+func (r *IdentityRepository) ConsumeState(stateHash string, now time.Time) (*domain.ExternalLoginState, error) {
+	query := `
+		DELETE FROM external_login_states WHERE state_hash = $1
+		RETURNING state_hash, provider, nonce, code_verifier, user_id, expires_at`
+
+	state := &domain.ExternalLoginState{}
+	var userID sql.NullInt64
+	err := r.db.QueryRow(query, stateHash).Scan(&state.StateHash, &state.Provider, &state.Nonce, &state.CodeVerifier, &userID, &state.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume login state: %w", err)
+	}
+	if !state.ExpiresAt.After(now) {
+		return nil, nil
+	}
+	state.UserID = int(userID.Int64)
+
+	return state, nil
+}
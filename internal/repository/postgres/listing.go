@@ -2,29 +2,117 @@ package postgres
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/fingerprint"
+	"vk/ecom/internal/pkg/geo"
+	"vk/ecom/internal/repository"
+
+	"github.com/lib/pq"
 )
 
+const listingColumns = "id, title, description, image_url, price, author_id, created_at, listing_type, status, text_hash, image_hash, category, popularity, latitude, longitude, city, region, attributes, currency, previous_price, price_dropped_at"
+
 type ListingRepository struct {
 	db *sql.DB
 }
 
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanListing reads listingColumns followed by any extra selected columns.
+func scanListing(row rowScanner, extra ...interface{}) (*domain.Listing, error) {
+	listing := &domain.Listing{}
+	var textHash int64
+	var imageHash sql.NullInt64
+	var latitude, longitude sql.NullFloat64
+	var attributes []byte
+	var previousPrice sql.NullInt64
+	var priceDroppedAt sql.NullTime
+	dest := []interface{}{&listing.ID, &listing.Title, &listing.Description, &listing.ImageURL, &listing.Price, &listing.AuthorID, &listing.CreatedAt, &listing.Type, &listing.Status,
+		&textHash, &imageHash, &listing.Category, &listing.Popularity, &latitude, &longitude, &listing.City, &listing.Region, &attributes, &listing.Currency,
+		&previousPrice, &priceDroppedAt}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	listing.TextHash = uint64(textHash)
+	if imageHash.Valid {
+		hash := uint64(imageHash.Int64)
+		listing.ImageHash = &hash
+	}
+	if latitude.Valid && longitude.Valid {
+		listing.Latitude = &latitude.Float64
+		listing.Longitude = &longitude.Float64
+	}
+	if previousPrice.Valid && priceDroppedAt.Valid {
+		listing.PreviousPrice = &previousPrice.Int64
+		listing.PriceDroppedAt = &priceDroppedAt.Time
+	}
+	if len(attributes) > 0 {
+		if err := json.Unmarshal(attributes, &listing.Attributes); err != nil {
+			return nil, fmt.Errorf("invalid listing attributes: %w", err)
+		}
+		if len(listing.Attributes) == 0 {
+			listing.Attributes = nil
+		}
+	}
+	return listing, nil
+}
+
+// attributesValue encodes listing attributes for the JSONB column.
+func attributesValue(attributes map[string]interface{}) (string, error) {
+	if len(attributes) == 0 {
+		return "{}", nil
+	}
+	encoded, err := json.Marshal(attributes)
+	return string(encoded), err
+}
+
+// Hashes are unsigned but stored in BIGINT columns bit for bit.
+func imageHashValue(hash *uint64) interface{} {
+	if hash == nil {
+		return nil
+	}
+	return int64(*hash)
+}
+
 func NewListingRepository(db *sql.DB) *ListingRepository {
 	return &ListingRepository{db: db}
 }
 
 func (r *ListingRepository) Create(listing *domain.Listing) error {
 	query := `
-		INSERT INTO listings (title, description, image_url, price, author_id, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO listings (title, description, image_url, price, author_id, created_at, listing_type, status, text_hash, image_hash, category, latitude, longitude, city, region, attributes, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 		RETURNING id`
 
+	attributes, err := attributesValue(listing.Attributes)
+	if err != nil {
+		return fmt.Errorf("failed to create listing: %w", err)
+	}
+
 	listing.CreatedAt = time.Now()
+	listing.TextHash = fingerprint.Text(listing.Title, listing.Description)
+	if listing.Type == "" {
+		listing.Type = domain.ListingTypeFixed
+	}
+	if listing.Status == "" {
+		listing.Status = domain.ListingStatusActive
+	}
+	if listing.Currency == "" {
+		listing.Currency = domain.DefaultCurrency
+	}
 
-	err := r.db.QueryRow(query, listing.Title, listing.Description, listing.ImageURL, listing.Price, listing.AuthorID, listing.CreatedAt).Scan(&listing.ID)
+	err = r.db.QueryRow(query, listing.Title, listing.Description, listing.ImageURL, listing.Price, listing.AuthorID, listing.CreatedAt, listing.Type, listing.Status,
+		int64(listing.TextHash), imageHashValue(listing.ImageHash), listing.Category, listing.Latitude, listing.Longitude, listing.City, listing.Region, attributes, listing.Currency).Scan(&listing.ID)
 	if err != nil {
 		return fmt.Errorf("failed to create listing: %w", err)
 	}
@@ -33,12 +121,9 @@ func (r *ListingRepository) Create(listing *domain.Listing) error {
 }
 
 func (r *ListingRepository) GetByID(id int64) (*domain.Listing, error) {
-	query := `SELECT id, title, description, image_url, price, author_id, created_at FROM listings WHERE id = $1`
+	query := `SELECT ` + listingColumns + ` FROM listings WHERE id = $1`
 
-	listing := &domain.Listing{}
-	err := r.db.QueryRow(query, id).Scan(
-		&listing.ID, &listing.Title, &listing.Description, &listing.ImageURL, &listing.Price, &listing.AuthorID, &listing.CreatedAt,
-	)
+	listing, err := scanListing(r.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("listing not found")
@@ -50,58 +135,125 @@ func (r *ListingRepository) GetByID(id int64) (*domain.Listing, error) {
 }
 
 func (r *ListingRepository) GetAll(sortBy, sortOrder string, minPrice, maxPrice *int64) ([]*domain.Listing, error) {
-	query, args := r.buildQuery(sortBy, sortOrder, minPrice, maxPrice, 0, 0)
+	query, args, _, _ := buildSearchQuery(&domain.ListingQuery{SortBy: sortBy, SortOrder: sortOrder, MinPrice: minPrice, MaxPrice: maxPrice})
 
-	rows, err := r.db.Query(query, args...)
+	listings, err := r.queryListings(query, args, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get listings: %w", err)
 	}
-	defer rows.Close()
-
-	var listings []*domain.Listing
-	for rows.Next() {
-		listing := &domain.Listing{}
-		err := rows.Scan(&listing.ID, &listing.Title, &listing.Description, &listing.ImageURL, &listing.Price, &listing.AuthorID, &listing.CreatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan listing: %w", err)
-		}
-		listings = append(listings, listing)
-	}
 
 	return listings, nil
 }
 
 func (r *ListingRepository) GetAllWithPagination(sortBy, sortOrder string, minPrice, maxPrice *int64, page, pageSize int) ([]*domain.Listing, int, error) {
-	countQuery, countArgs := r.buildCountQuery(minPrice, maxPrice)
+	return r.Search(&domain.ListingQuery{
+		SortBy:    sortBy,
+		SortOrder: sortOrder,
+		MinPrice:  minPrice,
+		MaxPrice:  maxPrice,
+		Page:      page,
+		PageSize:  pageSize,
+	})
+}
+
+// Search returns a page of the feed and the total number of matches.
+// Searches around a point prefilter on the indexed bounding box of the
+// radius and then compute exact haversine distances.
+func (r *ListingRepository) Search(q *domain.ListingQuery) ([]*domain.Listing, int, error) {
+	query, args, countQuery, countArgs := buildSearchQuery(q)
+
 	var total int
-	err := r.db.QueryRow(countQuery, countArgs...).Scan(&total)
-	if err != nil {
+	if err := r.db.QueryRow(countQuery, countArgs...).Scan(&total); err != nil {
 		return nil, 0, fmt.Errorf("failed to get total count: %w", err)
 	}
 
-	query, args := r.buildQuery(sortBy, sortOrder, minPrice, maxPrice, page, pageSize)
+	listings, err := r.queryListings(query, args, q.Near != nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get listings: %w", err)
+	}
+
+	return listings, total, nil
+}
+
+// Facets counts every requested facet with a single query.
+func (r *ListingRepository) Facets(q *domain.ListingQuery, facets *domain.FacetQuery) (*domain.ListingFacets, error) {
+	result := &domain.ListingFacets{}
+	if facets.Categories {
+		result.Categories = []domain.FacetCount{}
+	}
+	if len(facets.PriceBounds) > 0 {
+		result.Prices = domain.NewPriceBuckets(facets.PriceBounds)
+	}
+	ages := make(map[string]int, len(facets.AgeBuckets))
+	for i, bucket := range facets.AgeBuckets {
+		result.Ages = append(result.Ages, domain.FacetCount{Value: bucket.Name})
+		ages[bucket.Name] = i
+	}
+	if result.Categories == nil && result.Prices == nil && result.Ages == nil {
+		return result, nil
+	}
+
+	query, args := buildFacetsQuery(q, facets)
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count facets: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var facet, value string
+		var count int
+		if err := rows.Scan(&facet, &value, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan facet: %w", err)
+		}
+		switch facet {
+		case "category":
+			result.Categories = append(result.Categories, domain.FacetCount{Value: value, Count: count})
+		case "price":
+			bucket, err := strconv.Atoi(value)
+			if err == nil && bucket >= 1 && bucket <= len(result.Prices) {
+				result.Prices[bucket-1].Count = count
+			}
+		case "age":
+			result.Ages[ages[value]].Count = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count facets: %w", err)
+	}
+
+	return result, nil
+}
 
+func (r *ListingRepository) queryListings(query string, args []interface{}, withDistance bool) ([]*domain.Listing, error) {
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get listings: %w", err)
+		return nil, err
 	}
 	defer rows.Close()
 
 	var listings []*domain.Listing
 	for rows.Next() {
-		listing := &domain.Listing{}
-		err := rows.Scan(&listing.ID, &listing.Title, &listing.Description, &listing.ImageURL, &listing.Price, &listing.AuthorID, &listing.CreatedAt)
+		var extra []interface{}
+		var distance float64
+		if withDistance {
+			extra = append(extra, &distance)
+		}
+		listing, err := scanListing(rows, extra...)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to scan listing: %w", err)
+			return nil, fmt.Errorf("failed to scan listing: %w", err)
+		}
+		if withDistance {
+			listing.DistanceKm = &distance
 		}
 		listings = append(listings, listing)
 	}
 
-	return listings, total, nil
+	return listings, nil
 }
 
 func (r *ListingRepository) GetByAuthorID(authorID int64) ([]*domain.Listing, error) {
-	query := `SELECT id, title, description, image_url, price, author_id, created_at FROM listings WHERE author_id = $1 ORDER BY created_at DESC`
+	query := `SELECT ` + listingColumns + ` FROM listings WHERE author_id = $1 ORDER BY created_at DESC`
 
 	rows, err := r.db.Query(query, authorID)
 	if err != nil {
@@ -111,8 +263,7 @@ func (r *ListingRepository) GetByAuthorID(authorID int64) ([]*domain.Listing, er
 
 	var listings []*domain.Listing
 	for rows.Next() {
-		listing := &domain.Listing{}
-		err := rows.Scan(&listing.ID, &listing.Title, &listing.Description, &listing.ImageURL, &listing.Price, &listing.AuthorID, &listing.CreatedAt)
+		listing, err := scanListing(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan listing: %w", err)
 		}
@@ -122,73 +273,443 @@ func (r *ListingRepository) GetByAuthorID(authorID int64) ([]*domain.Listing, er
 	return listings, nil
 }
 
-func (r *ListingRepository) buildQuery(sortBy, sortOrder string, minPrice, maxPrice *int64, page, pageSize int) (string, []interface{}) {
-	query := `SELECT id, title, description, image_url, price, author_id, created_at FROM listings`
+// UpdateStatus moves a listing from one status to another only if it still has
+// the expected status, which makes reserving a listing safe against races.
+func (r *ListingRepository) UpdateStatus(id int64, from, to string) error {
+	result, err := r.db.Exec(`UPDATE listings SET status = $3 WHERE id = $1 AND status = $2`, id, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to update listing status: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update listing status: %w", err)
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+
+	return nil
+}
+
+// Update locks the row to compare the stored price, so a price change is
+// recorded in the history together with the update.
+func (r *ListingRepository) Update(listing *domain.Listing) error {
+	query := `
+		UPDATE listings
+		SET title = $1, description = $2, image_url = $3, price = $4, text_hash = $5, image_hash = $6, category = $7,
+			latitude = $8, longitude = $9, city = $10, region = $11, attributes = $12, previous_price = $13, price_dropped_at = $14
+		WHERE id = $15`
+
+	listing.TextHash = fingerprint.Text(listing.Title, listing.Description)
+	attributes, err := attributesValue(listing.Attributes)
+	if err != nil {
+		return fmt.Errorf("failed to update listing: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var oldPrice int64
+	err = tx.QueryRow(`SELECT price FROM listings WHERE id = $1 FOR UPDATE`, listing.ID).Scan(&oldPrice)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("listing not found")
+		}
+		return fmt.Errorf("failed to update listing: %w", err)
+	}
+
+	_, err = tx.Exec(query, listing.Title, listing.Description, listing.ImageURL, listing.Price,
+		int64(listing.TextHash), imageHashValue(listing.ImageHash), listing.Category,
+		listing.Latitude, listing.Longitude, listing.City, listing.Region, attributes,
+		listing.PreviousPrice, listing.PriceDroppedAt, listing.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update listing: %w", err)
+	}
+
+	if listing.Price != oldPrice {
+		_, err = tx.Exec(`
+			INSERT INTO listing_price_history (listing_id, old_price, new_price, changed_at)
+			VALUES ($1, $2, $3, $4)`, listing.ID, oldPrice, listing.Price, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to record price change: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Delete relies on the price history cascading with the listing.
+func (r *ListingRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM listings WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete listing: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete listing: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("listing not found")
+	}
+
+	return nil
+}
+
+func (r *ListingRepository) GetPriceHistory(listingID int64) ([]*domain.PriceChange, error) {
+	query := `
+		SELECT listing_id, old_price, new_price, changed_at
+		FROM listing_price_history
+		WHERE listing_id = $1
+		ORDER BY changed_at, id`
+
+	rows, err := r.db.Query(query, listingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*domain.PriceChange
+	for rows.Next() {
+		change := &domain.PriceChange{}
+		if err := rows.Scan(&change.ListingID, &change.OldPrice, &change.NewPrice, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan price change: %w", err)
+		}
+		history = append(history, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get price history: %w", err)
+	}
+
+	return history, nil
+}
+
+// FindNearDuplicates narrows the search to listings sharing a 16-bit band
+// of either hash with the target, which uses the band indexes and finds
+// every listing within fingerprint.MaxBandDistance. Distances are then
+// checked exactly.
+func (r *ListingRepository) FindNearDuplicates(listing *domain.Listing, maxDistance int) ([]*domain.NearDuplicate, error) {
+	var conditions []string
+	args := []interface{}{listing.ID}
+	if listing.TextHash != 0 {
+		for i, band := range fingerprint.Bands(listing.TextHash) {
+			args = append(args, band)
+			conditions = append(conditions, fmt.Sprintf("text_band%d = $%d", i, len(args)))
+		}
+	}
+	if listing.ImageHash != nil {
+		for i, band := range fingerprint.Bands(*listing.ImageHash) {
+			args = append(args, band)
+			conditions = append(conditions, fmt.Sprintf("image_band%d = $%d", i, len(args)))
+		}
+	}
+	if len(conditions) == 0 {
+		return []*domain.NearDuplicate{}, nil
+	}
+
+	query := `SELECT ` + listingColumns + ` FROM listings WHERE id <> $1 AND (` + strings.Join(conditions, " OR ") + `)`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find near duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	duplicates := []*domain.NearDuplicate{}
+	for rows.Next() {
+		candidate, err := scanListing(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan listing: %w", err)
+		}
+		if duplicate, ok := fingerprint.Compare(listing, candidate, maxDistance); ok {
+			duplicates = append(duplicates, duplicate)
+		}
+	}
+
+	return duplicates, nil
+}
+
+// SetPopularity stores the given scores and resets every other listing to
+// zero, in one transaction.
+func (r *ListingRepository) SetPopularity(scores map[int64]float64) error {
+	ids := make([]int64, 0, len(scores))
+	values := make([]float64, 0, len(scores))
+	for id, score := range scores {
+		ids = append(ids, id)
+		values = append(values, score)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE listings SET popularity = 0 WHERE popularity <> 0 AND NOT (id = ANY($1))`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to reset popularity: %w", err)
+	}
+	_, err = tx.Exec(`
+		UPDATE listings l SET popularity = s.score
+		FROM unnest($1::BIGINT[], $2::DOUBLE PRECISION[]) AS s(id, score)
+		WHERE l.id = s.id`, pq.Array(ids), pq.Array(values))
+	if err != nil {
+		return fmt.Errorf("failed to update popularity: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to update popularity: %w", err)
+	}
+
+	return nil
+}
+
+// FindSimilar ranks active listings of other authors by full-text relevance
+// against the listing's words, using the search_vector GIN index. Listings
+// in the same category are candidates even without shared words.
+func (r *ListingRepository) FindSimilar(listing *domain.Listing, limit int) ([]*domain.SimilarListing, error) {
+	query := `
+		SELECT ` + listingColumns + `, ts_rank(search_vector, q, 32) AS text_score
+		FROM listings, websearch_to_tsquery('simple', $1) q
+		WHERE id <> $2 AND author_id <> $3 AND status = $4
+			AND (search_vector @@ q OR (category <> '' AND category = $5))
+		ORDER BY ts_rank(search_vector, q, 32) + CASE WHEN category <> '' AND category = $5 THEN 1 ELSE 0 END DESC, created_at DESC
+		LIMIT $6`
+
+	rows, err := r.db.Query(query, similarityQuery(listing), listing.ID, listing.AuthorID, domain.ListingStatusActive, listing.Category, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find similar listings: %w", err)
+	}
+	defer rows.Close()
+
+	similar := []*domain.SimilarListing{}
+	for rows.Next() {
+		candidate := &domain.SimilarListing{}
+		candidate.Listing, err = scanListing(rows, &candidate.TextScore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan listing: %w", err)
+		}
+		similar = append(similar, candidate)
+	}
+
+	return similar, nil
+}
+
+// similarityQuery turns the listing text into a websearch query matching
+// any of its words. Only letters and digits are kept, so the text cannot
+// inject query operators.
+func similarityQuery(listing *domain.Listing) string {
+	const maxTerms = 32
+
+	seen := make(map[string]bool)
+	var terms []string
+	words := strings.FieldsFunc(strings.ToLower(listing.Title+" "+listing.Description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if len([]rune(word)) < 2 || word == "or" || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+		if len(terms) == maxTerms {
+			break
+		}
+	}
+	return strings.Join(terms, " or ")
+}
+
+// searchFilter holds the SQL of a ListingQuery's filters. source selects
+// the active listings, restricted to the radius for searches around a
+// point; the other conditions apply to its rows, grouped by the dimension
+// they filter on so facets can leave their own dimension out.
+type searchFilter struct {
+	args     []interface{}
+	source   string
+	base     []string
+	category []string
+	price    []string
+	age      []string
+
+	rates map[string]float64
+}
+
+// priceExpr is the price in the display currency of the query, or the raw
+// price without rates. The rates are inlined: they are numbers, and a
+// parameter referenced by no statement using this filter would fail.
+func (f *searchFilter) priceExpr() string {
+	if f.rates == nil {
+		return "price"
+	}
+	currencies := make([]string, 0, len(f.rates))
+	for currency := range f.rates {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	var expr strings.Builder
+	expr.WriteString("(CASE currency")
+	for _, currency := range currencies {
+		fmt.Fprintf(&expr, " WHEN %s THEN price * %s", pq.QuoteLiteral(currency), strconv.FormatFloat(f.rates[currency], 'g', -1, 64))
+	}
+	expr.WriteString(" END)")
+	return expr.String()
+}
+
+func (f *searchFilter) arg(value interface{}) string {
+	f.args = append(f.args, value)
+	return fmt.Sprintf("$%d", len(f.args))
+}
+
+// where joins the base conditions and those of every dimension.
+func (f *searchFilter) where() string {
+	return strings.Join(append(append([]string{}, f.base...), f.dimensions("")...), " AND ")
+}
+
+// dimensions returns the conditions of every dimension but skip.
+func (f *searchFilter) dimensions(skip string) []string {
 	var conditions []string
-	var args []interface{}
-	argIndex := 1
+	if skip != "category" {
+		conditions = append(conditions, f.category...)
+	}
+	if skip != "price" {
+		conditions = append(conditions, f.price...)
+	}
+	if skip != "age" {
+		conditions = append(conditions, f.age...)
+	}
+	return conditions
+}
 
-	if minPrice != nil {
-		conditions = append(conditions, fmt.Sprintf("price >= $%d", argIndex))
-		args = append(args, *minPrice)
-		argIndex++
+func newSearchFilter(q *domain.ListingQuery) *searchFilter {
+	f := &searchFilter{source: `SELECT ` + listingColumns + ` FROM listings`, rates: q.PriceRates}
+	f.base = []string{"status = " + f.arg(domain.ListingStatusActive)}
+
+	if q.Near != nil {
+		box := geo.BoundingBox(*q.Near, q.RadiusKm)
+		conditions := append(f.base, fmt.Sprintf("latitude BETWEEN %s AND %s", f.arg(box.MinLat), f.arg(box.MaxLat)))
+		if box.WrapsLng() {
+			conditions = append(conditions, fmt.Sprintf("(longitude >= %s OR longitude <= %s)", f.arg(box.MinLng), f.arg(box.MaxLng)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("longitude BETWEEN %s AND %s", f.arg(box.MinLng), f.arg(box.MaxLng)))
+		}
+		lat, lng := f.arg(q.Near.Lat), f.arg(q.Near.Lng)
+		distance := fmt.Sprintf(`2 * 6371 * asin(least(1, sqrt(
+			power(sin(radians(latitude - %[1]s) / 2), 2) +
+			cos(radians(%[1]s)) * cos(radians(latitude)) * power(sin(radians(longitude - %[2]s) / 2), 2))))`, lat, lng)
+		f.source = `SELECT * FROM (SELECT ` + listingColumns + `, ` + distance + ` AS distance_km FROM listings WHERE ` +
+			strings.Join(conditions, " AND ") + `) l`
+		f.base = []string{"distance_km <= " + f.arg(q.RadiusKm)}
 	}
 
-	if maxPrice != nil {
-		conditions = append(conditions, fmt.Sprintf("price <= $%d", argIndex))
-		args = append(args, *maxPrice)
-		argIndex++
+	if q.PriceDroppedSince != nil {
+		f.base = append(f.base, "previous_price > price AND price_dropped_at >= "+f.arg(*q.PriceDroppedSince))
 	}
 
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+	if q.Category != "" {
+		f.category = append(f.category, "category = "+f.arg(q.Category))
+	}
+	// Attribute filters depend on the category, so they share its
+	// dimension. Equality uses the GIN index through containment; numbers
+	// of other types never match a range.
+	for _, filter := range q.Attributes {
+		if filter.Op == domain.AttributeOpEq {
+			encoded, _ := json.Marshal(map[string]interface{}{filter.Key: filter.Value})
+			f.category = append(f.category, "attributes @> "+f.arg(string(encoded))+"::jsonb")
+			continue
+		}
+		op := ">="
+		if filter.Op == domain.AttributeOpLte {
+			op = "<="
+		}
+		key := f.arg(filter.Key)
+		f.category = append(f.category, fmt.Sprintf("CASE WHEN jsonb_typeof(attributes -> %[1]s) = 'number' THEN (attributes ->> %[1]s)::numeric END %[2]s %[3]s",
+			key, op, f.arg(filter.Value)))
+	}
+	if q.MinPrice != nil {
+		f.price = append(f.price, f.priceExpr()+" >= "+f.arg(*q.MinPrice))
 	}
+	if q.MaxPrice != nil {
+		f.price = append(f.price, f.priceExpr()+" <= "+f.arg(*q.MaxPrice))
+	}
+	if q.CreatedAfter != nil {
+		f.age = append(f.age, "created_at >= "+f.arg(*q.CreatedAfter))
+	}
+	return f
+}
+
+// buildSearchQuery returns the page query and the count query with their
+// arguments. A zero q.PageSize selects all matches.
+func buildSearchQuery(q *domain.ListingQuery) (string, []interface{}, string, []interface{}) {
+	f := newSearchFilter(q)
+	from := f.source + " WHERE " + f.where()
+	countQuery := `SELECT COUNT(*) FROM (` + from + `) c`
+	countArgs := append([]interface{}{}, f.args...)
 
 	orderBy := "created_at"
-	switch sortBy {
+	switch q.SortBy {
 	case "price":
-		orderBy = "price"
+		orderBy = f.priceExpr()
 	case "title":
 		orderBy = "title"
+	case "popular":
+		orderBy = "popularity"
+	case "distance":
+		if q.Near != nil {
+			orderBy = "distance_km"
+		}
 	}
-
 	order := "DESC"
-	if sortOrder == "asc" {
+	if q.SortOrder == "asc" {
 		order = "ASC"
 	}
+	query := from + fmt.Sprintf(" ORDER BY %s %s NULLS LAST", orderBy, order)
+	if orderBy != "created_at" {
+		query += ", created_at DESC"
+	}
 
-	query += fmt.Sprintf(" ORDER BY %s %s", orderBy, order)
-
-	if pageSize > 0 {
-		offset := (page - 1) * pageSize
-		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
-		args = append(args, pageSize, offset)
+	if q.PageSize > 0 {
+		offset := (q.Page - 1) * q.PageSize
+		query += fmt.Sprintf(" LIMIT %s OFFSET %s", f.arg(q.PageSize), f.arg(offset))
 	}
 
-	return query, args
+	return query, f.args, countQuery, countArgs
 }
 
-func (r *ListingRepository) buildCountQuery(minPrice, maxPrice *int64) (string, []interface{}) {
-	query := `SELECT COUNT(*) FROM listings`
-	var conditions []string
-	var args []interface{}
-	argIndex := 1
-
-	if minPrice != nil {
-		conditions = append(conditions, fmt.Sprintf("price >= $%d", argIndex))
-		args = append(args, *minPrice)
-		argIndex++
+// buildFacetsQuery counts all requested facets in one statement returning
+// (facet, value, count) rows. Price buckets are numbered from 1 by
+// width_bucket.
+func buildFacetsQuery(q *domain.ListingQuery, facets *domain.FacetQuery) (string, []interface{}) {
+	f := newSearchFilter(q)
+	without := func(dimension string) string {
+		return strings.Join(append([]string{"TRUE"}, f.dimensions(dimension)...), " AND ")
 	}
 
-	if maxPrice != nil {
-		conditions = append(conditions, fmt.Sprintf("price <= $%d", argIndex))
-		args = append(args, *maxPrice)
-		argIndex++
+	var parts []string
+	if facets.Categories {
+		parts = append(parts, `SELECT 'category', category, COUNT(*) FROM base
+			WHERE category <> '' AND `+without("category")+` GROUP BY category`)
 	}
-
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+	if len(facets.PriceBounds) > 0 {
+		parts = append(parts, `SELECT 'price', width_bucket(`+f.priceExpr()+`, `+f.arg(pq.Array(facets.PriceBounds))+`::bigint[])::text, COUNT(*) FROM base
+			WHERE `+without("price")+` GROUP BY 2`)
+	}
+	if len(facets.AgeBuckets) > 0 {
+		values := make([]string, 0, len(facets.AgeBuckets))
+		for _, bucket := range facets.AgeBuckets {
+			values = append(values, fmt.Sprintf("(%s::text, %s::timestamptz)", f.arg(bucket.Name), f.arg(bucket.Since)))
+		}
+		parts = append(parts, `SELECT 'age', b.name, COUNT(base.id) FROM (VALUES `+strings.Join(values, ", ")+`) b(name, since)
+			LEFT JOIN base ON base.created_at >= b.since AND `+without("age")+` GROUP BY b.name`)
 	}
 
-	return query, args
+	query := `WITH base AS (` + f.source + ` WHERE ` + strings.Join(f.base, " AND ") + `) ` + strings.Join(parts, " UNION ALL ")
+	return query, f.args
 }
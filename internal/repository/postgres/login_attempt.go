@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+const loginAttemptColumns = "attempt_key, failures, last_failure_at, locked_until"
+
+type LoginAttemptRepository struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepository(db *sql.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+func scanLoginAttempts(row rowScanner) (*domain.LoginAttempts, error) {
+	attempts := &domain.LoginAttempts{}
+	var lockedUntil sql.NullTime
+	if err := row.Scan(&attempts.Key, &attempts.Failures, &attempts.LastFailureAt, &lockedUntil); err != nil {
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		attempts.LockedUntil = &lockedUntil.Time
+	}
+	return attempts, nil
+}
+
+// Update locks the key's row for the duration of fn, inserting an empty
+// row first so that the first attempts for a new key are serialized too.
+// The empty row is rolled back unless fn stores the record.
+func (r *LoginAttemptRepository) Update(key string, fn func(attempts *domain.LoginAttempts) bool) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO login_attempts (attempt_key, failures, last_failure_at)
+		VALUES ($1, 0, 'epoch')
+		ON CONFLICT (attempt_key) DO NOTHING`
+	if _, err := tx.Exec(query, key); err != nil {
+		return fmt.Errorf("failed to create login attempts: %w", err)
+	}
+
+	query = `SELECT ` + loginAttemptColumns + ` FROM login_attempts WHERE attempt_key = $1 FOR UPDATE`
+	attempts, err := scanLoginAttempts(tx.QueryRow(query, key))
+	if err != nil {
+		return fmt.Errorf("failed to get login attempts: %w", err)
+	}
+
+	if !fn(attempts) {
+		return nil
+	}
+	query = `UPDATE login_attempts SET failures = $2, last_failure_at = $3, locked_until = $4 WHERE attempt_key = $1`
+	if _, err := tx.Exec(query, key, attempts.Failures, attempts.LastFailureAt, attempts.LockedUntil); err != nil {
+		return fmt.Errorf("failed to update login attempts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *LoginAttemptRepository) Clear(key string) error {
+	if _, err := r.db.Exec(`DELETE FROM login_attempts WHERE attempt_key = $1`, key); err != nil {
+		return fmt.Errorf("failed to clear login attempts: %w", err)
+	}
+
+	return nil
+}
+
+func (r *LoginAttemptRepository) GetLocked(now time.Time) ([]*domain.LoginAttempts, error) {
+	query := `SELECT ` + loginAttemptColumns + ` FROM login_attempts WHERE locked_until > $1 ORDER BY attempt_key`
+
+	rows, err := r.db.Query(query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lockouts: %w", err)
+	}
+	defer rows.Close()
+
+	var locked []*domain.LoginAttempts
+	for rows.Next() {
+		attempts, err := scanLoginAttempts(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lockout: %w", err)
+		}
+		locked = append(locked, attempts)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get lockouts: %w", err)
+	}
+
+	return locked, nil
+}
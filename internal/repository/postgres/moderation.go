@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository"
+)
+
+const moderationCaseColumns = "id, listing_id, status, report_count, auto_hidden, claimed_by, claimed_at, resolution, resolved_by, resolved_at, note, created_at"
+
+type ModerationRepository struct {
+	db *sql.DB
+}
+
+func NewModerationRepository(db *sql.DB) *ModerationRepository {
+	return &ModerationRepository{db: db}
+}
+
+func scanModerationCase(row rowScanner) (*domain.ModerationCase, error) {
+	c := &domain.ModerationCase{}
+	var claimedBy, resolvedBy sql.NullInt64
+	var claimedAt, resolvedAt sql.NullTime
+	err := row.Scan(&c.ID, &c.ListingID, &c.Status, &c.ReportCount, &c.AutoHidden, &claimedBy, &claimedAt,
+		&c.Resolution, &resolvedBy, &resolvedAt, &c.Note, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if claimedBy.Valid {
+		c.ClaimedBy = &claimedBy.Int64
+	}
+	if claimedAt.Valid {
+		c.ClaimedAt = &claimedAt.Time
+	}
+	if resolvedBy.Valid {
+		c.ResolvedBy = &resolvedBy.Int64
+	}
+	if resolvedAt.Valid {
+		c.ResolvedAt = &resolvedAt.Time
+	}
+	return c, nil
+}
+
+// AddReport locks the listing's unresolved case for the duration of the
+// transaction, so concurrent reports are counted exactly once each.
+func (r *ModerationRepository) AddReport(report *domain.Report) (*domain.ModerationCase, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO moderation_cases (listing_id, status)
+		VALUES ($1, $2)
+		ON CONFLICT (listing_id) WHERE status <> 'resolved' DO NOTHING`,
+		report.ListingID, domain.CaseStatusOpen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open moderation case: %w", err)
+	}
+
+	var caseID int64
+	err = tx.QueryRow(`SELECT id FROM moderation_cases WHERE listing_id = $1 AND status <> 'resolved' FOR UPDATE`,
+		report.ListingID).Scan(&caseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock moderation case: %w", err)
+	}
+
+	report.CaseID = caseID
+	report.CreatedAt = time.Now()
+	err = tx.QueryRow(`
+		INSERT INTO listing_reports (case_id, listing_id, reporter_id, reason, comment, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (case_id, reporter_id) DO NOTHING
+		RETURNING id`,
+		caseID, report.ListingID, report.ReporterID, report.Reason, report.Comment, report.CreatedAt).Scan(&report.ID)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrDuplicate
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report: %w", err)
+	}
+
+	c, err := scanModerationCase(tx.QueryRow(`
+		UPDATE moderation_cases SET report_count = report_count + 1
+		WHERE id = $1
+		RETURNING `+moderationCaseColumns, caseID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update moderation case: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit report: %w", err)
+	}
+
+	return c, nil
+}
+
+func (r *ModerationRepository) CountReportsSince(reporterID int64, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM listing_reports WHERE reporter_id = $1 AND created_at >= $2`,
+		reporterID, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reports: %w", err)
+	}
+	return count, nil
+}
+
+func (r *ModerationRepository) GetCase(id int64) (*domain.ModerationCase, error) {
+	query := `SELECT ` + moderationCaseColumns + ` FROM moderation_cases WHERE id = $1`
+
+	c, err := scanModerationCase(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("moderation case not found")
+		}
+		return nil, fmt.Errorf("failed to get moderation case: %w", err)
+	}
+
+	return c, nil
+}
+
+func (r *ModerationRepository) GetCases(status string, limit int) ([]*domain.ModerationCase, error) {
+	query := `SELECT ` + moderationCaseColumns + ` FROM moderation_cases
+		WHERE status = $1
+		ORDER BY report_count DESC, id
+		LIMIT $2`
+
+	rows, err := r.db.Query(query, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderation cases: %w", err)
+	}
+	defer rows.Close()
+
+	cases := []*domain.ModerationCase{}
+	for rows.Next() {
+		c, err := scanModerationCase(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan moderation case: %w", err)
+		}
+		cases = append(cases, c)
+	}
+
+	return cases, nil
+}
+
+func (r *ModerationRepository) GetReports(caseID int64) ([]*domain.Report, error) {
+	query := `SELECT id, case_id, listing_id, reporter_id, reason, comment, created_at
+		FROM listing_reports WHERE case_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Query(query, caseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []*domain.Report{}
+	for rows.Next() {
+		report := &domain.Report{}
+		err := rows.Scan(&report.ID, &report.CaseID, &report.ListingID, &report.ReporterID,
+			&report.Reason, &report.Comment, &report.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report: %w", err)
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func (r *ModerationRepository) SetAutoHidden(caseID int64) error {
+	if _, err := r.db.Exec(`UPDATE moderation_cases SET auto_hidden = TRUE WHERE id = $1`, caseID); err != nil {
+		return fmt.Errorf("failed to update moderation case: %w", err)
+	}
+	return nil
+}
+
+func (r *ModerationRepository) UpdateCase(c *domain.ModerationCase, expectedStatus string, expectedClaimant *int64) error {
+	query := `
+		UPDATE moderation_cases
+		SET status = $1, claimed_by = $2, claimed_at = $3, resolution = $4, resolved_by = $5, resolved_at = $6, note = $7
+		WHERE id = $8 AND status = $9 AND claimed_by IS NOT DISTINCT FROM $10`
+
+	result, err := r.db.Exec(query, c.Status, c.ClaimedBy, c.ClaimedAt, c.Resolution, c.ResolvedBy, c.ResolvedAt,
+		c.Note, c.ID, expectedStatus, expectedClaimant)
+	if err != nil {
+		return fmt.Errorf("failed to update moderation case: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update moderation case: %w", err)
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+
+	return nil
+}
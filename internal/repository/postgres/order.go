@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository"
+
+	"github.com/lib/pq"
+)
+
+const orderColumns = "id, buyer_id, seller_id, amount, currency, status, payment_id, created_at, updated_at"
+
+type OrderRepository struct {
+	db *sql.DB
+}
+
+func NewOrderRepository(db *sql.DB) *OrderRepository {
+	return &OrderRepository{db: db}
+}
+
+func scanOrder(row rowScanner) (*domain.Order, error) {
+	order := &domain.Order{}
+	err := row.Scan(&order.ID, &order.BuyerID, &order.SellerID, &order.Amount, &order.Currency,
+		&order.Status, &order.PaymentID, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (r *OrderRepository) Create(order *domain.Order) error {
+	query := `
+		INSERT INTO orders (buyer_id, seller_id, amount, currency, status, payment_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`
+
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(query, order.BuyerID, order.SellerID, order.Amount, order.Currency,
+		order.Status, order.PaymentID, order.CreatedAt, order.UpdatedAt).Scan(&order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+
+	for _, item := range order.Items {
+		item.OrderID = order.ID
+		_, err := tx.Exec(`INSERT INTO order_items (order_id, listing_id, price) VALUES ($1, $2, $3)`,
+			item.OrderID, item.ListingID, item.Price)
+		if err != nil {
+			return fmt.Errorf("failed to create order item: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit order: %w", err)
+	}
+
+	return nil
+}
+
+func (r *OrderRepository) GetByID(id int64) (*domain.Order, error) {
+	return r.getOne(`SELECT `+orderColumns+` FROM orders WHERE id = $1`, id)
+}
+
+func (r *OrderRepository) GetByPaymentID(paymentID string) (*domain.Order, error) {
+	return r.getOne(`SELECT `+orderColumns+` FROM orders WHERE payment_id = $1`, paymentID)
+}
+
+func (r *OrderRepository) GetByBuyerID(buyerID int64) ([]*domain.Order, error) {
+	return r.getMany(`SELECT `+orderColumns+` FROM orders WHERE buyer_id = $1 ORDER BY created_at DESC`, buyerID)
+}
+
+func (r *OrderRepository) GetBySellerID(sellerID int64) ([]*domain.Order, error) {
+	return r.getMany(`SELECT `+orderColumns+` FROM orders WHERE seller_id = $1 ORDER BY created_at DESC`, sellerID)
+}
+
+func (r *OrderRepository) Update(order *domain.Order, expectedStatus string) error {
+	query := `
+		UPDATE orders SET status = $2, payment_id = $3, updated_at = $4
+		WHERE id = $1 AND status = $5`
+
+	updatedAt := time.Now()
+	result, err := r.db.Exec(query, order.ID, order.Status, order.PaymentID, updatedAt, expectedStatus)
+	if err != nil {
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+
+	order.UpdatedAt = updatedAt
+	return nil
+}
+
+func (r *OrderRepository) SetPaymentID(id int64, expected, paymentID string) error {
+	query := `
+		UPDATE orders SET payment_id = $3, updated_at = $4
+		WHERE id = $1 AND status = $5 AND payment_id = $2`
+
+	result, err := r.db.Exec(query, id, expected, paymentID, time.Now(), domain.OrderStatusCreated)
+	if err != nil {
+		return fmt.Errorf("failed to update order payment: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update order payment: %w", err)
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+
+	return nil
+}
+
+func (r *OrderRepository) getOne(query string, arg interface{}) (*domain.Order, error) {
+	order, err := scanOrder(r.db.QueryRow(query, arg))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("order not found")
+		}
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	if err := r.loadItems([]*domain.Order{order}); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (r *OrderRepository) getMany(query string, arg interface{}) ([]*domain.Order, error) {
+	rows, err := r.db.Query(query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []*domain.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get orders: %w", err)
+	}
+
+	if err := r.loadItems(orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// loadItems fetches the items of all given orders with a single query.
+func (r *OrderRepository) loadItems(orders []*domain.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*domain.Order, len(orders))
+	ids := make([]int64, 0, len(orders))
+	for _, order := range orders {
+		order.Items = []*domain.OrderItem{}
+		byID[order.ID] = order
+		ids = append(ids, order.ID)
+	}
+
+	rows, err := r.db.Query(`SELECT order_id, listing_id, price FROM order_items WHERE order_id = ANY($1) ORDER BY order_id, listing_id`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to get order items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &domain.OrderItem{}
+		if err := rows.Scan(&item.OrderID, &item.ListingID, &item.Price); err != nil {
+			return fmt.Errorf("failed to scan order item: %w", err)
+		}
+		byID[item.OrderID].Items = append(byID[item.OrderID].Items, item)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+type PasswordResetRepository struct {
+	db *sql.DB
+}
+
+func NewPasswordResetRepository(db *sql.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+func (r *PasswordResetRepository) Create(token *domain.PasswordResetToken) error {
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(query, token.UserID, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	return nil
+}
+
+// Consume claims the token in a single update, so a token raced by two
+// requests is only returned to one of them.
+func (r *PasswordResetRepository) Consume(tokenHash string, now time.Time) (*domain.PasswordResetToken, error) {
+	query := `
+		UPDATE password_reset_tokens SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING id, user_id, token_hash, expires_at, used_at, created_at`
+
+	token := &domain.PasswordResetToken{}
+	var usedAt time.Time
+	err := r.db.QueryRow(query, tokenHash, now).Scan(&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &usedAt, &token.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume password reset token: %w", err)
+	}
+	token.UsedAt = &usedAt
+
+	return token, nil
+}
+
+func (r *PasswordResetRepository) DeleteByUserID(userID int) error {
+	if _, err := r.db.Exec(`DELETE FROM password_reset_tokens WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete password reset tokens: %w", err)
+	}
+
+	return nil
+}
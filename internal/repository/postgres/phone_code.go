@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+type PhoneCodeRepository struct {
+	db *sql.DB
+}
+
+func NewPhoneCodeRepository(db *sql.DB) *PhoneCodeRepository {
+	return &PhoneCodeRepository{db: db}
+}
+
+func (r *PhoneCodeRepository) Set(code *domain.PhoneCode) error {
+	query := `
+		INSERT INTO phone_verification_codes (user_id, phone, code_hash, attempts, expires_at)
+		VALUES ($1, $2, $3, 0, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			phone = EXCLUDED.phone,
+			code_hash = EXCLUDED.code_hash,
+			attempts = 0,
+			expires_at = EXCLUDED.expires_at`
+
+	if _, err := r.db.Exec(query, code.UserID, code.Phone, code.CodeHash, code.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to store phone code: %w", err)
+	}
+
+	return nil
+}
+
+// Attempt counts the guess in a single update, so concurrent guesses cannot
+// exceed the limit together.
+func (r *PhoneCodeRepository) Attempt(userID int, now time.Time) (*domain.PhoneCode, error) {
+	query := `
+		UPDATE phone_verification_codes SET attempts = attempts + 1
+		WHERE user_id = $1 AND expires_at > $2
+		RETURNING user_id, phone, code_hash, attempts, expires_at`
+
+	code := &domain.PhoneCode{}
+	err := r.db.QueryRow(query, userID, now).Scan(&code.UserID, &code.Phone, &code.CodeHash, &code.Attempts, &code.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check phone code: %w", err)
+	}
+
+	return code, nil
+}
+
+func (r *PhoneCodeRepository) Delete(userID int) error {
+	if _, err := r.db.Exec(`DELETE FROM phone_verification_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete phone code: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type RateLimitRepository struct {
+	db *sql.DB
+}
+
+func NewRateLimitRepository(db *sql.DB) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Update locks the key's row for the duration of fn, inserting an empty
+// row first so that the first requests for a new key are serialized too.
+func (r *RateLimitRepository) Update(key string, fn func(tat time.Time) (time.Time, bool)) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO rate_limits (limit_key) VALUES ($1) ON CONFLICT (limit_key) DO NOTHING`, key); err != nil {
+		return fmt.Errorf("failed to create rate limit: %w", err)
+	}
+
+	var stored sql.NullTime
+	if err := tx.QueryRow(`SELECT tat FROM rate_limits WHERE limit_key = $1 FOR UPDATE`, key).Scan(&stored); err != nil {
+		return fmt.Errorf("failed to get rate limit: %w", err)
+	}
+
+	tat, ok := fn(stored.Time)
+	if !ok {
+		return nil
+	}
+	if _, err := tx.Exec(`UPDATE rate_limits SET tat = $2 WHERE limit_key = $1`, key, tat); err != nil {
+		return fmt.Errorf("failed to update rate limit: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *RateLimitRepository) DeleteExpired(before time.Time) error {
+	if _, err := r.db.Exec(`DELETE FROM rate_limits WHERE tat IS NULL OR tat < $1`, before); err != nil {
+		return fmt.Errorf("failed to delete expired rate limits: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository"
+
+	"github.com/lib/pq"
+)
+
+const reviewColumns = "id, listing_id, seller_id, author_id, rating, text, reply, replied_at, created_at"
+
+type ReviewRepository struct {
+	db *sql.DB
+}
+
+func NewReviewRepository(db *sql.DB) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+func scanReview(row rowScanner) (*domain.Review, error) {
+	review := &domain.Review{}
+	var repliedAt sql.NullTime
+	err := row.Scan(&review.ID, &review.ListingID, &review.SellerID, &review.AuthorID, &review.Rating,
+		&review.Text, &review.Reply, &repliedAt, &review.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if repliedAt.Valid {
+		review.RepliedAt = &repliedAt.Time
+	}
+	return review, nil
+}
+
+func (r *ReviewRepository) ConfirmPurchase(confirmation *domain.PurchaseConfirmation) error {
+	query := `
+		INSERT INTO purchase_confirmations (listing_id, seller_id, buyer_id, confirmed_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (listing_id) DO UPDATE SET buyer_id = EXCLUDED.buyer_id, confirmed_at = EXCLUDED.confirmed_at`
+
+	confirmation.ConfirmedAt = time.Now()
+
+	_, err := r.db.Exec(query, confirmation.ListingID, confirmation.SellerID, confirmation.BuyerID, confirmation.ConfirmedAt)
+	if err != nil {
+		return fmt.Errorf("failed to confirm purchase: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ReviewRepository) GetPurchaseConfirmation(listingID int64) (*domain.PurchaseConfirmation, error) {
+	query := `SELECT listing_id, seller_id, buyer_id, confirmed_at FROM purchase_confirmations WHERE listing_id = $1`
+
+	c := &domain.PurchaseConfirmation{}
+	err := r.db.QueryRow(query, listingID).Scan(&c.ListingID, &c.SellerID, &c.BuyerID, &c.ConfirmedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("purchase confirmation not found")
+		}
+		return nil, fmt.Errorf("failed to get purchase confirmation: %w", err)
+	}
+
+	return c, nil
+}
+
+func (r *ReviewRepository) Create(review *domain.Review) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	review.CreatedAt = time.Now()
+
+	err = tx.QueryRow(`
+		INSERT INTO reviews (listing_id, seller_id, author_id, rating, text, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (listing_id) DO NOTHING
+		RETURNING id`,
+		review.ListingID, review.SellerID, review.AuthorID, review.Rating, review.Text, review.CreatedAt).Scan(&review.ID)
+	if err == sql.ErrNoRows {
+		return repository.ErrDuplicate
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create review: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO seller_ratings (seller_id, rating_sum, review_count)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (seller_id) DO UPDATE
+		SET rating_sum = seller_ratings.rating_sum + EXCLUDED.rating_sum,
+			review_count = seller_ratings.review_count + 1`,
+		review.SellerID, review.Rating)
+	if err != nil {
+		return fmt.Errorf("failed to update seller rating: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit review: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ReviewRepository) GetByID(id int64) (*domain.Review, error) {
+	query := `SELECT ` + reviewColumns + ` FROM reviews WHERE id = $1`
+
+	review, err := scanReview(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("review not found")
+		}
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+
+	return review, nil
+}
+
+func (r *ReviewRepository) GetBySellerID(sellerID int64) ([]*domain.Review, error) {
+	query := `SELECT ` + reviewColumns + ` FROM reviews WHERE seller_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query, sellerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []*domain.Review{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, nil
+}
+
+func (r *ReviewRepository) SetReply(reviewID int64, reply string, repliedAt time.Time) error {
+	result, err := r.db.Exec(`UPDATE reviews SET reply = $2, replied_at = $3 WHERE id = $1 AND replied_at IS NULL`,
+		reviewID, reply, repliedAt)
+	if err != nil {
+		return fmt.Errorf("failed to reply to review: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to reply to review: %w", err)
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+
+	return nil
+}
+
+// GetSellerRatings loads the aggregates of many sellers in one query, so
+// listing pages don't issue a rating lookup per row.
+func (r *ReviewRepository) GetSellerRatings(sellerIDs []int64) (map[int64]*domain.SellerRating, error) {
+	ratings := make(map[int64]*domain.SellerRating, len(sellerIDs))
+	if len(sellerIDs) == 0 {
+		return ratings, nil
+	}
+
+	query := `SELECT seller_id, rating_sum, review_count FROM seller_ratings WHERE seller_id = ANY($1)`
+
+	rows, err := r.db.Query(query, pq.Array(sellerIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seller ratings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rating := &domain.SellerRating{}
+		if err := rows.Scan(&rating.SellerID, &rating.RatingSum, &rating.ReviewCount); err != nil {
+			return nil, fmt.Errorf("failed to scan seller rating: %w", err)
+		}
+		ratings[rating.SellerID] = rating
+	}
+
+	return ratings, nil
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"vk/ecom/internal/domain"
+)
+
+const sessionColumns = "id, user_id, ip, user_agent, created_at, last_seen_at, expires_at, revoked_at"
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func scanSession(row rowScanner) (*domain.Session, error) {
+	session := &domain.Session{}
+	var revokedAt sql.NullTime
+	err := row.Scan(&session.ID, &session.UserID, &session.IP, &session.UserAgent, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	return session, nil
+}
+
+func (r *SessionRepository) Create(session *domain.Session) error {
+	// The user's expired sessions are cleaned up here rather than by a
+	// scheduler.
+	if _, err := r.db.Exec(`DELETE FROM user_sessions WHERE user_id = $1 AND expires_at < $2`, session.UserID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+
+	query := `
+		INSERT INTO user_sessions (user_id, ip, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, last_seen_at`
+
+	err := r.db.QueryRow(query, session.UserID, session.IP, session.UserAgent, session.ExpiresAt).
+		Scan(&session.ID, &session.CreatedAt, &session.LastSeenAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SessionRepository) Get(id int64) (*domain.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions WHERE id = $1`
+
+	session, err := scanSession(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return session, nil
+}
+
+func (r *SessionRepository) GetActive(userID int, now time.Time) ([]*domain.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + ` FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY last_seen_at DESC, id DESC`
+
+	rows, err := r.db.Query(query, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*domain.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+func (r *SessionRepository) SetLastSeen(id int64, at time.Time) error {
+	if _, err := r.db.Exec(`UPDATE user_sessions SET last_seen_at = $2 WHERE id = $1`, id, at); err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SessionRepository) Revoke(userID int, id int64, now time.Time) (bool, error) {
+	query := `UPDATE user_sessions SET revoked_at = $3 WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	result, err := r.db.Exec(query, id, userID, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+func (r *SessionRepository) RevokeAll(userID int, exceptID int64, now time.Time) (int, error) {
+	query := `UPDATE user_sessions SET revoked_at = $3 WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`
+
+	result, err := r.db.Exec(query, userID, exceptID, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return int(rows), nil
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository"
+)
+
+type TwoFactorRepository struct {
+	db *sql.DB
+}
+
+func NewTwoFactorRepository(db *sql.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+func (r *TwoFactorRepository) Get(userID int) (*domain.TwoFactor, error) {
+	query := `SELECT user_id, secret, enabled, last_used_step, created_at FROM user_two_factor WHERE user_id = $1`
+
+	tf := &domain.TwoFactor{}
+	err := r.db.QueryRow(query, userID).Scan(&tf.UserID, &tf.Secret, &tf.Enabled, &tf.LastUsedStep, &tf.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor setup: %w", err)
+	}
+	return tf, nil
+}
+
+func (r *TwoFactorRepository) SetSecret(userID int, secret string) error {
+	query := `
+		INSERT INTO user_two_factor (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+		WHERE user_two_factor.enabled = FALSE`
+
+	result, err := r.db.Exec(query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save two-factor secret: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save two-factor secret: %w", err)
+	}
+	if rows == 0 {
+		return repository.ErrConflict
+	}
+
+	return nil
+}
+
+func (r *TwoFactorRepository) Enable(userID int, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE user_two_factor SET enabled = TRUE, last_used_step = $2 WHERE user_id = $1 AND enabled = FALSE`, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to enable two-factor auth: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to enable two-factor auth: %w", err)
+	}
+	if rows == 0 {
+		return repository.ErrConflict
+	}
+
+	if _, err := tx.Exec(`DELETE FROM two_factor_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(`INSERT INTO two_factor_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return fmt.Errorf("failed to replace recovery codes: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// UseStep compares and sets the last used step in one update, so a code
+// sent to two instances at once is only accepted by one.
+func (r *TwoFactorRepository) UseStep(userID int, step int64) (bool, error) {
+	result, err := r.db.Exec(`UPDATE user_two_factor SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record two-factor code: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record two-factor code: %w", err)
+	}
+	return rows == 1, nil
+}
+
+func (r *TwoFactorRepository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM two_factor_recovery_codes WHERE user_id = $1 AND code_hash = $2`, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return rows == 1, nil
+}
+
+func (r *TwoFactorRepository) CountRecoveryCodes(userID int) (int, error) {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM two_factor_recovery_codes WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+func (r *TwoFactorRepository) Delete(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM two_factor_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM user_two_factor WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete two-factor setup: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
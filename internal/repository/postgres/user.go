@@ -2,10 +2,16 @@ package postgres
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository"
+
+	"github.com/lib/pq"
 )
 
+const userColumns = "id, login, password, email, email_verified, phone, phone_verified, role, banned, session_version"
+
 type UserRepository struct {
 	db *sql.DB
 }
@@ -14,46 +20,161 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+func scanUser(row rowScanner) (*domain.User, error) {
+	user := &domain.User{}
+	err := row.Scan(&user.ID, &user.Login, &user.Password, &user.Email, &user.EmailVerified, &user.Phone, &user.PhoneVerified,
+		&user.Role, &user.Banned, &user.SessionVersion)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
 func (r *UserRepository) Create(user *domain.User) error {
 	query := `
-		INSERT INTO users (login, password)
-		VALUES ($1, $2)
+		INSERT INTO users (login, password, email, email_verified, phone, phone_verified)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT DO NOTHING
 		RETURNING id`
 
-	err := r.db.QueryRow(query, user.Login, user.Password).Scan(&user.ID)
+	err := r.db.QueryRow(query, user.Login, user.Password, user.Email, user.EmailVerified, user.Phone, user.PhoneVerified).Scan(&user.ID)
+	if err == sql.ErrNoRows {
+		return repository.ErrDuplicate
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
+	user.Role = domain.UserRoleUser
 
 	return nil
 }
 
 func (r *UserRepository) GetByID(id int) (*domain.User, error) {
-	query := `SELECT id, login, password FROM users WHERE id = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 
-	user := &domain.User{}
-	err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Login, &user.Password)
+	return scanUser(r.db.QueryRow(query, id))
+}
+
+func (r *UserRepository) GetByLogin(login string) (*domain.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE login = $1`
+
+	return scanUser(r.db.QueryRow(query, login))
+}
+
+func (r *UserRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM users WHERE id = $1`, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
-		}
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
-	return user, nil
+	return requireUserRow(result)
 }
 
-func (r *UserRepository) GetByLogin(login string) (*domain.User, error) {
-	query := `SELECT id, login, password FROM users WHERE login = $1`
+func (r *UserRepository) SetBanned(id int, banned bool) error {
+	result, err := r.db.Exec(`UPDATE users SET banned = $1 WHERE id = $2`, banned, id)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
 
-	user := &domain.User{}
-	err := r.db.QueryRow(query, login).Scan(&user.ID, &user.Login, &user.Password)
+	return requireUserRow(result)
+}
+
+func (r *UserRepository) SetPassword(id int, passwordHash string, revokeSessions bool) error {
+	query := `
+		UPDATE users SET
+			password = $1,
+			session_version = session_version + CASE WHEN $2 THEN 1 ELSE 0 END
+		WHERE id = $3`
+
+	result, err := r.db.Exec(query, passwordHash, revokeSessions, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
-		}
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return fmt.Errorf("failed to update password: %w", err)
 	}
 
-	return user, nil
+	return requireUserRow(result)
+}
+
+func (r *UserRepository) RehashPassword(id int, oldHash, newHash string) error {
+	result, err := r.db.Exec(`UPDATE users SET password = $1 WHERE id = $2 AND password = $3`, newHash, id, oldHash)
+	if err != nil {
+		return fmt.Errorf("failed to rehash password: %w", err)
+	}
+
+	return requireMatchedRow(result)
+}
+
+func (r *UserRepository) SetEmail(id int, email string) error {
+	result, err := r.db.Exec(`UPDATE users SET email = $1, email_verified = FALSE WHERE id = $2`, email, id)
+	if err != nil {
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+
+	return requireUserRow(result)
+}
+
+func (r *UserRepository) SetPhone(id int, phone string) error {
+	result, err := r.db.Exec(`UPDATE users SET phone = $1, phone_verified = FALSE WHERE id = $2`, phone, id)
+	if err != nil {
+		return fmt.Errorf("failed to update phone: %w", err)
+	}
+
+	return requireUserRow(result)
+}
+
+func (r *UserRepository) MarkEmailVerified(id int, email string) error {
+	result, err := r.db.Exec(`UPDATE users SET email_verified = TRUE WHERE id = $1 AND email = $2 AND email <> ''`, id, email)
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicate
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	return requireMatchedRow(result)
+}
+
+func (r *UserRepository) MarkPhoneVerified(id int, phone string) error {
+	result, err := r.db.Exec(`UPDATE users SET phone_verified = TRUE WHERE id = $1 AND phone = $2 AND phone <> ''`, id, phone)
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicate
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify phone: %w", err)
+	}
+
+	return requireMatchedRow(result)
+}
+
+// isUniqueViolation reports whether err is a unique_violation, here from
+// the partial unique indexes on email and phone.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func requireMatchedRow(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if rows == 0 {
+		return repository.ErrConflict
+	}
+
+	return nil
+}
+
+func requireUserRow(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
 }
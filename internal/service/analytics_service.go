@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/interfaces"
+	"vk/ecom/internal/repository"
+)
+
+const defaultStatsDays = 30
+
+// Weights of the events in the popularity score.
+const (
+	popularityViewWeight     = 1
+	popularityFavoriteWeight = 3
+	popularityMessageWeight  = 5
+)
+
+var ErrInvalidListingEvent = errors.New("event must be favorite or message")
+
+type AnalyticsConfig struct {
+	// Repeated events of one visitor on one listing within DedupWindow are
+	// counted once.
+	DedupWindow time.Duration
+	// Buffered counts are written every FlushInterval, or earlier once
+	// MaxPending listing-days are waiting.
+	FlushInterval time.Duration
+	MaxPending    int
+	// Stats requests cover at most MaxStatsDays days.
+	MaxStatsDays int
+	// Popularity is recomputed every PopularityInterval from the events of
+	// the last PopularityWindow; an event loses half of its weight every
+	// PopularityHalfLife.
+	PopularityInterval time.Duration
+	PopularityWindow   time.Duration
+	PopularityHalfLife time.Duration
+}
+
+func DefaultAnalyticsConfig() AnalyticsConfig {
+	return AnalyticsConfig{
+		DedupWindow:   30 * time.Minute,
+		FlushInterval: 10 * time.Second,
+		MaxPending:    1000,
+		MaxStatsDays:  90,
+
+		PopularityInterval: 5 * time.Minute,
+		PopularityWindow:   14 * 24 * time.Hour,
+		PopularityHalfLife: 3 * 24 * time.Hour,
+	}
+}
+
+type pendingKey struct {
+	listingID int64
+	day       time.Time
+}
+
+type seenKey struct {
+	listingID int64
+	event     string
+	visitor   string
+}
+
+// AnalyticsService counts listing events for sellers. Events are
+// deduplicated and aggregated in memory and written to the repository in
+// batches by Run, so recording an event never waits for the database.
+type AnalyticsService struct {
+	analyticsRepo repository.AnalyticsRepository
+	listingRepo   repository.ListingRepository
+	config        AnalyticsConfig
+
+	mu      sync.Mutex
+	pending map[pendingKey]*domain.ListingDailyStats
+	seen    map[seenKey]time.Time
+	flush   chan struct{}
+}
+
+var _ interfaces.AnalyticsServiceInterface = (*AnalyticsService)(nil)
+
+func NewAnalyticsService(analyticsRepo repository.AnalyticsRepository, listingRepo repository.ListingRepository, config AnalyticsConfig) *AnalyticsService {
+	return &AnalyticsService{
+		analyticsRepo: analyticsRepo,
+		listingRepo:   listingRepo,
+		config:        config,
+		pending:       make(map[pendingKey]*domain.ListingDailyStats),
+		seen:          make(map[seenKey]time.Time),
+		flush:         make(chan struct{}, 1),
+	}
+}
+
+// VisitorKey identifies a visitor for deduplication: the user if logged in,
+// otherwise a hash of the client address and user agent.
+func VisitorKey(userID *int64, clientIP, userAgent string) string {
+	if userID != nil {
+		return "user:" + strconv.FormatInt(*userID, 10)
+	}
+	sum := sha256.Sum256([]byte(clientIP + "\n" + userAgent))
+	return "anon:" + hex.EncodeToString(sum[:8])
+}
+
+// TrackView records a detail-page view. The caller has already loaded the
+// listing, so it is not looked up again.
+func (s *AnalyticsService) TrackView(listingID int64, visitor string) {
+	s.track(listingID, domain.ListingEventView, visitor, time.Now())
+}
+
+// TrackEvent records a favorite or a message start by a signed-in user, who
+// counts once per window like a visitor.
+func (s *AnalyticsService) TrackEvent(listingID, userID int64, event string) error {
+	if event != domain.ListingEventFavorite && event != domain.ListingEventMessage {
+		return ErrInvalidListingEvent
+	}
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil || listing.Status == domain.ListingStatusHidden {
+		return ErrListingNotFound
+	}
+
+	s.track(listingID, event, VisitorKey(&userID, "", ""), time.Now())
+	return nil
+}
+
+func (s *AnalyticsService) track(listingID int64, event, visitor string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := seenKey{listingID: listingID, event: event, visitor: visitor}
+	if last, ok := s.seen[key]; ok && now.Sub(last) < s.config.DedupWindow {
+		return
+	}
+	s.seen[key] = now
+
+	day := now.UTC().Truncate(24 * time.Hour)
+	stats, ok := s.pending[pendingKey{listingID: listingID, day: day}]
+	if !ok {
+		stats = &domain.ListingDailyStats{ListingID: listingID, Day: day}
+		s.pending[pendingKey{listingID: listingID, day: day}] = stats
+	}
+	stats.Add(event, 1)
+
+	if len(s.pending) >= s.config.MaxPending {
+		select {
+		case s.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run flushes buffered counts every FlushInterval, or sooner when the
+// buffer fills up, and once more when ctx is done. It also recomputes
+// listing popularity every PopularityInterval.
+func (s *AnalyticsService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+	popularityTicker := time.NewTicker(s.config.PopularityInterval)
+	defer popularityTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.Flush(); err != nil {
+				log.Println("Failed to flush listing stats:", err)
+			}
+			return
+		case <-popularityTicker.C:
+			if err := s.RecomputePopularity(time.Now()); err != nil {
+				log.Println("Failed to recompute listing popularity:", err)
+			}
+			continue
+		case <-ticker.C:
+		case <-s.flush:
+		}
+		if err := s.Flush(); err != nil {
+			log.Println("Failed to flush listing stats:", err)
+		}
+	}
+}
+
+// RecomputePopularity scores every listing with events in the popularity
+// window: each day's weighted events, halved for every PopularityHalfLife
+// between the middle of that day and now. Other listings score zero.
+func (s *AnalyticsService) RecomputePopularity(now time.Time) error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	stats, err := s.analyticsRepo.GetDailyStatsSince(now.Add(-s.config.PopularityWindow))
+	if err != nil {
+		return err
+	}
+
+	scores := make(map[int64]float64)
+	for _, day := range stats {
+		events := float64(day.Views*popularityViewWeight + day.Favorites*popularityFavoriteWeight + day.Messages*popularityMessageWeight)
+		age := max(now.Sub(day.Day.Add(12*time.Hour)), 0)
+		scores[day.ListingID] += events * math.Exp2(-float64(age)/float64(s.config.PopularityHalfLife))
+	}
+	return s.listingRepo.SetPopularity(scores)
+}
+
+// Flush writes the buffered counts in one batch. On failure they are put
+// back and retried with the next flush.
+func (s *AnalyticsService) Flush() error {
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = make(map[pendingKey]*domain.ListingDailyStats)
+	cutoff := time.Now().Add(-s.config.DedupWindow)
+	for key, last := range s.seen {
+		if last.Before(cutoff) {
+			delete(s.seen, key)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	stats := make([]*domain.ListingDailyStats, 0, len(batch))
+	for _, st := range batch {
+		stats = append(stats, st)
+	}
+	if err := s.analyticsRepo.AddDailyStats(stats); err != nil {
+		s.mu.Lock()
+		for key, st := range batch {
+			if current, ok := s.pending[key]; ok {
+				current.Merge(st)
+			} else {
+				s.pending[key] = st
+			}
+		}
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// GetListingStats returns the daily series of the last days days, today
+// included, for the listing's author; zero means the default of 30 days.
+// Counts that are not flushed yet are included.
+func (s *AnalyticsService) GetListingStats(listingID, sellerID int64, days int) (*dto.ListingStatsDTO, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, ErrListingNotFound
+	}
+	if listing.AuthorID != sellerID {
+		return nil, ErrNotListingOwner
+	}
+	if days < 1 {
+		days = defaultStatsDays
+	}
+	days = min(days, s.config.MaxStatsDays)
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -(days - 1))
+	stored, err := s.analyticsRepo.GetDailyStats(listingID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	byDay := make(map[time.Time]*domain.ListingDailyStats, days)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		byDay[day] = &domain.ListingDailyStats{ListingID: listingID, Day: day}
+	}
+	for _, st := range stored {
+		if day, ok := byDay[st.Day.UTC().Truncate(24*time.Hour)]; ok {
+			day.Merge(st)
+		}
+	}
+	s.mu.Lock()
+	for key, st := range s.pending {
+		if day, ok := byDay[key.day]; ok && key.listingID == listingID {
+			day.Merge(st)
+		}
+	}
+	s.mu.Unlock()
+
+	series := make([]*domain.ListingDailyStats, 0, days)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		series = append(series, byDay[day])
+	}
+	return dto.ToListingStatsDTO(listingID, series), nil
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/repository"
+)
+
+// APIKeyPrefix starts every API key, which tells keys apart from JWTs in
+// the Authorization header.
+const APIKeyPrefix = "ek_"
+
+var (
+	ErrAPIKeysDisabled   = errors.New("api keys are not enabled")
+	ErrInvalidAPIKey     = errors.New("api key is invalid or revoked")
+	ErrAPIKeyNotFound    = errors.New("api key not found")
+	ErrInvalidScope      = errors.New("unknown api key scope")
+	ErrScopeNotAllowed   = errors.New("scope requires staff access")
+	ErrTooManyAPIKeys    = errors.New("too many active api keys")
+	ErrInvalidAPIKeyName = errors.New("api key name must be between 1 and 64 characters")
+)
+
+// apiKeyScopes lists the known scopes and whether they need staff access.
+var apiKeyScopes = map[string]bool{
+	domain.ScopeListingsRead:  false,
+	domain.ScopeListingsWrite: false,
+	domain.ScopeAdminModerate: true,
+}
+
+type APIKeyConfig struct {
+	// MaxKeys is the number of active keys a user may have.
+	MaxKeys int
+	// LastUsedInterval limits how often the last-used time is written for
+	// a busy key.
+	LastUsedInterval time.Duration
+}
+
+func DefaultAPIKeyConfig() APIKeyConfig {
+	return APIKeyConfig{
+		MaxKeys:          10,
+		LastUsedInterval: time.Minute,
+	}
+}
+
+type apiKeys struct {
+	keys   repository.APIKeyRepository
+	config APIKeyConfig
+}
+
+// WithAPIKeys lets users create API keys for non-interactive access.
+func WithAPIKeys(keys repository.APIKeyRepository, config APIKeyConfig) AuthServiceOption {
+	return func(s *AuthService) {
+		s.apiKeys = &apiKeys{keys: keys, config: config}
+	}
+}
+
+// CreateAPIKey issues a key with the given scopes. The key itself is only
+// returned here; afterwards just its prefix is shown.
+func (s *AuthService) CreateAPIKey(userID int, name string, scopes []string) (*dto.APIKeyCreatedDTO, error) {
+	if s.apiKeys == nil {
+		return nil, ErrAPIKeysDisabled
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 64 {
+		return nil, ErrInvalidAPIKeyName
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	scopes, err = normalizeScopes(user, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.apiKeys.keys.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	active := 0
+	for _, key := range existing {
+		if key.RevokedAt == nil {
+			active++
+		}
+	}
+	if active >= s.apiKeys.config.MaxKeys {
+		return nil, ErrTooManyAPIKeys
+	}
+
+	id := make([]byte, 4)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	prefix := APIKeyPrefix + hex.EncodeToString(id)
+	token := prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &domain.APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  prefix,
+		KeyHash: hashToken(token),
+		Scopes:  scopes,
+	}
+	if err := s.apiKeys.keys.Create(key); err != nil {
+		return nil, err
+	}
+
+	return &dto.APIKeyCreatedDTO{Key: token, APIKey: key}, nil
+}
+
+// normalizeScopes checks the requested scopes against the user's role and
+// drops duplicates.
+func normalizeScopes(user *domain.User, scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+	seen := make(map[string]bool, len(scopes))
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		staffOnly, ok := apiKeyScopes[scope]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+		if staffOnly && user.Role != domain.UserRoleStaff {
+			return nil, fmt.Errorf("%w: %s", ErrScopeNotAllowed, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
+
+// GetAPIKeys returns the user's keys, revoked ones included.
+func (s *AuthService) GetAPIKeys(userID int) ([]*domain.APIKey, error) {
+	if s.apiKeys == nil {
+		return nil, ErrAPIKeysDisabled
+	}
+	keys, err := s.apiKeys.keys.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []*domain.APIKey{}
+	}
+	return keys, nil
+}
+
+func (s *AuthService) RevokeAPIKey(userID int, keyID int64) error {
+	if s.apiKeys == nil {
+		return ErrAPIKeysDisabled
+	}
+	revoked, err := s.apiKeys.keys.Revoke(userID, keyID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// ValidateAPIKey returns the owner of an active key and the key with its
+// scopes. Like ValidateToken, the role comes from the stored user.
+func (s *AuthService) ValidateAPIKey(token string) (*domain.User, *domain.APIKey, error) {
+	if s.apiKeys == nil {
+		return nil, nil, ErrAPIKeysDisabled
+	}
+	if !strings.HasPrefix(token, APIKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeys.keys.GetByHash(hashToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	if key == nil || key.RevokedAt != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepo.GetByID(key.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if user.Banned {
+		return nil, nil, ErrUserBanned
+	}
+
+	now := time.Now().UTC()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= s.apiKeys.config.LastUsedInterval {
+		if err := s.apiKeys.keys.SetLastUsed(key.ID, now); err != nil {
+			log.Println("Failed to update api key last use:", err)
+		}
+		key.LastUsedAt = &now
+	}
+
+	return user, key, nil
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"vk/ecom/internal/domain"
+)
+
+const defaultAttributeTextLen = 200
+
+var (
+	ErrCategoryNotFound        = errors.New("category has no attribute schema")
+	ErrInvalidAttributeFilter  = errors.New("invalid attribute filter")
+	ErrAttributeFilterCategory = errors.New("attribute filters require a category")
+)
+
+// AttributeCatalog holds the attribute schema of each category. Listings
+// of categories without a schema cannot carry attributes. A nil catalog
+// has no schemas.
+type AttributeCatalog struct {
+	schemas map[string]*domain.CategorySchema
+}
+
+func NewAttributeCatalog(schemas ...*domain.CategorySchema) *AttributeCatalog {
+	c := &AttributeCatalog{schemas: make(map[string]*domain.CategorySchema, len(schemas))}
+	for _, schema := range schemas {
+		c.schemas[schema.Category] = schema
+	}
+	return c
+}
+
+// DefaultCategorySchemas returns the built-in category schemas.
+func DefaultCategorySchemas() []*domain.CategorySchema {
+	bound := func(v float64) *float64 { return &v }
+	conditions := []string{"new", "like_new", "used", "for_parts"}
+
+	return []*domain.CategorySchema{
+		{Category: "phones", Attributes: []domain.AttributeDefinition{
+			{Key: "condition", Label: "Condition", Type: domain.AttributeTypeEnum, Required: true, Values: conditions},
+			{Key: "brand", Label: "Brand", Type: domain.AttributeTypeText, MaxLength: 50},
+			{Key: "storage_gb", Label: "Storage", Type: domain.AttributeTypeNumber, Unit: "GB", Min: bound(1), Max: bound(4096)},
+			{Key: "unlocked", Label: "Unlocked", Type: domain.AttributeTypeBoolean},
+		}},
+		{Category: "bicycles", Attributes: []domain.AttributeDefinition{
+			{Key: "condition", Label: "Condition", Type: domain.AttributeTypeEnum, Required: true, Values: conditions},
+			{Key: "brand", Label: "Brand", Type: domain.AttributeTypeText, MaxLength: 50},
+			{Key: "frame_size_cm", Label: "Frame size", Type: domain.AttributeTypeNumber, Unit: "cm", Min: bound(30), Max: bound(70)},
+			{Key: "electric", Label: "Electric", Type: domain.AttributeTypeBoolean},
+		}},
+		{Category: "clothing", Attributes: []domain.AttributeDefinition{
+			{Key: "condition", Label: "Condition", Type: domain.AttributeTypeEnum, Required: true, Values: conditions},
+			{Key: "brand", Label: "Brand", Type: domain.AttributeTypeText, MaxLength: 50},
+			{Key: "size", Label: "Size", Type: domain.AttributeTypeEnum, Values: []string{"xs", "s", "m", "l", "xl", "xxl"}},
+		}},
+	}
+}
+
+func (c *AttributeCatalog) Schema(category string) (*domain.CategorySchema, bool) {
+	if c == nil {
+		return nil, false
+	}
+	schema, ok := c.schemas[category]
+	return schema, ok
+}
+
+// Validate checks the attribute values of a listing in the category and
+// returns them normalized: text is trimmed and nil values are dropped.
+func (c *AttributeCatalog) Validate(category string, values map[string]interface{}) (map[string]interface{}, error) {
+	schema, ok := c.Schema(category)
+	if !ok {
+		for _, value := range values {
+			if value != nil {
+				return nil, errors.New("listings of this category cannot have attributes")
+			}
+		}
+		return nil, nil
+	}
+
+	result := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if value == nil {
+			continue
+		}
+		attribute, ok := schema.Attribute(key)
+		if !ok {
+			return nil, fmt.Errorf("unknown attribute %q", key)
+		}
+		normalized, err := validateAttribute(attribute, value)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = normalized
+	}
+	for _, attribute := range schema.Attributes {
+		if _, ok := result[attribute.Key]; attribute.Required && !ok {
+			return nil, fmt.Errorf("attribute %q is required", attribute.Key)
+		}
+	}
+	return result, nil
+}
+
+func validateAttribute(attribute *domain.AttributeDefinition, value interface{}) (interface{}, error) {
+	switch attribute.Type {
+	case domain.AttributeTypeEnum:
+		s, ok := value.(string)
+		if !ok || !slices.Contains(attribute.Values, s) {
+			return nil, fmt.Errorf("attribute %q must be one of %s", attribute.Key, strings.Join(attribute.Values, ", "))
+		}
+		return s, nil
+	case domain.AttributeTypeNumber:
+		n, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("attribute %q must be a number", attribute.Key)
+		}
+		if (attribute.Min != nil && n < *attribute.Min) || (attribute.Max != nil && n > *attribute.Max) {
+			return nil, fmt.Errorf("attribute %q is out of range", attribute.Key)
+		}
+		return n, nil
+	case domain.AttributeTypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("attribute %q must be true or false", attribute.Key)
+		}
+		return b, nil
+	default:
+		s, ok := value.(string)
+		maxLen := attribute.MaxLength
+		if maxLen == 0 {
+			maxLen = defaultAttributeTextLen
+		}
+		if !ok || len(strings.TrimSpace(s)) == 0 || len(strings.TrimSpace(s)) > maxLen {
+			return nil, fmt.Errorf("attribute %q must be text of up to %d characters", attribute.Key, maxLen)
+		}
+		return strings.TrimSpace(s), nil
+	}
+}
+
+// ParseFilters parses "key" and "key.op" query filters with their raw
+// values into typed filters for the category, ordered by key.
+func (c *AttributeCatalog) ParseFilters(category string, raw map[string]string) ([]domain.AttributeFilter, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	if category == "" {
+		return nil, ErrAttributeFilterCategory
+	}
+	schema, ok := c.Schema(category)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAttributeFilter, ErrCategoryNotFound)
+	}
+
+	filters := make([]domain.AttributeFilter, 0, len(raw))
+	for name, rawValue := range raw {
+		key, op, hasOp := strings.Cut(name, ".")
+		if !hasOp {
+			op = domain.AttributeOpEq
+		}
+		attribute, ok := schema.Attribute(key)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown attribute %q", ErrInvalidAttributeFilter, key)
+		}
+		if op != domain.AttributeOpEq && (attribute.Type != domain.AttributeTypeNumber || (op != domain.AttributeOpGte && op != domain.AttributeOpLte)) {
+			return nil, fmt.Errorf("%w: unsupported operator %q for %q", ErrInvalidAttributeFilter, op, key)
+		}
+
+		var value interface{} = rawValue
+		switch attribute.Type {
+		case domain.AttributeTypeNumber:
+			n, err := strconv.ParseFloat(rawValue, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q must be a number", ErrInvalidAttributeFilter, key)
+			}
+			value = n
+		case domain.AttributeTypeBoolean:
+			b, err := strconv.ParseBool(rawValue)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q must be true or false", ErrInvalidAttributeFilter, key)
+			}
+			value = b
+		}
+		filters = append(filters, domain.AttributeFilter{Key: key, Op: op, Value: value})
+	}
+
+	slices.SortFunc(filters, func(a, b domain.AttributeFilter) int {
+		return strings.Compare(a.Key+"."+a.Op, b.Key+"."+b.Op)
+	})
+	return filters, nil
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/interfaces"
+	"vk/ecom/internal/repository"
+)
+
+var (
+	ErrNotAnAuction      = errors.New("listing is not an auction")
+	ErrAuctionEnded      = errors.New("auction has ended")
+	ErrBidTooLow         = errors.New("bid is below the minimum allowed amount")
+	ErrOwnAuctionBid     = errors.New("cannot bid on your own auction")
+	ErrInvalidAuctionArg = errors.New("invalid auction parameters")
+	// ErrAuctionUnavailable is returned for bids on auctions whose listing
+	// is no longer active, e.g. hidden by moderation.
+	ErrAuctionUnavailable = errors.New("auction is not available for bidding")
+)
+
+type AuctionConfig struct {
+	// A bid placed less than SnipeWindow before the end pushes the end
+	// time to SnipeExtension after the bid.
+	SnipeWindow    time.Duration
+	SnipeExtension time.Duration
+	MinDuration    time.Duration
+	MaxDuration    time.Duration
+}
+
+func DefaultAuctionConfig() AuctionConfig {
+	return AuctionConfig{
+		SnipeWindow:    2 * time.Minute,
+		SnipeExtension: 2 * time.Minute,
+		MinDuration:    time.Hour,
+		MaxDuration:    30 * 24 * time.Hour,
+	}
+}
+
+type AuctionService struct {
+	auctionRepo repository.AuctionRepository
+	listingRepo repository.ListingRepository
+	userRepo    repository.UserRepository
+	config      AuctionConfig
+	screener    *ListingScreener
+	attributes  *AttributeCatalog
+	// requireVerifiedEmail is set by WithAuctionVerifiedEmailRequired.
+	requireVerifiedEmail bool
+}
+
+var _ interfaces.AuctionServiceInterface = (*AuctionService)(nil)
+
+// AuctionServiceOption enables optional collaborators of AuctionService.
+type AuctionServiceOption func(*AuctionService)
+
+// WithAuctionScreener screens auction listings on create.
+func WithAuctionScreener(screener *ListingScreener) AuctionServiceOption {
+	return func(s *AuctionService) {
+		s.screener = screener
+	}
+}
+
+// WithAuctionAttributeCatalog validates auction listing attributes, see
+// WithAttributeCatalog.
+func WithAuctionAttributeCatalog(catalog *AttributeCatalog) AuctionServiceOption {
+	return func(s *AuctionService) {
+		s.attributes = catalog
+	}
+}
+
+// WithAuctionVerifiedEmailRequired is WithVerifiedEmailRequired for
+// auction listings.
+func WithAuctionVerifiedEmailRequired() AuctionServiceOption {
+	return func(s *AuctionService) {
+		s.requireVerifiedEmail = true
+	}
+}
+
+func NewAuctionService(auctionRepo repository.AuctionRepository, listingRepo repository.ListingRepository, userRepo repository.UserRepository, config AuctionConfig, opts ...AuctionServiceOption) *AuctionService {
+	s := &AuctionService{
+		auctionRepo: auctionRepo,
+		listingRepo: listingRepo,
+		userRepo:    userRepo,
+		config:      config,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *AuctionService) CreateAuction(req *dto.ListingRequest, authorID int64) (*dto.ListingDTO, error) {
+	if req.Auction == nil {
+		return nil, ErrInvalidAuctionArg
+	}
+	if s.requireVerifiedEmail {
+		if err := checkVerifiedEmail(s.userRepo, authorID); err != nil {
+			return nil, err
+		}
+	}
+	params := req.Auction
+
+	listingReq := *req
+	listingReq.Price = params.StartPrice
+	if err := validateListingRequest(&listingReq); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAuctionArg, err)
+	}
+	attributes, err := s.attributes.Validate(req.Category, req.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAuctionArg, err)
+	}
+	currency, _ := requestCurrency(req.Currency, domain.DefaultCurrency)
+
+	if params.MinIncrement < 1 {
+		return nil, fmt.Errorf("%w: min_increment must be positive", ErrInvalidAuctionArg)
+	}
+	if params.ReservePrice != 0 && params.ReservePrice < params.StartPrice {
+		return nil, fmt.Errorf("%w: reserve_price cannot be lower than start_price", ErrInvalidAuctionArg)
+	}
+	duration := time.Until(params.EndsAt)
+	if duration < s.config.MinDuration || duration > s.config.MaxDuration {
+		return nil, fmt.Errorf("%w: ends_at is outside of the allowed auction duration", ErrInvalidAuctionArg)
+	}
+
+	listing := &domain.Listing{
+		Title:       req.Title,
+		Description: req.Description,
+		ImageURL:    req.ImageURL,
+		Price:       params.StartPrice,
+		Currency:    currency,
+		Category:    req.Category,
+		Latitude:    req.Latitude,
+		Longitude:   req.Longitude,
+		City:        strings.TrimSpace(req.City),
+		Region:      strings.TrimSpace(req.Region),
+		Attributes:  attributes,
+		AuthorID:    authorID,
+		Type:        domain.ListingTypeAuction,
+	}
+	screened, err := s.screener.Screen(listing)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.listingRepo.Create(listing); err != nil {
+		return nil, err
+	}
+
+	auction := &domain.Auction{
+		ListingID:    listing.ID,
+		StartPrice:   params.StartPrice,
+		ReservePrice: params.ReservePrice,
+		MinIncrement: params.MinIncrement,
+		EndsAt:       params.EndsAt.UTC(),
+		Status:       domain.AuctionStatusOpen,
+	}
+	if err := s.auctionRepo.Create(auction); err != nil {
+		if deleteErr := s.listingRepo.Delete(listing.ID); deleteErr != nil {
+			log.Println("Failed to delete listing without auction:", deleteErr)
+		}
+		return nil, err
+	}
+	s.screener.Flag(listing.ID, screened)
+
+	author, err := s.userRepo.GetByID(int(authorID))
+	if err != nil {
+		return dto.ToListingDTOWithAuthor(listing, "", &authorID), nil
+	}
+
+	return dto.ToListingDTOWithAuthor(listing, author.Login, &authorID), nil
+}
+
+func (s *AuctionService) PlaceBid(listingID, bidderID, amount int64) (*dto.AuctionDTO, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, ErrListingNotFound
+	}
+	if listing.Type != domain.ListingTypeAuction {
+		return nil, ErrNotAnAuction
+	}
+	if listing.Status != domain.ListingStatusActive {
+		return nil, ErrAuctionUnavailable
+	}
+	if listing.AuthorID == bidderID {
+		return nil, ErrOwnAuctionBid
+	}
+
+	var result domain.Auction
+	_, err = s.auctionRepo.Update(listingID, func(auction *domain.Auction) (*domain.Bid, error) {
+		now := time.Now().UTC()
+		if auction.Status != domain.AuctionStatusOpen || !now.Before(auction.EndsAt) {
+			return nil, ErrAuctionEnded
+		}
+
+		minBid := auction.StartPrice
+		if auction.BidCount > 0 {
+			minBid = auction.CurrentPrice + auction.MinIncrement
+		}
+		if amount < minBid {
+			return nil, ErrBidTooLow
+		}
+
+		auction.CurrentPrice = amount
+		auction.LeaderID = &bidderID
+		auction.BidCount++
+		if auction.EndsAt.Sub(now) < s.config.SnipeWindow {
+			auction.EndsAt = now.Add(s.config.SnipeExtension)
+		}
+		result = *auction
+
+		return &domain.Bid{
+			BidderID:  bidderID,
+			Amount:    amount,
+			CreatedAt: now,
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.ToAuctionDTO(&result, listing.AuthorID, &bidderID), nil
+}
+
+func (s *AuctionService) GetBidHistory(listingID int64, currentUserID *int64) (*dto.BidHistoryResponse, error) {
+	auction, err := s.auctionRepo.GetByListingID(listingID)
+	if err != nil {
+		return nil, ErrNotAnAuction
+	}
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	bids, err := s.auctionRepo.GetBids(listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	logins := make(map[int64]string)
+	result := make([]*dto.BidDTO, 0, len(bids))
+	for _, bid := range bids {
+		login, ok := logins[bid.BidderID]
+		if !ok {
+			if bidder, err := s.userRepo.GetByID(int(bid.BidderID)); err == nil {
+				login = bidder.Login
+			}
+			logins[bid.BidderID] = login
+		}
+		result = append(result, dto.ToBidDTO(bid, login))
+	}
+
+	return &dto.BidHistoryResponse{
+		Auction: dto.ToAuctionDTO(auction, listing.AuthorID, currentUserID),
+		Bids:    result,
+	}, nil
+}
+
+// CloseExpiredAuctions closes every open auction whose end time is not after
+// now. The highest bidder wins if their bid reached the reserve price.
+func (s *AuctionService) CloseExpiredAuctions(now time.Time) (int, error) {
+	expired, err := s.auctionRepo.GetExpired(now)
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, candidate := range expired {
+		_, err := s.auctionRepo.Update(candidate.ListingID, func(auction *domain.Auction) (*domain.Bid, error) {
+			// A late bid may have extended the auction since GetExpired ran.
+			if auction.Status != domain.AuctionStatusOpen || auction.EndsAt.After(now) {
+				return nil, nil
+			}
+			auction.Status = domain.AuctionStatusClosed
+			closedAt := now
+			auction.ClosedAt = &closedAt
+			if auction.LeaderID != nil && auction.CurrentPrice >= auction.ReservePrice {
+				winner := *auction.LeaderID
+				auction.WinnerID = &winner
+			}
+			closed++
+			return nil, nil
+		})
+		if err != nil {
+			return closed, err
+		}
+	}
+
+	return closed, nil
+}
+
+// RunScheduler closes expired auctions every interval until ctx is done.
+func (s *AuctionService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.CloseExpiredAuctions(time.Now().UTC()); err != nil {
+				log.Println("Failed to close expired auctions:", err)
+			}
+		}
+	}
+}
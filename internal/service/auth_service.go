@@ -2,78 +2,245 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
 	"vk/ecom/internal/domain"
 	"vk/ecom/internal/interfaces"
 	"vk/ecom/internal/pkg/jwt"
+	"vk/ecom/internal/pkg/password"
 	"vk/ecom/internal/repository"
+)
+
+var (
+	ErrUserBanned     = errors.New("user is banned")
+	ErrWrongPassword  = errors.New("current password is incorrect")
+	ErrWeakPassword   = errors.New("password must be between 6 and 128 characters")
+	ErrCommonPassword = errors.New("password is too common")
+)
 
-	"golang.org/x/crypto/bcrypt"
+const (
+	minPasswordLength = 6
+	maxPasswordLength = 128
 )
 
 type AuthService struct {
 	userRepo repository.UserRepository
+	hasher   password.Hasher
+	// blocklist is optional, see WithPasswordBlocklist.
+	blocklist *password.Blocklist
+	// throttle is optional, see WithLoginThrottle.
+	throttle *LoginThrottle
+	// reset is optional, see WithPasswordReset.
+	reset *passwordReset
+	// twoFactor is optional, see WithTwoFactor.
+	twoFactor *twoFactor
+	// external is optional, see WithExternalLogin.
+	external *externalLogin
+	// apiKeys is optional, see WithAPIKeys.
+	apiKeys *apiKeys
+	// contact is optional, see WithContactVerification.
+	contact *contactVerification
+	// sessions is optional, see WithSessions.
+	sessions *sessionTracker
+
+	dummyHashOnce sync.Once
+	dummyHash     string
 }
 
 var _ interfaces.AuthServiceInterface = (*AuthService)(nil)
 
-func NewAuthService(userRepo repository.UserRepository) *AuthService {
-	return &AuthService{
+// AuthServiceOption enables optional collaborators of AuthService.
+type AuthServiceOption func(*AuthService)
+
+// WithLoginThrottle delays and locks out repeated failed logins.
+func WithLoginThrottle(throttle *LoginThrottle) AuthServiceOption {
+	return func(s *AuthService) {
+		s.throttle = throttle
+	}
+}
+
+// WithPasswordHasher replaces the default argon2id hasher. Existing
+// hashes of any supported format keep verifying and are rehashed with it
+// on login.
+func WithPasswordHasher(hasher password.Hasher) AuthServiceOption {
+	return func(s *AuthService) {
+		s.hasher = hasher
+	}
+}
+
+// WithPasswordBlocklist refuses new passwords on the list with
+// ErrCommonPassword.
+func WithPasswordBlocklist(blocklist *password.Blocklist) AuthServiceOption {
+	return func(s *AuthService) {
+		s.blocklist = blocklist
+	}
+}
+
+func NewAuthService(userRepo repository.UserRepository, opts ...AuthServiceOption) *AuthService {
+	s := &AuthService{
 		userRepo: userRepo,
+		hasher:   password.NewArgon2id(password.DefaultArgon2idParams()),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// compareDummyHash spends the time of a password check for logins that do
+// not exist, so response times do not reveal them.
+func (s *AuthService) compareDummyHash(plain string) {
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = s.hasher.Hash("dummy password")
+	})
+	password.Verify(s.dummyHash, plain)
+}
+
+// checkPassword reports whether plain matches the user's password hash.
+// Users without a password, who only log in externally, never match.
+func checkPassword(user *domain.User, plain string) bool {
+	ok, err := password.Verify(user.Password, plain)
+	if err != nil && user.Password != "" {
+		log.Println("Failed to verify password hash:", err)
 	}
+	return ok
+}
+
+// rehashPassword upgrades a matching hash made with another algorithm or
+// outdated parameters. The hash is only replaced if it has not changed
+// since it was checked.
+func (s *AuthService) rehashPassword(user *domain.User, plain string) {
+	if !s.hasher.NeedsRehash(user.Password) {
+		return
+	}
+	hash, err := s.hasher.Hash(plain)
+	if err != nil {
+		log.Println("Failed to rehash password:", err)
+		return
+	}
+	if err := s.userRepo.RehashPassword(user.ID, user.Password, hash); err != nil && !errors.Is(err, repository.ErrConflict) {
+		log.Println("Failed to store rehashed password:", err)
+	}
+}
+
+func (s *AuthService) validatePassword(plain string) error {
+	if len(plain) < minPasswordLength || len(plain) > maxPasswordLength {
+		return ErrWeakPassword
+	}
+	if s.blocklist != nil && s.blocklist.Contains(plain) {
+		return ErrCommonPassword
+	}
+	return nil
+}
+
+func (s *AuthService) hashPassword(plain string) (string, error) {
+	hash, err := s.hasher.Hash(plain)
+	if errors.Is(err, password.ErrTooLong) {
+		return "", fmt.Errorf("%w, and at most 72 bytes with bcrypt", ErrWeakPassword)
+	}
+	if err != nil {
+		return "", errors.New("failed to hash password")
+	}
+	return hash, nil
 }
 
 func (s *AuthService) RegisterUser(login, password string) (*domain.User, error) {
+	return s.RegisterUserWithEmail(login, password, "")
+}
+
+// RegisterUserWithEmail is RegisterUser with an optional email address,
+// which password reset links are sent to. With contact verification a
+// link to verify it is mailed as well.
+func (s *AuthService) RegisterUserWithEmail(login, password, email string) (*domain.User, error) {
 	if len(login) < 3 || len(login) > 20 {
 		return nil, errors.New("login must be between 3 and 20 characters")
 	}
 
-	if len(password) < 6 || len(password) > 40 {
-		return nil, errors.New("password must be between 6 and 40 characters")
+	if err := s.validatePassword(password); err != nil {
+		return nil, err
+	}
+
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err := s.userRepo.GetByLogin(login)
+	_, err = s.userRepo.GetByLogin(login)
 	if err == nil {
 		return nil, errors.New("user with this login already exists")
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-
+	hashedPassword, err := s.hashPassword(password)
 	if err != nil {
-		return nil, errors.New("failed to hash password")
+		return nil, err
 	}
 
 	user := &domain.User{
 		Login:    login,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
+		Email:    email,
 	}
 
 	err = s.userRepo.Create(user)
-
 	if err != nil {
 		return nil, errors.New("failed to create user")
 	}
 
+	s.sendRegistrationVerification(user)
 	return user, nil
 }
 
 func (s *AuthService) LoginUser(login, password string) (string, *domain.User, error) {
-	user, err := s.userRepo.GetByLogin(login)
+	return s.LoginUserFrom(login, password, domain.ClientInfo{})
+}
 
-	if err != nil {
-		return "", nil, errors.New("invalid login or password")
+// LoginUserFrom is LoginUser for a request from client, whose address is
+// also throttled as a whole and which the new session records. Throttled
+// attempts fail with a LoginThrottledError before the password is checked.
+// Users with two-factor auth get a TwoFactorRequiredError for
+// CompleteTwoFactorLogin instead of a token. A matching hash of another
+// algorithm or with outdated parameters is rehashed with the current hasher.
+func (s *AuthService) LoginUserFrom(login, password string, client domain.ClientInfo) (string, *domain.User, error) {
+	now := time.Now()
+	if s.throttle != nil {
+		if err := s.throttle.Acquire(login, client.IP, now); err != nil {
+			return "", nil, err
+		}
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-
+	user, err := s.userRepo.GetByLogin(login)
+	if err != nil {
+		s.compareDummyHash(password)
+	} else if !checkPassword(user, password) {
+		err = errors.New("invalid password")
+	}
 	if err != nil {
 		return "", nil, errors.New("invalid login or password")
 	}
+	if s.throttle != nil {
+		if err := s.throttle.Release(login, client.IP); err != nil {
+			log.Println("Failed to release login attempt:", err)
+		}
+	}
 
-	token, err := jwt.GenerateToken(user)
-	if err != nil {
-		return "", nil, errors.New("failed to generate token")
+	if user.Banned {
+		return "", nil, ErrUserBanned
+	}
+	s.rehashPassword(user, password)
+	// Failures are only reset after the second factor, so that the
+	// password cannot be used to reset the count while guessing codes.
+	if err := s.challenge(user); err != nil {
+		return "", nil, err
+	}
+	if s.throttle != nil {
+		if err := s.throttle.RecordSuccess(login); err != nil {
+			log.Println("Failed to reset login failures:", err)
+		}
 	}
-	return token, user, nil
+
+	return s.issueToken(user, client)
 }
 
 func (s *AuthService) ValidateToken(tokenString string) (*domain.User, error) {
@@ -82,10 +249,67 @@ func (s *AuthService) ValidateToken(tokenString string) (*domain.User, error) {
 		return nil, errors.New("invalid token")
 	}
 
-	_, err = s.userRepo.GetByID(user.ID)
+	stored, err := s.userRepo.GetByID(user.ID)
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
+	if stored.Banned {
+		return nil, ErrUserBanned
+	}
+	// Tokens from before a password reset are revoked.
+	if user.SessionVersion != stored.SessionVersion {
+		return nil, errors.New("invalid token")
+	}
+	if s.sessions != nil && !s.sessions.checkSession(user) {
+		return nil, errors.New("invalid token")
+	}
+	user.Role = stored.Role
 
 	return user, nil
 }
+
+// ChangePassword sets a new password after checking the current one.
+// Pending reset tokens are dropped; sessions stay valid.
+func (s *AuthService) ChangePassword(userID int, currentPassword, newPassword string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if !checkPassword(user, currentPassword) {
+		return ErrWrongPassword
+	}
+	if err := s.validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.SetPassword(userID, hashedPassword, false); err != nil {
+		return err
+	}
+
+	if s.reset != nil {
+		if err := s.reset.tokens.DeleteByUserID(userID); err != nil {
+			log.Println("Failed to delete password reset tokens:", err)
+		}
+	}
+	return nil
+}
+
+// GetLockouts returns the accounts and addresses currently locked out.
+func (s *AuthService) GetLockouts() ([]*domain.LoginAttempts, error) {
+	if s.throttle == nil {
+		return nil, ErrLoginThrottleDisabled
+	}
+	return s.throttle.Lockouts(time.Now())
+}
+
+// ClearLockout lifts the lockout of a login or a client address.
+func (s *AuthService) ClearLockout(kind, value string) error {
+	if s.throttle == nil {
+		return ErrLoginThrottleDisabled
+	}
+	return s.throttle.Clear(kind, value)
+}
@@ -4,10 +4,10 @@ import (
 	"errors"
 	"testing"
 	"vk/ecom/internal/mocks"
+	pwhash "vk/ecom/internal/pkg/password"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func TestAuthService_RegisterUser_InPackage(t *testing.T) {
@@ -33,8 +33,9 @@ func TestAuthService_RegisterUser_InPackage(t *testing.T) {
 		assert.NotEqual(t, password, user.Password) // Password should be hashed
 
 		// Verify password is properly hashed
-		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+		ok, err := pwhash.Verify(user.Password, password)
 		assert.NoError(t, err)
+		assert.True(t, ok)
 
 		mockRepo.AssertExpectations(t)
 	})
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/interfaces"
+	"vk/ecom/internal/repository"
+)
+
+const maxCartItems = 100
+
+var (
+	ErrCartEmpty           = errors.New("cart is empty")
+	ErrCartFull            = errors.New("cart is full")
+	ErrCartItemUnavailable = errors.New("some cart items are no longer available")
+	ErrCartPriceChanged    = errors.New("prices of some cart items have changed")
+	ErrInvalidCartToken    = errors.New("invalid cart token")
+	ErrListingNotAddable   = errors.New("listing cannot be added to the cart")
+)
+
+type CartService struct {
+	cartRepo     repository.CartRepository
+	listingRepo  repository.ListingRepository
+	userRepo     repository.UserRepository
+	orderService interfaces.OrderServiceInterface
+}
+
+var _ interfaces.CartServiceInterface = (*CartService)(nil)
+
+func NewCartService(cartRepo repository.CartRepository, listingRepo repository.ListingRepository, userRepo repository.UserRepository, orderService interfaces.OrderServiceInterface) *CartService {
+	return &CartService{
+		cartRepo:     cartRepo,
+		listingRepo:  listingRepo,
+		userRepo:     userRepo,
+		orderService: orderService,
+	}
+}
+
+func UserCartKey(userID int64) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// AnonymousCartKey validates a client supplied cart token and returns the key
+// of the corresponding anonymous cart.
+func AnonymousCartKey(token string) (string, error) {
+	if len(token) != 32 {
+		return "", ErrInvalidCartToken
+	}
+	if _, err := hex.DecodeString(token); err != nil {
+		return "", ErrInvalidCartToken
+	}
+	return "anon:" + token, nil
+}
+
+func NewCartToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GetCart revalidates every item against the current listing: the price
+// shown is always the current one and items that can no longer be bought are
+// reported separately.
+func (s *CartService) GetCart(cartKey string, viewerID *int64) (*dto.CartDTO, error) {
+	items, err := s.cartRepo.GetItems(cartKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cart := &dto.CartDTO{
+		Groups:      []*dto.CartSellerGroupDTO{},
+		Unavailable: []*dto.CartItemDTO{},
+		ItemCount:   len(items),
+		Totals:      map[string]int64{},
+	}
+	type groupKey struct {
+		sellerID int64
+		currency string
+	}
+	groups := make(map[groupKey]*dto.CartSellerGroupDTO)
+
+	for _, item := range items {
+		itemDTO := &dto.CartItemDTO{
+			ListingID:  item.ListingID,
+			PriceAtAdd: item.PriceAtAdd,
+			AddedAt:    item.AddedAt,
+		}
+
+		listing, err := s.listingRepo.GetByID(item.ListingID)
+		if err != nil {
+			cart.Unavailable = append(cart.Unavailable, itemDTO)
+			cart.HasUnavailableItems = true
+			continue
+		}
+
+		itemDTO.Title = listing.Title
+		itemDTO.ImageURL = listing.ImageURL
+		itemDTO.Price = listing.Price
+		itemDTO.Currency = listing.Currency
+		itemDTO.PriceChanged = listing.Price != item.PriceAtAdd
+		itemDTO.Available = isPurchasable(listing, viewerID)
+
+		if !itemDTO.Available {
+			cart.Unavailable = append(cart.Unavailable, itemDTO)
+			cart.HasUnavailableItems = true
+			continue
+		}
+		if itemDTO.PriceChanged {
+			cart.HasPriceChanges = true
+		}
+
+		key := groupKey{sellerID: listing.AuthorID, currency: listing.Currency}
+		group, exists := groups[key]
+		if !exists {
+			group = &dto.CartSellerGroupDTO{SellerID: listing.AuthorID, Currency: listing.Currency, Items: []*dto.CartItemDTO{}}
+			if seller, err := s.userRepo.GetByID(int(listing.AuthorID)); err == nil {
+				group.SellerLogin = seller.Login
+			}
+			groups[key] = group
+			cart.Groups = append(cart.Groups, group)
+		}
+		group.Items = append(group.Items, itemDTO)
+		group.Subtotal += listing.Price
+		cart.Totals[listing.Currency] += listing.Price
+	}
+	if len(cart.Totals) == 1 {
+		for _, total := range cart.Totals {
+			cart.Total = total
+		}
+	}
+
+	sort.Slice(cart.Groups, func(i, j int) bool {
+		if cart.Groups[i].SellerID != cart.Groups[j].SellerID {
+			return cart.Groups[i].SellerID < cart.Groups[j].SellerID
+		}
+		return cart.Groups[i].Currency < cart.Groups[j].Currency
+	})
+
+	return cart, nil
+}
+
+func (s *CartService) AddItem(cartKey string, listingID int64, viewerID *int64) (*dto.CartDTO, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil || !isPurchasable(listing, viewerID) {
+		return nil, ErrListingNotAddable
+	}
+
+	items, err := s.cartRepo.GetItems(cartKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) >= maxCartItems {
+		return nil, ErrCartFull
+	}
+
+	err = s.cartRepo.AddItem(&domain.CartItem{
+		CartKey:    cartKey,
+		ListingID:  listing.ID,
+		PriceAtAdd: listing.Price,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCart(cartKey, viewerID)
+}
+
+func (s *CartService) RemoveItem(cartKey string, listingID int64, viewerID *int64) (*dto.CartDTO, error) {
+	if err := s.cartRepo.RemoveItems(cartKey, []int64{listingID}); err != nil {
+		return nil, err
+	}
+	return s.GetCart(cartKey, viewerID)
+}
+
+// MergeAnonymousCart moves the items collected before login into the user's
+// cart. Listings already in the user's cart keep their original price.
+func (s *CartService) MergeAnonymousCart(token string, userID int64) error {
+	anonKey, err := AnonymousCartKey(token)
+	if err != nil {
+		return err
+	}
+	return s.cartRepo.Merge(anonKey, UserCartKey(userID))
+}
+
+// Checkout creates one order per seller. If one of the orders cannot be
+// created the ones created before it are cancelled, so the buyer never ends
+// up with a partially checked out cart.
+func (s *CartService) Checkout(userID int64, acceptPriceChanges bool) (*dto.CheckoutResponse, error) {
+	cartKey := UserCartKey(userID)
+	cart, err := s.GetCart(cartKey, &userID)
+	if err != nil {
+		return nil, err
+	}
+	if cart.ItemCount == 0 {
+		return nil, ErrCartEmpty
+	}
+	if cart.HasUnavailableItems {
+		return nil, ErrCartItemUnavailable
+	}
+	if cart.HasPriceChanges && !acceptPriceChanges {
+		return nil, ErrCartPriceChanged
+	}
+
+	var orders []*dto.OrderDTO
+	var checkedOut []int64
+	for _, group := range cart.Groups {
+		listingIDs := make([]int64, 0, len(group.Items))
+		for _, item := range group.Items {
+			listingIDs = append(listingIDs, item.ListingID)
+		}
+
+		order, err := s.orderService.CreateOrder(listingIDs, userID)
+		if err != nil {
+			for _, created := range orders {
+				s.orderService.CancelOrder(created.ID, userID)
+			}
+			if errors.Is(err, ErrListingUnavailable) {
+				return nil, ErrCartItemUnavailable
+			}
+			return nil, err
+		}
+		orders = append(orders, order)
+		checkedOut = append(checkedOut, listingIDs...)
+	}
+
+	if err := s.cartRepo.RemoveItems(cartKey, checkedOut); err != nil {
+		return nil, err
+	}
+
+	return &dto.CheckoutResponse{Orders: orders}, nil
+}
+
+func isPurchasable(listing *domain.Listing, viewerID *int64) bool {
+	if listing.Status != domain.ListingStatusActive || listing.Type == domain.ListingTypeAuction {
+		return false
+	}
+	return viewerID == nil || listing.AuthorID != *viewerID
+}
@@ -0,0 +1,349 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	mailer "vk/ecom/internal/pkg/mail"
+	"vk/ecom/internal/pkg/signed"
+	"vk/ecom/internal/pkg/sms"
+	"vk/ecom/internal/repository"
+)
+
+var (
+	ErrContactVerificationDisabled = errors.New("contact verification is not enabled")
+	ErrInvalidEmail                = errors.New("invalid email address")
+	ErrInvalidPhone                = errors.New("phone number must be in international format, e.g. +4915112345678")
+	ErrEmailTaken                  = errors.New("email address is already in use")
+	ErrPhoneTaken                  = errors.New("phone number is already in use")
+	ErrNoEmail                     = errors.New("no email address to verify")
+	ErrNoPhone                     = errors.New("no phone number to verify")
+	ErrAlreadyVerified             = errors.New("already verified")
+	ErrInvalidVerification         = errors.New("verification link or code is invalid or expired")
+)
+
+// purposeVerifyEmail binds signed tokens to their use.
+const purposeVerifyEmail = "verify-email"
+
+// normalizeEmail trims and lower-cases an address so that uniqueness does
+// not depend on case. "" stays empty.
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", nil
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email || len(email) > 255 {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
+}
+
+// normalizePhone returns a number in E.164 form, dropping spaces, dashes,
+// dots and parentheses and reading a leading 00 as +. "" stays empty.
+func normalizePhone(phone string) (string, error) {
+	phone = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" -.()", r) {
+			return -1
+		}
+		return r
+	}, phone)
+	if phone == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(phone, "00") {
+		phone = "+" + phone[2:]
+	}
+	digits, ok := strings.CutPrefix(phone, "+")
+	if !ok || len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", ErrInvalidPhone
+		}
+	}
+	return phone, nil
+}
+
+type ContactVerificationConfig struct {
+	// Key signs verification links; keep it secret.
+	Key      []byte
+	EmailTTL time.Duration
+	// PhoneCodeTTL is how long a texted code is accepted.
+	PhoneCodeTTL time.Duration
+	// MaxPhoneCodeAttempts is how many guesses a code allows before it is
+	// discarded and a new one must be sent.
+	MaxPhoneCodeAttempts int
+	// VerifyURL is the frontend page that takes the email token. If set,
+	// the email links to it with the token in the token query parameter.
+	VerifyURL string
+}
+
+func DefaultContactVerificationConfig(key []byte) ContactVerificationConfig {
+	return ContactVerificationConfig{
+		Key:                  key,
+		EmailTTL:             24 * time.Hour,
+		PhoneCodeTTL:         10 * time.Minute,
+		MaxPhoneCodeAttempts: 5,
+	}
+}
+
+type contactVerification struct {
+	signer     *signed.Signer
+	phoneCodes repository.PhoneCodeRepository
+	mailer     mailer.Mailer
+	sms        sms.Sender
+	config     ContactVerificationConfig
+}
+
+// WithContactVerification sends verification links for email addresses
+// and codes for phone numbers. Without it contact details can be set but
+// not verified.
+func WithContactVerification(phoneCodes repository.PhoneCodeRepository, mailer mailer.Mailer, sender sms.Sender, config ContactVerificationConfig) AuthServiceOption {
+	return func(s *AuthService) {
+		s.contact = &contactVerification{
+			signer:     signed.NewSigner(config.Key),
+			phoneCodes: phoneCodes,
+			mailer:     mailer,
+			sms:        sender,
+			config:     config,
+		}
+	}
+}
+
+func (s *AuthService) GetContactDetails(userID int) (*dto.ContactDTO, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	return dto.ToContactDTO(user), nil
+}
+
+// UpdateEmail sets a new, unverified address, "" to remove it, and sends
+// a verification link to it. Whether another account uses the address is
+// only revealed to whoever can open the link.
+func (s *AuthService) UpdateEmail(userID int, email string) error {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if user.Email == email {
+		return nil
+	}
+
+	if err := s.userRepo.SetEmail(userID, email); err != nil {
+		return err
+	}
+	if email != "" && s.contact != nil {
+		user.Email = email
+		return s.contact.sendEmailVerification(user)
+	}
+	return nil
+}
+
+// UpdatePhone sets a new, unverified number, "" to remove it, and sends a
+// verification code to it.
+func (s *AuthService) UpdatePhone(userID int, phone string) error {
+	phone, err := normalizePhone(phone)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if user.Phone == phone {
+		return nil
+	}
+
+	if err := s.userRepo.SetPhone(userID, phone); err != nil {
+		return err
+	}
+	if phone != "" && s.contact != nil {
+		user.Phone = phone
+		return s.contact.sendPhoneVerification(user)
+	}
+	return nil
+}
+
+// SendEmailVerification sends the verification link again.
+func (s *AuthService) SendEmailVerification(userID int) error {
+	if s.contact == nil {
+		return ErrContactVerificationDisabled
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	switch {
+	case user.Email == "":
+		return ErrNoEmail
+	case user.EmailVerified:
+		return ErrAlreadyVerified
+	}
+	return s.contact.sendEmailVerification(user)
+}
+
+// SendPhoneVerification sends a verification code again.
+func (s *AuthService) SendPhoneVerification(userID int) error {
+	if s.contact == nil {
+		return ErrContactVerificationDisabled
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	switch {
+	case user.Phone == "":
+		return ErrNoPhone
+	case user.PhoneVerified:
+		return ErrAlreadyVerified
+	}
+	return s.contact.sendPhoneVerification(user)
+}
+
+// VerifyEmail verifies the address a link was sent to. It needs no
+// session, as the link may be opened on another device; links for an
+// address the user has since replaced, or another account has verified,
+// are refused.
+func (s *AuthService) VerifyEmail(token string) error {
+	if s.contact == nil {
+		return ErrContactVerificationDisabled
+	}
+	payload, err := s.contact.signer.Parse(token, purposeVerifyEmail, time.Now())
+	if err != nil {
+		return ErrInvalidVerification
+	}
+	id, email, _ := strings.Cut(payload, "|")
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		return ErrInvalidVerification
+	}
+
+	if err := s.userRepo.MarkEmailVerified(userID, email); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return ErrInvalidVerification
+		}
+		if errors.Is(err, repository.ErrDuplicate) {
+			return ErrEmailTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// VerifyPhone checks a code sent to the user's current number. Every guess
+// counts against the code, which is discarded after MaxPhoneCodeAttempts.
+// A number another account has verified is refused with ErrPhoneTaken.
+func (s *AuthService) VerifyPhone(userID int, code string) error {
+	if s.contact == nil {
+		return ErrContactVerificationDisabled
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if user.Phone == "" {
+		return ErrNoPhone
+	}
+	pending, err := s.contact.phoneCodes.Attempt(userID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if pending == nil || pending.Phone != user.Phone {
+		return ErrInvalidVerification
+	}
+	if pending.Attempts > s.contact.config.MaxPhoneCodeAttempts {
+		if err := s.contact.phoneCodes.Delete(userID); err != nil {
+			log.Println("Failed to delete phone code:", err)
+		}
+		return ErrInvalidVerification
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(strings.TrimSpace(code))), []byte(pending.CodeHash)) != 1 {
+		return ErrInvalidVerification
+	}
+	if err := s.contact.phoneCodes.Delete(userID); err != nil {
+		log.Println("Failed to delete phone code:", err)
+	}
+
+	if err := s.userRepo.MarkPhoneVerified(userID, user.Phone); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return ErrInvalidVerification
+		}
+		if errors.Is(err, repository.ErrDuplicate) {
+			return ErrPhoneTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// contactPayload binds a token to the user and the exact value,
+// so it is useless once the value changes.
+func contactPayload(userID int, value string) string {
+	return strconv.Itoa(userID) + "|" + value
+}
+
+func (v *contactVerification) sendEmailVerification(user *domain.User) error {
+	token := v.signer.Token(purposeVerifyEmail, contactPayload(user.ID, user.Email), time.Now().Add(v.config.EmailTTL))
+	action := "use this code to confirm your email address:\n\n" + token
+	if v.config.VerifyURL != "" {
+		action = "open this link to confirm your email address:\n\n" + v.config.VerifyURL + "?token=" + url.QueryEscape(token)
+	}
+
+	return v.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hello %s,\n\n%s\n\nIt expires in %d hours. If you did not add this address to an account, ignore this email.\n",
+			user.Login, action, int(v.config.EmailTTL.Hours())),
+	})
+}
+
+// sendPhoneVerification texts a new random code, replacing the pending one
+// and its attempts.
+func (v *contactVerification) sendPhoneVerification(user *domain.User) error {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return fmt.Errorf("failed to generate phone code: %w", err)
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+
+	err = v.phoneCodes.Set(&domain.PhoneCode{
+		UserID:    user.ID,
+		Phone:     user.Phone,
+		CodeHash:  hashToken(code),
+		ExpiresAt: time.Now().UTC().Add(v.config.PhoneCodeTTL),
+	})
+	if err != nil {
+		return err
+	}
+	return v.sms.Send(&sms.Message{
+		To:   user.Phone,
+		Body: fmt.Sprintf("Your ecom verification code is %s", code),
+	})
+}
+
+// sendRegistrationVerification mails the link for an address given at
+// registration. Failures are only logged, as the user can ask again.
+func (s *AuthService) sendRegistrationVerification(user *domain.User) {
+	if s.contact == nil || user.Email == "" {
+		return
+	}
+	if err := s.contact.sendEmailVerification(user); err != nil {
+		log.Println("Failed to send email verification:", err)
+	}
+}
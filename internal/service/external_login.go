@@ -0,0 +1,344 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/pkg/oidc"
+	"vk/ecom/internal/repository"
+)
+
+var (
+	ErrExternalLoginUnavailable = errors.New("external login is not available")
+	ErrUnknownProvider          = errors.New("unknown login provider")
+	ErrInvalidLoginState        = errors.New("login state is invalid or expired")
+	ErrExternalLoginFailed      = errors.New("external login failed")
+	ErrIdentityLinked           = errors.New("identity is already linked")
+	ErrLastLoginMethod          = errors.New("cannot remove the only way to log in")
+	// ErrLoginProviderFailed wraps errors talking to the provider, from
+	// discovery to the token exchange.
+	ErrLoginProviderFailed = errors.New("login provider is unavailable")
+)
+
+type ExternalLoginConfig struct {
+	// StateTTL is how long the user may take at the provider.
+	StateTTL time.Duration
+}
+
+func DefaultExternalLoginConfig() ExternalLoginConfig {
+	return ExternalLoginConfig{
+		StateTTL: 10 * time.Minute,
+	}
+}
+
+type externalLogin struct {
+	providers  map[string]*oidc.Provider
+	identities repository.IdentityRepository
+	config     ExternalLoginConfig
+}
+
+// WithExternalLogin enables login through OpenID providers next to local
+// passwords.
+func WithExternalLogin(identities repository.IdentityRepository, config ExternalLoginConfig, providers ...*oidc.Provider) AuthServiceOption {
+	return func(s *AuthService) {
+		s.external = &externalLogin{
+			providers:  make(map[string]*oidc.Provider, len(providers)),
+			identities: identities,
+			config:     config,
+		}
+		for _, provider := range providers {
+			s.external.providers[provider.Name()] = provider
+		}
+	}
+}
+
+func (s *AuthService) provider(name string) (*oidc.Provider, error) {
+	if s.external == nil {
+		return nil, ErrExternalLoginUnavailable
+	}
+	provider, ok := s.external.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+// ExternalLoginProviders returns the names of the configured providers.
+func (s *AuthService) ExternalLoginProviders() []string {
+	names := []string{}
+	if s.external != nil {
+		for name := range s.external.providers {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BeginExternalLogin returns the provider URL to send the user to. With a
+// userID the identity is linked to that user instead of logging in.
+func (s *AuthService) BeginExternalLogin(providerName string, userID int) (string, error) {
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return "", err
+	}
+
+	var secrets [3]string
+	for i := range secrets {
+		if secrets[i], err = oidc.RandomString(); err != nil {
+			return "", err
+		}
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+
+	err = s.external.identities.CreateState(&domain.ExternalLoginState{
+		StateHash:    hashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		UserID:       userID,
+		ExpiresAt:    time.Now().UTC().Add(s.external.config.StateTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(state, nonce, verifier)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrLoginProviderFailed, err)
+	}
+	return authURL, nil
+}
+
+// CompleteExternalLogin handles the provider's callback. A login finds the
+// user by the linked identity, creating both on first login, and returns
+// a token, or a TwoFactorRequiredError for users with two-factor auth.
+// client is recorded with the new session.
+func (s *AuthService) CompleteExternalLogin(providerName, code, state string, client domain.ClientInfo) (*dto.ExternalLoginResult, error) {
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	loginState, err := s.external.identities.ConsumeState(hashToken(state), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if loginState == nil || loginState.Provider != providerName {
+		return nil, ErrInvalidLoginState
+	}
+
+	claims, err := provider.Exchange(code, loginState.CodeVerifier)
+	if err != nil {
+		log.Printf("External login with %s failed: %v", providerName, err)
+		return nil, fmt.Errorf("%w: %v", ErrLoginProviderFailed, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(loginState.Nonce)) != 1 {
+		log.Printf("External login with %s failed: nonce mismatch", providerName)
+		return nil, ErrExternalLoginFailed
+	}
+	// Unverified addresses are not trusted for password resets.
+	email := ""
+	if claims.EmailVerified {
+		email, _ = normalizeEmail(claims.Email)
+	}
+
+	if loginState.UserID != 0 {
+		return s.linkIdentity(loginState.UserID, providerName, claims.Subject, email)
+	}
+
+	result := &dto.ExternalLoginResult{}
+	identity, err := s.external.identities.Get(providerName, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity == nil {
+		result.User, identity, err = s.createExternalUser(providerName, claims, email)
+		if errors.Is(err, repository.ErrDuplicate) {
+			// A concurrent login stored the identity first; use its user.
+			if identity, err = s.external.identities.Get(providerName, claims.Subject); err == nil && identity == nil {
+				err = fmt.Errorf("identity %s/%s conflicts but does not exist", providerName, claims.Subject)
+			}
+		} else if err == nil {
+			result.Created = true
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if result.User == nil {
+		if result.User, err = s.userRepo.GetByID(identity.UserID); err != nil {
+			return nil, err
+		}
+	}
+	result.Identity = identity
+
+	if result.User.Banned {
+		return nil, ErrUserBanned
+	}
+	if err := s.challenge(result.User); err != nil {
+		return nil, err
+	}
+	if result.Token, result.User, err = s.issueToken(result.User, client); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *AuthService) linkIdentity(userID int, providerName, subject, email string) (*dto.ExternalLoginResult, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	identity := &domain.Identity{UserID: userID, Provider: providerName, Subject: subject, Email: email}
+	if err := s.external.identities.Create(identity); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrIdentityLinked
+		}
+		return nil, err
+	}
+	return &dto.ExternalLoginResult{User: user, Identity: identity}, nil
+}
+
+// createExternalUser creates a user without a local password for a new
+// identity. The provider's verified address counts as verified here too,
+// unless another account has already verified it. It returns ErrDuplicate
+// if the identity was stored concurrently.
+func (s *AuthService) createExternalUser(providerName string, claims *oidc.Claims, email string) (*domain.User, *domain.Identity, error) {
+	user, err := s.createExternalUserRecord(providerName, claims, email)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	identity := &domain.Identity{UserID: user.ID, Provider: providerName, Subject: claims.Subject, Email: email}
+	if err := s.external.identities.Create(identity); err != nil {
+		// Without the identity nobody can log in as the new user.
+		if deleteErr := s.userRepo.Delete(user.ID); deleteErr != nil {
+			log.Println("Failed to delete user without identity:", deleteErr)
+		}
+		return nil, nil, err
+	}
+	return user, identity, nil
+}
+
+// maxExternalUserAttempts bounds the retries of createExternalUserRecord.
+const maxExternalUserAttempts = 5
+
+// createExternalUserRecord stores the user for createExternalUser. Create
+// does not say which unique value was taken, so a duplicate is retried
+// with a new login if the login was taken in between, or otherwise
+// without the address.
+func (s *AuthService) createExternalUserRecord(providerName string, claims *oidc.Claims, email string) (*domain.User, error) {
+	login, err := s.availableLogin(providerName, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &domain.User{Login: login, Email: email, EmailVerified: email != ""}
+	for attempt := 1; ; attempt++ {
+		err := s.userRepo.Create(user)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, repository.ErrDuplicate) || attempt == maxExternalUserAttempts {
+			log.Println("Failed to create external user:", err)
+			return nil, errors.New("failed to create user")
+		}
+
+		if _, lookupErr := s.userRepo.GetByLogin(user.Login); lookupErr == nil {
+			if user.Login, err = s.availableLogin(providerName, claims); err != nil {
+				return nil, err
+			}
+		} else if user.Email != "" {
+			user = &domain.User{Login: user.Login}
+		} else {
+			log.Println("Failed to create external user:", err)
+			return nil, errors.New("failed to create user")
+		}
+	}
+}
+
+// availableLogin derives a free login from the provider's claims, adding a
+// number if needed.
+func (s *AuthService) availableLogin(providerName string, claims *oidc.Claims) (string, error) {
+	base := ""
+	for _, candidate := range []string{claims.PreferredUsername, strings.Split(claims.Email, "@")[0], claims.Name} {
+		if base = sanitizeLogin(candidate); len(base) >= 3 {
+			break
+		}
+	}
+	if len(base) < 3 {
+		base = sanitizeLogin(providerName + "_user")
+	}
+
+	for i := 1; i <= 100; i++ {
+		login := base
+		if i > 1 {
+			suffix := strconv.Itoa(i)
+			login = base[:min(len(base), 20-len(suffix))] + suffix
+		}
+		if _, err := s.userRepo.GetByLogin(login); err != nil {
+			return login, nil
+		}
+	}
+	return "", fmt.Errorf("no free login for %q", base)
+}
+
+func sanitizeLogin(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if b.Len() >= 16 {
+			break
+		}
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		case r == '_' || r == '-' || r == '.' || r == ' ':
+			b.WriteByte('_')
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+func (s *AuthService) GetIdentities(userID int) ([]*domain.Identity, error) {
+	if s.external == nil {
+		return nil, ErrExternalLoginUnavailable
+	}
+	identities, err := s.external.identities.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if identities == nil {
+		identities = []*domain.Identity{}
+	}
+	return identities, nil
+}
+
+// UnlinkIdentity removes an identity unless it is the only way the user
+// can log in.
+func (s *AuthService) UnlinkIdentity(userID int, providerName string) error {
+	if s.external == nil {
+		return ErrExternalLoginUnavailable
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	identities, err := s.external.identities.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+	if user.Password == "" && len(identities) <= 1 {
+		return ErrLastLoginMethod
+	}
+	return s.external.identities.Delete(userID, providerName)
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/fingerprint"
+	"vk/ecom/internal/pkg/screening"
+	"vk/ecom/internal/repository"
+)
+
+// ListingScreener runs the content screening pipeline for listing services
+// and files flagged listings into the moderation queue. It also fingerprints
+// listing images when an image hasher is configured. A nil screener lets
+// everything through.
+type ListingScreener struct {
+	pipeline       *screening.Pipeline
+	moderationRepo repository.ModerationRepository
+	imageHasher    fingerprint.ImageHasher
+}
+
+// NewListingScreener builds the standard pipeline plus the cross-seller
+// near-duplicate rule. imageHasher may be nil.
+func NewListingScreener(config screening.Config, listingRepo repository.ListingRepository, moderationRepo repository.ModerationRepository, imageHasher fingerprint.ImageHasher) (*ListingScreener, error) {
+	recent := func(authorID int64, since time.Time) ([]*screening.Content, error) {
+		// GetByAuthorID reports an error when the author has no listings.
+		listings, _ := listingRepo.GetByAuthorID(authorID)
+		var result []*screening.Content
+		for _, listing := range listings {
+			if listing.CreatedAt.Before(since) {
+				continue
+			}
+			result = append(result, &screening.Content{
+				ListingID:   listing.ID,
+				AuthorID:    listing.AuthorID,
+				Title:       listing.Title,
+				Description: listing.Description,
+			})
+		}
+		return result, nil
+	}
+
+	pipeline, err := screening.New(config, recent)
+	if err != nil {
+		return nil, err
+	}
+
+	maxDistance := min(config.NearDuplicateMaxDistance, fingerprint.MaxBandDistance)
+	nearDuplicates := func(content *screening.Content) ([]string, error) {
+		target := &domain.Listing{
+			ID:        content.ListingID,
+			TextHash:  fingerprint.Text(content.Title, content.Description),
+			ImageHash: content.ImageHash,
+		}
+		duplicates, err := listingRepo.FindNearDuplicates(target, maxDistance)
+		if err != nil {
+			return nil, err
+		}
+		var matches []string
+		for _, duplicate := range duplicates {
+			matches = append(matches, fmt.Sprintf("listing %d", duplicate.ListingID))
+		}
+		return matches, nil
+	}
+	pipeline.Add(screening.NewRule(screening.RuleNearDuplicate, nearDuplicates), config.Actions[screening.RuleNearDuplicate])
+
+	return &ListingScreener{
+		pipeline:       pipeline,
+		moderationRepo: moderationRepo,
+		imageHasher:    imageHasher,
+	}, nil
+}
+
+// Screen returns a *screening.RejectedError if the listing must not be
+// published. A listing with an image but no image hash gets one computed;
+// images that cannot be fetched or decoded are screened by text only.
+func (s *ListingScreener) Screen(listing *domain.Listing) (*screening.Result, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	if s.imageHasher != nil && listing.ImageURL != "" && listing.ImageHash == nil {
+		if hash, err := s.imageHasher.Hash(listing.ImageURL); err == nil {
+			listing.ImageHash = &hash
+		}
+	}
+
+	result, err := s.pipeline.Screen(&screening.Content{
+		ListingID:   listing.ID,
+		AuthorID:    listing.AuthorID,
+		Title:       listing.Title,
+		Description: listing.Description,
+		ImageHash:   listing.ImageHash,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Rejected() {
+		return nil, &screening.RejectedError{Findings: result.Findings}
+	}
+	return result, nil
+}
+
+// Flag reports a published listing to the moderation queue on behalf of the
+// screening pipeline. A listing that is already queued is left as is. The
+// listing is saved by then, so a failure is only logged.
+func (s *ListingScreener) Flag(listingID int64, result *screening.Result) {
+	if s == nil || !result.Flagged() {
+		return
+	}
+
+	_, err := s.moderationRepo.AddReport(&domain.Report{
+		ListingID:  listingID,
+		ReporterID: domain.SystemReporterID,
+		Reason:     domain.ReportReasonAutomated,
+		Comment:    result.Summary(),
+	})
+	if err != nil && !errors.Is(err, repository.ErrDuplicate) {
+		log.Println("Failed to flag listing for moderation:", err)
+	}
+}
@@ -3,78 +3,179 @@ package service
 import (
 	"errors"
 	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
 	"vk/ecom/internal/domain"
 	"vk/ecom/internal/dto"
 	"vk/ecom/internal/interfaces"
+	"vk/ecom/internal/pkg/geo"
+	"vk/ecom/internal/pkg/money"
+	"vk/ecom/internal/pkg/notify"
 	"vk/ecom/internal/repository"
 )
 
+var categoryPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+var (
+	ErrListingNotFound    = errors.New("listing not found")
+	ErrListingNotEditable = errors.New("only active fixed-price listings can be edited")
+	ErrEmailNotVerified   = errors.New("a verified email address is required to create listings")
+
+	ErrInvalidLocation             = errors.New("near must be a valid latitude,longitude pair")
+	ErrDistanceSortWithoutLocation = errors.New("sort=distance requires near")
+	ErrInvalidAgeFilter            = errors.New("age must be today, week or month")
+	ErrInvalidFacet                = errors.New("facets must be category, price or age")
+	ErrInvalidPriceBuckets         = errors.New("price buckets must be at most 20 ascending non-negative prices")
+	ErrCurrencyChange              = errors.New("listing currency cannot be changed")
+)
+
+// listingAges are the age filters and facet buckets, newest first.
+var listingAges = []struct {
+	name string
+	age  time.Duration
+}{
+	{"today", 24 * time.Hour},
+	{"week", 7 * 24 * time.Hour},
+	{"month", 30 * 24 * time.Hour},
+}
+
+// defaultPriceBounds are the lower bounds of the default price buckets.
+var defaultPriceBounds = []int64{0, 1000, 5000, 10000, 50000, 100000}
+
+const maxPriceBuckets = 20
+
 type ListingService struct {
 	listingRepo repository.ListingRepository
 	userRepo    repository.UserRepository
+	reviewRepo  repository.ReviewRepository
+	screener    *ListingScreener
+	attributes  *AttributeCatalog
+	// similarCache is optional, see WithSimilarListingsCache.
+	similarCache *SimilarListingsCache
+	// rates is optional, see WithExchangeRates.
+	rates money.RateProvider
+	// favorites and notifier are optional, see WithFavorites.
+	favorites repository.FavoriteRepository
+	notifier  notify.Notifier
+	// requireVerifiedEmail is set by WithVerifiedEmailRequired.
+	requireVerifiedEmail bool
 }
 
 // Ensure ListingService implements ListingServiceInterface
 var _ interfaces.ListingServiceInterface = (*ListingService)(nil)
 
-func NewListingService(listingRepo repository.ListingRepository, userRepo repository.UserRepository) *ListingService {
-	return &ListingService{
-		listingRepo: listingRepo,
-		userRepo:    userRepo,
+// ListingServiceOption enables optional collaborators of ListingService.
+type ListingServiceOption func(*ListingService)
+
+// WithSellerRatings makes listing responses include the author's rating.
+func WithSellerRatings(reviewRepo repository.ReviewRepository) ListingServiceOption {
+	return func(s *ListingService) {
+		s.reviewRepo = reviewRepo
 	}
 }
 
-func (s *ListingService) CreateListing(req *dto.ListingRequest, authorID int64) (*dto.ListingDTO, error) {
-	const (
-		minTitleLen       = 3
-		maxTitleLen       = 100
-		minDescLen        = 10
-		maxDescLen        = 2000
-		minPrice    int64 = 1
-		maxPrice    int64 = 1_000_000_000
-	)
-	allowedImageFormats := map[string]bool{
-		".jpg":  true,
-		".jpeg": true,
-		".png":  true,
-		".webp": true,
+// WithScreener screens listings on create and update.
+func WithScreener(screener *ListingScreener) ListingServiceOption {
+	return func(s *ListingService) {
+		s.screener = screener
 	}
+}
 
-	if len(req.Title) < minTitleLen || len(req.Title) > maxTitleLen {
-		errString := "title must be between %d and %d characters"
-		return nil, fmt.Errorf(errString, minTitleLen, maxTitleLen)
+// WithAttributeCatalog validates listing attributes against the category
+// schemas of catalog. Without it listings cannot have attributes.
+func WithAttributeCatalog(catalog *AttributeCatalog) ListingServiceOption {
+	return func(s *ListingService) {
+		s.attributes = catalog
 	}
-	if len(req.Description) < minDescLen || len(req.Description) > maxDescLen {
-		errString := "description must be between %d and %d characters"
-		return nil, fmt.Errorf(errString, minDescLen, maxDescLen)
+}
+
+// WithExchangeRates lets searches filter and sort by price across listing
+// currencies. Without it only listings in the display currency have a
+// comparable price.
+func WithExchangeRates(rates money.RateProvider) ListingServiceOption {
+	return func(s *ListingService) {
+		s.rates = rates
 	}
-	if req.Price < minPrice || req.Price > maxPrice {
-		errString := "price must be between %d and %d"
-		return nil, fmt.Errorf(errString, minPrice, maxPrice)
+}
+
+// WithVerifiedEmailRequired only lets users with a verified email address
+// create listings.
+func WithVerifiedEmailRequired() ListingServiceOption {
+	return func(s *ListingService) {
+		s.requireVerifiedEmail = true
 	}
-	if req.ImageURL != "" {
-		ext := ""
-		if dot := len(req.ImageURL) - 4; dot >= 0 {
-			ext = req.ImageURL[dot:]
-		}
-		if !allowedImageFormats[ext] {
-			return nil, errors.New("unsupported image format")
+}
+
+// checkVerifiedEmail returns ErrEmailNotVerified unless the author has a
+// verified email address.
+func checkVerifiedEmail(userRepo repository.UserRepository, authorID int64) error {
+	user, err := userRepo.GetByID(int(authorID))
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if !user.EmailVerified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+func NewListingService(listingRepo repository.ListingRepository, userRepo repository.UserRepository, opts ...ListingServiceOption) *ListingService {
+	s := &ListingService{
+		listingRepo: listingRepo,
+		userRepo:    userRepo,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *ListingService) CreateListing(req *dto.ListingRequest, authorID int64) (*dto.ListingDTO, error) {
+	if req.Type != "" && req.Type != domain.ListingTypeFixed {
+		return nil, errors.New("unsupported listing type")
+	}
+	if s.requireVerifiedEmail {
+		if err := checkVerifiedEmail(s.userRepo, authorID); err != nil {
+			return nil, err
 		}
 	}
+	if err := validateListingRequest(req); err != nil {
+		return nil, err
+	}
+	attributes, err := s.attributes.Validate(req.Category, req.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	currency, _ := requestCurrency(req.Currency, domain.DefaultCurrency)
 
 	listing := &domain.Listing{
 		Title:       req.Title,
 		Description: req.Description,
 		ImageURL:    req.ImageURL,
 		Price:       req.Price,
+		Currency:    currency,
+		Category:    req.Category,
+		Latitude:    req.Latitude,
+		Longitude:   req.Longitude,
+		City:        strings.TrimSpace(req.City),
+		Region:      strings.TrimSpace(req.Region),
+		Attributes:  attributes,
 		AuthorID:    authorID,
+		Type:        domain.ListingTypeFixed,
 	}
 
-	err := s.listingRepo.Create(listing)
+	screened, err := s.screener.Screen(listing)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.listingRepo.Create(listing); err != nil {
+		return nil, err
+	}
+	s.screener.Flag(listing.ID, screened)
+
 	author, err := s.userRepo.GetByID(int(authorID))
 	if err != nil {
 		return dto.ToListingDTOWithAuthor(listing, "", &authorID), nil
@@ -83,6 +184,102 @@ func (s *ListingService) CreateListing(req *dto.ListingRequest, authorID int64)
 	return dto.ToListingDTOWithAuthor(listing, author.Login, &authorID), nil
 }
 
+// UpdateListing lets the author edit an active fixed-price listing. The
+// edited listing goes through content screening again.
+func (s *ListingService) UpdateListing(listingID, authorID int64, req *dto.ListingRequest) (*dto.ListingDTO, error) {
+	if err := validateListingRequest(req); err != nil {
+		return nil, err
+	}
+	attributes, err := s.attributes.Validate(req.Category, req.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, ErrListingNotFound
+	}
+	if current.AuthorID != authorID {
+		return nil, ErrNotListingOwner
+	}
+	if current.Type != domain.ListingTypeFixed || current.Status != domain.ListingStatusActive {
+		return nil, ErrListingNotEditable
+	}
+	if currency, _ := requestCurrency(req.Currency, current.Currency); currency != current.Currency {
+		return nil, ErrCurrencyChange
+	}
+
+	listing := *current
+	oldPrice := current.Price
+	if req.ImageURL != current.ImageURL {
+		listing.ImageHash = nil
+	}
+	listing.Title = req.Title
+	listing.Description = req.Description
+	listing.ImageURL = req.ImageURL
+	listing.SetPrice(req.Price, time.Now())
+	listing.Category = req.Category
+	listing.Latitude = req.Latitude
+	listing.Longitude = req.Longitude
+	listing.City = strings.TrimSpace(req.City)
+	listing.Region = strings.TrimSpace(req.Region)
+	listing.Attributes = attributes
+
+	screened, err := s.screener.Screen(&listing)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.listingRepo.Update(&listing); err != nil {
+		return nil, err
+	}
+	s.screener.Flag(listing.ID, screened)
+	if listing.Price < oldPrice {
+		s.notifyPriceDrop(&listing, oldPrice)
+	}
+
+	author, err := s.userRepo.GetByID(int(authorID))
+	if err != nil {
+		return dto.ToListingDTOWithAuthor(&listing, "", &authorID), nil
+	}
+
+	return dto.ToListingDTOWithAuthor(&listing, author.Login, &authorID), nil
+}
+
+// GetCategorySchema returns the attribute schema of a category.
+func (s *ListingService) GetCategorySchema(category string) (*domain.CategorySchema, error) {
+	schema, ok := s.attributes.Schema(category)
+	if !ok {
+		return nil, ErrCategoryNotFound
+	}
+	return schema, nil
+}
+
+// GetListing returns a single listing. Hidden listings are only visible to
+// their author.
+func (s *ListingService) GetListing(listingID int64, currency string, currentUserID *int64) (*dto.ListingDTO, error) {
+	displayCurrency, err := requestCurrency(currency, "")
+	if err != nil {
+		return nil, err
+	}
+	listing, err := s.visibleListing(listingID, currentUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *dto.ListingDTO
+	author, err := s.userRepo.GetByID(int(listing.AuthorID))
+	if err != nil {
+		result = dto.ToListingDTOWithAuthor(listing, "", currentUserID)
+	} else {
+		result = dto.ToListingDTOWithAuthor(listing, author.Login, currentUserID)
+	}
+	s.attachAuthorRatings([]*dto.ListingDTO{result})
+	s.attachDisplayPrices([]*dto.ListingDTO{result}, displayCurrency)
+
+	return result, nil
+}
+
 func (s *ListingService) GetListings(sortBy, sortOrder string, minPrice, maxPrice *int64, currentUserID *int64) ([]*dto.ListingDTO, error) {
 	listings, err := s.listingRepo.GetAll(sortBy, sortOrder, minPrice, maxPrice)
 	if err != nil {
@@ -98,11 +295,199 @@ func (s *ListingService) GetListings(sortBy, sortOrder string, minPrice, maxPric
 			result = append(result, dto.ToListingDTOWithAuthor(listing, author.Login, currentUserID))
 		}
 	}
+	s.attachAuthorRatings(result)
 
 	return result, nil
 }
 
 func (s *ListingService) GetListingsWithPagination(sortBy, sortOrder string, minPrice, maxPrice *int64, page, pageSize int, currentUserID *int64) (*dto.ListingsResponse, error) {
+	page, pageSize = normalizeFeedPage(page, pageSize)
+
+	if sortBy != "price" && sortBy != "date" && sortBy != "popular" {
+		sortBy = "date"
+	}
+	if sortOrder != "asc" && sortOrder != "desc" {
+		sortOrder = "desc"
+	}
+
+	listings, totalCount, err := s.listingRepo.GetAllWithPagination(sortBy, sortOrder, minPrice, maxPrice, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.listingsResponse(listings, totalCount, page, pageSize, currentUserID), nil
+}
+
+// SearchListings is GetListingsWithPagination with optional location
+// filtering. With Near set only listings within RadiusKm of it are
+// returned, each with its distance, and they can be sorted by distance.
+func (s *ListingService) SearchListings(req *dto.ListingSearchRequest, currentUserID *int64) (*dto.ListingsResponse, error) {
+	const (
+		defaultRadiusKm = 25
+		maxRadiusKm     = 500
+	)
+
+	now := time.Now()
+	query := &domain.ListingQuery{
+		SortBy:    req.SortBy,
+		SortOrder: req.SortOrder,
+		Category:  strings.TrimSpace(req.Category),
+		MinPrice:  req.MinPrice,
+		MaxPrice:  req.MaxPrice,
+	}
+	query.Page, query.PageSize = normalizeFeedPage(req.Page, req.PageSize)
+	if req.PriceDropped {
+		query.PriceDroppedSince = priceDroppedSince(now)
+	}
+
+	if req.Age != "" {
+		for _, age := range listingAges {
+			if age.name == req.Age {
+				since := now.Add(-age.age)
+				query.CreatedAfter = &since
+			}
+		}
+		if query.CreatedAfter == nil {
+			return nil, ErrInvalidAgeFilter
+		}
+	}
+	attributeFilters, err := s.attributes.ParseFilters(query.Category, req.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	query.Attributes = attributeFilters
+	displayCurrency, err := requestCurrency(req.Currency, domain.DefaultCurrency)
+	if err != nil {
+		return nil, err
+	}
+	query.PriceRates = s.priceRates(displayCurrency)
+	facets, err := facetQuery(req.Facets, req.PriceBuckets, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Near != nil {
+		if !req.Near.Valid() {
+			return nil, ErrInvalidLocation
+		}
+		near := *req.Near
+		query.Near = &near
+		query.RadiusKm = req.RadiusKm
+		if query.RadiusKm <= 0 {
+			query.RadiusKm = defaultRadiusKm
+		}
+		query.RadiusKm = min(query.RadiusKm, maxRadiusKm)
+	}
+
+	switch query.SortBy {
+	case "price", "date", "popular":
+	case "distance":
+		if query.Near == nil {
+			return nil, ErrDistanceSortWithoutLocation
+		}
+	default:
+		query.SortBy = "date"
+	}
+	if query.SortOrder != "asc" && query.SortOrder != "desc" {
+		query.SortOrder = "desc"
+		if query.SortBy == "distance" {
+			query.SortOrder = "asc"
+		}
+	}
+
+	listings, totalCount, err := s.listingRepo.Search(query)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.listingsResponse(listings, totalCount, query.Page, query.PageSize, currentUserID)
+	if req.Currency != "" {
+		s.attachDisplayPrices(response.Listings, displayCurrency)
+	}
+	if facets != nil {
+		counts, err := s.listingRepo.Facets(query, facets)
+		if err != nil {
+			return nil, err
+		}
+		response.Facets = dto.ToListingFacetsDTO(counts)
+	}
+	return response, nil
+}
+
+// priceRates returns the factors converting each currency's minor units
+// into those of the display currency, for the currencies with a rate.
+func (s *ListingService) priceRates(displayCurrency string) map[string]float64 {
+	rates := map[string]float64{displayCurrency: 1}
+	if s.rates == nil {
+		return rates
+	}
+	for _, currency := range money.Currencies() {
+		if factor, err := money.Factor(s.rates, currency, displayCurrency); err == nil {
+			rates[currency] = factor
+		}
+	}
+	return rates
+}
+
+// attachDisplayPrices fills DisplayPrice for the listings whose currency can
+// be converted into the display currency. An empty display currency leaves
+// the listings as they are.
+func (s *ListingService) attachDisplayPrices(listings []*dto.ListingDTO, displayCurrency string) {
+	if displayCurrency == "" {
+		return
+	}
+	rates := s.priceRates(displayCurrency)
+	for _, listing := range listings {
+		if factor, ok := rates[listing.Currency]; ok {
+			converted := money.New(int64(math.Round(float64(listing.Price)*factor)), displayCurrency)
+			listing.DisplayPrice = &converted
+		}
+	}
+}
+
+// facetQuery validates the requested facet names and price bucket bounds.
+// It returns nil when no facets are requested.
+func facetQuery(names []string, priceBounds []int64, now time.Time) (*domain.FacetQuery, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	facets := &domain.FacetQuery{}
+	for _, name := range names {
+		switch name {
+		case "category":
+			facets.Categories = true
+		case "price":
+			facets.PriceBounds = defaultPriceBounds
+		case "age":
+			facets.AgeBuckets = nil
+			for _, age := range listingAges {
+				facets.AgeBuckets = append(facets.AgeBuckets, domain.AgeBucket{Name: age.name, Since: now.Add(-age.age)})
+			}
+		default:
+			return nil, ErrInvalidFacet
+		}
+	}
+
+	if len(priceBounds) > 0 && facets.PriceBounds != nil {
+		if len(priceBounds) > maxPriceBuckets || priceBounds[0] < 0 {
+			return nil, ErrInvalidPriceBuckets
+		}
+		for i := 1; i < len(priceBounds); i++ {
+			if priceBounds[i] <= priceBounds[i-1] {
+				return nil, ErrInvalidPriceBuckets
+			}
+		}
+		// The first bucket always starts at zero so every price is counted.
+		facets.PriceBounds = priceBounds
+		if priceBounds[0] > 0 {
+			facets.PriceBounds = append([]int64{0}, priceBounds...)
+		}
+	}
+	return facets, nil
+}
+
+func normalizeFeedPage(page, pageSize int) (int, int) {
 	const (
 		defaultPageSize = 10
 		maxPageSize     = 100
@@ -118,19 +503,10 @@ func (s *ListingService) GetListingsWithPagination(sortBy, sortOrder string, min
 	if pageSize > maxPageSize {
 		pageSize = maxPageSize
 	}
+	return page, pageSize
+}
 
-	if sortBy != "price" && sortBy != "date" {
-		sortBy = "date"
-	}
-	if sortOrder != "asc" && sortOrder != "desc" {
-		sortOrder = "desc"
-	}
-
-	listings, totalCount, err := s.listingRepo.GetAllWithPagination(sortBy, sortOrder, minPrice, maxPrice, page, pageSize)
-	if err != nil {
-		return nil, err
-	}
-
+func (s *ListingService) listingsResponse(listings []*domain.Listing, totalCount, page, pageSize int, currentUserID *int64) *dto.ListingsResponse {
 	var result []*dto.ListingDTO
 	for _, listing := range listings {
 		author, err := s.userRepo.GetByID(int(listing.AuthorID))
@@ -140,6 +516,7 @@ func (s *ListingService) GetListingsWithPagination(sortBy, sortOrder string, min
 			result = append(result, dto.ToListingDTOWithAuthor(listing, author.Login, currentUserID))
 		}
 	}
+	s.attachAuthorRatings(result)
 
 	totalPages := (totalCount + pageSize - 1) / pageSize
 	if totalPages == 0 {
@@ -153,5 +530,140 @@ func (s *ListingService) GetListingsWithPagination(sortBy, sortOrder string, min
 		Page:       page,
 		PageSize:   pageSize,
 		TotalPages: totalPages,
-	}, nil
+	}
+}
+
+// GetTrendingListings returns the most popular active listings. Listings
+// without recent engagement are left out.
+func (s *ListingService) GetTrendingListings(limit int, currency string, currentUserID *int64) ([]*dto.ListingDTO, error) {
+	const (
+		defaultTrendingLimit = 20
+		maxTrendingLimit     = 50
+	)
+	displayCurrency, err := requestCurrency(currency, "")
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 {
+		limit = defaultTrendingLimit
+	}
+	limit = min(limit, maxTrendingLimit)
+
+	listings, _, err := s.listingRepo.GetAllWithPagination("popular", "desc", nil, nil, 1, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*dto.ListingDTO{}
+	for _, listing := range listings {
+		if listing.Popularity <= 0 {
+			break
+		}
+		author, err := s.userRepo.GetByID(int(listing.AuthorID))
+		if err != nil {
+			result = append(result, dto.ToListingDTOWithAuthor(listing, "", currentUserID))
+		} else {
+			result = append(result, dto.ToListingDTOWithAuthor(listing, author.Login, currentUserID))
+		}
+	}
+	s.attachAuthorRatings(result)
+	s.attachDisplayPrices(result, displayCurrency)
+
+	return result, nil
+}
+
+// attachAuthorRatings fills AuthorRating for a page of listings with a single
+// repository call.
+func (s *ListingService) attachAuthorRatings(listings []*dto.ListingDTO) {
+	if s.reviewRepo == nil || len(listings) == 0 {
+		return
+	}
+
+	seen := make(map[int64]bool)
+	authorIDs := make([]int64, 0, len(listings))
+	for _, listing := range listings {
+		if !seen[listing.AuthorID] {
+			seen[listing.AuthorID] = true
+			authorIDs = append(authorIDs, listing.AuthorID)
+		}
+	}
+
+	ratings, err := s.reviewRepo.GetSellerRatings(authorIDs)
+	if err != nil {
+		return
+	}
+	for _, listing := range listings {
+		listing.AuthorRating = dto.RoundedRating(ratings[listing.AuthorID])
+	}
+}
+
+// requestCurrency normalizes the currency of a listing request; an empty
+// currency means fallback.
+func requestCurrency(currency, fallback string) (string, error) {
+	if strings.TrimSpace(currency) == "" {
+		return fallback, nil
+	}
+	return money.Normalize(currency)
+}
+
+func validateListingRequest(req *dto.ListingRequest) error {
+	const (
+		minTitleLen       = 3
+		maxTitleLen       = 100
+		minDescLen        = 10
+		maxDescLen        = 2000
+		minPrice    int64 = 1
+		// The upper bound is in major units, so it is the same in
+		// every currency.
+		maxPriceUnits int64 = 10_000_000
+		maxPlaceLen         = 100
+	)
+	allowedImageFormats := map[string]bool{
+		".jpg":  true,
+		".jpeg": true,
+		".png":  true,
+		".webp": true,
+	}
+
+	if len(req.Title) < minTitleLen || len(req.Title) > maxTitleLen {
+		errString := "title must be between %d and %d characters"
+		return fmt.Errorf(errString, minTitleLen, maxTitleLen)
+	}
+	if len(req.Description) < minDescLen || len(req.Description) > maxDescLen {
+		errString := "description must be between %d and %d characters"
+		return fmt.Errorf(errString, minDescLen, maxDescLen)
+	}
+	currency, err := requestCurrency(req.Currency, domain.DefaultCurrency)
+	if err != nil {
+		return err
+	}
+	exp, _ := money.Exponent(currency)
+	maxPrice := maxPriceUnits * int64(math.Pow10(exp))
+	if req.Price < minPrice || req.Price > maxPrice {
+		errString := "price must be between %d and %d minor units of %s"
+		return fmt.Errorf(errString, minPrice, maxPrice, currency)
+	}
+	if req.Category != "" && !categoryPattern.MatchString(req.Category) {
+		return errors.New("category must be a lowercase slug of up to 50 characters")
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return errors.New("latitude and longitude must be set together")
+	}
+	if req.Latitude != nil && !(geo.Point{Lat: *req.Latitude, Lng: *req.Longitude}).Valid() {
+		return errors.New("latitude must be between -90 and 90 and longitude between -180 and 180")
+	}
+	if len(strings.TrimSpace(req.City)) > maxPlaceLen || len(strings.TrimSpace(req.Region)) > maxPlaceLen {
+		return fmt.Errorf("city and region must be at most %d characters", maxPlaceLen)
+	}
+	if req.ImageURL != "" {
+		ext := ""
+		if dot := len(req.ImageURL) - 4; dot >= 0 {
+			ext = req.ImageURL[dot:]
+		}
+		if !allowedImageFormats[ext] {
+			return errors.New("unsupported image format")
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository"
+)
+
+const (
+	lockoutKindLogin = "login"
+	lockoutKindIP    = "ip"
+
+	maxLoginKeyLen = 64
+)
+
+var (
+	ErrTooManyLoginAttempts  = errors.New("too many login attempts")
+	ErrLoginThrottleDisabled = errors.New("login throttling is not enabled")
+	ErrInvalidLockoutKind    = errors.New("lockout kind must be login or ip")
+)
+
+// LoginThrottledError is returned while a login is delayed or locked out.
+// It matches ErrTooManyLoginAttempts.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return ErrTooManyLoginAttempts.Error()
+}
+
+func (e *LoginThrottledError) Is(target error) bool {
+	return target == ErrTooManyLoginAttempts
+}
+
+type LoginThrottleConfig struct {
+	// Window is how long a failure counts towards delays and lockouts.
+	Window time.Duration
+	// After FreeAttempts failures each attempt has to wait BaseDelay,
+	// doubling with every further failure up to MaxDelay.
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	// LockoutThreshold failures lock the account for LockoutDuration.
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// IPThreshold failures from one address, over any accounts, block
+	// the address for IPLockoutDuration.
+	IPThreshold       int
+	IPLockoutDuration time.Duration
+}
+
+func DefaultLoginThrottleConfig() LoginThrottleConfig {
+	return LoginThrottleConfig{
+		Window:            time.Hour,
+		FreeAttempts:      3,
+		BaseDelay:         time.Second,
+		MaxDelay:          30 * time.Second,
+		LockoutThreshold:  10,
+		LockoutDuration:   15 * time.Minute,
+		IPThreshold:       100,
+		IPLockoutDuration: 15 * time.Minute,
+	}
+}
+
+// LoginThrottle delays and locks out logins after failed attempts, per
+// account and per client address. Accounts are keyed by the submitted
+// login whether or not it exists, so throttling does not reveal which
+// logins are registered.
+type LoginThrottle struct {
+	attempts repository.LoginAttemptRepository
+	config   LoginThrottleConfig
+}
+
+func NewLoginThrottle(attempts repository.LoginAttemptRepository, config LoginThrottleConfig) *LoginThrottle {
+	return &LoginThrottle{attempts: attempts, config: config}
+}
+
+func loginKey(login string) string {
+	login = strings.ToLower(strings.TrimSpace(login))
+	if len(login) > maxLoginKeyLen {
+		login = login[:maxLoginKeyLen]
+	}
+	return lockoutKindLogin + ":" + login
+}
+
+func ipKey(clientIP string) string {
+	return lockoutKindIP + ":" + clientIP
+}
+
+// Acquire admits a login attempt unless the login or the address is
+// delayed or locked out, and counts it as a failure in the same atomic
+// step, so that concurrent attempts cannot all pass before any of them
+// fails. Attempts that do not fail are taken back with Release. An empty
+// clientIP skips the address.
+func (t *LoginThrottle) Acquire(login, clientIP string, now time.Time) error {
+	if err := t.acquire(loginKey(login), t.config.LockoutThreshold, t.config.LockoutDuration, true, now); err != nil {
+		return err
+	}
+	if clientIP == "" {
+		return nil
+	}
+	err := t.acquire(ipKey(clientIP), t.config.IPThreshold, t.config.IPLockoutDuration, false, now)
+	if err != nil {
+		if err := t.release(loginKey(login)); err != nil {
+			log.Println("Failed to release login attempt:", err)
+		}
+	}
+	return err
+}
+
+// acquire locks the key once it has reached threshold failures within the
+// window, and otherwise counts the attempt unless it has to wait.
+func (t *LoginThrottle) acquire(key string, threshold int, lockout time.Duration, progressive bool, now time.Time) error {
+	var wait time.Duration
+	err := t.attempts.Update(key, func(attempts *domain.LoginAttempts) bool {
+		if attempts.LockedUntil != nil && attempts.LockedUntil.After(now) {
+			wait = attempts.LockedUntil.Sub(now)
+			return false
+		}
+		if now.Sub(attempts.LastFailureAt) > t.config.Window {
+			attempts.Failures = 0
+		}
+		// An expired lockout lets one more attempt through before the key
+		// is locked again.
+		served := attempts.LockedUntil != nil && attempts.LockedUntil.After(attempts.LastFailureAt)
+		if attempts.Failures >= threshold && !served {
+			log.Printf("Locking %s after %d failed logins", key, attempts.Failures)
+			until := now.Add(lockout)
+			attempts.LockedUntil = &until
+			wait = lockout
+			return true
+		}
+		if progressive {
+			wait = attempts.LastFailureAt.Add(t.delay(attempts.Failures)).Sub(now)
+			if wait > 0 {
+				return false
+			}
+		}
+		attempts.Failures++
+		attempts.LastFailureAt = now
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &LoginThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// delay is the wait after the given number of failures.
+func (t *LoginThrottle) delay(failures int) time.Duration {
+	extra := failures - t.config.FreeAttempts
+	if extra < 0 {
+		return 0
+	}
+	delay := float64(t.config.BaseDelay) * math.Pow(2, float64(extra))
+	return time.Duration(min(delay, float64(t.config.MaxDelay)))
+}
+
+// Release takes back an attempt admitted by Acquire that did not fail.
+func (t *LoginThrottle) Release(login, clientIP string) error {
+	if err := t.release(loginKey(login)); err != nil {
+		return err
+	}
+	if clientIP == "" {
+		return nil
+	}
+	return t.release(ipKey(clientIP))
+}
+
+func (t *LoginThrottle) release(key string) error {
+	return t.attempts.Update(key, func(attempts *domain.LoginAttempts) bool {
+		if attempts.Failures == 0 {
+			return false
+		}
+		attempts.Failures--
+		return true
+	})
+}
+
+// RecordSuccess resets the account's failures. Address failures are kept
+// so that logging into one's own account does not reset a spraying run.
+func (t *LoginThrottle) RecordSuccess(login string) error {
+	return t.attempts.Clear(loginKey(login))
+}
+
+// Lockouts returns the accounts and addresses locked at now.
+func (t *LoginThrottle) Lockouts(now time.Time) ([]*domain.LoginAttempts, error) {
+	locked, err := t.attempts.GetLocked(now)
+	if err != nil {
+		return nil, err
+	}
+	if locked == nil {
+		locked = []*domain.LoginAttempts{}
+	}
+	return locked, nil
+}
+
+// Clear lifts the lockout and forgets the failures of a login or address.
+func (t *LoginThrottle) Clear(kind, value string) error {
+	switch kind {
+	case lockoutKindLogin:
+		return t.attempts.Clear(loginKey(value))
+	case lockoutKindIP:
+		return t.attempts.Clear(ipKey(value))
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidLockoutKind, kind)
+	}
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/interfaces"
+	"vk/ecom/internal/pkg/fingerprint"
+	"vk/ecom/internal/repository"
+)
+
+const (
+	maxReportCommentLen = 1000
+	moderationQueueSize = 100
+)
+
+var (
+	ErrInvalidReportReason = errors.New("invalid report reason")
+	ErrReportTooLong       = errors.New("comment must be at most 1000 characters")
+	ErrOwnListingReport    = errors.New("cannot report your own listing")
+	ErrAlreadyReported     = errors.New("you have already reported this listing")
+	ErrReportRateLimited   = errors.New("too many reports, try again later")
+	ErrCaseNotFound        = errors.New("moderation case not found")
+	ErrCaseClaimed         = errors.New("moderation case is claimed by another moderator")
+	ErrCaseNotClaimed      = errors.New("claim the moderation case before resolving it")
+	ErrCaseResolved        = errors.New("moderation case is already resolved")
+	ErrInvalidResolution   = errors.New("invalid resolution")
+	ErrInvalidCaseStatus   = errors.New("invalid moderation case status")
+)
+
+type ModerationConfig struct {
+	// A listing whose open case collects ReportThreshold reports is hidden
+	// from the feed until a moderator looks at it.
+	ReportThreshold int
+	// Each user may file at most MaxReports reports per ReportWindow.
+	MaxReports   int
+	ReportWindow time.Duration
+	// A claim older than ClaimTimeout can be taken over by another moderator.
+	ClaimTimeout time.Duration
+	// Listings whose text or image fingerprints differ in at most
+	// DuplicateMaxDistance bits are reported as near-duplicates.
+	DuplicateMaxDistance int
+}
+
+func DefaultModerationConfig() ModerationConfig {
+	return ModerationConfig{
+		ReportThreshold: 5,
+		MaxReports:      10,
+		ReportWindow:    time.Hour,
+		ClaimTimeout:    30 * time.Minute,
+
+		DuplicateMaxDistance: fingerprint.MaxBandDistance,
+	}
+}
+
+type ModerationService struct {
+	moderationRepo repository.ModerationRepository
+	listingRepo    repository.ListingRepository
+	userRepo       repository.UserRepository
+	config         ModerationConfig
+}
+
+var _ interfaces.ModerationServiceInterface = (*ModerationService)(nil)
+
+func NewModerationService(moderationRepo repository.ModerationRepository, listingRepo repository.ListingRepository, userRepo repository.UserRepository, config ModerationConfig) *ModerationService {
+	return &ModerationService{
+		moderationRepo: moderationRepo,
+		listingRepo:    listingRepo,
+		userRepo:       userRepo,
+		config:         config,
+	}
+}
+
+func (s *ModerationService) ReportListing(listingID, reporterID int64, req *dto.ReportRequest) (*dto.ReportDTO, error) {
+	if !isReportReason(req.Reason) {
+		return nil, ErrInvalidReportReason
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if len(comment) > maxReportCommentLen {
+		return nil, ErrReportTooLong
+	}
+
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, ErrListingNotFound
+	}
+	if listing.AuthorID == reporterID {
+		return nil, ErrOwnListingReport
+	}
+
+	recent, err := s.moderationRepo.CountReportsSince(reporterID, time.Now().Add(-s.config.ReportWindow))
+	if err != nil {
+		return nil, err
+	}
+	if recent >= s.config.MaxReports {
+		return nil, ErrReportRateLimited
+	}
+
+	report := &domain.Report{
+		ListingID:  listingID,
+		ReporterID: reporterID,
+		Reason:     req.Reason,
+		Comment:    comment,
+	}
+	c, err := s.moderationRepo.AddReport(report)
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrAlreadyReported
+		}
+		return nil, err
+	}
+
+	if c.ReportCount >= s.config.ReportThreshold && !c.AutoHidden {
+		if err := s.autoHide(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return dto.ToReportDTO(report), nil
+}
+
+// autoHide takes the listing out of the feed pending review. Listings that
+// are reserved or sold are left alone; the case remains in the queue.
+func (s *ModerationService) autoHide(c *domain.ModerationCase) error {
+	err := s.listingRepo.UpdateStatus(c.ListingID, domain.ListingStatusActive, domain.ListingStatusHidden)
+	if errors.Is(err, repository.ErrConflict) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.moderationRepo.SetAutoHidden(c.ID)
+}
+
+func (s *ModerationService) GetQueue(status string) ([]*dto.ModerationCaseDTO, error) {
+	if status == "" {
+		status = domain.CaseStatusOpen
+	}
+	if status != domain.CaseStatusOpen && status != domain.CaseStatusClaimed && status != domain.CaseStatusResolved {
+		return nil, ErrInvalidCaseStatus
+	}
+
+	cases, err := s.moderationRepo.GetCases(status, moderationQueueSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*dto.ModerationCaseDTO, 0, len(cases))
+	for _, c := range cases {
+		result = append(result, s.toDTO(c))
+	}
+	return result, nil
+}
+
+func (s *ModerationService) GetCase(caseID int64) (*dto.ModerationCaseDTO, error) {
+	c, err := s.moderationRepo.GetCase(caseID)
+	if err != nil {
+		return nil, ErrCaseNotFound
+	}
+
+	reports, err := s.moderationRepo.GetReports(caseID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := s.toDTO(c)
+	result.Reports = make([]*dto.ReportDTO, 0, len(reports))
+	for _, report := range reports {
+		result.Reports = append(result.Reports, dto.ToReportDTO(report))
+	}
+	return result, nil
+}
+
+// ClaimCase assigns the case to a moderator so two people don't work on it
+// at once. Claiming again refreshes the claim; a stale claim of someone else
+// can be taken over.
+func (s *ModerationService) ClaimCase(caseID, staffID int64) (*dto.ModerationCaseDTO, error) {
+	c, err := s.moderationRepo.GetCase(caseID)
+	if err != nil {
+		return nil, ErrCaseNotFound
+	}
+
+	now := time.Now()
+	switch c.Status {
+	case domain.CaseStatusResolved:
+		return nil, ErrCaseResolved
+	case domain.CaseStatusClaimed:
+		ownClaim := c.ClaimedBy != nil && *c.ClaimedBy == staffID
+		stale := c.ClaimedAt == nil || now.Sub(*c.ClaimedAt) > s.config.ClaimTimeout
+		if !ownClaim && !stale {
+			return nil, ErrCaseClaimed
+		}
+	}
+
+	expectedStatus, expectedClaimant := c.Status, c.ClaimedBy
+	c.Status = domain.CaseStatusClaimed
+	c.ClaimedBy = &staffID
+	c.ClaimedAt = &now
+	if err := s.moderationRepo.UpdateCase(c, expectedStatus, expectedClaimant); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrCaseClaimed
+		}
+		return nil, err
+	}
+
+	return s.toDTO(c), nil
+}
+
+// ResolveCase closes a case claimed by the moderator and applies the
+// decision: dismiss restores an auto-hidden listing, hide keeps the listing
+// out of the feed and ban additionally blocks the author and hides all of
+// their active listings.
+func (s *ModerationService) ResolveCase(caseID, staffID int64, req *dto.ResolveCaseRequest) (*dto.ModerationCaseDTO, error) {
+	switch req.Resolution {
+	case domain.ResolutionDismiss, domain.ResolutionHide, domain.ResolutionBan:
+	default:
+		return nil, ErrInvalidResolution
+	}
+
+	c, err := s.moderationRepo.GetCase(caseID)
+	if err != nil {
+		return nil, ErrCaseNotFound
+	}
+	if c.Status == domain.CaseStatusResolved {
+		return nil, ErrCaseResolved
+	}
+	if c.Status != domain.CaseStatusClaimed || c.ClaimedBy == nil || *c.ClaimedBy != staffID {
+		return nil, ErrCaseNotClaimed
+	}
+
+	now := time.Now()
+	c.Status = domain.CaseStatusResolved
+	c.Resolution = req.Resolution
+	c.ResolvedBy = &staffID
+	c.ResolvedAt = &now
+	c.Note = strings.TrimSpace(req.Note)
+	if err := s.moderationRepo.UpdateCase(c, domain.CaseStatusClaimed, &staffID); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrCaseNotClaimed
+		}
+		return nil, err
+	}
+
+	switch req.Resolution {
+	case domain.ResolutionDismiss:
+		if c.AutoHidden {
+			err = s.setListingStatus(c.ListingID, domain.ListingStatusHidden, domain.ListingStatusActive)
+		}
+	case domain.ResolutionHide:
+		err = s.setListingStatus(c.ListingID, domain.ListingStatusActive, domain.ListingStatusHidden)
+	case domain.ResolutionBan:
+		err = s.banAuthor(c.ListingID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toDTO(c), nil
+}
+
+func (s *ModerationService) banAuthor(listingID int64) error {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return ErrListingNotFound
+	}
+	if err := s.userRepo.SetBanned(int(listing.AuthorID), true); err != nil {
+		return err
+	}
+
+	// GetByAuthorID reports an error when the author has no listings left.
+	listings, _ := s.listingRepo.GetByAuthorID(listing.AuthorID)
+	for _, l := range listings {
+		if err := s.setListingStatus(l.ID, domain.ListingStatusActive, domain.ListingStatusHidden); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setListingStatus ignores listings that are no longer in the from status,
+// e.g. a listing sold while its case was in the queue.
+func (s *ModerationService) setListingStatus(listingID int64, from, to string) error {
+	err := s.listingRepo.UpdateStatus(listingID, from, to)
+	if errors.Is(err, repository.ErrConflict) {
+		return nil
+	}
+	return err
+}
+
+// FindDuplicates lists listings of any seller that look like a repost of the
+// given one, closest first.
+func (s *ModerationService) FindDuplicates(listingID int64) ([]*dto.NearDuplicateDTO, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, ErrListingNotFound
+	}
+
+	duplicates, err := s.listingRepo.FindNearDuplicates(listing, min(s.config.DuplicateMaxDistance, fingerprint.MaxBandDistance))
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return duplicates[i].Distance() < duplicates[j].Distance()
+	})
+
+	result := make([]*dto.NearDuplicateDTO, 0, len(duplicates))
+	for _, duplicate := range duplicates {
+		other, err := s.listingRepo.GetByID(duplicate.ListingID)
+		if err != nil {
+			continue
+		}
+		result = append(result, dto.ToNearDuplicateDTO(duplicate, other))
+	}
+	return result, nil
+}
+
+func (s *ModerationService) toDTO(c *domain.ModerationCase) *dto.ModerationCaseDTO {
+	listing, err := s.listingRepo.GetByID(c.ListingID)
+	if err != nil {
+		listing = nil
+	}
+	return dto.ToModerationCaseDTO(c, listing)
+}
+
+func isReportReason(reason string) bool {
+	for _, r := range domain.ReportReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
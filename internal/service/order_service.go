@@ -0,0 +1,418 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/interfaces"
+	"vk/ecom/internal/pkg/payment"
+	"vk/ecom/internal/repository"
+)
+
+const (
+	OrderRoleBuyer  = "buyer"
+	OrderRoleSeller = "seller"
+)
+
+var (
+	ErrOrderNotFound      = errors.New("order not found")
+	ErrOrderForbidden     = errors.New("order belongs to another user")
+	ErrInvalidOrderState  = errors.New("order cannot be moved to this status")
+	ErrListingUnavailable = errors.New("listing is not available for purchase")
+	ErrOwnListingOrder    = errors.New("cannot order your own listing")
+	ErrMixedSellers       = errors.New("all listings of an order must belong to one seller")
+	ErrMixedCurrencies    = errors.New("all listings of an order must be priced in one currency")
+	ErrPaymentFailed      = errors.New("payment was declined")
+	ErrPaymentPending     = errors.New("payment is still being processed")
+)
+
+type OrderService struct {
+	orderRepo   repository.OrderRepository
+	listingRepo repository.ListingRepository
+	payments    payment.Provider
+}
+
+var _ interfaces.OrderServiceInterface = (*OrderService)(nil)
+
+func NewOrderService(orderRepo repository.OrderRepository, listingRepo repository.ListingRepository, payments payment.Provider) *OrderService {
+	return &OrderService{
+		orderRepo:   orderRepo,
+		listingRepo: listingRepo,
+		payments:    payments,
+	}
+}
+
+// CreateOrder reserves the listings and opens a single order for them. All
+// listings must belong to the same seller. Each reservation is a
+// compare-and-set on the listing status, so concurrent buyers cannot both
+// succeed; if any listing is taken the ones reserved so far are released.
+func (s *OrderService) CreateOrder(listingIDs []int64, buyerID int64) (*dto.OrderDTO, error) {
+	if len(listingIDs) == 0 {
+		return nil, ErrListingUnavailable
+	}
+
+	order := &domain.Order{
+		BuyerID: buyerID,
+		Status:  domain.OrderStatusCreated,
+	}
+	titles := make(map[int64]string, len(listingIDs))
+	for _, listingID := range listingIDs {
+		if _, dup := titles[listingID]; dup {
+			continue
+		}
+		listing, err := s.listingRepo.GetByID(listingID)
+		if err != nil || listing.Type == domain.ListingTypeAuction {
+			return nil, ErrListingUnavailable
+		}
+		if listing.AuthorID == buyerID {
+			return nil, ErrOwnListingOrder
+		}
+		if order.SellerID == 0 {
+			order.SellerID = listing.AuthorID
+		} else if order.SellerID != listing.AuthorID {
+			return nil, ErrMixedSellers
+		}
+		if order.Currency == "" {
+			order.Currency = listing.Currency
+		} else if order.Currency != listing.Currency {
+			return nil, ErrMixedCurrencies
+		}
+		titles[listingID] = listing.Title
+		order.Amount += listing.Price
+		order.Items = append(order.Items, &domain.OrderItem{ListingID: listing.ID, Price: listing.Price})
+	}
+
+	var reserved []int64
+	for _, item := range order.Items {
+		err := s.listingRepo.UpdateStatus(item.ListingID, domain.ListingStatusActive, domain.ListingStatusReserved)
+		if err != nil {
+			s.setListingsStatus(reserved, domain.ListingStatusReserved, domain.ListingStatusActive)
+			if errors.Is(err, repository.ErrConflict) {
+				return nil, ErrListingUnavailable
+			}
+			return nil, err
+		}
+		reserved = append(reserved, item.ListingID)
+	}
+
+	if err := s.orderRepo.Create(order); err != nil {
+		s.setListingsStatus(reserved, domain.ListingStatusReserved, domain.ListingStatusActive)
+		return nil, err
+	}
+
+	return dto.ToOrderDTO(order, titles), nil
+}
+
+func (s *OrderService) GetOrder(orderID, userID int64) (*dto.OrderDTO, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+	if order.BuyerID != userID && order.SellerID != userID {
+		return nil, ErrOrderForbidden
+	}
+	return s.toDTO(order), nil
+}
+
+func (s *OrderService) GetOrders(userID int64, role string) ([]*dto.OrderDTO, error) {
+	var orders []*domain.Order
+	var err error
+	if role == OrderRoleSeller {
+		orders, err = s.orderRepo.GetBySellerID(userID)
+	} else {
+		orders, err = s.orderRepo.GetByBuyerID(userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*dto.OrderDTO, 0, len(orders))
+	for _, order := range orders {
+		result = append(result, s.toDTO(order))
+	}
+	return result, nil
+}
+
+// paymentClaim is the payment ID of an order while a charge is in flight.
+// It doubles as the idempotency key of that charge, so each attempt gets a
+// fresh one: paying again after a refund has to charge again.
+func paymentClaim(orderID int64) (string, error) {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return paymentClaimPrefix(orderID) + hex.EncodeToString(nonce), nil
+}
+
+func paymentClaimPrefix(orderID int64) string {
+	return fmt.Sprintf("order_%d_", orderID)
+}
+
+// PayOrder charges the buyer. The order is claimed with a compare-and-set
+// on its empty payment ID first, so concurrent calls cannot both charge.
+func (s *OrderService) PayOrder(orderID, buyerID int64) (*dto.OrderDTO, error) {
+	order, err := s.getForBuyer(orderID, buyerID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != domain.OrderStatusCreated {
+		return nil, ErrInvalidOrderState
+	}
+	if order.PaymentID != "" {
+		return nil, ErrPaymentPending
+	}
+
+	claim, err := paymentClaim(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.orderRepo.SetPaymentID(order.ID, "", claim); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrPaymentPending
+		}
+		return nil, err
+	}
+
+	result, err := s.payments.Charge(payment.ChargeRequest{
+		OrderID:        order.ID,
+		Amount:         order.Amount,
+		Currency:       order.Currency,
+		IdempotencyKey: claim,
+	})
+	if err != nil || (result.Status != payment.StatusSucceeded && result.Status != payment.StatusPending) {
+		if releaseErr := s.orderRepo.SetPaymentID(order.ID, claim, ""); releaseErr != nil {
+			log.Println("Failed to release payment claim:", releaseErr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrPaymentFailed
+	}
+
+	order.PaymentID = result.ID
+	if result.Status == payment.StatusSucceeded {
+		if err := s.markPaid(order); err != nil {
+			// The order was cancelled meanwhile or its listings could not
+			// be sold; either way it is unpaid, so give the money back.
+			s.refund(result.ID, order.Amount)
+			return nil, err
+		}
+		return s.toDTO(order), nil
+	}
+
+	if err := s.orderRepo.SetPaymentID(order.ID, claim, result.ID); err != nil {
+		if !errors.Is(err, repository.ErrConflict) {
+			return nil, err
+		}
+		// The webhook settled the payment first.
+		if order, err = s.orderRepo.GetByID(order.ID); err != nil {
+			return nil, ErrOrderNotFound
+		}
+	}
+	return s.toDTO(order), nil
+}
+
+// HandlePaymentWebhook settles a pending payment. Events for orders that have
+// already left the created status are ignored, so redelivery is harmless.
+func (s *OrderService) HandlePaymentWebhook(payload []byte, signature string) error {
+	event, err := s.payments.ParseWebhook(payload, signature)
+	if err != nil {
+		return err
+	}
+
+	order, err := s.orderRepo.GetByPaymentID(event.PaymentID)
+	if err != nil {
+		// The webhook can beat PayOrder to storing the payment ID.
+		order, err = s.orderRepo.GetByID(event.OrderID)
+		if err != nil || !strings.HasPrefix(order.PaymentID, paymentClaimPrefix(order.ID)) {
+			return ErrOrderNotFound
+		}
+	}
+	if order.Status != domain.OrderStatusCreated {
+		return nil
+	}
+	order.PaymentID = event.PaymentID
+
+	switch event.Status {
+	case payment.StatusSucceeded:
+		err := s.markPaid(order)
+		if err != nil && !errors.Is(err, ErrInvalidOrderState) {
+			s.refund(event.PaymentID, order.Amount)
+		}
+		return err
+	case payment.StatusFailed:
+		order.PaymentID = ""
+		return s.conflict(s.orderRepo.Update(order, domain.OrderStatusCreated))
+	}
+	return nil
+}
+
+func (s *OrderService) ShipOrder(orderID, sellerID int64) (*dto.OrderDTO, error) {
+	order, err := s.getForSeller(orderID, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.transition(order, domain.OrderStatusPaid, domain.OrderStatusShipped); err != nil {
+		return nil, err
+	}
+	return s.toDTO(order), nil
+}
+
+func (s *OrderService) CompleteOrder(orderID, buyerID int64) (*dto.OrderDTO, error) {
+	order, err := s.getForBuyer(orderID, buyerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.transition(order, domain.OrderStatusShipped, domain.OrderStatusCompleted); err != nil {
+		return nil, err
+	}
+	return s.toDTO(order), nil
+}
+
+// CancelOrder is allowed for both parties until the order is paid and
+// releases the listing back to the feed.
+func (s *OrderService) CancelOrder(orderID, userID int64) (*dto.OrderDTO, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+	if order.BuyerID != userID && order.SellerID != userID {
+		return nil, ErrOrderForbidden
+	}
+	if order.PaymentID != "" && order.Status == domain.OrderStatusCreated {
+		return nil, ErrPaymentPending
+	}
+	if err := s.transition(order, domain.OrderStatusCreated, domain.OrderStatusCancelled); err != nil {
+		return nil, err
+	}
+	if err := s.setListingsStatus(orderListingIDs(order), domain.ListingStatusReserved, domain.ListingStatusActive); err != nil {
+		return nil, err
+	}
+	return s.toDTO(order), nil
+}
+
+func (s *OrderService) RefundOrder(orderID, sellerID int64) (*dto.OrderDTO, error) {
+	order, err := s.getForSeller(orderID, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	from := order.Status
+	if from != domain.OrderStatusPaid && from != domain.OrderStatusShipped {
+		return nil, ErrInvalidOrderState
+	}
+	// The transition comes first, so concurrent requests cannot both refund.
+	if err := s.transition(order, from, domain.OrderStatusRefunded); err != nil {
+		return nil, err
+	}
+	if err := s.payments.Refund(order.PaymentID, order.Amount); err != nil {
+		order.Status = from
+		if revertErr := s.orderRepo.Update(order, domain.OrderStatusRefunded); revertErr != nil {
+			log.Println("Failed to revert refunded order:", revertErr)
+		}
+		return nil, err
+	}
+	if err := s.setListingsStatus(orderListingIDs(order), domain.ListingStatusSold, domain.ListingStatusActive); err != nil {
+		return nil, err
+	}
+	return s.toDTO(order), nil
+}
+
+// markPaid marks order paid and its listings sold. If the listings cannot be
+// sold, the order goes back to created without a payment and the caller is
+// expected to refund it.
+func (s *OrderService) markPaid(order *domain.Order) error {
+	order.Status = domain.OrderStatusPaid
+	if err := s.orderRepo.Update(order, domain.OrderStatusCreated); err != nil {
+		return s.conflict(err)
+	}
+	listingIDs := orderListingIDs(order)
+	err := s.setListingsStatus(listingIDs, domain.ListingStatusReserved, domain.ListingStatusSold)
+	if err == nil {
+		return nil
+	}
+
+	s.setListingsStatus(listingIDs, domain.ListingStatusSold, domain.ListingStatusReserved)
+	order.Status = domain.OrderStatusCreated
+	order.PaymentID = ""
+	if revertErr := s.orderRepo.Update(order, domain.OrderStatusPaid); revertErr != nil {
+		log.Println("Failed to revert paid order:", revertErr)
+	}
+	return err
+}
+
+func (s *OrderService) refund(paymentID string, amount int64) {
+	if err := s.payments.Refund(paymentID, amount); err != nil {
+		log.Printf("Failed to refund payment %s: %v", paymentID, err)
+	}
+}
+
+// setListingsStatus moves every listing it can and reports the first error.
+func (s *OrderService) setListingsStatus(listingIDs []int64, from, to string) error {
+	var firstErr error
+	for _, id := range listingIDs {
+		if err := s.listingRepo.UpdateStatus(id, from, to); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func orderListingIDs(order *domain.Order) []int64 {
+	ids := make([]int64, 0, len(order.Items))
+	for _, item := range order.Items {
+		ids = append(ids, item.ListingID)
+	}
+	return ids
+}
+
+func (s *OrderService) transition(order *domain.Order, from, to string) error {
+	if order.Status != from {
+		return ErrInvalidOrderState
+	}
+	order.Status = to
+	return s.conflict(s.orderRepo.Update(order, from))
+}
+
+func (s *OrderService) conflict(err error) error {
+	if errors.Is(err, repository.ErrConflict) {
+		return ErrInvalidOrderState
+	}
+	return err
+}
+
+func (s *OrderService) getForBuyer(orderID, buyerID int64) (*domain.Order, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+	if order.BuyerID != buyerID {
+		return nil, ErrOrderForbidden
+	}
+	return order, nil
+}
+
+func (s *OrderService) getForSeller(orderID, sellerID int64) (*domain.Order, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+	if order.SellerID != sellerID {
+		return nil, ErrOrderForbidden
+	}
+	return order, nil
+}
+
+func (s *OrderService) toDTO(order *domain.Order) *dto.OrderDTO {
+	titles := make(map[int64]string, len(order.Items))
+	for _, item := range order.Items {
+		if listing, err := s.listingRepo.GetByID(item.ListingID); err == nil {
+			titles[item.ListingID] = listing.Title
+		}
+	}
+	return dto.ToOrderDTO(order, titles)
+}
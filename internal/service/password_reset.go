@@ -0,0 +1,149 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/mail"
+	"vk/ecom/internal/repository"
+)
+
+var (
+	ErrPasswordResetDisabled = errors.New("password reset is not enabled")
+	ErrInvalidResetToken     = errors.New("reset token is invalid or expired")
+)
+
+type PasswordResetConfig struct {
+	TokenTTL time.Duration
+	// ResetURL is the frontend page that takes the token. If set, the
+	// email links to it with the token in the token query parameter.
+	ResetURL string
+}
+
+func DefaultPasswordResetConfig() PasswordResetConfig {
+	return PasswordResetConfig{
+		TokenTTL: 30 * time.Minute,
+	}
+}
+
+type passwordReset struct {
+	tokens repository.PasswordResetRepository
+	mailer mail.Mailer
+	config PasswordResetConfig
+}
+
+// WithPasswordReset enables password resets with tokens delivered by mailer.
+func WithPasswordReset(tokens repository.PasswordResetRepository, mailer mail.Mailer, config PasswordResetConfig) AuthServiceOption {
+	return func(s *AuthService) {
+		s.reset = &passwordReset{tokens: tokens, mailer: mailer, config: config}
+	}
+}
+
+// hashToken is how single-use tokens are stored, so that a leaked table
+// cannot be used to redeem them.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestPasswordReset mails a single-use reset token to the user's email
+// address. Unknown logins and users without an address are ignored
+// silently, so the response does not reveal which accounts exist.
+func (s *AuthService) RequestPasswordReset(login string) error {
+	if s.reset == nil {
+		return ErrPasswordResetDisabled
+	}
+
+	user, err := s.userRepo.GetByLogin(login)
+	if err != nil || user.Banned {
+		return nil
+	}
+	if user.Email == "" {
+		log.Printf("Password reset requested for user %d without an email address", user.ID)
+		return nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	record := &domain.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().UTC().Add(s.reset.config.TokenTTL),
+	}
+	if err := s.reset.tokens.Create(record); err != nil {
+		return err
+	}
+
+	return s.reset.mailer.Send(&mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    s.reset.body(user, token),
+	})
+}
+
+func (r *passwordReset) body(user *domain.User, token string) string {
+	action := "use this code to reset your password:\n\n" + token
+	if r.config.ResetURL != "" {
+		action = "open this link to reset your password:\n\n" + r.config.ResetURL + "?token=" + url.QueryEscape(token)
+	}
+	return fmt.Sprintf("Hello %s,\n\nsomeone asked to reset the password of your account. If it was you, %s\n\nIt expires in %d minutes. If you did not ask for it, ignore this email.\n",
+		user.Login, action, int(r.config.TokenTTL.Minutes()))
+}
+
+// ResetPassword sets a new password with a reset token. The token and any
+// other pending ones are used up, all sessions are revoked and the
+// account's login lockout is lifted.
+func (s *AuthService) ResetPassword(token, newPassword string) error {
+	if s.reset == nil {
+		return ErrPasswordResetDisabled
+	}
+	if err := s.validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	record, err := s.reset.tokens.Consume(hashToken(token), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return ErrInvalidResetToken
+	}
+
+	hashedPassword, err := s.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.SetPassword(record.UserID, hashedPassword, true); err != nil {
+		return err
+	}
+
+	if err := s.reset.tokens.DeleteByUserID(record.UserID); err != nil {
+		log.Println("Failed to delete password reset tokens:", err)
+	}
+	// The session version already rejects old tokens; this keeps the
+	// session list accurate.
+	if s.sessions != nil {
+		if _, err := s.sessions.sessions.RevokeAll(record.UserID, 0, time.Now().UTC()); err != nil {
+			log.Println("Failed to revoke sessions:", err)
+		}
+	}
+	if s.throttle != nil {
+		if user, err := s.userRepo.GetByID(record.UserID); err == nil {
+			if err := s.throttle.RecordSuccess(user.Login); err != nil {
+				log.Println("Failed to reset login failures:", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/money"
+	"vk/ecom/internal/pkg/notify"
+	"vk/ecom/internal/repository"
+)
+
+var ErrFavoritesDisabled = errors.New("favorites are not enabled")
+
+// WithFavorites lets users favorite listings and notifies them through
+// notifier when the price of a favorited listing drops.
+func WithFavorites(favorites repository.FavoriteRepository, notifier notify.Notifier) ListingServiceOption {
+	return func(s *ListingService) {
+		s.favorites = favorites
+		s.notifier = notifier
+	}
+}
+
+func (s *ListingService) AddFavorite(listingID, userID int64) error {
+	if s.favorites == nil {
+		return ErrFavoritesDisabled
+	}
+	if _, err := s.visibleListing(listingID, &userID); err != nil {
+		return err
+	}
+	return s.favorites.Add(userID, listingID)
+}
+
+func (s *ListingService) RemoveFavorite(listingID, userID int64) error {
+	if s.favorites == nil {
+		return ErrFavoritesDisabled
+	}
+	return s.favorites.Remove(userID, listingID)
+}
+
+// GetPriceHistory returns the price changes of a listing, oldest first.
+func (s *ListingService) GetPriceHistory(listingID int64, currentUserID *int64) ([]*domain.PriceChange, error) {
+	if _, err := s.visibleListing(listingID, currentUserID); err != nil {
+		return nil, err
+	}
+	history, err := s.listingRepo.GetPriceHistory(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []*domain.PriceChange{}
+	}
+	return history, nil
+}
+
+// visibleListing loads a listing that is not hidden from the user.
+func (s *ListingService) visibleListing(listingID int64, currentUserID *int64) (*domain.Listing, error) {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return nil, ErrListingNotFound
+	}
+	if listing.Status == domain.ListingStatusHidden && (currentUserID == nil || *currentUserID != listing.AuthorID) {
+		return nil, ErrListingNotFound
+	}
+	return listing, nil
+}
+
+// notifyPriceDrop tells the users who favorited the listing that its price
+// went down from oldPrice. Failures are logged; the update already
+// succeeded.
+func (s *ListingService) notifyPriceDrop(listing *domain.Listing, oldPrice int64) {
+	if s.favorites == nil || s.notifier == nil {
+		return
+	}
+	userIDs, err := s.favorites.GetUserIDs(listing.ID)
+	if err != nil {
+		log.Println("Failed to load favorites for price drop:", err)
+		return
+	}
+
+	text := fmt.Sprintf("%q is now %s, was %s", listing.Title,
+		money.New(listing.Price, listing.Currency), money.New(oldPrice, listing.Currency))
+	for _, userID := range userIDs {
+		if userID == listing.AuthorID {
+			continue
+		}
+		err := s.notifier.Notify(&notify.Notification{
+			UserID:    userID,
+			Kind:      notify.KindPriceDrop,
+			ListingID: listing.ID,
+			Text:      text,
+		})
+		if err != nil {
+			log.Println("Failed to send price drop notification:", err)
+		}
+	}
+}
+
+// priceDroppedSince is the earliest drop the price_dropped filter keeps.
+func priceDroppedSince(now time.Time) *time.Time {
+	since := now.Add(-domain.PriceDropWindow)
+	return &since
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/interfaces"
+	"vk/ecom/internal/repository"
+)
+
+var (
+	ErrReviewNotAllowed = errors.New("only the confirmed buyer can review this listing")
+	ErrReviewExists     = errors.New("this listing has already been reviewed")
+	ErrReviewNotFound   = errors.New("review not found")
+	ErrReplyExists      = errors.New("review already has a reply")
+	ErrNotListingOwner  = errors.New("listing belongs to another user")
+	ErrInvalidReview    = errors.New("rating must be between 1 and 5")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrInvalidPurchaser = errors.New("invalid purchaser")
+	ErrReviewTooLong    = errors.New("review text is too long")
+	ErrInvalidReply     = errors.New("reply must be between 1 and 2000 characters")
+)
+
+const maxReviewTextLen = 2000
+
+type ReviewService struct {
+	reviewRepo  repository.ReviewRepository
+	listingRepo repository.ListingRepository
+	userRepo    repository.UserRepository
+}
+
+var _ interfaces.ReviewServiceInterface = (*ReviewService)(nil)
+
+func NewReviewService(reviewRepo repository.ReviewRepository, listingRepo repository.ListingRepository, userRepo repository.UserRepository) *ReviewService {
+	return &ReviewService{
+		reviewRepo:  reviewRepo,
+		listingRepo: listingRepo,
+		userRepo:    userRepo,
+	}
+}
+
+// ConfirmPurchaser lets the seller name the buyer of one of their listings.
+// Confirming again replaces the previously named buyer.
+func (s *ReviewService) ConfirmPurchaser(listingID, sellerID, buyerID int64) error {
+	listing, err := s.listingRepo.GetByID(listingID)
+	if err != nil {
+		return ErrListingNotFound
+	}
+	if listing.AuthorID != sellerID {
+		return ErrNotListingOwner
+	}
+	if buyerID == sellerID {
+		return ErrInvalidPurchaser
+	}
+	if _, err := s.userRepo.GetByID(int(buyerID)); err != nil {
+		return ErrInvalidPurchaser
+	}
+
+	return s.reviewRepo.ConfirmPurchase(&domain.PurchaseConfirmation{
+		ListingID: listingID,
+		SellerID:  sellerID,
+		BuyerID:   buyerID,
+	})
+}
+
+func (s *ReviewService) CreateReview(listingID, authorID int64, req *dto.ReviewRequest) (*dto.ReviewDTO, error) {
+	if req.Rating < 1 || req.Rating > 5 {
+		return nil, ErrInvalidReview
+	}
+	text := strings.TrimSpace(req.Text)
+	if len(text) > maxReviewTextLen {
+		return nil, ErrReviewTooLong
+	}
+
+	confirmation, err := s.reviewRepo.GetPurchaseConfirmation(listingID)
+	if err != nil || confirmation.BuyerID != authorID {
+		return nil, ErrReviewNotAllowed
+	}
+
+	review := &domain.Review{
+		ListingID: listingID,
+		SellerID:  confirmation.SellerID,
+		AuthorID:  authorID,
+		Rating:    req.Rating,
+		Text:      text,
+	}
+	if err := s.reviewRepo.Create(review); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrReviewExists
+		}
+		return nil, err
+	}
+
+	return dto.ToReviewDTO(review, s.login(authorID)), nil
+}
+
+func (s *ReviewService) ReplyToReview(reviewID, sellerID int64, text string) (*dto.ReviewDTO, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || len(text) > maxReviewTextLen {
+		return nil, ErrInvalidReply
+	}
+
+	review, err := s.reviewRepo.GetByID(reviewID)
+	if err != nil {
+		return nil, ErrReviewNotFound
+	}
+	if review.SellerID != sellerID {
+		return nil, ErrNotListingOwner
+	}
+
+	now := time.Now()
+	if err := s.reviewRepo.SetReply(reviewID, text, now); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrReplyExists
+		}
+		return nil, err
+	}
+	review.Reply = text
+	review.RepliedAt = &now
+
+	return dto.ToReviewDTO(review, s.login(review.AuthorID)), nil
+}
+
+func (s *ReviewService) GetSellerReviews(sellerID int64) ([]*dto.ReviewDTO, error) {
+	reviews, err := s.reviewRepo.GetBySellerID(sellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	logins := make(map[int64]string)
+	result := make([]*dto.ReviewDTO, 0, len(reviews))
+	for _, review := range reviews {
+		login, ok := logins[review.AuthorID]
+		if !ok {
+			login = s.login(review.AuthorID)
+			logins[review.AuthorID] = login
+		}
+		result = append(result, dto.ToReviewDTO(review, login))
+	}
+	return result, nil
+}
+
+func (s *ReviewService) GetUserProfile(userID int64) (*dto.UserProfileDTO, error) {
+	user, err := s.userRepo.GetByID(int(userID))
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	ratings, err := s.reviewRepo.GetSellerRatings([]int64{userID})
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &dto.UserProfileDTO{
+		ID:     user.ID,
+		Login:  user.Login,
+		Rating: dto.RoundedRating(ratings[userID]),
+	}
+	if rating := ratings[userID]; rating != nil {
+		profile.ReviewCount = rating.ReviewCount
+	}
+	return profile, nil
+}
+
+func (s *ReviewService) login(userID int64) string {
+	if user, err := s.userRepo.GetByID(int(userID)); err == nil {
+		return user.Login
+	}
+	return ""
+}
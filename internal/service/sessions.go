@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/pkg/jwt"
+	"vk/ecom/internal/repository"
+)
+
+var (
+	ErrSessionsDisabled = errors.New("session tracking is not enabled")
+	ErrSessionNotFound  = errors.New("session not found")
+)
+
+const maxUserAgentLength = 255
+
+type SessionConfig struct {
+	// TTL should match the lifetime of access tokens.
+	TTL time.Duration
+	// LastSeenInterval limits how often the last-seen time of a session
+	// is written, so there is no write per request.
+	LastSeenInterval time.Duration
+}
+
+func DefaultSessionConfig() SessionConfig {
+	return SessionConfig{
+		TTL:              24 * time.Hour,
+		LastSeenInterval: 5 * time.Minute,
+	}
+}
+
+type sessionTracker struct {
+	sessions repository.SessionRepository
+	config   SessionConfig
+}
+
+// WithSessions records a session for every login, which the user can list
+// and revoke.
+func WithSessions(sessions repository.SessionRepository, config SessionConfig) AuthServiceOption {
+	return func(s *AuthService) {
+		s.sessions = &sessionTracker{sessions: sessions, config: config}
+	}
+}
+
+// issueToken issues an access token at the end of a login, in a new
+// session if sessions are tracked. The returned user carries the session.
+func (s *AuthService) issueToken(user *domain.User, client domain.ClientInfo) (string, *domain.User, error) {
+	if s.sessions != nil {
+		userAgent := client.UserAgent
+		if len(userAgent) > maxUserAgentLength {
+			userAgent = userAgent[:maxUserAgentLength]
+		}
+		session := &domain.Session{
+			UserID:    user.ID,
+			IP:        client.IP,
+			UserAgent: userAgent,
+			ExpiresAt: time.Now().UTC().Add(s.sessions.config.TTL),
+		}
+		if err := s.sessions.sessions.Create(session); err != nil {
+			return "", nil, err
+		}
+		withSession := *user
+		withSession.SessionID = session.ID
+		user = &withSession
+	}
+
+	token, err := jwt.GenerateToken(user)
+	if err != nil {
+		return "", nil, errors.New("failed to generate token")
+	}
+	return token, user, nil
+}
+
+// checkSession reports whether a token's session is still active, and
+// records the use now and then.
+func (t *sessionTracker) checkSession(user *domain.User) bool {
+	// Tokens from before sessions were tracked have none and must log in
+	// again, otherwise they could not be revoked.
+	if user.SessionID == 0 {
+		return false
+	}
+	session, err := t.sessions.Get(user.SessionID)
+	if err != nil {
+		log.Println("Failed to get session:", err)
+		return false
+	}
+	now := time.Now().UTC()
+	if session == nil || session.UserID != user.ID || session.RevokedAt != nil || !session.ExpiresAt.After(now) {
+		return false
+	}
+
+	if now.Sub(session.LastSeenAt) >= t.config.LastSeenInterval {
+		if err := t.sessions.SetLastSeen(session.ID, now); err != nil {
+			log.Println("Failed to update session last seen:", err)
+		}
+	}
+	return true
+}
+
+// GetSessions returns the user's active sessions, marking currentID.
+func (s *AuthService) GetSessions(userID int, currentID int64) ([]*dto.SessionDTO, error) {
+	if s.sessions == nil {
+		return nil, ErrSessionsDisabled
+	}
+	sessions, err := s.sessions.sessions.GetActive(userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*dto.SessionDTO, len(sessions))
+	for i, session := range sessions {
+		result[i] = dto.ToSessionDTO(session, session.ID == currentID)
+	}
+	return result, nil
+}
+
+// RevokeSession logs a session out; its tokens stop working at once.
+func (s *AuthService) RevokeSession(userID int, sessionID int64) error {
+	if s.sessions == nil {
+		return ErrSessionsDisabled
+	}
+	revoked, err := s.sessions.sessions.Revoke(userID, sessionID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOtherSessions logs out all of the user's sessions but currentID
+// and returns how many there were.
+func (s *AuthService) RevokeOtherSessions(userID int, currentID int64) (int, error) {
+	if s.sessions == nil {
+		return 0, ErrSessionsDisabled
+	}
+	return s.sessions.sessions.RevokeAll(userID, currentID, time.Now().UTC())
+}
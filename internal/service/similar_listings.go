@@ -0,0 +1,251 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/repository"
+)
+
+const (
+	similarListingsLimit   = 10
+	similarCandidatesLimit = 50
+
+	// Weights of the ranking signals, each scaled to [0, 1].
+	similarTextWeight     = 0.55
+	similarCategoryWeight = 0.2
+	similarPriceWeight    = 0.15
+	similarRecencyWeight  = 0.1
+	// A listing this old gets half of the recency score.
+	similarRecencyHalfLife = 30 * 24 * time.Hour
+)
+
+// WithSimilarListingsCache caches GetSimilarListings results.
+func WithSimilarListingsCache(cache *SimilarListingsCache) ListingServiceOption {
+	return func(s *ListingService) {
+		s.similarCache = cache
+	}
+}
+
+// GetSimilarListings returns active listings of other sellers that resemble
+// the given one, ranked by text similarity, category, price proximity and
+// recency.
+func (s *ListingService) GetSimilarListings(listingID int64, currency string, currentUserID *int64) ([]*dto.ListingDTO, error) {
+	displayCurrency, err := requestCurrency(currency, "")
+	if err != nil {
+		return nil, err
+	}
+	similar, ok := s.similarCache.get(listingID)
+	if !ok {
+		listing, err := s.listingRepo.GetByID(listingID)
+		if err != nil {
+			return nil, ErrListingNotFound
+		}
+		candidates, err := s.listingRepo.FindSimilar(listing, similarCandidatesLimit)
+		if err != nil {
+			return nil, err
+		}
+		similar = rankSimilarListings(listing, candidates, time.Now())
+		s.similarCache.put(listingID, similar)
+	}
+
+	result := make([]*dto.ListingDTO, 0, len(similar))
+	for _, listing := range similar {
+		author, err := s.userRepo.GetByID(int(listing.AuthorID))
+		if err != nil {
+			result = append(result, dto.ToListingDTOWithAuthor(listing, "", currentUserID))
+		} else {
+			result = append(result, dto.ToListingDTOWithAuthor(listing, author.Login, currentUserID))
+		}
+	}
+	s.attachAuthorRatings(result)
+	s.attachDisplayPrices(result, displayCurrency)
+
+	return result, nil
+}
+
+// rankSimilarListings scores the candidates and keeps the best ones. Text
+// scores are relative to the best candidate, since each backend measures
+// relevance on its own scale.
+func rankSimilarListings(listing *domain.Listing, candidates []*domain.SimilarListing, now time.Time) []*domain.Listing {
+	var maxText float64
+	for _, c := range candidates {
+		maxText = max(maxText, c.TextScore)
+	}
+
+	scores := make(map[int64]float64, len(candidates))
+	for _, c := range candidates {
+		var score float64
+		if maxText > 0 {
+			score += similarTextWeight * c.TextScore / maxText
+		}
+		if listing.Category != "" && c.Listing.Category == listing.Category {
+			score += similarCategoryWeight
+		}
+		if listing.Price > 0 && c.Listing.Price > 0 {
+			score += similarPriceWeight * float64(min(listing.Price, c.Listing.Price)) / float64(max(listing.Price, c.Listing.Price))
+		}
+		age := max(now.Sub(c.Listing.CreatedAt), 0)
+		score += similarRecencyWeight * float64(similarRecencyHalfLife) / float64(similarRecencyHalfLife+age)
+		scores[c.Listing.ID] = score
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return scores[candidates[i].Listing.ID] > scores[candidates[j].Listing.ID]
+	})
+	result := make([]*domain.Listing, 0, min(len(candidates), similarListingsLimit))
+	for _, c := range candidates[:min(len(candidates), similarListingsLimit)] {
+		result = append(result, c.Listing)
+	}
+	return result
+}
+
+// SimilarListingsCache keeps ranked similar listings per source listing for
+// a limited time. Entries are dropped as soon as the source listing or one
+// of the listings in its result changes; listings created after an entry
+// was computed show up once it expires. A nil cache caches nothing.
+type SimilarListingsCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[int64]*similarEntry
+	// referencedBy maps a listing to the entries whose result contains it.
+	referencedBy map[int64]map[int64]struct{}
+}
+
+type similarEntry struct {
+	listings  []*domain.Listing
+	expiresAt time.Time
+}
+
+func NewSimilarListingsCache(ttl time.Duration, maxEntries int) *SimilarListingsCache {
+	return &SimilarListingsCache{
+		ttl:          ttl,
+		maxEntries:   maxEntries,
+		entries:      make(map[int64]*similarEntry),
+		referencedBy: make(map[int64]map[int64]struct{}),
+	}
+}
+
+// Watch wraps a listing repository so that every successful write through
+// it invalidates the affected cache entries. All services writing listings
+// should share the wrapped repository.
+func (c *SimilarListingsCache) Watch(repo repository.ListingRepository) repository.ListingRepository {
+	return &watchedListingRepository{ListingRepository: repo, cache: c}
+}
+
+// Invalidate drops the cached result of the listing and every cached result
+// that contains it.
+func (c *SimilarListingsCache) Invalidate(listingID int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(listingID)
+	for sourceID := range c.referencedBy[listingID] {
+		c.remove(sourceID)
+	}
+}
+
+func (c *SimilarListingsCache) get(listingID int64) ([]*domain.Listing, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[listingID]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		c.remove(listingID)
+		return nil, false
+	}
+	return entry.listings, true
+}
+
+func (c *SimilarListingsCache) put(listingID int64, listings []*domain.Listing) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(listingID)
+	if len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+	c.entries[listingID] = &similarEntry{listings: listings, expiresAt: time.Now().Add(c.ttl)}
+	for _, listing := range listings {
+		if c.referencedBy[listing.ID] == nil {
+			c.referencedBy[listing.ID] = make(map[int64]struct{})
+		}
+		c.referencedBy[listing.ID][listingID] = struct{}{}
+	}
+}
+
+// evict drops expired entries, or the entry closest to expiry if none are.
+func (c *SimilarListingsCache) evict() {
+	now := time.Now()
+	var oldestID int64
+	var oldest time.Time
+	for id, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			c.remove(id)
+		} else if oldest.IsZero() || entry.expiresAt.Before(oldest) {
+			oldestID, oldest = id, entry.expiresAt
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		c.remove(oldestID)
+	}
+}
+
+func (c *SimilarListingsCache) remove(listingID int64) {
+	entry, ok := c.entries[listingID]
+	if !ok {
+		return
+	}
+	delete(c.entries, listingID)
+	for _, listing := range entry.listings {
+		delete(c.referencedBy[listing.ID], listingID)
+		if len(c.referencedBy[listing.ID]) == 0 {
+			delete(c.referencedBy, listing.ID)
+		}
+	}
+}
+
+type watchedListingRepository struct {
+	repository.ListingRepository
+	cache *SimilarListingsCache
+}
+
+func (r *watchedListingRepository) UpdateStatus(id int64, from, to string) error {
+	if err := r.ListingRepository.UpdateStatus(id, from, to); err != nil {
+		return err
+	}
+	r.cache.Invalidate(id)
+	return nil
+}
+
+func (r *watchedListingRepository) Update(listing *domain.Listing) error {
+	if err := r.ListingRepository.Update(listing); err != nil {
+		return err
+	}
+	r.cache.Invalidate(listing.ID)
+	return nil
+}
+
+func (r *watchedListingRepository) Delete(id int64) error {
+	if err := r.ListingRepository.Delete(id); err != nil {
+		return err
+	}
+	r.cache.Invalidate(id)
+	return nil
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/pkg/jwt"
+	"vk/ecom/internal/pkg/totp"
+	"vk/ecom/internal/repository"
+)
+
+var (
+	ErrTwoFactorUnavailable    = errors.New("two-factor authentication is not available")
+	ErrTwoFactorRequired       = errors.New("two-factor code required")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor enrollment has not been started")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrInvalidLoginChallenge   = errors.New("login challenge is invalid or expired")
+)
+
+// TwoFactorRequiredError is returned by the password step of a login for
+// users with two-factor auth. It matches ErrTwoFactorRequired.
+type TwoFactorRequiredError struct {
+	ChallengeToken string
+}
+
+func (e *TwoFactorRequiredError) Error() string {
+	return ErrTwoFactorRequired.Error()
+}
+
+func (e *TwoFactorRequiredError) Is(target error) bool {
+	return target == ErrTwoFactorRequired
+}
+
+type TwoFactorConfig struct {
+	// Issuer is the account name shown by authenticator apps.
+	Issuer string
+	// Skew is how many time steps before and after the current one are
+	// accepted, to tolerate clock drift.
+	Skew int
+	// ChallengeTTL is how long the second login step may take.
+	ChallengeTTL  time.Duration
+	RecoveryCodes int
+}
+
+func DefaultTwoFactorConfig() TwoFactorConfig {
+	return TwoFactorConfig{
+		Issuer:        "ecom",
+		Skew:          1,
+		ChallengeTTL:  5 * time.Minute,
+		RecoveryCodes: 10,
+	}
+}
+
+type twoFactor struct {
+	setups repository.TwoFactorRepository
+	config TwoFactorConfig
+}
+
+// WithTwoFactor lets users protect their login with TOTP codes.
+func WithTwoFactor(setups repository.TwoFactorRepository, config TwoFactorConfig) AuthServiceOption {
+	return func(s *AuthService) {
+		s.twoFactor = &twoFactor{setups: setups, config: config}
+	}
+}
+
+// EnrollTwoFactor starts enrollment with a new secret, replacing one that
+// was not confirmed yet.
+func (s *AuthService) EnrollTwoFactor(userID int) (*dto.TwoFactorEnrollmentDTO, error) {
+	if s.twoFactor == nil {
+		return nil, ErrTwoFactorUnavailable
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactor.setups.SetSecret(userID, secret); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return &dto.TwoFactorEnrollmentDTO{
+		Secret: secret,
+		URI:    totp.URI(s.twoFactor.config.Issuer, user.Login, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables two-factor auth once the user proves the app is
+// set up, and returns the recovery codes. They are only shown here.
+func (s *AuthService) ConfirmTwoFactor(userID int, code string) ([]string, error) {
+	if s.twoFactor == nil {
+		return nil, ErrTwoFactorUnavailable
+	}
+	setup, err := s.twoFactor.setups.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if setup == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if setup.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok, err := totp.Match(setup.Secret, normalizeTwoFactorCode(code), time.Now(), s.twoFactor.config.Skew)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes := make([]string, s.twoFactor.config.RecoveryCodes)
+	hashes := make([]string, len(codes))
+	for i := range codes {
+		if codes[i], err = generateRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	if err := s.twoFactor.setups.Enable(userID, step, hashes); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor turns two-factor auth off. It needs the password and a
+// code or recovery code.
+func (s *AuthService) DisableTwoFactor(userID int, password, code string) error {
+	if s.twoFactor == nil {
+		return ErrTwoFactorUnavailable
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if !checkPassword(user, password) {
+		return ErrWrongPassword
+	}
+
+	setup, err := s.twoFactor.setups.Get(userID)
+	if err != nil {
+		return err
+	}
+	if setup == nil || !setup.Enabled {
+		return ErrTwoFactorNotEnabled
+	}
+	ok, err := s.twoFactor.verify(setup, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return s.twoFactor.setups.Delete(userID)
+}
+
+func (s *AuthService) GetTwoFactorStatus(userID int) (*dto.TwoFactorStatusDTO, error) {
+	if s.twoFactor == nil {
+		return nil, ErrTwoFactorUnavailable
+	}
+	setup, err := s.twoFactor.setups.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &dto.TwoFactorStatusDTO{}
+	if setup != nil && setup.Enabled {
+		status.Enabled = true
+		if status.RecoveryCodesLeft, err = s.twoFactor.setups.CountRecoveryCodes(userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// challenge returns a TwoFactorRequiredError if the user has two-factor
+// auth enabled.
+func (s *AuthService) challenge(user *domain.User) error {
+	if s.twoFactor == nil {
+		return nil
+	}
+	setup, err := s.twoFactor.setups.Get(user.ID)
+	if err != nil {
+		return err
+	}
+	if setup == nil || !setup.Enabled {
+		return nil
+	}
+
+	token, err := jwt.GenerateChallengeToken(user, s.twoFactor.config.ChallengeTTL)
+	if err != nil {
+		return errors.New("failed to generate token")
+	}
+	return &TwoFactorRequiredError{ChallengeToken: token}
+}
+
+// CompleteTwoFactorLogin exchanges a login challenge and a code or
+// recovery code for an access token. Wrong codes count as failed logins.
+func (s *AuthService) CompleteTwoFactorLogin(challengeToken, code string, client domain.ClientInfo) (string, *domain.User, error) {
+	if s.twoFactor == nil {
+		return "", nil, ErrTwoFactorUnavailable
+	}
+	claims, err := jwt.ParseChallengeToken(challengeToken)
+	if err != nil {
+		return "", nil, ErrInvalidLoginChallenge
+	}
+
+	now := time.Now()
+	if s.throttle != nil {
+		if err := s.throttle.Acquire(claims.Login, client.IP, now); err != nil {
+			return "", nil, err
+		}
+	}
+
+	user, err := s.userRepo.GetByID(claims.ID)
+	if err != nil {
+		return "", nil, ErrInvalidLoginChallenge
+	}
+	if user.Banned {
+		return "", nil, ErrUserBanned
+	}
+
+	setup, err := s.twoFactor.setups.Get(user.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	// Two-factor auth may have been turned off since the password step.
+	if setup != nil && setup.Enabled {
+		ok, err := s.twoFactor.verify(setup, code)
+		if err != nil {
+			return "", nil, err
+		}
+		if !ok {
+			return "", nil, ErrInvalidTwoFactorCode
+		}
+	}
+
+	if s.throttle != nil {
+		if err := s.throttle.Release(claims.Login, client.IP); err != nil {
+			log.Println("Failed to release login attempt:", err)
+		}
+		if err := s.throttle.RecordSuccess(user.Login); err != nil {
+			log.Println("Failed to reset login failures:", err)
+		}
+	}
+	return s.issueToken(user, client)
+}
+
+// verify accepts a TOTP code whose time step was not used before, or an
+// unused recovery code.
+func (t *twoFactor) verify(setup *domain.TwoFactor, code string) (bool, error) {
+	code = normalizeTwoFactorCode(code)
+	if len(code) == totp.Digits && strings.Trim(code, "0123456789") == "" {
+		step, ok, err := totp.Match(setup.Secret, code, time.Now(), t.config.Skew)
+		if err != nil || !ok {
+			return false, err
+		}
+		return t.setups.UseStep(setup.UserID, step)
+	}
+	return t.setups.UseRecoveryCode(setup.UserID, hashRecoveryCode(code))
+}
+
+func normalizeTwoFactorCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// generateRecoveryCode returns 40 random bits as "xxxx-xxxx".
+func generateRecoveryCode() (string, error) {
+	raw := make([]byte, 5)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+	code := recoveryCodeEncoding.EncodeToString(raw)
+	return code[:4] + "-" + code[4:], nil
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeTwoFactorCode(code)))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,9 +2,11 @@ package coverage_test
 
 import (
 	"errors"
+	"strings"
 	"testing"
 	"vk/ecom/internal/domain"
 	"vk/ecom/internal/mocks"
+	pwhash "vk/ecom/internal/pkg/password"
 	"vk/ecom/internal/service"
 
 	"github.com/stretchr/testify/assert"
@@ -38,8 +40,9 @@ func TestAuthService_Coverage(t *testing.T) {
 		assert.NotEqual(t, password, user.Password)
 
 		// Verify password is properly hashed
-		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+		ok, err := pwhash.Verify(user.Password, password)
 		assert.NoError(t, err)
+		assert.True(t, ok)
 
 		mockRepo.AssertExpectations(t)
 	})
@@ -60,6 +63,10 @@ func TestAuthService_Coverage(t *testing.T) {
 		}
 
 		mockRepo.On("GetByLogin", login).Return(existingUser, nil)
+		// bcrypt hashes are upgraded to argon2id on login
+		mockRepo.On("RehashPassword", 1, string(hashedPassword), mock.MatchedBy(func(hash string) bool {
+			return strings.HasPrefix(hash, "$argon2id$")
+		})).Return(nil)
 
 		token, user, err := authService.LoginUser(login, password)
 
@@ -13,6 +13,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestAuthHandler_Coverage(t *testing.T) {
@@ -28,7 +29,7 @@ func TestAuthHandler_Coverage(t *testing.T) {
 			Login: "testuser",
 		}
 
-		mockAuthService.On("RegisterUser", "testuser", "password123").Return(user, nil)
+		mockAuthService.On("RegisterUserWithEmail", "testuser", "password123", "").Return(user, nil)
 
 		router := gin.New()
 		router.POST("/register", h.Register)
@@ -69,7 +70,7 @@ func TestAuthHandler_Coverage(t *testing.T) {
 		}
 		token := "jwt.token.here"
 
-		mockAuthService.On("LoginUser", "testuser", "password123").Return(token, user, nil)
+		mockAuthService.On("LoginUserFrom", "testuser", "password123", mock.Anything).Return(token, user, nil)
 
 		router := gin.New()
 		router.POST("/login", h.Login)
@@ -103,7 +104,7 @@ func TestAuthHandler_Coverage(t *testing.T) {
 		mockListingService := new(mocks.MockListingService)
 		h := handler.NewHandler(mockAuthService, mockListingService)
 
-		mockAuthService.On("RegisterUser", "testuser", "password123").Return(nil, errors.New("user already exists"))
+		mockAuthService.On("RegisterUserWithEmail", "testuser", "password123", "").Return(nil, errors.New("user already exists"))
 
 		router := gin.New()
 		router.POST("/register", h.Register)
@@ -129,7 +129,7 @@ func (suite *IntegrationTestSuite) TestListingFlow() {
 	jsonBody, _ := json.Marshal(listingReq)
 	req, _ := http.NewRequest("POST", "/api/listings", bytes.NewBuffer(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", token) // Remove "Bearer " prefix as the middleware expects just the token
+	req.Header.Set("Authorization", "Bearer "+token)
 	w := httptest.NewRecorder()
 
 	suite.router.ServeHTTP(w, req)
@@ -0,0 +1,119 @@
+package fingerprint_test
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/fingerprint"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const bikeDescription = "Red city bicycle with a steel frame, seven gears and new tyres. Barely used, always kept indoors. Pickup only."
+
+func gradient(width, height int, invert bool) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8(x * 255 / width)
+			if invert {
+				v = 255 - v
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+func TestText(t *testing.T) {
+	t.Run("should survive small edits", func(t *testing.T) {
+		original := fingerprint.Text("Red bicycle", bikeDescription)
+		edited := fingerprint.Text("RED BICYCLE!!", "Red city bicycle with a steel frame, seven gears and new tyres. Barely used, always kept indoors. Pickup only")
+
+		assert.LessOrEqual(t, fingerprint.Distance(original, edited), fingerprint.MaxBandDistance)
+	})
+
+	t.Run("should tell different listings apart", func(t *testing.T) {
+		bike := fingerprint.Text("Red bicycle", bikeDescription)
+		sofa := fingerprint.Text("Leather sofa", "Three seat leather sofa in dark brown, some wear on the armrests. Must go this week.")
+
+		assert.Greater(t, fingerprint.Distance(bike, sofa), 10)
+	})
+
+	t.Run("should return zero for empty text", func(t *testing.T) {
+		assert.Zero(t, fingerprint.Text("", " ... "))
+	})
+}
+
+func TestDHash(t *testing.T) {
+	small, err := fingerprint.DHash(gradient(64, 48, false))
+	require.NoError(t, err)
+	large, err := fingerprint.DHash(gradient(640, 480, false))
+	require.NoError(t, err)
+	inverted, err := fingerprint.DHash(gradient(64, 48, true))
+	require.NoError(t, err)
+
+	assert.LessOrEqual(t, fingerprint.Distance(small, large), fingerprint.MaxBandDistance)
+	assert.Greater(t, fingerprint.Distance(small, inverted), 32)
+}
+
+func TestCompare(t *testing.T) {
+	hash := fingerprint.Text("Red bicycle", bikeDescription)
+	imageHash := uint64(0xF0F0)
+	otherImage := imageHash ^ 0b111
+
+	target := &domain.Listing{ID: 1, TextHash: hash, ImageHash: &imageHash}
+
+	t.Run("should match close text", func(t *testing.T) {
+		duplicate, ok := fingerprint.Compare(target, &domain.Listing{ID: 2, AuthorID: 7, TextHash: hash ^ 1}, 3)
+
+		require.True(t, ok)
+		assert.Equal(t, int64(2), duplicate.ListingID)
+		assert.Equal(t, int64(7), duplicate.AuthorID)
+		assert.Equal(t, 1, duplicate.TextDistance)
+		assert.Nil(t, duplicate.ImageDistance)
+	})
+
+	t.Run("should match close images with different text", func(t *testing.T) {
+		duplicate, ok := fingerprint.Compare(target, &domain.Listing{ID: 2, TextHash: ^hash, ImageHash: &otherImage}, 3)
+
+		require.True(t, ok)
+		require.NotNil(t, duplicate.ImageDistance)
+		assert.Equal(t, 3, *duplicate.ImageDistance)
+	})
+
+	t.Run("should not match listings without a fingerprint", func(t *testing.T) {
+		_, ok := fingerprint.Compare(&domain.Listing{ID: 1}, &domain.Listing{ID: 2}, 3)
+
+		assert.False(t, ok)
+	})
+}
+
+func TestHTTPImageHasher(t *testing.T) {
+	hasher := fingerprint.NewHTTPImageHasher(time.Second, 1<<20)
+
+	t.Run("should only fetch http and https URLs", func(t *testing.T) {
+		_, err := hasher.Hash("file:///etc/passwd")
+
+		assert.ErrorIs(t, err, fingerprint.ErrUnsupportedScheme)
+	})
+
+	t.Run("should refuse internal addresses", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			png.Encode(w, gradient(64, 64, false))
+		}))
+		defer server.Close()
+
+		for _, url := range []string{server.URL, "http://169.254.169.254/latest/meta-data/", "http://[::1]:1/"} {
+			_, err := hasher.Hash(url)
+
+			assert.ErrorIs(t, err, fingerprint.ErrForbiddenAddress, url)
+		}
+	})
+}
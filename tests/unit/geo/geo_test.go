@@ -0,0 +1,51 @@
+package geo_test
+
+import (
+	"testing"
+	"vk/ecom/internal/pkg/geo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistanceKm(t *testing.T) {
+	moscow := geo.Point{Lat: 55.7558, Lng: 37.6173}
+	petersburg := geo.Point{Lat: 59.9343, Lng: 30.3351}
+
+	assert.InDelta(t, 634, geo.DistanceKm(moscow, petersburg), 5)
+	assert.Equal(t, 0.0, geo.DistanceKm(moscow, moscow))
+	assert.InDelta(t, 111.2, geo.DistanceKm(geo.Point{Lat: 0, Lng: 179.5}, geo.Point{Lat: 0, Lng: -179.5}), 0.5)
+}
+
+func TestBoundingBox(t *testing.T) {
+	t.Run("should contain every point within the radius", func(t *testing.T) {
+		center := geo.Point{Lat: 55.7558, Lng: 37.6173}
+		box := geo.BoundingBox(center, 50)
+
+		assert.False(t, box.WrapsLng())
+		for _, p := range []geo.Point{
+			{Lat: 56.2, Lng: 37.6173},
+			{Lat: 55.7558, Lng: 38.4},
+			{Lat: 55.4, Lng: 37.2},
+		} {
+			assert.Less(t, geo.DistanceKm(center, p), 50.0)
+			assert.True(t, box.Contains(p))
+		}
+		assert.False(t, box.Contains(geo.Point{Lat: 56.3, Lng: 37.6173}))
+	})
+
+	t.Run("should wrap around the antimeridian", func(t *testing.T) {
+		box := geo.BoundingBox(geo.Point{Lat: 0, Lng: 179.9}, 50)
+
+		assert.True(t, box.WrapsLng())
+		assert.True(t, box.Contains(geo.Point{Lat: 0, Lng: -179.9}))
+		assert.True(t, box.Contains(geo.Point{Lat: 0, Lng: 179.8}))
+		assert.False(t, box.Contains(geo.Point{Lat: 0, Lng: 0}))
+	})
+
+	t.Run("should span all longitudes near a pole", func(t *testing.T) {
+		box := geo.BoundingBox(geo.Point{Lat: 89.9, Lng: 10}, 50)
+
+		assert.Equal(t, 90.0, box.MaxLat)
+		assert.True(t, box.Contains(geo.Point{Lat: 89.8, Lng: -170}))
+	})
+}
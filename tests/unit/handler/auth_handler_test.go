@@ -4,15 +4,20 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
 	"vk/ecom/internal/handler"
 	"vk/ecom/internal/mocks"
+	"vk/ecom/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func setupTestRouter() *gin.Engine {
@@ -31,7 +36,7 @@ func TestHandler_Register(t *testing.T) {
 			Login: "testuser",
 		}
 
-		mockAuthService.On("RegisterUser", "testuser", "password123").Return(user, nil)
+		mockAuthService.On("RegisterUserWithEmail", "testuser", "password123", "").Return(user, nil)
 
 		router := setupTestRouter()
 		router.POST("/register", h.Register)
@@ -88,7 +93,7 @@ func TestHandler_Register(t *testing.T) {
 		mockListingService := new(mocks.MockListingService)
 		h := handler.NewHandler(mockAuthService, mockListingService)
 
-		mockAuthService.On("RegisterUser", "testuser", "password123").Return(nil, errors.New("user already exists"))
+		mockAuthService.On("RegisterUserWithEmail", "testuser", "password123", "").Return(nil, errors.New("user already exists"))
 
 		router := setupTestRouter()
 		router.POST("/register", h.Register)
@@ -128,7 +133,7 @@ func TestHandler_Login(t *testing.T) {
 		}
 		token := "jwt.token.here"
 
-		mockAuthService.On("LoginUser", "testuser", "password123").Return(token, user, nil)
+		mockAuthService.On("LoginUserFrom", "testuser", "password123", mock.Anything).Return(token, user, nil)
 
 		router := setupTestRouter()
 		router.POST("/login", h.Login)
@@ -184,7 +189,7 @@ func TestHandler_Login(t *testing.T) {
 		mockListingService := new(mocks.MockListingService)
 		h := handler.NewHandler(mockAuthService, mockListingService)
 
-		mockAuthService.On("LoginUser", "testuser", "wrongpassword").Return("", nil, errors.New("invalid credentials"))
+		mockAuthService.On("LoginUserFrom", "testuser", "wrongpassword", mock.Anything).Return("", nil, errors.New("invalid credentials"))
 
 		router := setupTestRouter()
 		router.POST("/login", h.Login)
@@ -210,4 +215,173 @@ func TestHandler_Login(t *testing.T) {
 
 		mockAuthService.AssertExpectations(t)
 	})
+
+	t.Run("should return a challenge when two-factor auth is on", func(t *testing.T) {
+		mockAuthService := new(mocks.MockAuthService)
+		h := handler.NewHandler(mockAuthService, new(mocks.MockListingService))
+		challenge := &service.TwoFactorRequiredError{ChallengeToken: "challenge"}
+		mockAuthService.On("LoginUserFrom", "testuser", "password123", mock.Anything).Return("", nil, challenge)
+
+		router := setupTestRouter()
+		router.POST("/login", h.Login)
+
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(`{"login":"testuser","password":"password123"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, true, response["two_factor_required"])
+		assert.Equal(t, "challenge", response["challenge_token"])
+		assert.NotContains(t, response, "token")
+	})
+
+	t.Run("should return 429 with Retry-After when throttled", func(t *testing.T) {
+		mockAuthService := new(mocks.MockAuthService)
+		mockListingService := new(mocks.MockListingService)
+		h := handler.NewHandler(mockAuthService, mockListingService)
+
+		throttled := &service.LoginThrottledError{RetryAfter: 1500 * time.Millisecond}
+		mockAuthService.On("LoginUserFrom", "testuser", "password123", mock.Anything).Return("", nil, throttled)
+
+		router := setupTestRouter()
+		router.POST("/login", h.Login)
+
+		jsonBody, _ := json.Marshal(map[string]string{"login": "testuser", "password": "password123"})
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "2", w.Header().Get("Retry-After"))
+
+		mockAuthService.AssertExpectations(t)
+	})
+}
+
+func TestHandler_PasswordReset(t *testing.T) {
+	t.Run("should accept reset requests for any login", func(t *testing.T) {
+		mockAuthService := new(mocks.MockAuthService)
+		h := handler.NewHandler(mockAuthService, new(mocks.MockListingService))
+		mockAuthService.On("RequestPasswordReset", "nobody").Return(nil)
+
+		router := setupTestRouter()
+		router.POST("/password-reset", h.RequestPasswordReset)
+
+		req, _ := http.NewRequest("POST", "/password-reset", bytes.NewBufferString(`{"login":"nobody"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		mockAuthService.AssertExpectations(t)
+	})
+
+	t.Run("should reject an invalid reset token", func(t *testing.T) {
+		mockAuthService := new(mocks.MockAuthService)
+		h := handler.NewHandler(mockAuthService, new(mocks.MockListingService))
+		mockAuthService.On("ResetPassword", "bad", "newpassword").Return(service.ErrInvalidResetToken)
+
+		router := setupTestRouter()
+		router.POST("/password-reset/confirm", h.ConfirmPasswordReset)
+
+		req, _ := http.NewRequest("POST", "/password-reset/confirm", bytes.NewBufferString(`{"token":"bad","new_password":"newpassword"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockAuthService.AssertExpectations(t)
+	})
+
+	t.Run("should require the current password to change it", func(t *testing.T) {
+		mockAuthService := new(mocks.MockAuthService)
+		h := handler.NewHandler(mockAuthService, new(mocks.MockListingService))
+		mockAuthService.On("ChangePassword", 7, "wrong", "newpassword").Return(service.ErrWrongPassword)
+
+		router := setupTestRouter()
+		router.POST("/me/password", func(c *gin.Context) { c.Set("user_id", int64(7)) }, h.ChangePassword)
+
+		req, _ := http.NewRequest("POST", "/me/password", bytes.NewBufferString(`{"current_password":"wrong","new_password":"newpassword"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockAuthService.AssertExpectations(t)
+	})
+}
+
+func TestHandler_ExternalLogin(t *testing.T) {
+	t.Run("should reject callbacks from another browser", func(t *testing.T) {
+		mockAuthService := new(mocks.MockAuthService)
+		mockListingService := new(mocks.MockListingService)
+		h := handler.NewHandler(mockAuthService, mockListingService)
+
+		router := setupTestRouter()
+		router.GET("/oidc/:provider/callback", h.ExternalLoginCallback)
+
+		req, _ := http.NewRequest(http.MethodGet, "/oidc/stub/callback?code=abc&state=victim", nil)
+		req.AddCookie(&http.Cookie{Name: "oidc_state", Value: "attacker"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockAuthService.AssertNotCalled(t, "CompleteExternalLogin", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should complete callbacks in the browser that began the login", func(t *testing.T) {
+		mockAuthService := new(mocks.MockAuthService)
+		mockListingService := new(mocks.MockListingService)
+		h := handler.NewHandler(mockAuthService, mockListingService)
+
+		user := &domain.User{ID: 1, Login: "alice"}
+		mockAuthService.On("BeginExternalLogin", "stub", 0).Return("https://idp.test/authorize?state=s1", nil)
+		mockAuthService.On("CompleteExternalLogin", "stub", "abc", "s1", mock.Anything).
+			Return(&dto.ExternalLoginResult{User: user, Token: "token"}, nil)
+
+		router := setupTestRouter()
+		router.GET("/oidc/:provider", h.BeginExternalLogin)
+		router.GET("/oidc/:provider/callback", h.ExternalLoginCallback)
+
+		req, _ := http.NewRequest(http.MethodGet, "/oidc/stub", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusFound, w.Code)
+		cookies := w.Result().Cookies()
+		assert.Len(t, cookies, 1)
+
+		req, _ = http.NewRequest(http.MethodGet, "/oidc/stub/callback?code=abc&state=s1", nil)
+		req.AddCookie(cookies[0])
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockAuthService.AssertExpectations(t)
+	})
+
+	t.Run("should blame the provider only for provider errors", func(t *testing.T) {
+		mockAuthService := new(mocks.MockAuthService)
+		mockListingService := new(mocks.MockListingService)
+		h := handler.NewHandler(mockAuthService, mockListingService)
+
+		mockAuthService.On("BeginExternalLogin", "down", 0).
+			Return("", fmt.Errorf("%w: discovery timed out", service.ErrLoginProviderFailed))
+		mockAuthService.On("BeginExternalLogin", "broken", 0).Return("", errors.New("state store is down"))
+
+		router := setupTestRouter()
+		router.GET("/oidc/:provider", h.BeginExternalLogin)
+
+		for provider, status := range map[string]int{"down": http.StatusBadGateway, "broken": http.StatusInternalServerError} {
+			req, _ := http.NewRequest(http.MethodGet, "/oidc/"+provider, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, status, w.Code, provider)
+		}
+	})
 }
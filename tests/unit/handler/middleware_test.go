@@ -0,0 +1,200 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/handler"
+	"vk/ecom/internal/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newAuthRouter(h *handler.Handler) *gin.Engine {
+	router := setupTestRouter()
+	router.POST("/login", h.Login)
+	protected := router.Group("/", h.AuthMiddleware())
+	protected.GET("/me", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"user_id": c.GetInt64("user_id")}) })
+	protected.POST("/me", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return router
+}
+
+func TestHandler_AuthMiddleware(t *testing.T) {
+	mockAuthService := new(mocks.MockAuthService)
+	mockAuthService.On("ValidateToken", "good.token").Return(&domain.User{ID: 7, Login: "alice"}, nil)
+	mockAuthService.On("ValidateToken", "bad.token").Return(nil, errors.New("invalid token"))
+	router := newAuthRouter(handler.NewHandler(mockAuthService, new(mocks.MockListingService)))
+
+	request := func(authorization string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/me", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("should accept a bearer token with any scheme case", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("Bearer good.token").Code)
+		assert.Equal(t, http.StatusOK, request("bearer good.token").Code)
+	})
+
+	t.Run("should challenge requests without credentials", func(t *testing.T) {
+		w := request("")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, `Bearer realm="ecom"`, w.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("should reject a raw token or another scheme as invalid_request", func(t *testing.T) {
+		for _, header := range []string{"good.token", "Basic Z29vZA==", "Bearer ", "Bearer a b"} {
+			w := request(header)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, header)
+			assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="invalid_request"`)
+		}
+	})
+
+	t.Run("should report an invalid token", func(t *testing.T) {
+		w := request("Bearer bad.token")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+	})
+}
+
+func TestHandler_CookieSessions(t *testing.T) {
+	mockAuthService := new(mocks.MockAuthService)
+	mockAuthService.On("LoginUserFrom", "alice", "password123", mock.Anything).Return("session.token", &domain.User{ID: 7, Login: "alice"}, nil)
+	mockAuthService.On("ValidateToken", "session.token").Return(&domain.User{ID: 7, Login: "alice"}, nil)
+	config := handler.DefaultCookieSessionConfig([]byte("0123456789abcdef0123456789abcdef"))
+	router := newAuthRouter(handler.NewHandler(mockAuthService, new(mocks.MockListingService), handler.WithCookieSessions(config)))
+
+	body, _ := json.Marshal(map[string]string{"login": "alice", "password": "password123", "session": "cookie"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Nil(t, response["token"])
+	csrf, _ := response["csrf_token"].(string)
+	require.NotEmpty(t, csrf)
+
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	require.Contains(t, cookies, "session")
+	require.Contains(t, cookies, "csrf_token")
+	assert.True(t, cookies["session"].HttpOnly)
+	assert.True(t, cookies["session"].Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookies["session"].SameSite)
+	assert.False(t, cookies["csrf_token"].HttpOnly)
+	assert.Equal(t, csrf, cookies["csrf_token"].Value)
+
+	send := func(method, csrfCookie, csrfHeader string) int {
+		req, _ := http.NewRequest(method, "/me", nil)
+		req.AddCookie(cookies["session"])
+		if csrfCookie != "" {
+			req.AddCookie(&http.Cookie{Name: "csrf_token", Value: csrfCookie})
+		}
+		if csrfHeader != "" {
+			req.Header.Set("X-CSRF-Token", csrfHeader)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("should authenticate safe requests by cookie", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, send("GET", "", ""))
+	})
+
+	t.Run("should require the double-submitted CSRF token on state changes", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, send("POST", csrf, csrf))
+		assert.Equal(t, http.StatusForbidden, send("POST", csrf, ""))
+		assert.Equal(t, http.StatusForbidden, send("POST", "", csrf))
+	})
+
+	t.Run("should reject a matching pair not issued for the session", func(t *testing.T) {
+		forged := "00112233445566778899aabbccddeeff.00"
+
+		assert.Equal(t, http.StatusForbidden, send("POST", forged, forged))
+	})
+}
+
+func TestHandler_APIKeyAuth(t *testing.T) {
+	mockAuthService := new(mocks.MockAuthService)
+	user := &domain.User{ID: 7, Login: "importer"}
+	writeKey := &domain.APIKey{ID: 1, UserID: 7, Scopes: []string{domain.ScopeListingsWrite}}
+	readKey := &domain.APIKey{ID: 2, UserID: 7, Scopes: []string{domain.ScopeListingsRead}}
+	mockAuthService.On("ValidateAPIKey", "ek_write_secret").Return(user, writeKey, nil)
+	mockAuthService.On("ValidateAPIKey", "ek_read_secret").Return(user, readKey, nil)
+	mockAuthService.On("ValidateAPIKey", "ek_revoked_secret").Return(nil, nil, errors.New("api key is invalid or revoked"))
+	h := handler.NewHandler(mockAuthService, new(mocks.MockListingService))
+
+	router := setupTestRouter()
+	protected := router.Group("/api", h.AuthMiddleware())
+	h.APIKeyRoutes(protected, domain.ScopeListingsWrite).POST("/listings", func(c *gin.Context) { c.JSON(http.StatusCreated, gin.H{"user_id": c.GetInt64("user_id")}) })
+	protected.POST("/me/password", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	listings := router.Group("/api/listings", h.AuthMiddleware())
+	h.APIKeyRoutes(listings, domain.ScopeListingsRead).GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(method, path, key string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	request := func(path, key string) *httptest.ResponseRecorder {
+		return send("POST", path, key)
+	}
+
+	t.Run("should accept a key with the route's scope", func(t *testing.T) {
+		w := request("/api/listings", "ek_write_secret")
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.JSONEq(t, `{"user_id": 7}`, w.Body.String())
+	})
+
+	t.Run("should refuse a key without the route's scope", func(t *testing.T) {
+		w := request("/api/listings", "ek_read_secret")
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), `scope="listings:write"`)
+	})
+
+	t.Run("should match scopes on group root routes", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, send("GET", "/api/listings/", "ek_read_secret").Code)
+		assert.Equal(t, http.StatusForbidden, send("GET", "/api/listings/", "ek_write_secret").Code)
+	})
+
+	t.Run("should refuse keys on routes without scopes", func(t *testing.T) {
+		w := request("/api/me/password", "ek_write_secret")
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`)
+	})
+
+	t.Run("should report a revoked key as an invalid token", func(t *testing.T) {
+		w := request("/api/listings", "ek_revoked_secret")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+	})
+
+	mockAuthService.AssertNotCalled(t, "ValidateToken", mock.Anything)
+}
@@ -0,0 +1,90 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"vk/ecom/internal/handler"
+	"vk/ecom/internal/mocks"
+	"vk/ecom/internal/pkg/ratelimit"
+	"vk/ecom/internal/repository/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRateLimitedRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	limiter, err := ratelimit.NewLimiter(memory.NewInMemoryRateLimitRepository(), ratelimit.Config{
+		Policies: map[string]ratelimit.Policy{
+			"write": {Limit: 2, Period: time.Minute, KeyBy: ratelimit.KeyByUser},
+		},
+		Routes: map[string]string{"POST /api/listings": "write"},
+	})
+	require.NoError(t, err)
+	h := handler.NewHandler(new(mocks.MockAuthService), new(mocks.MockListingService), handler.WithRateLimiter(limiter))
+
+	router := setupTestRouter()
+	api := router.Group("/api")
+	api.Use(func(c *gin.Context) {
+		if id := c.GetHeader("X-Test-User"); id != "" {
+			c.Set("user_id", int64(len(id)))
+		}
+	}, h.RateLimitMiddleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	api.POST("/listings", ok)
+	api.GET("/listings", ok)
+	return router
+}
+
+func TestHandler_RateLimitMiddleware(t *testing.T) {
+	t.Run("limits per user with headers", func(t *testing.T) {
+		router := setupRateLimitedRouter(t)
+		send := func(user string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("POST", "/api/listings", nil)
+			req.Header.Set("X-Test-User", user)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		w := send("a")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+
+		assert.Equal(t, http.StatusOK, send("a").Code)
+		w = send("a")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "30", w.Header().Get("Retry-After"))
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+		assert.Equal(t, http.StatusOK, send("bb").Code, "another user has its own bucket")
+	})
+
+	t.Run("limits anonymous requests by address", func(t *testing.T) {
+		router := setupRateLimitedRouter(t)
+		for i := 0; i < 2; i++ {
+			req, _ := http.NewRequest("POST", "/api/listings", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+		req, _ := http.NewRequest("POST", "/api/listings", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+
+	t.Run("skips routes without a policy", func(t *testing.T) {
+		router := setupRateLimitedRouter(t)
+		req, _ := http.NewRequest("GET", "/api/listings", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	})
+}
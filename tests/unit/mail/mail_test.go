@@ -0,0 +1,28 @@
+package mail_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"vk/ecom/internal/pkg/mail"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	mailer := mail.FileMailer{Dir: dir}
+
+	require.NoError(t, mailer.Send(&mail.Message{To: "alice@example.com", Subject: "Hello", Body: "First"}))
+	require.NoError(t, mailer.Send(&mail.Message{To: "bob@example.com", Subject: "Hello", Body: "Second"}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Subject: Hello\r\n")
+	assert.Regexp(t, `(?s)^To: \w+@example\.com\r\n.*\r\n\r\n(First|Second)$`, string(data))
+}
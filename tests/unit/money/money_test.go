@@ -0,0 +1,80 @@
+package money_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"vk/ecom/internal/pkg/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoney_String(t *testing.T) {
+	assert.Equal(t, "12.05 USD", money.New(1205, "USD").String())
+	assert.Equal(t, "-0.50 EUR", money.New(-50, "EUR").String())
+	assert.Equal(t, "1500 JPY", money.New(1500, "JPY").String())
+}
+
+func TestNormalize(t *testing.T) {
+	code, err := money.Normalize(" usd ")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", code)
+
+	_, err = money.Normalize("XXX")
+	assert.ErrorIs(t, err, money.ErrUnsupportedCurrency)
+}
+
+func TestConvert(t *testing.T) {
+	rates, err := money.NewStaticRates("RUB", map[string]float64{"USD": 0.0125, "JPY": 1.6})
+	require.NoError(t, err)
+
+	t.Run("should convert through the base currency", func(t *testing.T) {
+		converted, err := money.Convert(money.New(10000, "USD"), "RUB", rates)
+
+		require.NoError(t, err)
+		assert.Equal(t, money.New(800000, "RUB"), converted)
+	})
+
+	t.Run("should account for currency exponents", func(t *testing.T) {
+		converted, err := money.Convert(money.New(100000, "RUB"), "JPY", rates)
+
+		require.NoError(t, err)
+		assert.Equal(t, money.New(1600, "JPY"), converted)
+
+		factor, err := money.Factor(rates, "JPY", "USD")
+		require.NoError(t, err)
+		assert.InDelta(t, 0.78125, factor, 1e-9)
+	})
+
+	t.Run("should fail without a rate", func(t *testing.T) {
+		_, err := money.Convert(money.New(100, "EUR"), "RUB", rates)
+
+		assert.ErrorIs(t, err, money.ErrNoRate)
+	})
+}
+
+func TestLoadRatesFile(t *testing.T) {
+	t.Run("should load rates against the base", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rates.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"base": "EUR", "rates": {"USD": 1.1}}`), 0o600))
+
+		rates, err := money.LoadRatesFile(path)
+
+		require.NoError(t, err)
+		assert.Equal(t, "EUR", rates.Base())
+		rate, err := rates.Rate("USD", "EUR")
+		require.NoError(t, err)
+		assert.InDelta(t, 1/1.1, rate, 1e-9)
+	})
+
+	t.Run("should reject unknown currencies and invalid rates", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rates.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"base": "RUB", "rates": {"ABC": 2}}`), 0o600))
+		_, err := money.LoadRatesFile(path)
+		assert.ErrorIs(t, err, money.ErrUnsupportedCurrency)
+
+		_, err = money.NewStaticRates("RUB", map[string]float64{"USD": 0})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,99 @@
+package oidc_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"vk/ecom/internal/pkg/oidc"
+	"vk/ecom/tests/oidcstub"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://shop.test/api/auth/oidc/stub/callback"
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	stub := oidcstub.New()
+	defer stub.Close()
+	stub.SetUser(oidcstub.User{Subject: "42", Email: "alice@example.com", EmailVerified: true})
+	provider := oidc.NewProvider(stub.Config("stub", redirectURL), http.DefaultClient)
+
+	verifier, err := oidc.RandomString()
+	require.NoError(t, err)
+	authURL, err := provider.AuthCodeURL("state-1", "nonce-1", verifier)
+	require.NoError(t, err)
+
+	callback, err := stub.Authorize(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "state-1", callback.Query().Get("state"))
+	code := callback.Query().Get("code")
+
+	claims, err := provider.Exchange(code, verifier)
+	require.NoError(t, err)
+	assert.Equal(t, "42", claims.Subject)
+	assert.Equal(t, "alice@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, "nonce-1", claims.Nonce)
+
+	_, err = provider.Exchange(code, verifier)
+	assert.Error(t, err, "codes are single-use")
+}
+
+func TestProvider_RequiresPKCEVerifier(t *testing.T) {
+	stub := oidcstub.New()
+	defer stub.Close()
+	stub.SetUser(oidcstub.User{Subject: "42"})
+	provider := oidc.NewProvider(stub.Config("stub", redirectURL), http.DefaultClient)
+
+	authURL, err := provider.AuthCodeURL("state", "nonce", "the-real-verifier-which-is-long-enough")
+	require.NoError(t, err)
+	callback, err := stub.Authorize(authURL)
+	require.NoError(t, err)
+
+	_, err = provider.Exchange(callback.Query().Get("code"), "an-intercepted-code-without-the-verifier")
+	assert.Error(t, err)
+}
+
+func TestProvider_Verify(t *testing.T) {
+	stub := oidcstub.New()
+	defer stub.Close()
+	provider := oidc.NewProvider(stub.Config("stub", redirectURL), http.DefaultClient)
+	user := oidcstub.User{Subject: "42"}
+
+	token, err := stub.SignIDToken(user, "nonce", stub.ClientID)
+	require.NoError(t, err)
+	_, err = provider.Verify(token)
+	assert.NoError(t, err)
+
+	otherAudience, err := stub.SignIDToken(user, "nonce", "another-client")
+	require.NoError(t, err)
+	_, err = provider.Verify(otherAudience)
+	assert.Error(t, err)
+
+	other := oidcstub.New()
+	defer other.Close()
+	foreign, err := other.SignIDToken(user, "nonce", stub.ClientID)
+	require.NoError(t, err)
+	_, err = provider.Verify(foreign)
+	assert.Error(t, err, "tokens from another issuer and key are rejected")
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "oidc.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"providers": [
+		{"name": "google", "issuer": "https://accounts.google.com", "client_id": "id", "client_secret": "secret",
+		 "redirect_url": "https://shop.example.com/api/auth/oidc/google/callback", "scopes": ["email"]}
+	]}`), 0o644))
+
+	configs, err := oidc.LoadConfig(path)
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "google", configs[0].Name)
+	assert.Equal(t, []string{"email"}, configs[0].Scopes)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"providers": [{"name": "Bad Name", "issuer": "x", "client_id": "x", "redirect_url": "x"}]}`), 0o644))
+	_, err = oidc.LoadConfig(path)
+	assert.Error(t, err)
+}
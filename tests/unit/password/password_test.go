@@ -0,0 +1,87 @@
+package password_test
+
+import (
+	"strings"
+	"testing"
+	"vk/ecom/internal/pkg/password"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func fastParams() password.Argon2idParams {
+	params := password.DefaultArgon2idParams()
+	params.Memory = 1024
+	params.Iterations = 1
+	return params
+}
+
+func TestArgon2id(t *testing.T) {
+	hasher := password.NewArgon2id(fastParams())
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), hash)
+
+	other, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "salts are random")
+
+	ok, err := password.Verify(hash, "correct horse")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = password.Verify(hash, "wrong horse")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.False(t, hasher.NeedsRehash(hash))
+	stronger := fastParams()
+	stronger.Iterations = 2
+	assert.True(t, password.NewArgon2id(stronger).NeedsRehash(hash))
+	longerKey := fastParams()
+	longerKey.KeyLength = 64
+	assert.True(t, password.NewArgon2id(longerKey).NeedsRehash(hash))
+}
+
+func TestBcrypt(t *testing.T) {
+	hasher := password.NewBcrypt(bcrypt.MinCost)
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+
+	ok, err := password.Verify(hash, "correct horse")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = password.Verify(hash, "wrong horse")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.False(t, hasher.NeedsRehash(hash))
+	assert.True(t, password.NewBcrypt(bcrypt.MinCost+1).NeedsRehash(hash))
+	assert.True(t, password.NewArgon2id(fastParams()).NeedsRehash(hash))
+
+	_, err = hasher.Hash(strings.Repeat("a", 73))
+	assert.ErrorIs(t, err, password.ErrTooLong)
+}
+
+func TestVerify_UnknownHash(t *testing.T) {
+	for _, encoded := range []string{"", "plaintext", "$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5", "$argon2id$v=19$m=1024$c2FsdA$a2V5"} {
+		ok, err := password.Verify(encoded, "plaintext")
+		assert.ErrorIs(t, err, password.ErrUnknownHash, encoded)
+		assert.False(t, ok)
+	}
+}
+
+func TestBlocklist(t *testing.T) {
+	list, err := password.NewBlocklist(strings.NewReader("# comment\n\nHunter2\n  letmein  \n"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.Len())
+	assert.True(t, list.Contains("hunter2"))
+	assert.True(t, list.Contains("LetMeIn"))
+	assert.False(t, list.Contains("# comment"))
+	assert.False(t, list.Contains("correct horse battery staple"))
+
+	common := password.CommonPasswords()
+	assert.True(t, common.Contains("password123"))
+	assert.True(t, common.Contains("Qwerty123"))
+	assert.False(t, common.Contains("correct horse battery staple"))
+}
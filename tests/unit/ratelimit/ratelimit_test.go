@@ -0,0 +1,116 @@
+package ratelimit_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"vk/ecom/internal/pkg/ratelimit"
+	"vk/ecom/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLimiter(t *testing.T, policy ratelimit.Policy) *ratelimit.Limiter {
+	t.Helper()
+	limiter, err := ratelimit.NewLimiter(memory.NewInMemoryRateLimitRepository(), ratelimit.Config{
+		Policies: map[string]ratelimit.Policy{"test": policy},
+		Routes:   map[string]string{"POST /api/listings": "test"},
+	})
+	require.NoError(t, err)
+	return limiter
+}
+
+func TestLimiter_AllowsBurstThenRefills(t *testing.T) {
+	limiter := newLimiter(t, ratelimit.Policy{Limit: 6, Period: time.Minute, Burst: 3, KeyBy: ratelimit.KeyByUser})
+	policy, ok := limiter.Policy("POST", "/api/listings")
+	require.True(t, ok)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 2; i >= 0; i-- {
+		result, err := limiter.Allow(policy, "user:1", now)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result, err := limiter.Allow(policy, "user:1", now)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 10*time.Second, result.RetryAfter)
+	assert.Equal(t, 30*time.Second, result.ResetAfter)
+
+	other, err := limiter.Allow(policy, "user:2", now)
+	require.NoError(t, err)
+	assert.True(t, other.Allowed, "keys are limited separately")
+
+	// One request is earned back every 10 seconds.
+	result, err = limiter.Allow(policy, "user:1", now.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestLimiter_Policy(t *testing.T) {
+	limiter := newLimiter(t, ratelimit.Policy{Limit: 1, Period: time.Second, KeyBy: ratelimit.KeyByIP})
+
+	policy, ok := limiter.Policy("POST", "/api/listings")
+	assert.True(t, ok)
+	assert.Equal(t, "test", policy.Name)
+
+	_, ok = limiter.Policy("GET", "/api/listings")
+	assert.False(t, ok, "routes without a policy and no default are not limited")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, ratelimit.DefaultConfig().Validate())
+
+	config := ratelimit.DefaultConfig()
+	config.Routes["GET /api/listings/"] = "missing"
+	assert.Error(t, config.Validate())
+
+	config = ratelimit.DefaultConfig()
+	config.Policies["bad"] = ratelimit.Policy{Limit: 0, Period: time.Minute, KeyBy: ratelimit.KeyByIP}
+	assert.Error(t, config.Validate())
+
+	config = ratelimit.DefaultConfig()
+	config.Policies["bad"] = ratelimit.Policy{Limit: 2000, Period: time.Microsecond, KeyBy: ratelimit.KeyByIP}
+	assert.Error(t, config.Validate(), "limits above one per nanosecond have no interval")
+}
+
+func TestLimiter_RejectsInvalidPolicies(t *testing.T) {
+	limiter := newLimiter(t, ratelimit.Policy{Limit: 1, Period: time.Second, KeyBy: ratelimit.KeyByIP})
+
+	for _, policy := range []ratelimit.Policy{
+		{Name: "zero", KeyBy: ratelimit.KeyByIP},
+		{Name: "fast", Limit: 10, Period: time.Nanosecond, KeyBy: ratelimit.KeyByIP},
+	} {
+		_, err := limiter.Allow(policy, "203.0.113.7", time.Now())
+		assert.Error(t, err, policy.Name)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"policies": {
+			"write": {"limit": 100, "period": "1h", "burst": 20},
+			"reads": {"limit": 600, "period": "1m", "key_by": "ip"}
+		},
+		"routes": {"GET /api/listings/": "reads"},
+		"default": "reads"
+	}`), 0o644))
+
+	config, err := ratelimit.LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Policy{Limit: 100, Period: time.Hour, Burst: 20, KeyBy: ratelimit.KeyByUser}, config.Policies["write"])
+	assert.Equal(t, "reads", config.Routes["GET /api/listings/"])
+	assert.Equal(t, "register", config.Routes["POST /api/auth/register"], "default routes are kept")
+	assert.Equal(t, "reads", config.Default)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"policies": {"write": {"limit": 1, "period": "soon"}}}`), 0o644))
+	_, err = ratelimit.LoadConfig(path)
+	assert.Error(t, err)
+}
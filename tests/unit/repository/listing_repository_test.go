@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/geo"
 	"vk/ecom/internal/repository/postgres"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -28,8 +29,8 @@ func TestListingRepository_Create(t *testing.T) {
 		}
 
 		expectedID := int64(1)
-		mock.ExpectQuery(`INSERT INTO listings \(title, description, image_url, price, author_id, created_at\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\) RETURNING id`).
-			WithArgs(listing.Title, listing.Description, listing.ImageURL, listing.Price, listing.AuthorID, sqlmock.AnyArg()).
+		mock.ExpectQuery(`INSERT INTO listings \(title, description, image_url, price, author_id, created_at, listing_type, status, text_hash, image_hash, category, latitude, longitude, city, region, attributes, currency\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10, \$11, \$12, \$13, \$14, \$15, \$16, \$17\) RETURNING id`).
+			WithArgs(listing.Title, listing.Description, listing.ImageURL, listing.Price, listing.AuthorID, sqlmock.AnyArg(), domain.ListingTypeFixed, domain.ListingStatusActive, sqlmock.AnyArg(), nil, "", nil, nil, "", "", "{}", "RUB").
 			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(expectedID))
 
 		err = repo.Create(listing)
@@ -54,8 +55,8 @@ func TestListingRepository_Create(t *testing.T) {
 			AuthorID:    1,
 		}
 
-		mock.ExpectQuery(`INSERT INTO listings \(title, description, image_url, price, author_id, created_at\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\) RETURNING id`).
-			WithArgs(listing.Title, listing.Description, listing.ImageURL, listing.Price, listing.AuthorID, sqlmock.AnyArg()).
+		mock.ExpectQuery(`INSERT INTO listings \(title, description, image_url, price, author_id, created_at, listing_type, status, text_hash, image_hash, category, latitude, longitude, city, region, attributes, currency\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10, \$11, \$12, \$13, \$14, \$15, \$16, \$17\) RETURNING id`).
+			WithArgs(listing.Title, listing.Description, listing.ImageURL, listing.Price, listing.AuthorID, sqlmock.AnyArg(), domain.ListingTypeFixed, domain.ListingStatusActive, sqlmock.AnyArg(), nil, "", nil, nil, "", "", "{}", "RUB").
 			WillReturnError(sql.ErrConnDone)
 
 		err = repo.Create(listing)
@@ -85,11 +86,11 @@ func TestListingRepository_GetByID(t *testing.T) {
 			CreatedAt:   now,
 		}
 
-		mock.ExpectQuery(`SELECT id, title, description, image_url, price, author_id, created_at FROM listings WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT id, title, description, image_url, price, author_id, created_at, listing_type, status, text_hash, image_hash, category, popularity, latitude, longitude, city, region, attributes, currency, previous_price, price_dropped_at FROM listings WHERE id = \$1`).
 			WithArgs(int64(1)).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "image_url", "price", "author_id", "created_at"}).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "image_url", "price", "author_id", "created_at", "listing_type", "status", "text_hash", "image_hash", "category", "popularity", "latitude", "longitude", "city", "region", "attributes", "currency", "previous_price", "price_dropped_at"}).
 				AddRow(expectedListing.ID, expectedListing.Title, expectedListing.Description, expectedListing.ImageURL,
-					expectedListing.Price, expectedListing.AuthorID, expectedListing.CreatedAt))
+					expectedListing.Price, expectedListing.AuthorID, expectedListing.CreatedAt, domain.ListingTypeFixed, domain.ListingStatusActive, 0, nil, "", 0.0, nil, nil, "", "", nil, "RUB", nil, nil))
 
 		listing, err := repo.GetByID(1)
 
@@ -112,7 +113,7 @@ func TestListingRepository_GetByID(t *testing.T) {
 
 		repo := postgres.NewListingRepository(db)
 
-		mock.ExpectQuery(`SELECT id, title, description, image_url, price, author_id, created_at FROM listings WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT id, title, description, image_url, price, author_id, created_at, listing_type, status, text_hash, image_hash, category, popularity, latitude, longitude, city, region, attributes, currency, previous_price, price_dropped_at FROM listings WHERE id = \$1`).
 			WithArgs(int64(999)).
 			WillReturnError(sql.ErrNoRows)
 
@@ -131,7 +132,7 @@ func TestListingRepository_GetByID(t *testing.T) {
 
 		repo := postgres.NewListingRepository(db)
 
-		mock.ExpectQuery(`SELECT id, title, description, image_url, price, author_id, created_at FROM listings WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT id, title, description, image_url, price, author_id, created_at, listing_type, status, text_hash, image_hash, category, popularity, latitude, longitude, city, region, attributes, currency, previous_price, price_dropped_at FROM listings WHERE id = \$1`).
 			WithArgs(int64(1)).
 			WillReturnError(sql.ErrConnDone)
 
@@ -143,3 +144,226 @@ func TestListingRepository_GetByID(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestListingRepository_FindSimilar(t *testing.T) {
+	t.Run("should query listing words without search operators", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := postgres.NewListingRepository(db)
+		listing := &domain.Listing{ID: 7, AuthorID: 1, Title: "iPhone-13 OR -used", Description: "iphone \"case\" included", Category: "phones"}
+		createdAt := time.Now()
+
+		mock.ExpectQuery(`SELECT (.+), ts_rank\(search_vector, q, 32\) AS text_score FROM listings, websearch_to_tsquery\('simple', \$1\) q`).
+			WithArgs("iphone or 13 or used or case or included", int64(7), int64(1), domain.ListingStatusActive, "phones", 50).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "image_url", "price", "author_id", "created_at", "listing_type", "status", "text_hash", "image_hash", "category", "popularity", "latitude", "longitude", "city", "region", "attributes", "currency", "previous_price", "price_dropped_at", "text_score"}).
+				AddRow(8, "iPhone 13", "Used iphone", "", 500, 2, createdAt, domain.ListingTypeFixed, domain.ListingStatusActive, 0, nil, "phones", 0.0, nil, nil, "", "", nil, "RUB", nil, nil, 0.25))
+
+		similar, err := repo.FindSimilar(listing, 50)
+
+		assert.NoError(t, err)
+		assert.Len(t, similar, 1)
+		assert.Equal(t, int64(8), similar[0].Listing.ID)
+		assert.Equal(t, "phones", similar[0].Listing.Category)
+		assert.Equal(t, 0.25, similar[0].TextScore)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestListingRepository_Search(t *testing.T) {
+	t.Run("should prefilter by bounding box and rank by distance", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := postgres.NewListingRepository(db)
+		query := &domain.ListingQuery{
+			Near:      &geo.Point{Lat: 55.75, Lng: 37.62},
+			RadiusKm:  10,
+			SortBy:    "distance",
+			SortOrder: "asc",
+			Page:      1,
+			PageSize:  10,
+		}
+		lat, lng := 55.76, 37.6
+		createdAt := time.Now()
+
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM \(SELECT \* FROM \(SELECT (.+) AS distance_km FROM listings WHERE status = \$1 AND latitude BETWEEN \$2 AND \$3 AND longitude BETWEEN \$4 AND \$5\) l WHERE distance_km <= \$8\) c`).
+			WithArgs(domain.ListingStatusActive, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 55.75, 37.62, 10.0).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(`WHERE distance_km <= \$8 ORDER BY distance_km ASC NULLS LAST, created_at DESC LIMIT \$9 OFFSET \$10`).
+			WithArgs(domain.ListingStatusActive, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 55.75, 37.62, 10.0, 10, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "image_url", "price", "author_id", "created_at", "listing_type", "status", "text_hash", "image_hash", "category", "popularity", "latitude", "longitude", "city", "region", "attributes", "currency", "previous_price", "price_dropped_at", "distance_km"}).
+				AddRow(3, "Sofa", "Grey sofa", "", 300, 2, createdAt, domain.ListingTypeFixed, domain.ListingStatusActive, 0, nil, "", 0.0, lat, lng, "Moscow", "", []byte(`{"condition": "used"}`), "RUB", nil, nil, 1.6))
+
+		listings, total, err := repo.Search(query)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, total)
+		assert.Len(t, listings, 1)
+		assert.Equal(t, &lat, listings[0].Latitude)
+		assert.Equal(t, "Moscow", listings[0].City)
+		assert.Equal(t, map[string]interface{}{"condition": "used"}, listings[0].Attributes)
+		assert.Equal(t, 1.6, *listings[0].DistanceKm)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestListingRepository_Facets(t *testing.T) {
+	t.Run("should count all facets in one query", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := postgres.NewListingRepository(db)
+		minPrice := int64(1000)
+		query := &domain.ListingQuery{Category: "phones", MinPrice: &minPrice}
+		now := time.Now()
+		facets := &domain.FacetQuery{
+			Categories:  true,
+			PriceBounds: []int64{0, 5000},
+			AgeBuckets:  []domain.AgeBucket{{Name: "today", Since: now.Add(-24 * time.Hour)}},
+		}
+
+		mock.ExpectQuery(`WITH base AS \(SELECT (.+) FROM listings WHERE status = \$1\) `+
+			`SELECT 'category', category, COUNT\(\*\) FROM base\s+WHERE category <> '' AND TRUE AND price >= \$3 GROUP BY category UNION ALL `+
+			`SELECT 'price', width_bucket\(price, \$4::bigint\[\]\)::text, COUNT\(\*\) FROM base\s+WHERE TRUE AND category = \$2 GROUP BY 2 UNION ALL `+
+			`SELECT 'age', b.name, COUNT\(base.id\) FROM \(VALUES \(\$5::text, \$6::timestamptz\)\) b\(name, since\)\s+`+
+			`LEFT JOIN base ON base.created_at >= b.since AND TRUE AND category = \$2 AND price >= \$3 GROUP BY b.name`).
+			WithArgs(domain.ListingStatusActive, "phones", int64(1000), sqlmock.AnyArg(), "today", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"facet", "value", "count"}).
+				AddRow("category", "phones", 4).
+				AddRow("category", "tablets", 2).
+				AddRow("price", "2", 3).
+				AddRow("age", "today", 1))
+
+		result, err := repo.Facets(query, facets)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.FacetCount{{Value: "phones", Count: 4}, {Value: "tablets", Count: 2}}, result.Categories)
+		assert.Equal(t, 0, result.Prices[0].Count)
+		assert.Equal(t, 3, result.Prices[1].Count)
+		assert.Equal(t, []domain.FacetCount{{Value: "today", Count: 1}}, result.Ages)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestListingRepository_SearchAttributes(t *testing.T) {
+	t.Run("should filter attributes by containment and numeric range", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := postgres.NewListingRepository(db)
+		query := &domain.ListingQuery{
+			Category: "phones",
+			Attributes: []domain.AttributeFilter{
+				{Key: "condition", Op: domain.AttributeOpEq, Value: "used"},
+				{Key: "storage_gb", Op: domain.AttributeOpGte, Value: 128.0},
+			},
+		}
+
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM \(SELECT (.+) FROM listings WHERE status = \$1 AND category = \$2 AND attributes @> \$3::jsonb `+
+			`AND CASE WHEN jsonb_typeof\(attributes -> \$4\) = 'number' THEN \(attributes ->> \$4\)::numeric END >= \$5\) c`).
+			WithArgs(domain.ListingStatusActive, "phones", `{"condition":"used"}`, "storage_gb", 128.0).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`ORDER BY created_at DESC`).
+			WithArgs(domain.ListingStatusActive, "phones", `{"condition":"used"}`, "storage_gb", 128.0).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		listings, total, err := repo.Search(query)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, total)
+		assert.Empty(t, listings)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestListingRepository_Update(t *testing.T) {
+	t.Run("should record a price change with the update", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := postgres.NewListingRepository(db)
+		previousPrice := int64(5000)
+		droppedAt := time.Now()
+		listing := &domain.Listing{ID: 4, Title: "Bicycle", Description: "City bicycle", Price: 4200, PreviousPrice: &previousPrice, PriceDroppedAt: &droppedAt}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT price FROM listings WHERE id = \$1 FOR UPDATE`).
+			WithArgs(int64(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"price"}).AddRow(5000))
+		mock.ExpectExec(`UPDATE listings SET (.+) previous_price = \$13, price_dropped_at = \$14 WHERE id = \$15`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO listing_price_history \(listing_id, old_price, new_price, changed_at\)`).
+			WithArgs(int64(4), int64(5000), int64(4200), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err = repo.Update(listing)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should not record an unchanged price", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := postgres.NewListingRepository(db)
+		listing := &domain.Listing{ID: 4, Title: "Bicycle", Description: "City bicycle", Price: 5000}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT price FROM listings WHERE id = \$1 FOR UPDATE`).
+			WithArgs(int64(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"price"}).AddRow(5000))
+		mock.ExpectExec(`UPDATE listings SET`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = repo.Update(listing)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestListingRepository_Delete(t *testing.T) {
+	t.Run("should delete the listing", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := postgres.NewListingRepository(db)
+
+		mock.ExpectExec(`DELETE FROM listings WHERE id = \$1`).
+			WithArgs(int64(4)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = repo.Delete(4)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should fail for unknown listings", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := postgres.NewListingRepository(db)
+
+		mock.ExpectExec(`DELETE FROM listings WHERE id = \$1`).
+			WithArgs(int64(4)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = repo.Delete(4)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -4,9 +4,11 @@ import (
 	"database/sql"
 	"testing"
 	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository"
 	"vk/ecom/internal/repository/postgres"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -24,8 +26,8 @@ func TestUserRepository_Create(t *testing.T) {
 		}
 
 		expectedID := 1
-		mock.ExpectQuery(`INSERT INTO users \(login, password\) VALUES \(\$1, \$2\) RETURNING id`).
-			WithArgs(user.Login, user.Password).
+		mock.ExpectQuery(`INSERT INTO users \(login, password, email, email_verified, phone, phone_verified\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\) ON CONFLICT DO NOTHING RETURNING id`).
+			WithArgs(user.Login, user.Password, user.Email, user.EmailVerified, user.Phone, user.PhoneVerified).
 			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(expectedID))
 
 		err = repo.Create(user)
@@ -47,8 +49,8 @@ func TestUserRepository_Create(t *testing.T) {
 			Password: "hashedpassword",
 		}
 
-		mock.ExpectQuery(`INSERT INTO users \(login, password\) VALUES \(\$1, \$2\) RETURNING id`).
-			WithArgs(user.Login, user.Password).
+		mock.ExpectQuery(`INSERT INTO users \(login, password, email, email_verified, phone, phone_verified\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\) ON CONFLICT DO NOTHING RETURNING id`).
+			WithArgs(user.Login, user.Password, user.Email, user.EmailVerified, user.Phone, user.PhoneVerified).
 			WillReturnError(sql.ErrConnDone)
 
 		err = repo.Create(user)
@@ -57,6 +59,94 @@ func TestUserRepository_Create(t *testing.T) {
 		assert.Contains(t, err.Error(), "failed to create user")
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("should report a taken login or email as a duplicate", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := postgres.NewUserRepository(db)
+
+		mock.ExpectQuery(`INSERT INTO users .* ON CONFLICT DO NOTHING RETURNING id`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		err = repo.Create(&domain.User{Login: "testuser", Email: "taken@example.com"})
+
+		assert.ErrorIs(t, err, repository.ErrDuplicate)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserRepository_ContactDetails(t *testing.T) {
+	t.Run("should report an email verified by another user as a duplicate", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := postgres.NewUserRepository(db)
+
+		mock.ExpectExec(`UPDATE users SET email_verified = TRUE WHERE id = \$1 AND email = \$2`).
+			WithArgs(1, "taken@example.com").
+			WillReturnError(&pq.Error{Code: "23505"})
+
+		err = repo.MarkEmailVerified(1, "taken@example.com")
+
+		assert.ErrorIs(t, err, repository.ErrDuplicate)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should only verify the current phone number", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := postgres.NewUserRepository(db)
+
+		mock.ExpectExec(`UPDATE users SET phone_verified = TRUE WHERE id = \$1 AND phone = \$2`).
+			WithArgs(1, "+4915112345678").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = repo.MarkPhoneVerified(1, "+4915112345678")
+
+		assert.ErrorIs(t, err, repository.ErrConflict)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should report a phone number verified by another user as a duplicate", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := postgres.NewUserRepository(db)
+
+		mock.ExpectExec(`UPDATE users SET phone_verified = TRUE WHERE id = \$1 AND phone = \$2`).
+			WithArgs(1, "+4915112345678").
+			WillReturnError(&pq.Error{Code: "23505"})
+
+		err = repo.MarkPhoneVerified(1, "+4915112345678")
+
+		assert.ErrorIs(t, err, repository.ErrDuplicate)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserRepository_RehashPassword(t *testing.T) {
+	t.Run("should not replace a password changed in between", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := postgres.NewUserRepository(db)
+
+		mock.ExpectExec(`UPDATE users SET password = \$1 WHERE id = \$2 AND password = \$3`).
+			WithArgs("$argon2id$new", 1, "$2a$10$old").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = repo.RehashPassword(1, "$2a$10$old", "$argon2id$new")
+
+		assert.ErrorIs(t, err, repository.ErrConflict)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestUserRepository_GetByID(t *testing.T) {
@@ -73,10 +163,10 @@ func TestUserRepository_GetByID(t *testing.T) {
 			Password: "hashedpassword",
 		}
 
-		mock.ExpectQuery(`SELECT id, login, password FROM users WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT id, login, password, email, email_verified, phone, phone_verified, role, banned, session_version FROM users WHERE id = \$1`).
 			WithArgs(1).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password"}).
-				AddRow(expectedUser.ID, expectedUser.Login, expectedUser.Password))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password", "email", "email_verified", "phone", "phone_verified", "role", "banned", "session_version"}).
+				AddRow(expectedUser.ID, expectedUser.Login, expectedUser.Password, "", false, "", false, domain.UserRoleUser, false, 0))
 
 		user, err := repo.GetByID(1)
 
@@ -95,7 +185,7 @@ func TestUserRepository_GetByID(t *testing.T) {
 
 		repo := postgres.NewUserRepository(db)
 
-		mock.ExpectQuery(`SELECT id, login, password FROM users WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT id, login, password, email, email_verified, phone, phone_verified, role, banned, session_version FROM users WHERE id = \$1`).
 			WithArgs(999).
 			WillReturnError(sql.ErrNoRows)
 
@@ -114,7 +204,7 @@ func TestUserRepository_GetByID(t *testing.T) {
 
 		repo := postgres.NewUserRepository(db)
 
-		mock.ExpectQuery(`SELECT id, login, password FROM users WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT id, login, password, email, email_verified, phone, phone_verified, role, banned, session_version FROM users WHERE id = \$1`).
 			WithArgs(1).
 			WillReturnError(sql.ErrConnDone)
 
@@ -141,10 +231,10 @@ func TestUserRepository_GetByLogin(t *testing.T) {
 			Password: "hashedpassword",
 		}
 
-		mock.ExpectQuery(`SELECT id, login, password FROM users WHERE login = \$1`).
+		mock.ExpectQuery(`SELECT id, login, password, email, email_verified, phone, phone_verified, role, banned, session_version FROM users WHERE login = \$1`).
 			WithArgs("testuser").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password"}).
-				AddRow(expectedUser.ID, expectedUser.Login, expectedUser.Password))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password", "email", "email_verified", "phone", "phone_verified", "role", "banned", "session_version"}).
+				AddRow(expectedUser.ID, expectedUser.Login, expectedUser.Password, "", false, "", false, domain.UserRoleUser, false, 0))
 
 		user, err := repo.GetByLogin("testuser")
 
@@ -163,7 +253,7 @@ func TestUserRepository_GetByLogin(t *testing.T) {
 
 		repo := postgres.NewUserRepository(db)
 
-		mock.ExpectQuery(`SELECT id, login, password FROM users WHERE login = \$1`).
+		mock.ExpectQuery(`SELECT id, login, password, email, email_verified, phone, phone_verified, role, banned, session_version FROM users WHERE login = \$1`).
 			WithArgs("nonexistent").
 			WillReturnError(sql.ErrNoRows)
 
@@ -182,7 +272,7 @@ func TestUserRepository_GetByLogin(t *testing.T) {
 
 		repo := postgres.NewUserRepository(db)
 
-		mock.ExpectQuery(`SELECT id, login, password FROM users WHERE login = \$1`).
+		mock.ExpectQuery(`SELECT id, login, password, email, email_verified, phone, phone_verified, role, banned, session_version FROM users WHERE login = \$1`).
 			WithArgs("testuser").
 			WillReturnError(sql.ErrConnDone)
 
@@ -0,0 +1,160 @@
+package screening_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"vk/ecom/internal/pkg/screening"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func noRecent(authorID int64, since time.Time) ([]*screening.Content, error) {
+	return nil, nil
+}
+
+func description(text string) *screening.Content {
+	return &screening.Content{AuthorID: 1, Title: "Item for sale", Description: text}
+}
+
+func TestNormalize(t *testing.T) {
+	t.Run("should fold case, leetspeak and repeats", func(t *testing.T) {
+		assert.Equal(t, screening.Normalize("free"), screening.Normalize("FR33EEE"))
+		assert.Equal(t, screening.Normalize("free"), screening.Normalize("f.r.e.e"))
+	})
+
+	t.Run("should fold cyrillic homoglyphs", func(t *testing.T) {
+		// "сасао" spelled with Cyrillic letters looks identical to Latin "cacao".
+		assert.Equal(t, screening.Normalize("cacao"), screening.Normalize("сасао"))
+	})
+
+	t.Run("should normalize russian words consistently", func(t *testing.T) {
+		assert.Equal(t, screening.Normalize("оружие"), screening.Normalize("0РУЖИЕ"))
+	})
+}
+
+func TestBannedWordsRule(t *testing.T) {
+	rule, err := screening.NewBannedWordsRule([]string{"replica", "оружие"}, []string{`viagr\w*`})
+	require.NoError(t, err)
+
+	t.Run("should match obfuscated words", func(t *testing.T) {
+		matches, err := rule.Check(description("Top quality r3pl1ca watches"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"replica"}, matches)
+
+		matches, err = rule.Check(description("Продаю 0РУЖИЕ"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"оружие"}, matches)
+	})
+
+	t.Run("should match whole words only", func(t *testing.T) {
+		matches, err := rule.Check(description("Replicator from a sci-fi movie"))
+		require.NoError(t, err)
+		assert.Empty(t, matches)
+	})
+
+	t.Run("should match patterns", func(t *testing.T) {
+		matches, err := rule.Check(description("Cheap VIAGRA pills"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"viagra"}, matches)
+	})
+
+	t.Run("should reject invalid patterns", func(t *testing.T) {
+		_, err := screening.NewBannedWordsRule(nil, []string{"("})
+		assert.Error(t, err)
+	})
+}
+
+func TestContactRules(t *testing.T) {
+	t.Run("should detect phone numbers", func(t *testing.T) {
+		matches, err := screening.PhoneRule{}.Check(description("Call me: +7 (900) 123-45-67, price 1500"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"+7 (900) 123-45-67"}, matches)
+	})
+
+	t.Run("should detect plain and obfuscated emails", func(t *testing.T) {
+		matches, err := screening.EmailRule{}.Check(description("Write to seller@example.com or seller (at) mail (dot) ru"))
+		require.NoError(t, err)
+		assert.Len(t, matches, 2)
+	})
+
+	t.Run("should detect links except allowed domains", func(t *testing.T) {
+		rule := screening.NewLinkRule([]string{"youtube.com"})
+
+		matches, err := rule.Check(description("Video: https://www.youtube.com/watch?v=1, buy at cheap-stuff.ru or http://shop.example.org/item"))
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"http://shop.example.org/item", "cheap-stuff.ru"}, matches)
+	})
+
+	t.Run("should not treat email domains as links", func(t *testing.T) {
+		matches, err := screening.NewLinkRule(nil).Check(description("seller@example.com"))
+		require.NoError(t, err)
+		assert.Empty(t, matches)
+	})
+}
+
+func TestDuplicateRule(t *testing.T) {
+	recent := func(authorID int64, since time.Time) ([]*screening.Content, error) {
+		return []*screening.Content{
+			{ListingID: 7, AuthorID: authorID, Title: "Red bicycle", Description: "Red city bicycle, barely used"},
+		}, nil
+	}
+	rule := screening.NewDuplicateRule(recent, time.Hour, 0.9)
+
+	t.Run("should detect a repost with cosmetic changes", func(t *testing.T) {
+		matches, err := rule.Check(&screening.Content{AuthorID: 1, Title: "RED bicycle!", Description: "Red city bicycle - barely used"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"listing 7"}, matches)
+	})
+
+	t.Run("should ignore the listing being edited", func(t *testing.T) {
+		matches, err := rule.Check(&screening.Content{ListingID: 7, AuthorID: 1, Title: "Red bicycle", Description: "Red city bicycle, barely used"})
+		require.NoError(t, err)
+		assert.Empty(t, matches)
+	})
+}
+
+func TestPipeline(t *testing.T) {
+	t.Run("should apply the most severe action", func(t *testing.T) {
+		config := screening.DefaultConfig()
+		config.BannedWords = []string{"replica"}
+		pipeline, err := screening.New(config, noRecent)
+		require.NoError(t, err)
+
+		flagged, err := pipeline.Screen(description("Call +7 900 123 45 67"))
+		require.NoError(t, err)
+		assert.Equal(t, screening.ActionFlag, flagged.Action)
+
+		rejected, err := pipeline.Screen(description("Replica bag, call +7 900 123 45 67"))
+		require.NoError(t, err)
+		assert.Equal(t, screening.ActionReject, rejected.Action)
+		assert.Len(t, rejected.Findings, 2)
+	})
+
+	t.Run("should skip allowed rules", func(t *testing.T) {
+		config := screening.DefaultConfig()
+		config.Actions[screening.RulePhone] = screening.ActionAllow
+		pipeline, err := screening.New(config, noRecent)
+		require.NoError(t, err)
+
+		result, err := pipeline.Screen(description("Call +7 900 123 45 67"))
+
+		require.NoError(t, err)
+		assert.Equal(t, screening.ActionAllow, result.Action)
+		assert.Empty(t, result.Findings)
+	})
+
+	t.Run("should surface lookup errors", func(t *testing.T) {
+		failing := func(authorID int64, since time.Time) ([]*screening.Content, error) {
+			return nil, errors.New("db down")
+		}
+		pipeline, err := screening.New(screening.DefaultConfig(), failing)
+		require.NoError(t, err)
+
+		_, err = pipeline.Screen(description("Anything"))
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,170 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/mocks"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchCountingRepository records how many batches reach the repository.
+type batchCountingRepository struct {
+	*memory.InMemoryAnalyticsRepository
+	batches int
+}
+
+func (r *batchCountingRepository) AddDailyStats(stats []*domain.ListingDailyStats) error {
+	r.batches++
+	return r.InMemoryAnalyticsRepository.AddDailyStats(stats)
+}
+
+func TestAnalyticsService(t *testing.T) {
+	t.Run("should count each visitor once per window", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		analyticsRepo := &batchCountingRepository{InMemoryAnalyticsRepository: memory.NewInMemoryAnalyticsRepository()}
+		analyticsService := service.NewAnalyticsService(analyticsRepo, listingRepo, service.DefaultAnalyticsConfig())
+
+		bicycle := &domain.Listing{Title: "Red bicycle", Description: "Barely used", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		userID := int64(2)
+		user := service.VisitorKey(&userID, "10.0.0.1", "firefox")
+		anonymous := service.VisitorKey(nil, "10.0.0.2", "chrome")
+
+		for i := 0; i < 3; i++ {
+			analyticsService.TrackView(bicycle.ID, user)
+			analyticsService.TrackView(bicycle.ID, anonymous)
+		}
+		require.NoError(t, analyticsService.TrackEvent(bicycle.ID, userID, domain.ListingEventFavorite))
+		require.NoError(t, analyticsService.TrackEvent(bicycle.ID, userID, domain.ListingEventFavorite))
+		require.NoError(t, analyticsService.TrackEvent(bicycle.ID, 3, domain.ListingEventMessage))
+
+		stats, err := analyticsService.GetListingStats(bicycle.ID, 1, 7)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, stats.Views)
+		assert.Equal(t, 1, stats.Favorites)
+		assert.Equal(t, 1, stats.Messages)
+	})
+
+	t.Run("should write buffered events in one batch", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		analyticsRepo := &batchCountingRepository{InMemoryAnalyticsRepository: memory.NewInMemoryAnalyticsRepository()}
+		analyticsService := service.NewAnalyticsService(analyticsRepo, listingRepo, service.DefaultAnalyticsConfig())
+
+		bicycle := &domain.Listing{Title: "Red bicycle", Description: "Barely used", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+			analyticsService.TrackView(bicycle.ID, service.VisitorKey(nil, ip, "curl"))
+		}
+		assert.Zero(t, analyticsRepo.batches)
+
+		require.NoError(t, analyticsService.Flush())
+		require.NoError(t, analyticsService.Flush())
+
+		assert.Equal(t, 1, analyticsRepo.batches)
+		stats, err := analyticsService.GetListingStats(bicycle.ID, 1, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 3, stats.Views)
+	})
+
+	t.Run("should return a zero-filled daily series", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		analyticsRepo := &batchCountingRepository{InMemoryAnalyticsRepository: memory.NewInMemoryAnalyticsRepository()}
+		analyticsService := service.NewAnalyticsService(analyticsRepo, listingRepo, service.DefaultAnalyticsConfig())
+
+		bicycle := &domain.Listing{Title: "Red bicycle", Description: "Barely used", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		analyticsService.TrackView(bicycle.ID, "visitor")
+		require.NoError(t, analyticsService.Flush())
+
+		stats, err := analyticsService.GetListingStats(bicycle.ID, 1, 7)
+
+		require.NoError(t, err)
+		require.Len(t, stats.Series, 7)
+		for _, day := range stats.Series[:6] {
+			assert.Zero(t, day.Views)
+		}
+		assert.Equal(t, 1, stats.Series[6].Views)
+	})
+
+	t.Run("should only show stats to the seller", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		analyticsRepo := &batchCountingRepository{InMemoryAnalyticsRepository: memory.NewInMemoryAnalyticsRepository()}
+		analyticsService := service.NewAnalyticsService(analyticsRepo, listingRepo, service.DefaultAnalyticsConfig())
+
+		bicycle := &domain.Listing{Title: "Red bicycle", Description: "Barely used", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+
+		_, err := analyticsService.GetListingStats(bicycle.ID, 2, 7)
+
+		assert.ErrorIs(t, err, service.ErrNotListingOwner)
+	})
+
+	t.Run("should reject unknown events", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		analyticsRepo := &batchCountingRepository{InMemoryAnalyticsRepository: memory.NewInMemoryAnalyticsRepository()}
+		analyticsService := service.NewAnalyticsService(analyticsRepo, listingRepo, service.DefaultAnalyticsConfig())
+
+		bicycle := &domain.Listing{Title: "Red bicycle", Description: "Barely used", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+
+		err := analyticsService.TrackEvent(bicycle.ID, 2, domain.ListingEventView)
+
+		assert.ErrorIs(t, err, service.ErrInvalidListingEvent)
+	})
+}
+
+func TestAnalyticsService_RecomputePopularity(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+	listingRepo := memory.NewInMemoryListingRepository()
+	var listings []*domain.Listing
+	for _, title := range []string{"Old hit", "Fresh hit", "Quiet listing", "Favorite"} {
+		listing := &domain.Listing{Title: title, Description: "Description", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(listing))
+		listings = append(listings, listing)
+	}
+	analyticsRepo := memory.NewInMemoryAnalyticsRepository()
+	analytics := service.NewAnalyticsService(analyticsRepo, listingRepo, service.DefaultAnalyticsConfig())
+	listingService := service.NewListingService(listingRepo, mockUserRepo)
+
+	now := time.Now()
+	require.NoError(t, analyticsRepo.AddDailyStats([]*domain.ListingDailyStats{
+		{ListingID: listings[0].ID, Day: now.AddDate(0, 0, -9), Views: 100},
+		{ListingID: listings[1].ID, Day: now, Views: 30},
+		{ListingID: listings[0].ID, Day: now.AddDate(0, 0, -30), Views: 1000},
+	}))
+	analytics.TrackView(listings[3].ID, "visitor")
+	require.NoError(t, analytics.TrackEvent(listings[3].ID, 2, domain.ListingEventFavorite))
+	require.NoError(t, analytics.TrackEvent(listings[3].ID, 2, domain.ListingEventMessage))
+
+	require.NoError(t, analytics.RecomputePopularity(now))
+
+	t.Run("should rank recent engagement first", func(t *testing.T) {
+		trending, err := listingService.GetTrendingListings(10, "", nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{listings[1].ID, listings[0].ID, listings[3].ID}, listingIDs(trending))
+	})
+
+	t.Run("should sort the feed by popularity", func(t *testing.T) {
+		response, err := listingService.GetListingsWithPagination("popular", "desc", nil, nil, 1, 10, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{listings[1].ID, listings[0].ID, listings[3].ID, listings[2].ID}, listingIDs(response.Listings))
+	})
+
+	t.Run("should reset listings whose events left the window", func(t *testing.T) {
+		require.NoError(t, analytics.RecomputePopularity(now.AddDate(0, 0, 20)))
+
+		trending, err := listingService.GetTrendingListings(10, "", nil)
+
+		require.NoError(t, err)
+		assert.Empty(t, trending)
+	})
+}
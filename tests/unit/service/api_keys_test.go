@@ -0,0 +1,112 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthService_APIKeys(t *testing.T) {
+	newService := func() (*service.AuthService, *memory.InMemoryUserRepository, *domain.User) {
+		users := memory.NewInMemoryUserRepository()
+		config := service.DefaultAPIKeyConfig()
+		config.MaxKeys = 2
+		authService := service.NewAuthService(users, service.WithAPIKeys(memory.NewInMemoryAPIKeyRepository(), config))
+		user, err := authService.RegisterUser("alice", "password123")
+		require.NoError(t, err)
+		return authService, users, user
+	}
+
+	t.Run("issues a key that is shown once and validates it", func(t *testing.T) {
+		authService, _, user := newService()
+
+		created, err := authService.CreateAPIKey(user.ID, " importer ", []string{domain.ScopeListingsWrite, domain.ScopeListingsWrite})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(created.Key, created.APIKey.Prefix+"_"))
+		assert.Equal(t, "importer", created.APIKey.Name)
+		assert.Equal(t, []string{domain.ScopeListingsWrite}, created.APIKey.Scopes)
+
+		owner, key, err := authService.ValidateAPIKey(created.Key)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, owner.ID)
+		assert.True(t, key.HasScope(domain.ScopeListingsWrite))
+		assert.False(t, key.HasScope(domain.ScopeListingsRead))
+
+		keys, err := authService.GetAPIKeys(user.ID)
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.NotEqual(t, created.Key, keys[0].KeyHash, "only the hash is stored")
+		assert.NotNil(t, keys[0].LastUsedAt, "validation records the last use")
+
+		_, _, err = authService.ValidateAPIKey(created.Key + "x")
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+	})
+
+	t.Run("revoked keys stop working", func(t *testing.T) {
+		authService, _, user := newService()
+		created, err := authService.CreateAPIKey(user.ID, "bot", []string{domain.ScopeListingsRead})
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, authService.RevokeAPIKey(user.ID+1, created.APIKey.ID), service.ErrAPIKeyNotFound)
+		require.NoError(t, authService.RevokeAPIKey(user.ID, created.APIKey.ID))
+		assert.ErrorIs(t, authService.RevokeAPIKey(user.ID, created.APIKey.ID), service.ErrAPIKeyNotFound)
+
+		_, _, err = authService.ValidateAPIKey(created.Key)
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+
+		keys, err := authService.GetAPIKeys(user.ID)
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.NotNil(t, keys[0].RevokedAt)
+	})
+
+	t.Run("checks scopes and the number of active keys", func(t *testing.T) {
+		authService, users, user := newService()
+
+		_, err := authService.CreateAPIKey(user.ID, "bot", nil)
+		assert.ErrorIs(t, err, service.ErrInvalidScope)
+		_, err = authService.CreateAPIKey(user.ID, "bot", []string{"listings:delete"})
+		assert.ErrorIs(t, err, service.ErrInvalidScope)
+		_, err = authService.CreateAPIKey(user.ID, "bot", []string{domain.ScopeAdminModerate})
+		assert.ErrorIs(t, err, service.ErrScopeNotAllowed)
+		_, err = authService.CreateAPIKey(user.ID, "", []string{domain.ScopeListingsRead})
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKeyName)
+
+		staff := &domain.User{Login: "moderator", Role: domain.UserRoleStaff}
+		require.NoError(t, users.Create(staff))
+		_, err = authService.CreateAPIKey(staff.ID, "moderation bot", []string{domain.ScopeAdminModerate})
+		assert.NoError(t, err)
+
+		first, err := authService.CreateAPIKey(user.ID, "one", []string{domain.ScopeListingsRead})
+		require.NoError(t, err)
+		_, err = authService.CreateAPIKey(user.ID, "two", []string{domain.ScopeListingsRead})
+		require.NoError(t, err)
+		_, err = authService.CreateAPIKey(user.ID, "three", []string{domain.ScopeListingsRead})
+		assert.ErrorIs(t, err, service.ErrTooManyAPIKeys)
+
+		require.NoError(t, authService.RevokeAPIKey(user.ID, first.APIKey.ID))
+		_, err = authService.CreateAPIKey(user.ID, "three", []string{domain.ScopeListingsRead})
+		assert.NoError(t, err, "revoked keys do not count")
+	})
+
+	t.Run("keys of banned users are refused", func(t *testing.T) {
+		authService, users, user := newService()
+		created, err := authService.CreateAPIKey(user.ID, "bot", []string{domain.ScopeListingsRead})
+		require.NoError(t, err)
+
+		require.NoError(t, users.SetBanned(user.ID, true))
+		_, _, err = authService.ValidateAPIKey(created.Key)
+		assert.ErrorIs(t, err, service.ErrUserBanned)
+	})
+
+	t.Run("needs a key store", func(t *testing.T) {
+		authService := service.NewAuthService(memory.NewInMemoryUserRepository())
+		_, err := authService.GetAPIKeys(1)
+		assert.ErrorIs(t, err, service.ErrAPIKeysDisabled)
+	})
+}
@@ -0,0 +1,111 @@
+package service_test
+
+import (
+	"testing"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAttributesListingService(t *testing.T) *service.ListingService {
+	userRepo := memory.NewInMemoryUserRepository()
+	require.NoError(t, userRepo.Create(&domain.User{Login: "alice"}))
+	catalog := service.NewAttributeCatalog(service.DefaultCategorySchemas()...)
+	return service.NewListingService(memory.NewInMemoryListingRepository(), userRepo, service.WithAttributeCatalog(catalog))
+}
+
+func phoneRequest(attributes map[string]interface{}) *dto.ListingRequest {
+	return &dto.ListingRequest{
+		Title:       "iPhone 13",
+		Description: "Works fine, small scratch on the back",
+		Price:       30000,
+		Category:    "phones",
+		Attributes:  attributes,
+	}
+}
+
+func TestListingService_CreateListingAttributes(t *testing.T) {
+	listingService := newAttributesListingService(t)
+
+	t.Run("should store validated attributes", func(t *testing.T) {
+		listing, err := listingService.CreateListing(phoneRequest(map[string]interface{}{
+			"condition": "used", "brand": "  Apple ", "storage_gb": 128.0, "unlocked": true,
+		}), 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"condition": "used", "brand": "Apple", "storage_gb": 128.0, "unlocked": true}, listing.Attributes)
+	})
+
+	t.Run("should reject invalid attributes", func(t *testing.T) {
+		for name, attributes := range map[string]map[string]interface{}{
+			"missing required": {"brand": "Apple"},
+			"unknown value":    {"condition": "broken"},
+			"wrong type":       {"condition": "used", "storage_gb": "128"},
+			"out of range":     {"condition": "used", "storage_gb": 0.0},
+			"unknown key":      {"condition": "used", "color": "red"},
+		} {
+			_, err := listingService.CreateListing(phoneRequest(attributes), 1)
+			assert.Error(t, err, name)
+		}
+	})
+
+	t.Run("should reject attributes for categories without a schema", func(t *testing.T) {
+		req := phoneRequest(map[string]interface{}{"condition": "used"})
+		req.Category = "sofas"
+
+		_, err := listingService.CreateListing(req, 1)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestListingService_SearchListingsAttributes(t *testing.T) {
+	listingService := newAttributesListingService(t)
+	create := func(condition string, storage float64) int64 {
+		listing, err := listingService.CreateListing(phoneRequest(map[string]interface{}{"condition": condition, "storage_gb": storage}), 1)
+		require.NoError(t, err)
+		return listing.ID
+	}
+	usedSmall := create("used", 64)
+	usedLarge := create("used", 256)
+	create("new", 512)
+
+	t.Run("should filter by value and range", func(t *testing.T) {
+		result, err := listingService.SearchListings(&dto.ListingSearchRequest{
+			Category:   "phones",
+			Attributes: map[string]string{"condition": "used", "storage_gb.gte": "128"},
+		}, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{usedLarge}, listingIDs(result.Listings))
+	})
+
+	t.Run("should drop attribute filters with the category from its facet", func(t *testing.T) {
+		result, err := listingService.SearchListings(&dto.ListingSearchRequest{
+			Category:   "phones",
+			Attributes: map[string]string{"storage_gb.lte": "64"},
+			Facets:     []string{"category"},
+		}, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{usedSmall}, listingIDs(result.Listings))
+		assert.Equal(t, []dto.FacetCountDTO{{Value: "phones", Count: 3}}, result.Facets.Categories)
+	})
+
+	t.Run("should reject invalid filters", func(t *testing.T) {
+		for name, req := range map[string]*dto.ListingSearchRequest{
+			"no category":    {Attributes: map[string]string{"condition": "used"}},
+			"unknown key":    {Category: "phones", Attributes: map[string]string{"color": "red"}},
+			"range on enum":  {Category: "phones", Attributes: map[string]string{"condition.gte": "used"}},
+			"not a number":   {Category: "phones", Attributes: map[string]string{"storage_gb": "lots"}},
+			"unknown schema": {Category: "sofas", Attributes: map[string]string{"condition": "used"}},
+		} {
+			_, err := listingService.SearchListings(req, nil)
+			assert.Error(t, err, name)
+		}
+	})
+}
@@ -0,0 +1,320 @@
+package service_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/mocks"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingAuctionRepository struct {
+	*memory.InMemoryAuctionRepository
+}
+
+func (r *failingAuctionRepository) Create(*domain.Auction) error {
+	return errors.New("auction store is down")
+}
+
+func auctionRequest(reserve int64) *dto.ListingRequest {
+	return &dto.ListingRequest{
+		Title:       "Vintage camera",
+		Description: "Fully working film camera from the seventies",
+		Type:        domain.ListingTypeAuction,
+		Auction: &dto.AuctionRequest{
+			StartPrice:   1000,
+			ReservePrice: reserve,
+			MinIncrement: 100,
+			EndsAt:       time.Now().Add(2 * time.Hour),
+		},
+	}
+}
+
+func TestAuctionService_CreateAuction(t *testing.T) {
+	t.Run("should create an auction listing at the start price", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		auctionService := service.NewAuctionService(memory.NewInMemoryAuctionRepository(),
+			memory.NewInMemoryListingRepository(), mockUserRepo, service.DefaultAuctionConfig())
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+
+		listing, err := auctionService.CreateAuction(auctionRequest(0), 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.ListingTypeAuction, listing.Type)
+		assert.Equal(t, int64(1000), listing.Price)
+		assert.Equal(t, "seller", listing.AuthorLogin)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("should reject reserve below start price", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		auctionService := service.NewAuctionService(memory.NewInMemoryAuctionRepository(),
+			memory.NewInMemoryListingRepository(), mockUserRepo, service.DefaultAuctionConfig())
+
+		req := auctionRequest(500)
+
+		_, err := auctionService.CreateAuction(req, 1)
+
+		assert.ErrorIs(t, err, service.ErrInvalidAuctionArg)
+	})
+
+	t.Run("should reject auctions that end too soon", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		auctionService := service.NewAuctionService(memory.NewInMemoryAuctionRepository(),
+			memory.NewInMemoryListingRepository(), mockUserRepo, service.DefaultAuctionConfig())
+
+		req := auctionRequest(0)
+		req.Auction.EndsAt = time.Now().Add(time.Minute)
+
+		_, err := auctionService.CreateAuction(req, 1)
+
+		assert.ErrorIs(t, err, service.ErrInvalidAuctionArg)
+	})
+
+	t.Run("should delete the listing when the auction cannot be stored", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		auctionRepo := &failingAuctionRepository{memory.NewInMemoryAuctionRepository()}
+		auctionService := service.NewAuctionService(auctionRepo, listingRepo, mockUserRepo, service.DefaultAuctionConfig())
+
+		_, err := auctionService.CreateAuction(auctionRequest(0), 1)
+
+		require.Error(t, err)
+		listings, err := listingRepo.GetAll("", "", nil, nil)
+		require.NoError(t, err)
+		assert.Empty(t, listings)
+	})
+}
+
+func TestAuctionService_PlaceBid(t *testing.T) {
+	t.Run("should enforce start price and minimum increment", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		auctionService := service.NewAuctionService(memory.NewInMemoryAuctionRepository(),
+			memory.NewInMemoryListingRepository(), mockUserRepo, service.DefaultAuctionConfig())
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		listing, err := auctionService.CreateAuction(auctionRequest(0), 1)
+		require.NoError(t, err)
+
+		_, err = auctionService.PlaceBid(listing.ID, 2, 999)
+		assert.ErrorIs(t, err, service.ErrBidTooLow)
+
+		auction, err := auctionService.PlaceBid(listing.ID, 2, 1000)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1100), auction.MinNextBid)
+
+		_, err = auctionService.PlaceBid(listing.ID, 3, 1050)
+		assert.ErrorIs(t, err, service.ErrBidTooLow)
+
+		auction, err = auctionService.PlaceBid(listing.ID, 3, 1100)
+		require.NoError(t, err)
+		assert.Equal(t, 2, auction.BidCount)
+	})
+
+	t.Run("should reject bids from the seller", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		auctionService := service.NewAuctionService(memory.NewInMemoryAuctionRepository(),
+			memory.NewInMemoryListingRepository(), mockUserRepo, service.DefaultAuctionConfig())
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		listing, err := auctionService.CreateAuction(auctionRequest(0), 1)
+		require.NoError(t, err)
+
+		_, err = auctionService.PlaceBid(listing.ID, 1, 5000)
+
+		assert.ErrorIs(t, err, service.ErrOwnAuctionBid)
+	})
+
+	t.Run("should reject bids on hidden listings", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		auctionService := service.NewAuctionService(memory.NewInMemoryAuctionRepository(),
+			listingRepo, mockUserRepo, service.DefaultAuctionConfig())
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		listing, err := auctionService.CreateAuction(auctionRequest(0), 1)
+		require.NoError(t, err)
+		require.NoError(t, listingRepo.UpdateStatus(listing.ID, domain.ListingStatusActive, domain.ListingStatusHidden))
+
+		_, err = auctionService.PlaceBid(listing.ID, 2, 5000)
+
+		assert.ErrorIs(t, err, service.ErrAuctionUnavailable)
+	})
+
+	t.Run("should extend the end time for last-minute bids", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		auctionRepo := memory.NewInMemoryAuctionRepository()
+		auctionService := service.NewAuctionService(auctionRepo,
+			memory.NewInMemoryListingRepository(), mockUserRepo, service.DefaultAuctionConfig())
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		listing, err := auctionService.CreateAuction(auctionRequest(0), 1)
+		require.NoError(t, err)
+
+		endsSoon := time.Now().UTC().Add(30 * time.Second)
+		_, err = auctionRepo.Update(listing.ID, func(a *domain.Auction) (*domain.Bid, error) {
+			a.EndsAt = endsSoon
+			return nil, nil
+		})
+		require.NoError(t, err)
+
+		auction, err := auctionService.PlaceBid(listing.ID, 2, 1000)
+
+		require.NoError(t, err)
+		assert.True(t, auction.EndsAt.After(endsSoon))
+	})
+
+	t.Run("should serialize concurrent bids", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		auctionService := service.NewAuctionService(memory.NewInMemoryAuctionRepository(),
+			memory.NewInMemoryListingRepository(), mockUserRepo, service.DefaultAuctionConfig())
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "alice"}, nil)
+		mockUserRepo.On("GetByID", 3).Return(&domain.User{ID: 3, Login: "bob"}, nil)
+		listing, err := auctionService.CreateAuction(auctionRequest(0), 1)
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(bidder int64) {
+				defer wg.Done()
+				auctionService.PlaceBid(listing.ID, bidder, 1000)
+			}(int64(2 + i%2))
+		}
+		wg.Wait()
+
+		history, err := auctionService.GetBidHistory(listing.ID, nil)
+		require.NoError(t, err)
+		assert.Len(t, history.Bids, 1)
+		assert.Equal(t, 1, history.Auction.BidCount)
+	})
+}
+
+func TestAuctionService_GetBidHistory(t *testing.T) {
+	t.Run("should mask bidder logins", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		auctionService := service.NewAuctionService(memory.NewInMemoryAuctionRepository(),
+			memory.NewInMemoryListingRepository(), mockUserRepo, service.DefaultAuctionConfig())
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "alice"}, nil)
+		mockUserRepo.On("GetByID", 3).Return(&domain.User{ID: 3, Login: "bob"}, nil)
+		listing, err := auctionService.CreateAuction(auctionRequest(0), 1)
+		require.NoError(t, err)
+
+		_, err = auctionService.PlaceBid(listing.ID, 2, 1000)
+		require.NoError(t, err)
+		_, err = auctionService.PlaceBid(listing.ID, 3, 1200)
+		require.NoError(t, err)
+
+		history, err := auctionService.GetBidHistory(listing.ID, nil)
+
+		require.NoError(t, err)
+		require.Len(t, history.Bids, 2)
+		assert.Equal(t, "b***b", history.Bids[0].BidderLogin)
+		assert.Equal(t, "a***e", history.Bids[1].BidderLogin)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("should show the winner only to the seller and the winner", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		auctionService := service.NewAuctionService(memory.NewInMemoryAuctionRepository(),
+			memory.NewInMemoryListingRepository(), mockUserRepo, service.DefaultAuctionConfig())
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "alice"}, nil)
+		listing, err := auctionService.CreateAuction(auctionRequest(0), 1)
+		require.NoError(t, err)
+		_, err = auctionService.PlaceBid(listing.ID, 2, 1000)
+		require.NoError(t, err)
+		_, err = auctionService.CloseExpiredAuctions(time.Now().Add(3 * time.Hour))
+		require.NoError(t, err)
+
+		for _, viewerID := range []int64{1, 2} {
+			history, err := auctionService.GetBidHistory(listing.ID, &viewerID)
+			require.NoError(t, err)
+			require.NotNil(t, history.Auction.WinnerID)
+			assert.Equal(t, int64(2), *history.Auction.WinnerID)
+		}
+		outsiderID := int64(3)
+		for _, viewerID := range []*int64{nil, &outsiderID} {
+			history, err := auctionService.GetBidHistory(listing.ID, viewerID)
+			require.NoError(t, err)
+			assert.Nil(t, history.Auction.WinnerID)
+		}
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestAuctionService_CloseExpiredAuctions(t *testing.T) {
+	t.Run("should pick the highest bidder when the reserve is met", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		auctionRepo := memory.NewInMemoryAuctionRepository()
+		auctionService := service.NewAuctionService(auctionRepo,
+			memory.NewInMemoryListingRepository(), mockUserRepo, service.DefaultAuctionConfig())
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		listing, err := auctionService.CreateAuction(auctionRequest(1100), 1)
+		require.NoError(t, err)
+
+		_, err = auctionService.PlaceBid(listing.ID, 2, 1000)
+		require.NoError(t, err)
+		_, err = auctionService.PlaceBid(listing.ID, 3, 1200)
+		require.NoError(t, err)
+
+		closed, err := auctionService.CloseExpiredAuctions(time.Now().Add(3 * time.Hour))
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, closed)
+		auction, _ := auctionRepo.GetByListingID(listing.ID)
+		assert.Equal(t, domain.AuctionStatusClosed, auction.Status)
+		require.NotNil(t, auction.WinnerID)
+		assert.Equal(t, int64(3), *auction.WinnerID)
+	})
+
+	t.Run("should close without a winner when the reserve is not met", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		auctionRepo := memory.NewInMemoryAuctionRepository()
+		auctionService := service.NewAuctionService(auctionRepo,
+			memory.NewInMemoryListingRepository(), mockUserRepo, service.DefaultAuctionConfig())
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		listing, err := auctionService.CreateAuction(auctionRequest(5000), 1)
+		require.NoError(t, err)
+
+		_, err = auctionService.PlaceBid(listing.ID, 2, 1000)
+		require.NoError(t, err)
+
+		_, err = auctionService.CloseExpiredAuctions(time.Now().Add(3 * time.Hour))
+
+		require.NoError(t, err)
+		auction, _ := auctionRepo.GetByListingID(listing.ID)
+		assert.Equal(t, domain.AuctionStatusClosed, auction.Status)
+		assert.Nil(t, auction.WinnerID)
+	})
+
+	t.Run("should leave running auctions open", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		auctionService := service.NewAuctionService(memory.NewInMemoryAuctionRepository(),
+			memory.NewInMemoryListingRepository(), mockUserRepo, service.DefaultAuctionConfig())
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		_, err := auctionService.CreateAuction(auctionRequest(0), 1)
+		require.NoError(t, err)
+
+		closed, err := auctionService.CloseExpiredAuctions(time.Now())
+
+		require.NoError(t, err)
+		assert.Equal(t, 0, closed)
+	})
+}
@@ -2,9 +2,11 @@ package service_test
 
 import (
 	"errors"
+	"strings"
 	"testing"
 	"vk/ecom/internal/domain"
 	"vk/ecom/internal/mocks"
+	pwhash "vk/ecom/internal/pkg/password"
 	"vk/ecom/internal/service"
 
 	"github.com/stretchr/testify/assert"
@@ -35,8 +37,9 @@ func TestAuthService_RegisterUser(t *testing.T) {
 		assert.NotEqual(t, password, user.Password) // Password should be hashed
 
 		// Verify password is properly hashed
-		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+		ok, err := pwhash.Verify(user.Password, password)
 		assert.NoError(t, err)
+		assert.True(t, ok)
 
 		mockRepo.AssertExpectations(t)
 	})
@@ -72,19 +75,19 @@ func TestAuthService_RegisterUser(t *testing.T) {
 
 		assert.Error(t, err)
 		assert.Nil(t, user)
-		assert.Contains(t, err.Error(), "password must be between 6 and 40 characters")
+		assert.Contains(t, err.Error(), "password must be between 6 and 128 characters")
 	})
 
 	t.Run("should fail with long password", func(t *testing.T) {
 		mockRepo := new(mocks.MockUserRepository)
 		authService := service.NewAuthService(mockRepo)
 
-		longPassword := "this_is_a_very_long_password_that_definitely_exceeds_the_forty_character_limit_set_by_validation"
+		longPassword := strings.Repeat("long password ", 10)
 		user, err := authService.RegisterUser("testuser", longPassword)
 
 		assert.Error(t, err)
 		assert.Nil(t, user)
-		assert.Contains(t, err.Error(), "password must be between 6 and 40 characters")
+		assert.Contains(t, err.Error(), "password must be between 6 and 128 characters")
 	})
 
 	t.Run("should fail when user already exists", func(t *testing.T) {
@@ -146,6 +149,10 @@ func TestAuthService_LoginUser(t *testing.T) {
 		}
 
 		mockRepo.On("GetByLogin", login).Return(existingUser, nil)
+		// bcrypt hashes are upgraded to argon2id on login
+		mockRepo.On("RehashPassword", 1, string(hashedPassword), mock.MatchedBy(func(hash string) bool {
+			return strings.HasPrefix(hash, "$argon2id$")
+		})).Return(nil)
 
 		token, user, err := authService.LoginUser(login, password)
 
@@ -220,6 +227,7 @@ func TestAuthService_ValidateToken(t *testing.T) {
 		loginUser := &domain.User{ID: 1, Login: "testuser", Password: string(hashedPassword)}
 
 		mockRepo2.On("GetByLogin", "testuser").Return(loginUser, nil)
+		mockRepo2.On("RehashPassword", 1, loginUser.Password, mock.AnythingOfType("string")).Return(nil)
 		token, _, _ := authService2.LoginUser("testuser", "password")
 
 		validatedUser, err := authService.ValidateToken(token)
@@ -257,6 +265,7 @@ func TestAuthService_ValidateToken(t *testing.T) {
 		loginUser := &domain.User{ID: 1, Login: "testuser", Password: string(hashedPassword)}
 
 		mockRepo2.On("GetByLogin", "testuser").Return(loginUser, nil)
+		mockRepo2.On("RehashPassword", 1, loginUser.Password, mock.AnythingOfType("string")).Return(nil)
 		token, _, _ := authService2.LoginUser("testuser", "password")
 
 		// Mock that user is not found
@@ -0,0 +1,228 @@
+package service_test
+
+import (
+	"testing"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/mocks"
+	"vk/ecom/internal/pkg/payment"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCartService_GetCart(t *testing.T) {
+	t.Run("should group items by seller", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+		cartService := service.NewCartService(memory.NewInMemoryCartRepository(), listingRepo, mockUserRepo, orderService)
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller1"}, nil)
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "seller2"}, nil)
+
+		lamp := &domain.Listing{Title: "Lamp", Description: "Furniture in good condition", Price: 1000, AuthorID: 1}
+		chair := &domain.Listing{Title: "Chair", Description: "Furniture in good condition", Price: 2000, AuthorID: 1}
+		table := &domain.Listing{Title: "Table", Description: "Furniture in good condition", Price: 3000, AuthorID: 2}
+		buyerID := int64(3)
+		key := service.UserCartKey(buyerID)
+		for _, listing := range []*domain.Listing{lamp, chair, table} {
+			require.NoError(t, listingRepo.Create(listing))
+			_, err := cartService.AddItem(key, listing.ID, &buyerID)
+			require.NoError(t, err)
+		}
+
+		cart, err := cartService.GetCart(key, &buyerID)
+
+		require.NoError(t, err)
+		require.Len(t, cart.Groups, 2)
+		assert.Equal(t, "seller1", cart.Groups[0].SellerLogin)
+		assert.Equal(t, int64(3000), cart.Groups[0].Subtotal)
+		assert.Equal(t, "seller2", cart.Groups[1].SellerLogin)
+		assert.Equal(t, int64(6000), cart.Total)
+		assert.False(t, cart.HasPriceChanges)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("should flag price changes and unavailable items", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+		cartService := service.NewCartService(memory.NewInMemoryCartRepository(), listingRepo, mockUserRepo, orderService)
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller1"}, nil)
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "seller2"}, nil)
+
+		lamp := &domain.Listing{Title: "Lamp", Description: "Furniture in good condition", Price: 1000, AuthorID: 1}
+		table := &domain.Listing{Title: "Table", Description: "Furniture in good condition", Price: 3000, AuthorID: 2}
+		buyerID := int64(3)
+		key := service.UserCartKey(buyerID)
+		for _, listing := range []*domain.Listing{lamp, table} {
+			require.NoError(t, listingRepo.Create(listing))
+			_, err := cartService.AddItem(key, listing.ID, &buyerID)
+			require.NoError(t, err)
+		}
+
+		lamp.Price = 800
+		require.NoError(t, listingRepo.UpdateStatus(table.ID, domain.ListingStatusActive, domain.ListingStatusSold))
+
+		cart, err := cartService.GetCart(key, &buyerID)
+
+		require.NoError(t, err)
+		assert.True(t, cart.HasPriceChanges)
+		assert.True(t, cart.HasUnavailableItems)
+		require.Len(t, cart.Unavailable, 1)
+		assert.Equal(t, table.ID, cart.Unavailable[0].ListingID)
+		assert.True(t, cart.Groups[0].Items[0].PriceChanged)
+		assert.Equal(t, int64(800), cart.Groups[0].Items[0].Price)
+		assert.Equal(t, int64(1000), cart.Groups[0].Items[0].PriceAtAdd)
+	})
+
+	t.Run("should not allow adding own listing", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+		cartService := service.NewCartService(memory.NewInMemoryCartRepository(), listingRepo, mockUserRepo, orderService)
+
+		lamp := &domain.Listing{Title: "Lamp", Description: "Furniture in good condition", Price: 1000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(lamp))
+		sellerID := int64(1)
+
+		_, err := cartService.AddItem(service.UserCartKey(sellerID), lamp.ID, &sellerID)
+
+		assert.ErrorIs(t, err, service.ErrListingNotAddable)
+	})
+}
+
+func TestCartService_MergeAnonymousCart(t *testing.T) {
+	t.Run("should move anonymous items into the user cart", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+		cartService := service.NewCartService(memory.NewInMemoryCartRepository(), listingRepo, mockUserRepo, orderService)
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller1"}, nil)
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "seller2"}, nil)
+
+		lamp := &domain.Listing{Title: "Lamp", Description: "Furniture in good condition", Price: 1000, AuthorID: 1}
+		table := &domain.Listing{Title: "Table", Description: "Furniture in good condition", Price: 3000, AuthorID: 2}
+		require.NoError(t, listingRepo.Create(lamp))
+		require.NoError(t, listingRepo.Create(table))
+		buyerID := int64(3)
+
+		token, err := service.NewCartToken()
+		require.NoError(t, err)
+		anonKey, err := service.AnonymousCartKey(token)
+		require.NoError(t, err)
+
+		_, err = cartService.AddItem(anonKey, lamp.ID, nil)
+		require.NoError(t, err)
+		_, err = cartService.AddItem(service.UserCartKey(buyerID), lamp.ID, &buyerID)
+		require.NoError(t, err)
+		_, err = cartService.AddItem(anonKey, table.ID, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, cartService.MergeAnonymousCart(token, buyerID))
+
+		cart, err := cartService.GetCart(service.UserCartKey(buyerID), &buyerID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, cart.ItemCount)
+		anonCart, err := cartService.GetCart(anonKey, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 0, anonCart.ItemCount)
+	})
+
+	t.Run("should reject malformed tokens", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+		cartService := service.NewCartService(memory.NewInMemoryCartRepository(), listingRepo, mockUserRepo, orderService)
+
+		err := cartService.MergeAnonymousCart("not-a-token", 3)
+
+		assert.ErrorIs(t, err, service.ErrInvalidCartToken)
+	})
+}
+
+func TestCartService_Checkout(t *testing.T) {
+	t.Run("should create one order per seller", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+		cartService := service.NewCartService(memory.NewInMemoryCartRepository(), listingRepo, mockUserRepo, orderService)
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller1"}, nil)
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "seller2"}, nil)
+
+		lamp := &domain.Listing{Title: "Lamp", Description: "Furniture in good condition", Price: 1000, AuthorID: 1}
+		chair := &domain.Listing{Title: "Chair", Description: "Furniture in good condition", Price: 2000, AuthorID: 1}
+		table := &domain.Listing{Title: "Table", Description: "Furniture in good condition", Price: 3000, AuthorID: 2}
+		buyerID := int64(3)
+		key := service.UserCartKey(buyerID)
+		for _, listing := range []*domain.Listing{lamp, chair, table} {
+			require.NoError(t, listingRepo.Create(listing))
+			_, err := cartService.AddItem(key, listing.ID, &buyerID)
+			require.NoError(t, err)
+		}
+
+		response, err := cartService.Checkout(buyerID, false)
+
+		require.NoError(t, err)
+		require.Len(t, response.Orders, 2)
+		assert.Len(t, response.Orders[0].Items, 2)
+		assert.Equal(t, int64(3000), response.Orders[0].Amount)
+		assert.Len(t, response.Orders[1].Items, 1)
+
+		cart, err := cartService.GetCart(key, &buyerID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, cart.ItemCount)
+	})
+
+	t.Run("should require accepting price changes", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+		cartService := service.NewCartService(memory.NewInMemoryCartRepository(), listingRepo, mockUserRepo, orderService)
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller1"}, nil)
+
+		lamp := &domain.Listing{Title: "Lamp", Description: "Furniture in good condition", Price: 1000, AuthorID: 1}
+		chair := &domain.Listing{Title: "Chair", Description: "Furniture in good condition", Price: 2000, AuthorID: 1}
+		buyerID := int64(3)
+		key := service.UserCartKey(buyerID)
+		for _, listing := range []*domain.Listing{lamp, chair} {
+			require.NoError(t, listingRepo.Create(listing))
+			_, err := cartService.AddItem(key, listing.ID, &buyerID)
+			require.NoError(t, err)
+		}
+
+		chair.Price = 2500
+
+		_, err := cartService.Checkout(buyerID, false)
+		assert.ErrorIs(t, err, service.ErrCartPriceChanged)
+
+		response, err := cartService.Checkout(buyerID, true)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3500), response.Orders[0].Amount)
+	})
+
+	t.Run("should fail on an empty cart", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+		cartService := service.NewCartService(memory.NewInMemoryCartRepository(), listingRepo, mockUserRepo, orderService)
+
+		_, err := cartService.Checkout(3, false)
+
+		assert.ErrorIs(t, err, service.ErrCartEmpty)
+	})
+}
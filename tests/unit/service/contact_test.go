@@ -0,0 +1,225 @@
+package service_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/pkg/mail"
+	"vk/ecom/internal/pkg/sms"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSender struct {
+	sent []*sms.Message
+}
+
+func (s *recordingSender) Send(msg *sms.Message) error {
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+var (
+	verifyTokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_.-]+)`)
+	smsCodePattern     = regexp.MustCompile(`\b(\d{6})$`)
+)
+
+func mailedVerifyToken(t *testing.T, msg *mail.Message) string {
+	t.Helper()
+	match := verifyTokenPattern.FindStringSubmatch(msg.Body)
+	require.NotNil(t, match, "no verification link in %q", msg.Body)
+	return match[1]
+}
+
+func textedCode(t *testing.T, msg *sms.Message) string {
+	t.Helper()
+	match := smsCodePattern.FindStringSubmatch(msg.Body)
+	require.NotNil(t, match, "no code in %q", msg.Body)
+	return match[1]
+}
+
+func newContactService(t *testing.T) (*service.AuthService, *memory.InMemoryUserRepository, *recordingMailer, *recordingSender) {
+	t.Helper()
+	users := memory.NewInMemoryUserRepository()
+	mailer := &recordingMailer{}
+	sender := &recordingSender{}
+	config := service.DefaultContactVerificationConfig([]byte("0123456789abcdef0123456789abcdef"))
+	config.VerifyURL = "https://ecom.example/verify-email"
+	authService := service.NewAuthService(users, service.WithContactVerification(memory.NewInMemoryPhoneCodeRepository(), mailer, sender, config))
+	return authService, users, mailer, sender
+}
+
+func TestAuthService_EmailVerification(t *testing.T) {
+	t.Run("verifies the normalized address given at registration", func(t *testing.T) {
+		authService, _, mailer, _ := newContactService(t)
+		user, err := authService.RegisterUserWithEmail("alice", "password123", " Alice@Example.COM ")
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", user.Email)
+
+		require.Len(t, mailer.sent, 1)
+		assert.Equal(t, "alice@example.com", mailer.sent[0].To)
+		assert.Contains(t, mailer.sent[0].Body, "https://ecom.example/verify-email?token=")
+
+		require.NoError(t, authService.VerifyEmail(mailedVerifyToken(t, mailer.sent[0])))
+		contact, err := authService.GetContactDetails(user.ID)
+		require.NoError(t, err)
+		assert.True(t, contact.EmailVerified)
+		assert.ErrorIs(t, authService.SendEmailVerification(user.ID), service.ErrAlreadyVerified)
+	})
+
+	t.Run("changing the address needs a new verification", func(t *testing.T) {
+		authService, _, mailer, _ := newContactService(t)
+		user, err := authService.RegisterUserWithEmail("alice", "password123", "alice@example.com")
+		require.NoError(t, err)
+		oldToken := mailedVerifyToken(t, mailer.sent[0])
+
+		require.NoError(t, authService.UpdateEmail(user.ID, "alice@work.example"))
+		require.Len(t, mailer.sent, 2)
+		assert.Equal(t, "alice@work.example", mailer.sent[1].To)
+
+		assert.ErrorIs(t, authService.VerifyEmail(oldToken), service.ErrInvalidVerification, "links for the old address are refused")
+		assert.ErrorIs(t, authService.VerifyEmail(oldToken+"x"), service.ErrInvalidVerification)
+		require.NoError(t, authService.VerifyEmail(mailedVerifyToken(t, mailer.sent[1])))
+	})
+
+	t.Run("verified addresses are unique regardless of case", func(t *testing.T) {
+		authService, _, mailer, _ := newContactService(t)
+		_, err := authService.RegisterUserWithEmail("alice", "password123", "alice@example.com")
+		require.NoError(t, err)
+		require.NoError(t, authService.VerifyEmail(mailedVerifyToken(t, mailer.sent[0])))
+
+		bob, err := authService.RegisterUserWithEmail("bob", "password123", "ALICE@example.com")
+		require.NoError(t, err, "registration does not reveal used addresses")
+		assert.ErrorIs(t, authService.VerifyEmail(mailedVerifyToken(t, mailer.sent[1])), service.ErrEmailTaken)
+
+		require.NoError(t, authService.UpdateEmail(bob.ID, ""))
+		assert.ErrorIs(t, authService.UpdateEmail(bob.ID, "not an address"), service.ErrInvalidEmail)
+		assert.ErrorIs(t, authService.SendEmailVerification(bob.ID), service.ErrNoEmail)
+	})
+
+	t.Run("unverified addresses do not reserve the address", func(t *testing.T) {
+		authService, _, mailer, _ := newContactService(t)
+		_, err := authService.RegisterUserWithEmail("mallory", "password123", "carol@example.com")
+		require.NoError(t, err)
+
+		carol, err := authService.RegisterUserWithEmail("carol", "password123", "")
+		require.NoError(t, err)
+		require.NoError(t, authService.UpdateEmail(carol.ID, "carol@example.com"))
+		require.Len(t, mailer.sent, 2)
+		require.NoError(t, authService.VerifyEmail(mailedVerifyToken(t, mailer.sent[1])))
+
+		assert.ErrorIs(t, authService.VerifyEmail(mailedVerifyToken(t, mailer.sent[0])), service.ErrEmailTaken)
+	})
+
+	t.Run("needs a verification config", func(t *testing.T) {
+		authService := service.NewAuthService(memory.NewInMemoryUserRepository())
+		assert.ErrorIs(t, authService.VerifyEmail("token"), service.ErrContactVerificationDisabled)
+	})
+}
+
+func TestAuthService_PhoneVerification(t *testing.T) {
+	t.Run("texts a code to the normalized number", func(t *testing.T) {
+		authService, _, _, sender := newContactService(t)
+		user, err := authService.RegisterUser("alice", "password123")
+		require.NoError(t, err)
+
+		require.NoError(t, authService.UpdatePhone(user.ID, "0049 (151) 123-456-78"))
+		require.Len(t, sender.sent, 1)
+		assert.Equal(t, "+4915112345678", sender.sent[0].To)
+		code := textedCode(t, sender.sent[0])
+
+		assert.ErrorIs(t, authService.VerifyPhone(user.ID, "000000"), service.ErrInvalidVerification)
+		require.NoError(t, authService.VerifyPhone(user.ID, code))
+		contact, err := authService.GetContactDetails(user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, &dto.ContactDTO{Phone: "+4915112345678", PhoneVerified: true}, contact)
+
+		require.NoError(t, authService.UpdatePhone(user.ID, ""))
+		assert.ErrorIs(t, authService.VerifyPhone(user.ID, code), service.ErrNoPhone)
+	})
+
+	t.Run("codes are bound to the number", func(t *testing.T) {
+		authService, _, _, sender := newContactService(t)
+		user, err := authService.RegisterUser("alice", "password123")
+		require.NoError(t, err)
+
+		require.NoError(t, authService.UpdatePhone(user.ID, "+4915112345678"))
+		code := textedCode(t, sender.sent[0])
+		require.NoError(t, authService.UpdatePhone(user.ID, "+4915187654321"))
+
+		assert.ErrorIs(t, authService.VerifyPhone(user.ID, code), service.ErrInvalidVerification)
+	})
+
+	t.Run("discards codes after too many guesses", func(t *testing.T) {
+		authService, _, _, sender := newContactService(t)
+		user, err := authService.RegisterUser("alice", "password123")
+		require.NoError(t, err)
+
+		require.NoError(t, authService.UpdatePhone(user.ID, "+4915112345678"))
+		code := textedCode(t, sender.sent[0])
+		n, err := strconv.Atoi(code)
+		require.NoError(t, err)
+		wrong := fmt.Sprintf("%06d", (n+1)%1_000_000)
+		for i := 0; i < service.DefaultContactVerificationConfig(nil).MaxPhoneCodeAttempts; i++ {
+			assert.ErrorIs(t, authService.VerifyPhone(user.ID, wrong), service.ErrInvalidVerification)
+		}
+		assert.ErrorIs(t, authService.VerifyPhone(user.ID, code), service.ErrInvalidVerification)
+
+		require.NoError(t, authService.SendPhoneVerification(user.ID))
+		require.Len(t, sender.sent, 2)
+		require.NoError(t, authService.VerifyPhone(user.ID, textedCode(t, sender.sent[1])))
+	})
+
+	t.Run("rejects malformed and taken numbers", func(t *testing.T) {
+		authService, _, _, sender := newContactService(t)
+		alice, err := authService.RegisterUser("alice", "password123")
+		require.NoError(t, err)
+		bob, err := authService.RegisterUser("bob", "password123")
+		require.NoError(t, err)
+
+		for _, phone := range []string{"015112345678", "+0151123", "+49 151 abc", "+1234567890123456"} {
+			assert.ErrorIs(t, authService.UpdatePhone(alice.ID, phone), service.ErrInvalidPhone, phone)
+		}
+		require.NoError(t, authService.UpdatePhone(alice.ID, "+4915112345678"))
+		require.NoError(t, authService.UpdatePhone(bob.ID, "+49 151 12345678"), "unverified numbers do not reserve the number")
+		require.Len(t, sender.sent, 2)
+		require.NoError(t, authService.VerifyPhone(bob.ID, textedCode(t, sender.sent[1])))
+
+		assert.ErrorIs(t, authService.VerifyPhone(alice.ID, textedCode(t, sender.sent[0])), service.ErrPhoneTaken)
+	})
+}
+
+func TestUserDTO_HidesContactDetails(t *testing.T) {
+	data, err := json.Marshal(dto.ToUserDTO(&domain.User{
+		ID: 7, Login: "alice", Password: "hash",
+		Email: "alice@example.com", EmailVerified: true, Phone: "+4915112345678", PhoneVerified: true,
+	}))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"id": 7, "login": "alice"}`, string(data))
+}
+
+func TestListingService_VerifiedEmailRequired(t *testing.T) {
+	users := memory.NewInMemoryUserRepository()
+	authService := service.NewAuthService(users)
+	user, err := authService.RegisterUserWithEmail("alice", "password123", "alice@example.com")
+	require.NoError(t, err)
+	listingService := service.NewListingService(memory.NewInMemoryListingRepository(), users, service.WithVerifiedEmailRequired())
+	req := &dto.ListingRequest{
+		Title: "Chair", Description: "Chair in good condition", ImageURL: "https://example.com/a.jpg", Price: 1000,
+	}
+
+	_, err = listingService.CreateListing(req, int64(user.ID))
+	assert.ErrorIs(t, err, service.ErrEmailNotVerified)
+
+	require.NoError(t, users.MarkEmailVerified(user.ID, "alice@example.com"))
+	_, err = listingService.CreateListing(req, int64(user.ID))
+	assert.NoError(t, err)
+}
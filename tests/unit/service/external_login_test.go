@@ -0,0 +1,244 @@
+package service_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/oidc"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+	"vk/ecom/tests/oidcstub"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingIdentityRepository refuses to store identities.
+type failingIdentityRepository struct {
+	*memory.InMemoryIdentityRepository
+}
+
+func (r *failingIdentityRepository) Create(*domain.Identity) error {
+	return errors.New("identity store is down")
+}
+
+// racingIdentityRepository links every new identity to winnerID just
+// before it is stored, like a concurrent first login would.
+type racingIdentityRepository struct {
+	*memory.InMemoryIdentityRepository
+	winnerID int
+}
+
+func (r *racingIdentityRepository) Create(identity *domain.Identity) error {
+	winner := *identity
+	winner.UserID = r.winnerID
+	if err := r.InMemoryIdentityRepository.Create(&winner); err != nil {
+		return err
+	}
+	return r.InMemoryIdentityRepository.Create(identity)
+}
+
+// racingUserRepository registers the login of the first user it creates
+// just before storing it, like a concurrent registration would.
+type racingUserRepository struct {
+	*memory.InMemoryUserRepository
+	raced bool
+}
+
+func (r *racingUserRepository) Create(user *domain.User) error {
+	if !r.raced {
+		r.raced = true
+		if err := r.InMemoryUserRepository.Create(&domain.User{Login: user.Login}); err != nil {
+			return err
+		}
+	}
+	return r.InMemoryUserRepository.Create(user)
+}
+
+func newExternalLoginService(t *testing.T) (*service.AuthService, *oidcstub.Provider) {
+	t.Helper()
+	stub := oidcstub.New()
+	t.Cleanup(stub.Close)
+	provider := oidc.NewProvider(stub.Config("stub", "http://shop.test/api/auth/oidc/stub/callback"), http.DefaultClient)
+	authService := service.NewAuthService(memory.NewInMemoryUserRepository(),
+		service.WithExternalLogin(memory.NewInMemoryIdentityRepository(), service.DefaultExternalLoginConfig(), provider),
+	)
+	return authService, stub
+}
+
+// providerCallback starts a login or link and returns the callback's code
+// and state.
+func providerCallback(t *testing.T, authService *service.AuthService, stub *oidcstub.Provider, userID int) (string, string) {
+	t.Helper()
+	authURL, err := authService.BeginExternalLogin("stub", userID)
+	require.NoError(t, err)
+	callback, err := stub.Authorize(authURL)
+	require.NoError(t, err)
+	return callback.Query().Get("code"), callback.Query().Get("state")
+}
+
+func TestAuthService_ExternalLogin(t *testing.T) {
+	t.Run("creates the user on first login and finds it afterwards", func(t *testing.T) {
+		authService, stub := newExternalLoginService(t)
+		assert.Equal(t, []string{"stub"}, authService.ExternalLoginProviders())
+		stub.SetUser(oidcstub.User{Subject: "42", Email: "alice@example.com", EmailVerified: true, PreferredUsername: "Alice.Smith"})
+
+		code, state := providerCallback(t, authService, stub, 0)
+		first, err := authService.CompleteExternalLogin("stub", code, state, domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.True(t, first.Created)
+		assert.Equal(t, "alice_smith", first.User.Login)
+		assert.Equal(t, "alice@example.com", first.User.Email)
+		_, err = authService.ValidateToken(first.Token)
+		assert.NoError(t, err)
+
+		_, err = authService.CompleteExternalLogin("stub", code, state, domain.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidLoginState, "states are single-use")
+
+		code, state = providerCallback(t, authService, stub, 0)
+		second, err := authService.CompleteExternalLogin("stub", code, state, domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.False(t, second.Created)
+		assert.Equal(t, first.User.ID, second.User.ID)
+	})
+
+	t.Run("picks a free login and ignores unverified email", func(t *testing.T) {
+		authService, stub := newExternalLoginService(t)
+		_, err := authService.RegisterUser("bob", "password123")
+		require.NoError(t, err)
+		stub.SetUser(oidcstub.User{Subject: "7", Email: "bob@example.com"})
+
+		code, state := providerCallback(t, authService, stub, 0)
+		result, err := authService.CompleteExternalLogin("stub", code, state, domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, "bob2", result.User.Login)
+		assert.Empty(t, result.User.Email)
+
+		_, _, err = authService.LoginUser("bob2", "")
+		assert.Error(t, err, "external users have no local password")
+	})
+
+	t.Run("links an identity to a signed-in user", func(t *testing.T) {
+		authService, stub := newExternalLoginService(t)
+		user, err := authService.RegisterUser("carol", "password123")
+		require.NoError(t, err)
+		stub.SetUser(oidcstub.User{Subject: "99"})
+
+		code, state := providerCallback(t, authService, stub, user.ID)
+		result, err := authService.CompleteExternalLogin("stub", code, state, domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.Empty(t, result.Token)
+		assert.Equal(t, user.ID, result.Identity.UserID)
+
+		code, state = providerCallback(t, authService, stub, 0)
+		login, err := authService.CompleteExternalLogin("stub", code, state, domain.ClientInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, login.User.ID)
+
+		identities, err := authService.GetIdentities(user.ID)
+		require.NoError(t, err)
+		assert.Len(t, identities, 1)
+		require.NoError(t, authService.UnlinkIdentity(user.ID, "stub"))
+	})
+
+	t.Run("keeps the only login method", func(t *testing.T) {
+		authService, stub := newExternalLoginService(t)
+		stub.SetUser(oidcstub.User{Subject: "5", PreferredUsername: "dave"})
+		code, state := providerCallback(t, authService, stub, 0)
+		result, err := authService.CompleteExternalLogin("stub", code, state, domain.ClientInfo{})
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, authService.UnlinkIdentity(result.User.ID, "stub"), service.ErrLastLoginMethod)
+	})
+
+	t.Run("removes the new user when the identity cannot be stored", func(t *testing.T) {
+		stub := oidcstub.New()
+		defer stub.Close()
+		provider := oidc.NewProvider(stub.Config("stub", "http://shop.test/api/auth/oidc/stub/callback"), http.DefaultClient)
+		userRepo := memory.NewInMemoryUserRepository()
+		identities := &failingIdentityRepository{InMemoryIdentityRepository: memory.NewInMemoryIdentityRepository()}
+		authService := service.NewAuthService(userRepo,
+			service.WithExternalLogin(identities, service.DefaultExternalLoginConfig(), provider),
+		)
+		stub.SetUser(oidcstub.User{Subject: "8", PreferredUsername: "erin"})
+
+		code, state := providerCallback(t, authService, stub, 0)
+		_, err := authService.CompleteExternalLogin("stub", code, state, domain.ClientInfo{})
+
+		assert.Error(t, err)
+		_, err = userRepo.GetByLogin("erin")
+		assert.Error(t, err, "the user was removed")
+	})
+
+	t.Run("keeps the verified address when the login is taken concurrently", func(t *testing.T) {
+		stub := oidcstub.New()
+		defer stub.Close()
+		provider := oidc.NewProvider(stub.Config("stub", "http://shop.test/api/auth/oidc/stub/callback"), http.DefaultClient)
+		authService := service.NewAuthService(&racingUserRepository{InMemoryUserRepository: memory.NewInMemoryUserRepository()},
+			service.WithExternalLogin(memory.NewInMemoryIdentityRepository(), service.DefaultExternalLoginConfig(), provider),
+		)
+		stub.SetUser(oidcstub.User{Subject: "11", Email: "frank@example.com", EmailVerified: true, PreferredUsername: "frank"})
+
+		code, state := providerCallback(t, authService, stub, 0)
+		result, err := authService.CompleteExternalLogin("stub", code, state, domain.ClientInfo{})
+
+		require.NoError(t, err)
+		assert.Equal(t, "frank2", result.User.Login)
+		assert.Equal(t, "frank@example.com", result.User.Email)
+		assert.True(t, result.User.EmailVerified)
+	})
+
+	t.Run("drops an address another account has verified", func(t *testing.T) {
+		stub := oidcstub.New()
+		defer stub.Close()
+		provider := oidc.NewProvider(stub.Config("stub", "http://shop.test/api/auth/oidc/stub/callback"), http.DefaultClient)
+		userRepo := memory.NewInMemoryUserRepository()
+		authService := service.NewAuthService(userRepo,
+			service.WithExternalLogin(memory.NewInMemoryIdentityRepository(), service.DefaultExternalLoginConfig(), provider),
+		)
+		owner, err := authService.RegisterUserWithEmail("grace", "password123", "grace@example.com")
+		require.NoError(t, err)
+		require.NoError(t, userRepo.MarkEmailVerified(owner.ID, "grace@example.com"))
+		stub.SetUser(oidcstub.User{Subject: "12", Email: "grace@example.com", EmailVerified: true, PreferredUsername: "gracie"})
+
+		code, state := providerCallback(t, authService, stub, 0)
+		result, err := authService.CompleteExternalLogin("stub", code, state, domain.ClientInfo{})
+
+		require.NoError(t, err)
+		assert.Equal(t, "gracie", result.User.Login)
+		assert.Empty(t, result.User.Email)
+	})
+
+	t.Run("logs in as the user of a concurrently created identity", func(t *testing.T) {
+		stub := oidcstub.New()
+		defer stub.Close()
+		provider := oidc.NewProvider(stub.Config("stub", "http://shop.test/api/auth/oidc/stub/callback"), http.DefaultClient)
+		userRepo := memory.NewInMemoryUserRepository()
+		identities := &racingIdentityRepository{InMemoryIdentityRepository: memory.NewInMemoryIdentityRepository()}
+		authService := service.NewAuthService(userRepo,
+			service.WithExternalLogin(identities, service.DefaultExternalLoginConfig(), provider),
+		)
+		winner, err := authService.RegisterUser("heidi", "password123")
+		require.NoError(t, err)
+		identities.winnerID = winner.ID
+		stub.SetUser(oidcstub.User{Subject: "13", PreferredUsername: "heidi_h"})
+
+		code, state := providerCallback(t, authService, stub, 0)
+		result, err := authService.CompleteExternalLogin("stub", code, state, domain.ClientInfo{})
+
+		require.NoError(t, err)
+		assert.False(t, result.Created)
+		assert.Equal(t, winner.ID, result.User.ID)
+		_, err = userRepo.GetByLogin("heidi_h")
+		assert.Error(t, err, "the losing user was removed")
+	})
+
+	t.Run("rejects unknown providers and states", func(t *testing.T) {
+		authService, _ := newExternalLoginService(t)
+		_, err := authService.BeginExternalLogin("nope", 0)
+		assert.ErrorIs(t, err, service.ErrUnknownProvider)
+		_, err = authService.CompleteExternalLogin("stub", "code", "forged", domain.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidLoginState)
+	})
+}
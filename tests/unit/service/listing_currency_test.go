@@ -0,0 +1,102 @@
+package service_test
+
+import (
+	"testing"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/pkg/money"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListingService_Currencies(t *testing.T) {
+	listingRepo := memory.NewInMemoryListingRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	require.NoError(t, userRepo.Create(&domain.User{Login: "alice"}))
+	rates, err := money.NewStaticRates("RUB", map[string]float64{"USD": 0.0125, "EUR": 0.01})
+	require.NoError(t, err)
+	listingService := service.NewListingService(listingRepo, userRepo, service.WithExchangeRates(rates))
+
+	create := func(title string, price int64, currency string) *dto.ListingDTO {
+		listing, err := listingService.CreateListing(&dto.ListingRequest{
+			Title: title, Description: title + " in good condition", ImageURL: "https://example.com/a.jpg", Price: price, Currency: currency,
+		}, 1)
+		require.NoError(t, err)
+		return listing
+	}
+	rub := create("Chair", 500000, "")
+	usd := create("Table", 10000, "usd")
+	eur := create("Lamp", 4000, "EUR")
+	jpy := create("Vase", 1000, "JPY")
+
+	t.Run("should default to rubles and normalize codes", func(t *testing.T) {
+		assert.Equal(t, "RUB", rub.Currency)
+		assert.Equal(t, "USD", usd.Currency)
+	})
+
+	t.Run("should reject unsupported currencies", func(t *testing.T) {
+		_, err := listingService.CreateListing(&dto.ListingRequest{
+			Title: "Rug", Description: "Rug in good condition", ImageURL: "https://example.com/a.jpg", Price: 100, Currency: "XXX",
+		}, 1)
+
+		assert.ErrorIs(t, err, money.ErrUnsupportedCurrency)
+	})
+
+	t.Run("should filter and sort in the display currency", func(t *testing.T) {
+		minPrice := int64(450000)
+		result, err := listingService.SearchListings(&dto.ListingSearchRequest{
+			Currency: "RUB", MinPrice: &minPrice, SortBy: "price", SortOrder: "desc",
+		}, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{usd.ID, rub.ID}, listingIDs(result.Listings))
+		require.NotNil(t, result.Listings[0].DisplayPrice)
+		assert.Equal(t, money.New(800000, "RUB"), *result.Listings[0].DisplayPrice)
+		assert.Equal(t, int64(10000), result.Listings[0].Price)
+	})
+
+	t.Run("should sort listings without a rate last", func(t *testing.T) {
+		result, err := listingService.SearchListings(&dto.ListingSearchRequest{Currency: "USD", SortBy: "price", SortOrder: "asc"}, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{eur.ID, rub.ID, usd.ID, jpy.ID}, listingIDs(result.Listings))
+		assert.Equal(t, money.New(5000, "USD"), *result.Listings[0].DisplayPrice)
+		assert.Nil(t, result.Listings[3].DisplayPrice)
+	})
+
+	t.Run("should reject an unsupported display currency", func(t *testing.T) {
+		_, err := listingService.SearchListings(&dto.ListingSearchRequest{Currency: "XXX"}, nil)
+
+		assert.ErrorIs(t, err, money.ErrUnsupportedCurrency)
+	})
+
+	t.Run("should convert the price of a single listing", func(t *testing.T) {
+		listing, err := listingService.GetListing(usd.ID, "RUB", nil)
+		require.NoError(t, err)
+		require.NotNil(t, listing.DisplayPrice)
+		assert.Equal(t, money.New(800000, "RUB"), *listing.DisplayPrice)
+
+		listing, err = listingService.GetListing(usd.ID, "", nil)
+		require.NoError(t, err)
+		assert.Nil(t, listing.DisplayPrice)
+
+		_, err = listingService.GetListing(usd.ID, "XXX", nil)
+		assert.ErrorIs(t, err, money.ErrUnsupportedCurrency)
+	})
+
+	t.Run("should not change the currency on update", func(t *testing.T) {
+		_, err := listingService.UpdateListing(usd.ID, 1, &dto.ListingRequest{
+			Title: "Table", Description: "Table in good condition", ImageURL: "https://example.com/a.jpg", Price: 9000, Currency: "EUR",
+		})
+		assert.ErrorIs(t, err, service.ErrCurrencyChange)
+
+		updated, err := listingService.UpdateListing(usd.ID, 1, &dto.ListingRequest{
+			Title: "Table", Description: "Table in good condition", ImageURL: "https://example.com/a.jpg", Price: 9000,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "USD", updated.Currency)
+	})
+}
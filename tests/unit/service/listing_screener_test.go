@@ -0,0 +1,196 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/mocks"
+	"vk/ecom/internal/pkg/screening"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingModerationRepository struct {
+	*memory.InMemoryModerationRepository
+}
+
+func (r *failingModerationRepository) AddReport(*domain.Report) (*domain.ModerationCase, error) {
+	return nil, errors.New("moderation store is down")
+}
+
+func TestListingScreening(t *testing.T) {
+	t.Run("should reject banned words", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		config := screening.DefaultConfig()
+		config.BannedWords = []string{"replica"}
+		screener, err := service.NewListingScreener(config, listingRepo, memory.NewInMemoryModerationRepository(), nil)
+		require.NoError(t, err)
+		listingService := service.NewListingService(listingRepo, mockUserRepo, service.WithScreener(screener))
+
+		req := &dto.ListingRequest{Title: "Watch", Description: "Swiss r3pl1ca, looks like the real thing", Price: 1000}
+
+		_, err = listingService.CreateListing(req, 1)
+
+		var rejected *screening.RejectedError
+		require.ErrorAs(t, err, &rejected)
+		assert.Equal(t, screening.RuleBannedWords, rejected.Findings[0].Rule)
+	})
+
+	t.Run("should publish and queue flagged listings", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		moderationRepo := memory.NewInMemoryModerationRepository()
+		screener, err := service.NewListingScreener(screening.DefaultConfig(), listingRepo, moderationRepo, nil)
+		require.NoError(t, err)
+		listingService := service.NewListingService(listingRepo, mockUserRepo, service.WithScreener(screener))
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		req := &dto.ListingRequest{Title: "Sofa", Description: "Comfortable sofa, call +7 900 123 45 67", Price: 1000}
+
+		listing, err := listingService.CreateListing(req, 1)
+		require.NoError(t, err)
+
+		cases, err := moderationRepo.GetCases(domain.CaseStatusOpen, 10)
+		require.NoError(t, err)
+		require.Len(t, cases, 1)
+		assert.Equal(t, listing.ID, cases[0].ListingID)
+		reports, err := moderationRepo.GetReports(cases[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ReportReasonAutomated, reports[0].Reason)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("should publish flagged listings when queueing fails", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		moderationRepo := &failingModerationRepository{memory.NewInMemoryModerationRepository()}
+		screener, err := service.NewListingScreener(screening.DefaultConfig(), listingRepo, moderationRepo, nil)
+		require.NoError(t, err)
+		listingService := service.NewListingService(listingRepo, mockUserRepo, service.WithScreener(screener))
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		req := &dto.ListingRequest{Title: "Sofa", Description: "Comfortable sofa, call +7 900 123 45 67", Price: 1000}
+
+		listing, err := listingService.CreateListing(req, 1)
+
+		require.NoError(t, err)
+		_, err = listingRepo.GetByID(listing.ID)
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("should reject reposts of recent listings", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		screener, err := service.NewListingScreener(screening.DefaultConfig(), listingRepo, memory.NewInMemoryModerationRepository(), nil)
+		require.NoError(t, err)
+		listingService := service.NewListingService(listingRepo, mockUserRepo, service.WithScreener(screener))
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		_, err = listingService.CreateListing(&dto.ListingRequest{Title: "Red bicycle", Description: "Red city bicycle, barely used", Price: 1000}, 1)
+		require.NoError(t, err)
+
+		_, err = listingService.CreateListing(&dto.ListingRequest{Title: "RED BICYCLE", Description: "Red city bicycle - barely used!", Price: 1000}, 1)
+
+		var rejected *screening.RejectedError
+		assert.ErrorAs(t, err, &rejected)
+	})
+
+	t.Run("should screen updates", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		config := screening.DefaultConfig()
+		config.BannedWords = []string{"replica"}
+		screener, err := service.NewListingScreener(config, listingRepo, memory.NewInMemoryModerationRepository(), nil)
+		require.NoError(t, err)
+		listingService := service.NewListingService(listingRepo, mockUserRepo, service.WithScreener(screener))
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		listing, err := listingService.CreateListing(&dto.ListingRequest{Title: "Watch", Description: "Classic wrist watch in good shape", Price: 1000}, 1)
+		require.NoError(t, err)
+
+		_, err = listingService.UpdateListing(listing.ID, 1, &dto.ListingRequest{Title: "Watch", Description: "Classic replica wrist watch", Price: 1000})
+		var rejected *screening.RejectedError
+		assert.ErrorAs(t, err, &rejected)
+
+		updated, err := listingService.UpdateListing(listing.ID, 1, &dto.ListingRequest{Title: "Watch", Description: "Classic wrist watch, new strap", Price: 1000})
+		require.NoError(t, err)
+		assert.Equal(t, "Classic wrist watch, new strap", updated.Description)
+
+		_, err = listingService.UpdateListing(listing.ID, 2, &dto.ListingRequest{Title: "Watch", Description: "Classic wrist watch, new strap", Price: 1000})
+		assert.ErrorIs(t, err, service.ErrNotListingOwner)
+	})
+}
+
+func TestListingScreening_NearDuplicates(t *testing.T) {
+	original := &dto.ListingRequest{Title: "Red bicycle", Description: "Red city bicycle with a steel frame, seven gears and new tyres. Barely used.", Price: 1000}
+	repost := &dto.ListingRequest{Title: "RED BICYCLE", Description: "Red city bicycle with a steel frame, seven gears and new tyres. Barely used!!", Price: 1000}
+
+	t.Run("should reject reposts by other sellers", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		config := screening.DefaultConfig()
+		config.Actions[screening.RuleNearDuplicate] = screening.ActionReject
+		screener, err := service.NewListingScreener(config, listingRepo, memory.NewInMemoryModerationRepository(), nil)
+		require.NoError(t, err)
+		listingService := service.NewListingService(listingRepo, mockUserRepo, service.WithScreener(screener))
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		_, err = listingService.CreateListing(original, 1)
+		require.NoError(t, err)
+
+		_, err = listingService.CreateListing(repost, 2)
+
+		var rejected *screening.RejectedError
+		require.ErrorAs(t, err, &rejected)
+		assert.Equal(t, screening.RuleNearDuplicate, rejected.Findings[0].Rule)
+	})
+
+	t.Run("should flag reposts by other sellers", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		moderationRepo := memory.NewInMemoryModerationRepository()
+		config := screening.DefaultConfig()
+		config.Actions[screening.RuleNearDuplicate] = screening.ActionFlag
+		screener, err := service.NewListingScreener(config, listingRepo, moderationRepo, nil)
+		require.NoError(t, err)
+		listingService := service.NewListingService(listingRepo, mockUserRepo, service.WithScreener(screener))
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "spammer"}, nil)
+		_, err = listingService.CreateListing(original, 1)
+		require.NoError(t, err)
+
+		listing, err := listingService.CreateListing(repost, 2)
+		require.NoError(t, err)
+
+		cases, err := moderationRepo.GetCases(domain.CaseStatusOpen, 10)
+		require.NoError(t, err)
+		require.Len(t, cases, 1)
+		assert.Equal(t, listing.ID, cases[0].ListingID)
+	})
+
+	t.Run("should allow reposts when the policy is off", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		config := screening.DefaultConfig()
+		config.Actions[screening.RuleNearDuplicate] = screening.ActionAllow
+		screener, err := service.NewListingScreener(config, listingRepo, memory.NewInMemoryModerationRepository(), nil)
+		require.NoError(t, err)
+		listingService := service.NewListingService(listingRepo, mockUserRepo, service.WithScreener(screener))
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "spammer"}, nil)
+		_, err = listingService.CreateListing(original, 1)
+		require.NoError(t, err)
+
+		_, err = listingService.CreateListing(repost, 2)
+
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,153 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/pkg/geo"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListingService_SearchListings(t *testing.T) {
+	listingRepo := memory.NewInMemoryListingRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	require.NoError(t, userRepo.Create(&domain.User{Login: "alice"}))
+	listingService := service.NewListingService(listingRepo, userRepo)
+
+	create := func(title string, lat, lng float64) *domain.Listing {
+		listing := &domain.Listing{Title: title, Description: title, Price: 1000, AuthorID: 1, Latitude: &lat, Longitude: &lng}
+		require.NoError(t, listingRepo.Create(listing))
+		return listing
+	}
+	kremlin := create("Sofa near the Kremlin", 55.752, 37.6175)
+	arbat := create("Chair on Arbat", 55.7494, 37.5912)
+	zelenograd := create("Table in Zelenograd", 55.9825, 37.1814)
+	create("Lamp in Petersburg", 59.9343, 30.3351)
+	require.NoError(t, listingRepo.Create(&domain.Listing{Title: "Rug", Description: "Rug", Price: 1000, AuthorID: 1}))
+	moscow := &geo.Point{Lat: 55.7558, Lng: 37.6173}
+
+	t.Run("should return listings within the radius sorted by distance", func(t *testing.T) {
+		result, err := listingService.SearchListings(&dto.ListingSearchRequest{Near: moscow, RadiusKm: 50, SortBy: "distance"}, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{kremlin.ID, arbat.ID, zelenograd.ID}, listingIDs(result.Listings))
+		assert.Equal(t, 3, result.TotalCount)
+		require.NotNil(t, result.Listings[0].DistanceKm)
+		assert.InDelta(t, 0.4, *result.Listings[0].DistanceKm, 0.1)
+	})
+
+	t.Run("should default to a 25 km radius", func(t *testing.T) {
+		result, err := listingService.SearchListings(&dto.ListingSearchRequest{Near: moscow, SortBy: "distance", SortOrder: "desc"}, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{arbat.ID, kremlin.ID}, listingIDs(result.Listings))
+	})
+
+	t.Run("should include listings without location when near is not set", func(t *testing.T) {
+		result, err := listingService.SearchListings(&dto.ListingSearchRequest{}, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, 5, result.TotalCount)
+		assert.Nil(t, result.Listings[0].DistanceKm)
+	})
+
+	t.Run("should reject distance sort without near", func(t *testing.T) {
+		_, err := listingService.SearchListings(&dto.ListingSearchRequest{SortBy: "distance"}, nil)
+
+		assert.ErrorIs(t, err, service.ErrDistanceSortWithoutLocation)
+	})
+
+	t.Run("should reject an invalid point", func(t *testing.T) {
+		_, err := listingService.SearchListings(&dto.ListingSearchRequest{Near: &geo.Point{Lat: 91, Lng: 0}}, nil)
+
+		assert.ErrorIs(t, err, service.ErrInvalidLocation)
+	})
+
+	t.Run("should find moved listings at their new location", func(t *testing.T) {
+		lat, lng := 59.94, 30.31
+		moved := *zelenograd
+		moved.Latitude, moved.Longitude = &lat, &lng
+		require.NoError(t, listingRepo.Update(&moved))
+
+		result, err := listingService.SearchListings(&dto.ListingSearchRequest{Near: moscow, RadiusKm: 50}, nil)
+
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []int64{kremlin.ID, arbat.ID}, listingIDs(result.Listings))
+	})
+}
+
+func TestListingService_SearchListingsFacets(t *testing.T) {
+	listingRepo := memory.NewInMemoryListingRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	require.NoError(t, userRepo.Create(&domain.User{Login: "alice"}))
+	listingService := service.NewListingService(listingRepo, userRepo)
+
+	create := func(category string, price int64, age time.Duration) {
+		listing := &domain.Listing{Title: "Item", Description: "Item", Price: price, AuthorID: 1, Category: category}
+		require.NoError(t, listingRepo.Create(listing))
+		listing.CreatedAt = listing.CreatedAt.Add(-age)
+	}
+	create("bicycles", 800, time.Hour)
+	create("bicycles", 12000, 3*24*time.Hour)
+	create("bicycles", 30000, 20*24*time.Hour)
+	create("phones", 4000, time.Hour)
+	create("phones", 60000, 60*24*time.Hour)
+	create("sofas", 2000, 10*24*time.Hour)
+
+	t.Run("should count each facet without its own filter", func(t *testing.T) {
+		maxPrice := int64(20000)
+		result, err := listingService.SearchListings(&dto.ListingSearchRequest{
+			Category: "bicycles",
+			MaxPrice: &maxPrice,
+			Age:      "month",
+			Facets:   []string{"category", "price", "age"},
+		}, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.TotalCount)
+		require.NotNil(t, result.Facets)
+		assert.Equal(t, []dto.FacetCountDTO{{Value: "bicycles", Count: 2}, {Value: "phones", Count: 1}, {Value: "sofas", Count: 1}}, result.Facets.Categories)
+		assert.Equal(t, []dto.FacetCountDTO{{Value: "today", Count: 1}, {Value: "week", Count: 2}, {Value: "month", Count: 2}}, result.Facets.Ages)
+
+		counts := make([]int, 0, len(result.Facets.Prices))
+		for _, bucket := range result.Facets.Prices {
+			counts = append(counts, bucket.Count)
+		}
+		// bicycles of the last month: 800, 12000 and 30000
+		assert.Equal(t, []int{1, 0, 0, 2, 0, 0}, counts)
+		assert.Nil(t, result.Facets.Prices[len(result.Facets.Prices)-1].Max)
+	})
+
+	t.Run("should use custom price buckets starting at zero", func(t *testing.T) {
+		result, err := listingService.SearchListings(&dto.ListingSearchRequest{Facets: []string{"price"}, PriceBuckets: []int64{5000, 50000}}, nil)
+
+		require.NoError(t, err)
+		require.Len(t, result.Facets.Prices, 3)
+		assert.Equal(t, int64(0), result.Facets.Prices[0].Min)
+		assert.Equal(t, []int{3, 2, 1}, []int{result.Facets.Prices[0].Count, result.Facets.Prices[1].Count, result.Facets.Prices[2].Count})
+		assert.Nil(t, result.Facets.Categories)
+	})
+
+	t.Run("should omit facets unless requested", func(t *testing.T) {
+		result, err := listingService.SearchListings(&dto.ListingSearchRequest{}, nil)
+
+		require.NoError(t, err)
+		assert.Nil(t, result.Facets)
+	})
+
+	t.Run("should reject invalid facet parameters", func(t *testing.T) {
+		_, err := listingService.SearchListings(&dto.ListingSearchRequest{Facets: []string{"color"}}, nil)
+		assert.ErrorIs(t, err, service.ErrInvalidFacet)
+
+		_, err = listingService.SearchListings(&dto.ListingSearchRequest{Facets: []string{"price"}, PriceBuckets: []int64{100, 100}}, nil)
+		assert.ErrorIs(t, err, service.ErrInvalidPriceBuckets)
+
+		_, err = listingService.SearchListings(&dto.ListingSearchRequest{Age: "year"}, nil)
+		assert.ErrorIs(t, err, service.ErrInvalidAgeFilter)
+	})
+}
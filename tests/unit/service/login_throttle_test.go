@@ -0,0 +1,194 @@
+package service_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testThrottleConfig() service.LoginThrottleConfig {
+	return service.LoginThrottleConfig{
+		Window:            time.Hour,
+		FreeAttempts:      2,
+		BaseDelay:         time.Second,
+		MaxDelay:          4 * time.Second,
+		LockoutThreshold:  6,
+		LockoutDuration:   15 * time.Minute,
+		IPThreshold:       8,
+		IPLockoutDuration: 10 * time.Minute,
+	}
+}
+
+func retryAfter(t *testing.T, err error) time.Duration {
+	t.Helper()
+	var throttled *service.LoginThrottledError
+	require.True(t, errors.As(err, &throttled), "expected LoginThrottledError, got %v", err)
+	assert.ErrorIs(t, err, service.ErrTooManyLoginAttempts)
+	return throttled.RetryAfter
+}
+
+func TestLoginThrottle_ProgressiveDelay(t *testing.T) {
+	throttle := service.NewLoginThrottle(memory.NewInMemoryLoginAttemptRepository(), testThrottleConfig())
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, throttle.Acquire("alice", "", now), "free attempts are not delayed")
+	}
+	assert.Equal(t, time.Second, retryAfter(t, throttle.Acquire("alice", "", now)))
+
+	now = now.Add(time.Second)
+	require.NoError(t, throttle.Acquire("alice", "", now))
+	assert.Equal(t, 2*time.Second, retryAfter(t, throttle.Acquire("ALICE", "", now)))
+
+	for i := 0; i < 2; i++ {
+		now = now.Add(4 * time.Second)
+		require.NoError(t, throttle.Acquire("alice", "", now))
+	}
+	assert.Equal(t, 4*time.Second, retryAfter(t, throttle.Acquire("alice", "", now)), "delay is capped at MaxDelay")
+	assert.NoError(t, throttle.Acquire("bob", "", now))
+}
+
+func TestLoginThrottle_Release(t *testing.T) {
+	throttle := service.NewLoginThrottle(memory.NewInMemoryLoginAttemptRepository(), testThrottleConfig())
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, throttle.Acquire("alice", "10.0.0.1", now))
+		require.NoError(t, throttle.Release("alice", "10.0.0.1"))
+	}
+
+	assert.NoError(t, throttle.Acquire("alice", "10.0.0.1", now), "released attempts are not failures")
+}
+
+func TestLoginThrottle_ConcurrentAttempts(t *testing.T) {
+	throttle := service.NewLoginThrottle(memory.NewInMemoryLoginAttemptRepository(), testThrottleConfig())
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var admitted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if throttle.Acquire("alice", "", now) == nil {
+				admitted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), admitted.Load(), "only the free attempts get through at once")
+}
+
+func TestLoginThrottle_LocksAccountAndAddress(t *testing.T) {
+	config := testThrottleConfig()
+	config.FreeAttempts = 10
+	throttle := service.NewLoginThrottle(memory.NewInMemoryLoginAttemptRepository(), config)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, throttle.Acquire("alice", "", now))
+	}
+	assert.Equal(t, 15*time.Minute, retryAfter(t, throttle.Acquire("alice", "", now)))
+	assert.Equal(t, time.Second, retryAfter(t, throttle.Acquire("alice", "", now.Add(15*time.Minute-time.Second))))
+	assert.NoError(t, throttle.Acquire("alice", "", now.Add(16*time.Minute)), "an expired lockout allows one more attempt")
+	assert.Equal(t, 15*time.Minute, retryAfter(t, throttle.Acquire("alice", "", now.Add(16*time.Minute))))
+
+	// Spraying from one address over many accounts blocks the address.
+	for i := 0; i < 8; i++ {
+		require.NoError(t, throttle.Acquire("user"+string(rune('a'+i)), "10.0.0.1", now))
+	}
+	assert.Equal(t, 10*time.Minute, retryAfter(t, throttle.Acquire("someone", "10.0.0.1", now)))
+	assert.NoError(t, throttle.Acquire("someone", "10.0.0.2", now))
+
+	lockouts, err := throttle.Lockouts(now)
+	require.NoError(t, err)
+	keys := make([]string, 0, len(lockouts))
+	for _, l := range lockouts {
+		keys = append(keys, l.Key)
+	}
+	assert.ElementsMatch(t, []string{"login:alice", "ip:10.0.0.1"}, keys)
+
+	require.NoError(t, throttle.Clear("ip", "10.0.0.1"))
+	assert.NoError(t, throttle.Acquire("someone", "10.0.0.1", now))
+	assert.ErrorIs(t, throttle.Clear("user", "alice"), service.ErrInvalidLockoutKind)
+}
+
+func TestAuthService_LoginThrottling(t *testing.T) {
+	newService := func() *service.AuthService {
+		users := memory.NewInMemoryUserRepository()
+		throttle := service.NewLoginThrottle(memory.NewInMemoryLoginAttemptRepository(), service.LoginThrottleConfig{
+			Window:            time.Hour,
+			FreeAttempts:      10,
+			BaseDelay:         time.Second,
+			MaxDelay:          time.Second,
+			LockoutThreshold:  3,
+			LockoutDuration:   time.Hour,
+			IPThreshold:       100,
+			IPLockoutDuration: time.Hour,
+		})
+		authService := service.NewAuthService(users, service.WithLoginThrottle(throttle))
+		_, err := authService.RegisterUser("alice", "password123")
+		require.NoError(t, err)
+		return authService
+	}
+
+	t.Run("locks the account even for the right password", func(t *testing.T) {
+		authService := newService()
+		for i := 0; i < 3; i++ {
+			_, _, err := authService.LoginUserFrom("alice", "wrong", domain.ClientInfo{IP: "10.0.0.1"})
+			assert.EqualError(t, err, "invalid login or password")
+		}
+
+		_, _, err := authService.LoginUserFrom("alice", "password123", domain.ClientInfo{IP: "10.0.0.2"})
+		assert.ErrorIs(t, err, service.ErrTooManyLoginAttempts)
+
+		require.NoError(t, authService.ClearLockout("login", "alice"))
+		token, _, err := authService.LoginUserFrom("alice", "password123", domain.ClientInfo{IP: "10.0.0.2"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token)
+	})
+
+	t.Run("throttles unknown logins the same way", func(t *testing.T) {
+		authService := newService()
+		for i := 0; i < 3; i++ {
+			_, _, err := authService.LoginUserFrom("ghost", "wrong", domain.ClientInfo{IP: "10.0.0.1"})
+			assert.EqualError(t, err, "invalid login or password")
+		}
+		_, _, err := authService.LoginUserFrom("ghost", "wrong", domain.ClientInfo{IP: "10.0.0.1"})
+		assert.ErrorIs(t, err, service.ErrTooManyLoginAttempts)
+
+		lockouts, err := authService.GetLockouts()
+		require.NoError(t, err)
+		require.Len(t, lockouts, 1)
+		assert.Equal(t, "login:ghost", lockouts[0].Key)
+	})
+
+	t.Run("success resets the failures", func(t *testing.T) {
+		authService := newService()
+		for i := 0; i < 2; i++ {
+			_, _, _ = authService.LoginUserFrom("alice", "wrong", domain.ClientInfo{})
+		}
+		_, _, err := authService.LoginUserFrom("alice", "password123", domain.ClientInfo{})
+		require.NoError(t, err)
+		for i := 0; i < 2; i++ {
+			_, _, _ = authService.LoginUserFrom("alice", "wrong", domain.ClientInfo{})
+		}
+		_, _, err = authService.LoginUserFrom("alice", "password123", domain.ClientInfo{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("lockout admin needs a throttle", func(t *testing.T) {
+		authService := service.NewAuthService(memory.NewInMemoryUserRepository())
+		_, err := authService.GetLockouts()
+		assert.ErrorIs(t, err, service.ErrLoginThrottleDisabled)
+	})
+}
@@ -0,0 +1,270 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/mocks"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModerationService_ReportListing(t *testing.T) {
+	t.Run("should collect reports into one case", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		moderationService := service.NewModerationService(memory.NewInMemoryModerationRepository(), listingRepo,
+			mockUserRepo, service.DefaultModerationConfig())
+
+		bag := &domain.Listing{Title: "Designer bag", Description: "Brand new, too good to be true", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bag))
+		for _, reporterID := range []int64{3, 4} {
+			_, err := moderationService.ReportListing(bag.ID, reporterID, &dto.ReportRequest{Reason: domain.ReportReasonScam})
+			require.NoError(t, err)
+		}
+
+		queue, err := moderationService.GetQueue("")
+
+		require.NoError(t, err)
+		require.Len(t, queue, 1)
+		assert.Equal(t, 2, queue[0].ReportCount)
+		assert.Equal(t, "Designer bag", queue[0].ListingTitle)
+	})
+
+	t.Run("should reject invalid and duplicate reports", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		moderationService := service.NewModerationService(memory.NewInMemoryModerationRepository(), listingRepo,
+			mockUserRepo, service.DefaultModerationConfig())
+
+		bag := &domain.Listing{Title: "Designer bag", Description: "Brand new, too good to be true", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bag))
+
+		_, err := moderationService.ReportListing(bag.ID, 3, &dto.ReportRequest{Reason: "boring"})
+		assert.ErrorIs(t, err, service.ErrInvalidReportReason)
+
+		_, err = moderationService.ReportListing(bag.ID, 1, &dto.ReportRequest{Reason: domain.ReportReasonSpam})
+		assert.ErrorIs(t, err, service.ErrOwnListingReport)
+
+		_, err = moderationService.ReportListing(bag.ID, 3, &dto.ReportRequest{Reason: domain.ReportReasonScam})
+		require.NoError(t, err)
+		_, err = moderationService.ReportListing(bag.ID, 3, &dto.ReportRequest{Reason: domain.ReportReasonSpam})
+		assert.ErrorIs(t, err, service.ErrAlreadyReported)
+	})
+
+	t.Run("should rate limit reporters", func(t *testing.T) {
+		config := service.DefaultModerationConfig()
+		config.MaxReports = 1
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		moderationService := service.NewModerationService(memory.NewInMemoryModerationRepository(), listingRepo,
+			mockUserRepo, config)
+
+		bag := &domain.Listing{Title: "Designer bag", Description: "Brand new, too good to be true", Price: 100, AuthorID: 1}
+		watch := &domain.Listing{Title: "Watch", Description: "Brand new, too good to be true", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bag))
+		require.NoError(t, listingRepo.Create(watch))
+		_, err := moderationService.ReportListing(bag.ID, 3, &dto.ReportRequest{Reason: domain.ReportReasonScam})
+		require.NoError(t, err)
+
+		_, err = moderationService.ReportListing(watch.ID, 3, &dto.ReportRequest{Reason: domain.ReportReasonSpam})
+
+		assert.ErrorIs(t, err, service.ErrReportRateLimited)
+	})
+
+	t.Run("should hide listing from the feed at the threshold", func(t *testing.T) {
+		config := service.DefaultModerationConfig()
+		config.ReportThreshold = 3
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		moderationService := service.NewModerationService(memory.NewInMemoryModerationRepository(), listingRepo,
+			mockUserRepo, config)
+		listingService := service.NewListingService(listingRepo, mockUserRepo)
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		bag := &domain.Listing{Title: "Designer bag", Description: "Brand new, too good to be true", Price: 100, AuthorID: 1}
+		watch := &domain.Listing{Title: "Watch", Description: "Brand new, too good to be true", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bag))
+		require.NoError(t, listingRepo.Create(watch))
+
+		for _, reporterID := range []int64{3, 4} {
+			_, err := moderationService.ReportListing(bag.ID, reporterID, &dto.ReportRequest{Reason: domain.ReportReasonScam})
+			require.NoError(t, err)
+		}
+		feed, err := listingService.GetListingsWithPagination("date", "desc", nil, nil, 1, 10, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 2, feed.TotalCount)
+
+		_, err = moderationService.ReportListing(bag.ID, 5, &dto.ReportRequest{Reason: domain.ReportReasonScam})
+		require.NoError(t, err)
+		feed, err = listingService.GetListingsWithPagination("date", "desc", nil, nil, 1, 10, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 1, feed.TotalCount)
+
+		queue, err := moderationService.GetQueue(domain.CaseStatusOpen)
+		require.NoError(t, err)
+		require.Len(t, queue, 1)
+		assert.True(t, queue[0].AutoHidden)
+	})
+}
+
+func TestModerationService_Cases(t *testing.T) {
+	t.Run("should require a claim before resolving", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		moderationService := service.NewModerationService(memory.NewInMemoryModerationRepository(), listingRepo,
+			mockUserRepo, service.DefaultModerationConfig())
+
+		bag := &domain.Listing{Title: "Designer bag", Description: "Brand new, too good to be true", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bag))
+		_, err := moderationService.ReportListing(bag.ID, 3, &dto.ReportRequest{Reason: domain.ReportReasonScam})
+		require.NoError(t, err)
+		queue, err := moderationService.GetQueue(domain.CaseStatusOpen)
+		require.NoError(t, err)
+		require.Len(t, queue, 1)
+		caseID := queue[0].ID
+
+		_, err = moderationService.ResolveCase(caseID, 2, &dto.ResolveCaseRequest{Resolution: domain.ResolutionHide})
+		assert.ErrorIs(t, err, service.ErrCaseNotClaimed)
+
+		claimed, err := moderationService.ClaimCase(caseID, 2)
+		require.NoError(t, err)
+		assert.Equal(t, domain.CaseStatusClaimed, claimed.Status)
+
+		_, err = moderationService.ClaimCase(caseID, 3)
+		assert.ErrorIs(t, err, service.ErrCaseClaimed)
+
+		resolved, err := moderationService.ResolveCase(caseID, 2, &dto.ResolveCaseRequest{Resolution: domain.ResolutionHide})
+		require.NoError(t, err)
+		assert.Equal(t, domain.CaseStatusResolved, resolved.Status)
+		assert.Equal(t, domain.ListingStatusHidden, resolved.ListingStatus)
+	})
+
+	t.Run("should allow taking over a stale claim", func(t *testing.T) {
+		config := service.DefaultModerationConfig()
+		config.ClaimTimeout = time.Nanosecond
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		moderationService := service.NewModerationService(memory.NewInMemoryModerationRepository(), listingRepo,
+			mockUserRepo, config)
+
+		bag := &domain.Listing{Title: "Designer bag", Description: "Brand new, too good to be true", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bag))
+		_, err := moderationService.ReportListing(bag.ID, 3, &dto.ReportRequest{Reason: domain.ReportReasonScam})
+		require.NoError(t, err)
+		queue, err := moderationService.GetQueue(domain.CaseStatusOpen)
+		require.NoError(t, err)
+		require.Len(t, queue, 1)
+		caseID := queue[0].ID
+
+		_, err = moderationService.ClaimCase(caseID, 2)
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond)
+
+		claimed, err := moderationService.ClaimCase(caseID, 4)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), *claimed.ClaimedBy)
+	})
+
+	t.Run("should restore an auto-hidden listing on dismiss", func(t *testing.T) {
+		config := service.DefaultModerationConfig()
+		config.ReportThreshold = 1
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		moderationService := service.NewModerationService(memory.NewInMemoryModerationRepository(), listingRepo,
+			mockUserRepo, config)
+
+		bag := &domain.Listing{Title: "Designer bag", Description: "Brand new, too good to be true", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bag))
+		_, err := moderationService.ReportListing(bag.ID, 3, &dto.ReportRequest{Reason: domain.ReportReasonScam})
+		require.NoError(t, err)
+		queue, err := moderationService.GetQueue(domain.CaseStatusOpen)
+		require.NoError(t, err)
+		require.Len(t, queue, 1)
+		caseID := queue[0].ID
+		_, err = moderationService.ClaimCase(caseID, 2)
+		require.NoError(t, err)
+
+		resolved, err := moderationService.ResolveCase(caseID, 2, &dto.ResolveCaseRequest{Resolution: domain.ResolutionDismiss})
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.ListingStatusActive, resolved.ListingStatus)
+		listing, err := listingRepo.GetByID(bag.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ListingStatusActive, listing.Status)
+	})
+
+	t.Run("should ban the author and hide their listings", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		moderationService := service.NewModerationService(memory.NewInMemoryModerationRepository(), listingRepo,
+			mockUserRepo, service.DefaultModerationConfig())
+
+		bag := &domain.Listing{Title: "Designer bag", Description: "Brand new, too good to be true", Price: 100, AuthorID: 1}
+		watch := &domain.Listing{Title: "Watch", Description: "Brand new, too good to be true", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bag))
+		require.NoError(t, listingRepo.Create(watch))
+		_, err := moderationService.ReportListing(bag.ID, 3, &dto.ReportRequest{Reason: domain.ReportReasonScam})
+		require.NoError(t, err)
+		queue, err := moderationService.GetQueue(domain.CaseStatusOpen)
+		require.NoError(t, err)
+		require.Len(t, queue, 1)
+		caseID := queue[0].ID
+		_, err = moderationService.ClaimCase(caseID, 2)
+		require.NoError(t, err)
+
+		mockUserRepo.On("SetBanned", 1, true).Return(nil)
+
+		_, err = moderationService.ResolveCase(caseID, 2, &dto.ResolveCaseRequest{Resolution: domain.ResolutionBan})
+
+		require.NoError(t, err)
+		for _, id := range []int64{bag.ID, watch.ID} {
+			listing, err := listingRepo.GetByID(id)
+			require.NoError(t, err)
+			assert.Equal(t, domain.ListingStatusHidden, listing.Status)
+		}
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestModerationService_FindDuplicates(t *testing.T) {
+	t.Run("should find reposts by other sellers", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		moderationService := service.NewModerationService(memory.NewInMemoryModerationRepository(), listingRepo,
+			mockUserRepo, service.DefaultModerationConfig())
+
+		original := &domain.Listing{Title: "Red bicycle", Description: "Red city bicycle with a steel frame, seven gears and new tyres. Barely used.", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(original))
+		repost := &domain.Listing{Title: "RED BICYCLE", Description: "Red city bicycle with a steel frame, seven gears and new tyres. Barely used!!", Price: 90, AuthorID: 3}
+		require.NoError(t, listingRepo.Create(repost))
+
+		duplicates, err := moderationService.FindDuplicates(original.ID)
+
+		require.NoError(t, err)
+		var found *dto.NearDuplicateDTO
+		for _, duplicate := range duplicates {
+			if duplicate.ListingID == repost.ID {
+				found = duplicate
+			}
+		}
+		require.NotNil(t, found)
+		assert.Equal(t, int64(3), found.AuthorID)
+		assert.Equal(t, "RED BICYCLE", found.ListingTitle)
+		assert.Greater(t, found.TextSimilarity, 0.9)
+	})
+
+	t.Run("should fail for unknown listings", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		moderationService := service.NewModerationService(memory.NewInMemoryModerationRepository(),
+			memory.NewInMemoryListingRepository(), mockUserRepo, service.DefaultModerationConfig())
+
+		_, err := moderationService.FindDuplicates(999)
+
+		assert.ErrorIs(t, err, service.ErrListingNotFound)
+	})
+}
@@ -0,0 +1,429 @@
+package service_test
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/payment"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chargeCountingProvider records how many charges and refunds reach the
+// provider.
+type chargeCountingProvider struct {
+	*payment.FakeProvider
+	charges atomic.Int32
+	refunds atomic.Int32
+}
+
+func (p *chargeCountingProvider) Charge(req payment.ChargeRequest) (*payment.Payment, error) {
+	p.charges.Add(1)
+	return p.FakeProvider.Charge(req)
+}
+
+func (p *chargeCountingProvider) Refund(paymentID string, amount int64) error {
+	p.refunds.Add(1)
+	return p.FakeProvider.Refund(paymentID, amount)
+}
+
+func TestOrderService_CreateOrder(t *testing.T) {
+	t.Run("should reserve the listing", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+
+		order, err := orderService.CreateOrder([]int64{bicycle.ID}, 2)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusCreated, order.Status)
+		assert.Equal(t, int64(5000), order.Amount)
+		assert.Equal(t, int64(1), order.SellerID)
+		listing, err := listingRepo.GetByID(bicycle.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ListingStatusReserved, listing.Status)
+	})
+
+	t.Run("should not allow ordering own listing", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+
+		_, err := orderService.CreateOrder([]int64{bicycle.ID}, 1)
+
+		assert.ErrorIs(t, err, service.ErrOwnListingOrder)
+	})
+
+	t.Run("should not mix currencies in one order", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		dollars := &domain.Listing{Title: "Helmet", Description: "Bicycle helmet", Price: 3000, Currency: "USD", AuthorID: 1}
+		require.NoError(t, listingRepo.Create(dollars))
+
+		_, err := orderService.CreateOrder([]int64{bicycle.ID, dollars.ID}, 2)
+
+		assert.ErrorIs(t, err, service.ErrMixedCurrencies)
+		listing, err := listingRepo.GetByID(bicycle.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ListingStatusActive, listing.Status)
+	})
+
+	t.Run("should sell a listing only once under concurrency", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+
+		var created atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(buyer int64) {
+				defer wg.Done()
+				if _, err := orderService.CreateOrder([]int64{bicycle.ID}, buyer); err == nil {
+					created.Add(1)
+				}
+			}(int64(100 + i))
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), created.Load())
+	})
+}
+
+func TestOrderService_PayOrder(t *testing.T) {
+	t.Run("should mark order paid and listing sold", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		order, err := orderService.CreateOrder([]int64{bicycle.ID}, 2)
+		require.NoError(t, err)
+
+		paid, err := orderService.PayOrder(order.ID, 2)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusPaid, paid.Status)
+		listing, err := listingRepo.GetByID(bicycle.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ListingStatusSold, listing.Status)
+	})
+
+	t.Run("should keep order open when payment is declined", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeFailure, 0))
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		order, err := orderService.CreateOrder([]int64{bicycle.ID}, 2)
+		require.NoError(t, err)
+
+		_, err = orderService.PayOrder(order.ID, 2)
+
+		assert.ErrorIs(t, err, service.ErrPaymentFailed)
+		current, err := orderService.GetOrder(order.ID, 2)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusCreated, current.Status)
+	})
+
+	t.Run("should settle asynchronous payments via webhook", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		provider := payment.NewFakeProvider("secret", payment.FakeModeAsync, 0)
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo, provider)
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		delivered := make(chan error, 1)
+		provider.OnWebhook(func(payload []byte, signature string) {
+			delivered <- orderService.HandlePaymentWebhook(payload, signature)
+		})
+		order, err := orderService.CreateOrder([]int64{bicycle.ID}, 2)
+		require.NoError(t, err)
+
+		pending, err := orderService.PayOrder(order.ID, 2)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusCreated, pending.Status)
+
+		select {
+		case err := <-delivered:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("webhook was not delivered")
+		}
+
+		current, err := orderService.GetOrder(order.ID, 2)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusPaid, current.Status)
+		listing, err := listingRepo.GetByID(bicycle.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ListingStatusSold, listing.Status)
+	})
+
+	t.Run("should charge an order only once under concurrency", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		provider := &chargeCountingProvider{FakeProvider: payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0)}
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo, provider)
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		order, err := orderService.CreateOrder([]int64{bicycle.ID}, 2)
+		require.NoError(t, err)
+
+		var paid atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := orderService.PayOrder(order.ID, 2); err == nil {
+					paid.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), paid.Load())
+		assert.Equal(t, int32(1), provider.charges.Load())
+	})
+
+	t.Run("should allow retrying a declined payment", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		provider := payment.NewFakeProvider("secret", payment.FakeModeFailure, 0)
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo, provider)
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		order, err := orderService.CreateOrder([]int64{bicycle.ID}, 2)
+		require.NoError(t, err)
+		_, err = orderService.PayOrder(order.ID, 2)
+		require.ErrorIs(t, err, service.ErrPaymentFailed)
+
+		provider.SetMode(payment.FakeModeSuccess)
+		paid, err := orderService.PayOrder(order.ID, 2)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusPaid, paid.Status)
+	})
+
+	t.Run("should refund and reopen the order when its listing cannot be sold", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		provider := &chargeCountingProvider{FakeProvider: payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0)}
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo, provider)
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		order, err := orderService.CreateOrder([]int64{bicycle.ID}, 2)
+		require.NoError(t, err)
+		require.NoError(t, listingRepo.UpdateStatus(bicycle.ID, domain.ListingStatusReserved, domain.ListingStatusHidden))
+
+		_, err = orderService.PayOrder(order.ID, 2)
+
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), provider.refunds.Load())
+		current, err := orderService.GetOrder(order.ID, 2)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusCreated, current.Status)
+	})
+
+	t.Run("should charge again when paying after a refund", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderRepo := memory.NewInMemoryOrderRepository()
+		provider := &chargeCountingProvider{FakeProvider: payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0)}
+		orderService := service.NewOrderService(orderRepo, listingRepo, provider)
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		order, err := orderService.CreateOrder([]int64{bicycle.ID}, 2)
+		require.NoError(t, err)
+		require.NoError(t, listingRepo.UpdateStatus(bicycle.ID, domain.ListingStatusReserved, domain.ListingStatusHidden))
+		_, err = orderService.PayOrder(order.ID, 2)
+		require.Error(t, err)
+		require.NoError(t, listingRepo.UpdateStatus(bicycle.ID, domain.ListingStatusHidden, domain.ListingStatusReserved))
+
+		paid, err := orderService.PayOrder(order.ID, 2)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusPaid, paid.Status)
+		assert.Equal(t, int32(1), provider.refunds.Load())
+		stored, err := orderRepo.GetByID(order.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "fake_2", stored.PaymentID, "the second attempt is a new charge")
+	})
+
+	t.Run("should reject webhooks with a bad signature", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeAsync, 0))
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		payload, _ := json.Marshal(payment.Event{PaymentID: "fake_1", Status: payment.StatusSucceeded})
+
+		err := orderService.HandlePaymentWebhook(payload, "bogus")
+
+		assert.ErrorIs(t, err, payment.ErrInvalidSignature)
+	})
+}
+
+func TestOrderService_Lifecycle(t *testing.T) {
+	t.Run("should release the listing on cancel", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		order, err := orderService.CreateOrder([]int64{bicycle.ID}, 2)
+		require.NoError(t, err)
+
+		cancelled, err := orderService.CancelOrder(order.ID, 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusCancelled, cancelled.Status)
+		listing, err := listingRepo.GetByID(bicycle.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ListingStatusActive, listing.Status)
+	})
+
+	t.Run("should go through shipping to completion", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		order, err := orderService.CreateOrder([]int64{bicycle.ID}, 2)
+		require.NoError(t, err)
+
+		_, err = orderService.ShipOrder(order.ID, 1)
+		assert.ErrorIs(t, err, service.ErrInvalidOrderState)
+
+		_, err = orderService.PayOrder(order.ID, 2)
+		require.NoError(t, err)
+		_, err = orderService.ShipOrder(order.ID, 2)
+		assert.ErrorIs(t, err, service.ErrOrderForbidden)
+		_, err = orderService.ShipOrder(order.ID, 1)
+		require.NoError(t, err)
+
+		completed, err := orderService.CompleteOrder(order.ID, 2)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusCompleted, completed.Status)
+	})
+
+	t.Run("should refund a paid order", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		order, err := orderService.CreateOrder([]int64{bicycle.ID}, 2)
+		require.NoError(t, err)
+		_, err = orderService.PayOrder(order.ID, 2)
+		require.NoError(t, err)
+
+		refunded, err := orderService.RefundOrder(order.ID, 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusRefunded, refunded.Status)
+		listing, err := listingRepo.GetByID(bicycle.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ListingStatusActive, listing.Status)
+	})
+
+	t.Run("should refund an order only once under concurrency", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		provider := &chargeCountingProvider{FakeProvider: payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0)}
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo, provider)
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		order, err := orderService.CreateOrder([]int64{bicycle.ID}, 2)
+		require.NoError(t, err)
+		_, err = orderService.PayOrder(order.ID, 2)
+		require.NoError(t, err)
+
+		var refunded atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := orderService.RefundOrder(order.ID, 1); err == nil {
+					refunded.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), refunded.Load())
+		assert.Equal(t, int32(1), provider.refunds.Load())
+	})
+
+	t.Run("should keep the order paid when the refund fails", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderRepo := memory.NewInMemoryOrderRepository()
+		orderService := service.NewOrderService(orderRepo, listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		order, err := orderService.CreateOrder([]int64{bicycle.ID}, 2)
+		require.NoError(t, err)
+		_, err = orderService.PayOrder(order.ID, 2)
+		require.NoError(t, err)
+		stored, err := orderRepo.GetByID(order.ID)
+		require.NoError(t, err)
+		stored.PaymentID = "fake_unknown"
+		require.NoError(t, orderRepo.Update(stored, domain.OrderStatusPaid))
+
+		_, err = orderService.RefundOrder(order.ID, 1)
+
+		assert.Error(t, err)
+		current, err := orderService.GetOrder(order.ID, 2)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusPaid, current.Status)
+	})
+
+	t.Run("should list orders by role", func(t *testing.T) {
+		listingRepo := memory.NewInMemoryListingRepository()
+		orderService := service.NewOrderService(memory.NewInMemoryOrderRepository(), listingRepo,
+			payment.NewFakeProvider("secret", payment.FakeModeSuccess, 0))
+
+		bicycle := &domain.Listing{Title: "Bicycle", Description: "City bicycle in good shape", Price: 5000, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(bicycle))
+		_, err := orderService.CreateOrder([]int64{bicycle.ID}, 2)
+		require.NoError(t, err)
+
+		bought, err := orderService.GetOrders(2, service.OrderRoleBuyer)
+		require.NoError(t, err)
+		sold, err := orderService.GetOrders(1, service.OrderRoleSeller)
+		require.NoError(t, err)
+
+		assert.Len(t, bought, 1)
+		assert.Len(t, sold, 1)
+		require.Len(t, sold[0].Items, 1)
+		assert.Equal(t, "Bicycle", sold[0].Items[0].ListingTitle)
+	})
+}
@@ -0,0 +1,100 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/password"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func storedHash(t *testing.T, userRepo *memory.InMemoryUserRepository, login string) string {
+	t.Helper()
+	user, err := userRepo.GetByLogin(login)
+	require.NoError(t, err)
+	return user.Password
+}
+
+func TestAuthService_PasswordRehash(t *testing.T) {
+	t.Run("upgrades bcrypt hashes on login", func(t *testing.T) {
+		userRepo := memory.NewInMemoryUserRepository()
+		legacy, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+		require.NoError(t, err)
+		require.NoError(t, userRepo.Create(&domain.User{Login: "alice", Password: string(legacy)}))
+		authService := service.NewAuthService(userRepo)
+
+		_, _, err = authService.LoginUser("alice", "wrong password")
+		require.Error(t, err)
+		assert.Equal(t, string(legacy), storedHash(t, userRepo, "alice"), "failed logins keep the hash")
+
+		_, _, err = authService.LoginUser("alice", "password123")
+		require.NoError(t, err)
+		upgraded := storedHash(t, userRepo, "alice")
+		assert.True(t, strings.HasPrefix(upgraded, "$argon2id$"), upgraded)
+
+		_, _, err = authService.LoginUser("alice", "password123")
+		require.NoError(t, err)
+		assert.Equal(t, upgraded, storedHash(t, userRepo, "alice"), "current hashes are kept")
+	})
+
+	t.Run("upgrades hashes with outdated parameters", func(t *testing.T) {
+		userRepo := memory.NewInMemoryUserRepository()
+		old := password.DefaultArgon2idParams()
+		old.Memory = 1024
+		_, err := service.NewAuthService(userRepo, service.WithPasswordHasher(password.NewArgon2id(old))).
+			RegisterUser("alice", "password123")
+		require.NoError(t, err)
+		assert.Contains(t, storedHash(t, userRepo, "alice"), "$m=1024,")
+
+		authService := service.NewAuthService(userRepo)
+		_, _, err = authService.LoginUser("alice", "password123")
+		require.NoError(t, err)
+		assert.Contains(t, storedHash(t, userRepo, "alice"), "$m=19456,")
+	})
+
+	t.Run("accepts passwords beyond the bcrypt limit", func(t *testing.T) {
+		authService := service.NewAuthService(memory.NewInMemoryUserRepository())
+		long := strings.Repeat("a", 100)
+		_, err := authService.RegisterUser("alice", long)
+		require.NoError(t, err)
+
+		_, _, err = authService.LoginUser("alice", long)
+		assert.NoError(t, err)
+		_, _, err = authService.LoginUser("alice", strings.Repeat("a", 72))
+		assert.Error(t, err, "bytes past 72 count")
+
+		_, err = authService.RegisterUser("bob", strings.Repeat("a", 129))
+		assert.ErrorIs(t, err, service.ErrWeakPassword)
+	})
+
+	t.Run("bcrypt hasher refuses passwords it would truncate", func(t *testing.T) {
+		authService := service.NewAuthService(memory.NewInMemoryUserRepository(),
+			service.WithPasswordHasher(password.NewBcrypt(bcrypt.MinCost)))
+		_, err := authService.RegisterUser("alice", strings.Repeat("a", 100))
+		assert.ErrorIs(t, err, service.ErrWeakPassword)
+	})
+}
+
+func TestAuthService_PasswordBlocklist(t *testing.T) {
+	userRepo := memory.NewInMemoryUserRepository()
+	authService := service.NewAuthService(userRepo, service.WithPasswordBlocklist(password.CommonPasswords()))
+
+	_, err := authService.RegisterUser("alice", "Password123")
+	assert.ErrorIs(t, err, service.ErrCommonPassword)
+
+	user, err := authService.RegisterUser("alice", "correct horse")
+	require.NoError(t, err)
+	assert.ErrorIs(t, authService.ChangePassword(user.ID, "correct horse", "qwerty123"), service.ErrCommonPassword)
+	assert.NoError(t, authService.ChangePassword(user.ID, "correct horse", "battery staple"))
+
+	// Existing passwords on the list still log in.
+	_, err = service.NewAuthService(userRepo).RegisterUser("bob", "letmein")
+	require.NoError(t, err)
+	_, _, err = authService.LoginUser("bob", "letmein")
+	assert.NoError(t, err)
+}
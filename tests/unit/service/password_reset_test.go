@@ -0,0 +1,129 @@
+package service_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+	"vk/ecom/internal/pkg/mail"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMailer struct {
+	sent []*mail.Message
+}
+
+func (m *recordingMailer) Send(msg *mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var resetTokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+func mailedResetToken(t *testing.T, msg *mail.Message) string {
+	t.Helper()
+	match := resetTokenPattern.FindStringSubmatch(msg.Body)
+	require.NotNil(t, match, "no reset link in %q", msg.Body)
+	return match[1]
+}
+
+func newPasswordResetService(t *testing.T, config service.PasswordResetConfig) (*service.AuthService, *recordingMailer) {
+	t.Helper()
+	mailer := &recordingMailer{}
+	authService := service.NewAuthService(memory.NewInMemoryUserRepository(),
+		service.WithPasswordReset(memory.NewInMemoryPasswordResetRepository(), mailer, config),
+	)
+	_, err := authService.RegisterUserWithEmail("alice", "password123", "alice@example.com")
+	require.NoError(t, err)
+	return authService, mailer
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	authService := service.NewAuthService(memory.NewInMemoryUserRepository())
+	user, err := authService.RegisterUser("alice", "password123")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, authService.ChangePassword(user.ID, "wrong", "newpassword"), service.ErrWrongPassword)
+	assert.ErrorIs(t, authService.ChangePassword(user.ID, "password123", "short"), service.ErrWeakPassword)
+
+	require.NoError(t, authService.ChangePassword(user.ID, "password123", "newpassword"))
+	_, _, err = authService.LoginUser("alice", "password123")
+	assert.Error(t, err)
+	_, _, err = authService.LoginUser("alice", "newpassword")
+	assert.NoError(t, err)
+}
+
+func TestAuthService_PasswordReset(t *testing.T) {
+	config := service.DefaultPasswordResetConfig()
+	config.ResetURL = "https://shop.example.com/reset"
+
+	t.Run("resets the password once and revokes sessions", func(t *testing.T) {
+		authService, mailer := newPasswordResetService(t, config)
+		oldToken, _, err := authService.LoginUser("alice", "password123")
+		require.NoError(t, err)
+
+		require.NoError(t, authService.RequestPasswordReset("alice"))
+		require.Len(t, mailer.sent, 1)
+		assert.Equal(t, "alice@example.com", mailer.sent[0].To)
+		assert.Contains(t, mailer.sent[0].Body, "https://shop.example.com/reset?token=")
+		token := mailedResetToken(t, mailer.sent[0])
+
+		assert.ErrorIs(t, authService.ResetPassword(token, "short"), service.ErrWeakPassword)
+		require.NoError(t, authService.ResetPassword(token, "newpassword"))
+		assert.ErrorIs(t, authService.ResetPassword(token, "otherpassword"), service.ErrInvalidResetToken, "tokens are single-use")
+
+		_, err = authService.ValidateToken(oldToken)
+		assert.Error(t, err, "sessions from before the reset are revoked")
+
+		newToken, _, err := authService.LoginUser("alice", "newpassword")
+		require.NoError(t, err)
+		_, err = authService.ValidateToken(newToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("a reset uses up other pending tokens", func(t *testing.T) {
+		authService, mailer := newPasswordResetService(t, config)
+		require.NoError(t, authService.RequestPasswordReset("alice"))
+		require.NoError(t, authService.RequestPasswordReset("alice"))
+		require.Len(t, mailer.sent, 2)
+
+		require.NoError(t, authService.ResetPassword(mailedResetToken(t, mailer.sent[1]), "newpassword"))
+		assert.ErrorIs(t, authService.ResetPassword(mailedResetToken(t, mailer.sent[0]), "otherpassword"), service.ErrInvalidResetToken)
+	})
+
+	t.Run("expired tokens are rejected", func(t *testing.T) {
+		expired := config
+		expired.TokenTTL = -time.Minute
+		authService, mailer := newPasswordResetService(t, expired)
+		require.NoError(t, authService.RequestPasswordReset("alice"))
+		require.Len(t, mailer.sent, 1)
+
+		assert.ErrorIs(t, authService.ResetPassword(mailedResetToken(t, mailer.sent[0]), "newpassword"), service.ErrInvalidResetToken)
+	})
+
+	t.Run("unknown logins get no mail and no error", func(t *testing.T) {
+		authService, mailer := newPasswordResetService(t, config)
+		assert.NoError(t, authService.RequestPasswordReset("nobody"))
+		assert.Empty(t, mailer.sent)
+	})
+
+	t.Run("reset needs a mailer", func(t *testing.T) {
+		authService := service.NewAuthService(memory.NewInMemoryUserRepository())
+		assert.ErrorIs(t, authService.RequestPasswordReset("alice"), service.ErrPasswordResetDisabled)
+		assert.ErrorIs(t, authService.ResetPassword("token", "newpassword"), service.ErrPasswordResetDisabled)
+	})
+}
+
+func TestAuthService_RegisterUserWithEmail(t *testing.T) {
+	authService := service.NewAuthService(memory.NewInMemoryUserRepository())
+
+	_, err := authService.RegisterUserWithEmail("alice", "password123", "Alice <alice@example.com>")
+	assert.EqualError(t, err, "invalid email address")
+
+	user, err := authService.RegisterUserWithEmail("alice", "password123", "alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", user.Email)
+}
@@ -0,0 +1,105 @@
+package service_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/pkg/notify"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []*notify.Notification
+}
+
+func (r *recordingNotifier) Notify(n *notify.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func TestListingService_PriceDrops(t *testing.T) {
+	listingRepo := memory.NewInMemoryListingRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	require.NoError(t, userRepo.Create(&domain.User{Login: "alice"}))
+	notifier := &recordingNotifier{}
+	listingService := service.NewListingService(listingRepo, userRepo,
+		service.WithFavorites(memory.NewInMemoryFavoriteRepository(), notifier))
+
+	request := func(price int64) *dto.ListingRequest {
+		return &dto.ListingRequest{Title: "Bicycle", Description: "City bicycle in good shape", ImageURL: "https://example.com/a.jpg", Price: price}
+	}
+	listing, err := listingService.CreateListing(request(5000), 1)
+	require.NoError(t, err)
+	other, err := listingService.CreateListing(request(3000), 1)
+	require.NoError(t, err)
+
+	require.NoError(t, listingService.AddFavorite(listing.ID, 2))
+	require.NoError(t, listingService.AddFavorite(listing.ID, 3))
+	require.NoError(t, listingService.AddFavorite(listing.ID, 1))
+	require.NoError(t, listingService.RemoveFavorite(listing.ID, 3))
+
+	t.Run("should show the previous price and notify favoriters on a drop", func(t *testing.T) {
+		updated, err := listingService.UpdateListing(listing.ID, 1, request(4500))
+		require.NoError(t, err)
+		updated, err = listingService.UpdateListing(listing.ID, 1, request(4200))
+		require.NoError(t, err)
+
+		require.NotNil(t, updated.PreviousPrice)
+		assert.Equal(t, int64(5000), *updated.PreviousPrice)
+		require.Len(t, notifier.sent, 2)
+		assert.Equal(t, int64(2), notifier.sent[1].UserID)
+		assert.Equal(t, notify.KindPriceDrop, notifier.sent[1].Kind)
+		assert.Contains(t, notifier.sent[1].Text, "42.00 RUB")
+	})
+
+	t.Run("should filter the feed by recent drops", func(t *testing.T) {
+		result, err := listingService.SearchListings(&dto.ListingSearchRequest{PriceDropped: true}, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{listing.ID}, listingIDs(result.Listings))
+	})
+
+	t.Run("should record every price change", func(t *testing.T) {
+		history, err := listingService.GetPriceHistory(listing.ID, nil)
+
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, int64(5000), history[0].OldPrice)
+		assert.Equal(t, int64(4200), history[1].NewPrice)
+
+		history, err = listingService.GetPriceHistory(other.ID, nil)
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+
+	t.Run("should clear the drop once the price is back up", func(t *testing.T) {
+		updated, err := listingService.UpdateListing(listing.ID, 1, request(5000))
+
+		require.NoError(t, err)
+		assert.Nil(t, updated.PreviousPrice)
+		assert.Len(t, notifier.sent, 2)
+	})
+
+	t.Run("should not show drops older than the window", func(t *testing.T) {
+		old := time.Now().Add(-domain.PriceDropWindow - time.Hour)
+		previous := int64(4000)
+		stored, err := listingRepo.GetByID(other.ID)
+		require.NoError(t, err)
+		stored.PreviousPrice, stored.PriceDroppedAt = &previous, &old
+
+		_, ok := stored.RecentPriceDrop(time.Now())
+		assert.False(t, ok)
+		result, err := listingService.SearchListings(&dto.ListingSearchRequest{PriceDropped: true}, nil)
+		require.NoError(t, err)
+		assert.Empty(t, result.Listings)
+	})
+}
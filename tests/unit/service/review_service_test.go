@@ -0,0 +1,170 @@
+package service_test
+
+import (
+	"testing"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/mocks"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewService_CreateReview(t *testing.T) {
+	t.Run("should require a confirmed purchase", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		reviewService := service.NewReviewService(memory.NewInMemoryReviewRepository(), listingRepo, mockUserRepo)
+
+		guitar := &domain.Listing{Title: "Guitar", Description: "Musical equipment", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(guitar))
+
+		_, err := reviewService.CreateReview(guitar.ID, 2, &dto.ReviewRequest{Rating: 5})
+
+		assert.ErrorIs(t, err, service.ErrReviewNotAllowed)
+	})
+
+	t.Run("should only allow the confirmed buyer", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		reviewService := service.NewReviewService(memory.NewInMemoryReviewRepository(), listingRepo, mockUserRepo)
+
+		guitar := &domain.Listing{Title: "Guitar", Description: "Musical equipment", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(guitar))
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "buyer"}, nil)
+		require.NoError(t, reviewService.ConfirmPurchaser(guitar.ID, 1, 2))
+
+		_, err := reviewService.CreateReview(guitar.ID, 3, &dto.ReviewRequest{Rating: 5})
+
+		assert.ErrorIs(t, err, service.ErrReviewNotAllowed)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("should allow one review per listing", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		reviewService := service.NewReviewService(memory.NewInMemoryReviewRepository(), listingRepo, mockUserRepo)
+
+		guitar := &domain.Listing{Title: "Guitar", Description: "Musical equipment", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(guitar))
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "buyer"}, nil)
+		require.NoError(t, reviewService.ConfirmPurchaser(guitar.ID, 1, 2))
+
+		review, err := reviewService.CreateReview(guitar.ID, 2, &dto.ReviewRequest{Rating: 4, Text: "Works well"})
+		require.NoError(t, err)
+		assert.Equal(t, "buyer", review.AuthorLogin)
+
+		_, err = reviewService.CreateReview(guitar.ID, 2, &dto.ReviewRequest{Rating: 1})
+		assert.ErrorIs(t, err, service.ErrReviewExists)
+	})
+
+	t.Run("should validate the rating", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		reviewService := service.NewReviewService(memory.NewInMemoryReviewRepository(), listingRepo, mockUserRepo)
+
+		guitar := &domain.Listing{Title: "Guitar", Description: "Musical equipment", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(guitar))
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "buyer"}, nil)
+		require.NoError(t, reviewService.ConfirmPurchaser(guitar.ID, 1, 2))
+
+		_, err := reviewService.CreateReview(guitar.ID, 2, &dto.ReviewRequest{Rating: 6})
+
+		assert.ErrorIs(t, err, service.ErrInvalidReview)
+	})
+
+	t.Run("should not let others confirm purchasers", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		reviewService := service.NewReviewService(memory.NewInMemoryReviewRepository(), listingRepo, mockUserRepo)
+
+		guitar := &domain.Listing{Title: "Guitar", Description: "Musical equipment", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(guitar))
+
+		err := reviewService.ConfirmPurchaser(guitar.ID, 3, 2)
+
+		assert.ErrorIs(t, err, service.ErrNotListingOwner)
+	})
+}
+
+func TestReviewService_ReplyToReview(t *testing.T) {
+	t.Run("should accept a single seller reply", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		reviewService := service.NewReviewService(memory.NewInMemoryReviewRepository(), listingRepo, mockUserRepo)
+
+		guitar := &domain.Listing{Title: "Guitar", Description: "Musical equipment", Price: 100, AuthorID: 1}
+		require.NoError(t, listingRepo.Create(guitar))
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "buyer"}, nil)
+		require.NoError(t, reviewService.ConfirmPurchaser(guitar.ID, 1, 2))
+		review, err := reviewService.CreateReview(guitar.ID, 2, &dto.ReviewRequest{Rating: 3})
+		require.NoError(t, err)
+
+		_, err = reviewService.ReplyToReview(review.ID, 2, "Thanks")
+		assert.ErrorIs(t, err, service.ErrNotListingOwner)
+
+		_, err = reviewService.ReplyToReview(review.ID, 1, "   ")
+		assert.ErrorIs(t, err, service.ErrInvalidReply)
+
+		replied, err := reviewService.ReplyToReview(review.ID, 1, "Thanks for the feedback")
+		require.NoError(t, err)
+		assert.Equal(t, "Thanks for the feedback", replied.Reply)
+
+		_, err = reviewService.ReplyToReview(review.ID, 1, "Edited reply")
+		assert.ErrorIs(t, err, service.ErrReplyExists)
+	})
+}
+
+func TestReviewService_Ratings(t *testing.T) {
+	t.Run("should aggregate ratings on profile and listings", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		reviewRepo := memory.NewInMemoryReviewRepository()
+		reviewService := service.NewReviewService(reviewRepo, listingRepo, mockUserRepo)
+		listingService := service.NewListingService(listingRepo, mockUserRepo, service.WithSellerRatings(reviewRepo))
+
+		mockUserRepo.On("GetByID", 1).Return(&domain.User{ID: 1, Login: "seller"}, nil)
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "buyer"}, nil)
+		for _, review := range []struct {
+			title  string
+			rating int
+		}{{"Guitar", 5}, {"Amplifier", 4}} {
+			listing := &domain.Listing{Title: review.title, Description: "Musical equipment", Price: 100, AuthorID: 1}
+			require.NoError(t, listingRepo.Create(listing))
+			require.NoError(t, reviewService.ConfirmPurchaser(listing.ID, 1, 2))
+			_, err := reviewService.CreateReview(listing.ID, 2, &dto.ReviewRequest{Rating: review.rating})
+			require.NoError(t, err)
+		}
+
+		profile, err := reviewService.GetUserProfile(1)
+		require.NoError(t, err)
+		require.NotNil(t, profile.Rating)
+		assert.Equal(t, 4.5, *profile.Rating)
+		assert.Equal(t, 2, profile.ReviewCount)
+
+		response, err := listingService.GetListingsWithPagination("date", "desc", nil, nil, 1, 10, nil)
+		require.NoError(t, err)
+		require.Len(t, response.Listings, 2)
+		for _, listing := range response.Listings {
+			require.NotNil(t, listing.AuthorRating)
+			assert.Equal(t, 4.5, *listing.AuthorRating)
+		}
+	})
+
+	t.Run("should leave rating empty for unreviewed sellers", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		reviewService := service.NewReviewService(memory.NewInMemoryReviewRepository(),
+			memory.NewInMemoryListingRepository(), mockUserRepo)
+
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "buyer"}, nil)
+
+		profile, err := reviewService.GetUserProfile(2)
+
+		require.NoError(t, err)
+		assert.Nil(t, profile.Rating)
+		assert.Equal(t, 0, profile.ReviewCount)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
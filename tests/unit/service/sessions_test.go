@@ -0,0 +1,123 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/jwt"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingSessionRepository counts last-seen writes.
+type countingSessionRepository struct {
+	*memory.InMemorySessionRepository
+	lastSeenWrites int
+}
+
+func (r *countingSessionRepository) SetLastSeen(id int64, at time.Time) error {
+	r.lastSeenWrites++
+	return r.InMemorySessionRepository.SetLastSeen(id, at)
+}
+
+func newSessionService(t *testing.T, config service.SessionConfig) (*service.AuthService, *countingSessionRepository) {
+	t.Helper()
+	sessions := &countingSessionRepository{InMemorySessionRepository: memory.NewInMemorySessionRepository()}
+	authService := service.NewAuthService(memory.NewInMemoryUserRepository(), service.WithSessions(sessions, config))
+	_, err := authService.RegisterUser("alice", "password123")
+	require.NoError(t, err)
+	return authService, sessions
+}
+
+func TestAuthService_Sessions(t *testing.T) {
+	laptop := domain.ClientInfo{IP: "10.0.0.1", UserAgent: "Firefox"}
+	phone := domain.ClientInfo{IP: "10.0.0.2", UserAgent: "Safari"}
+
+	t.Run("records a session per login", func(t *testing.T) {
+		authService, _ := newSessionService(t, service.DefaultSessionConfig())
+		token, user, err := authService.LoginUserFrom("alice", "password123", laptop)
+		require.NoError(t, err)
+		require.NotZero(t, user.SessionID)
+		_, _, err = authService.LoginUserFrom("alice", "password123", phone)
+		require.NoError(t, err)
+
+		validated, err := authService.ValidateToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, user.SessionID, validated.SessionID)
+
+		sessions, err := authService.GetSessions(user.ID, user.SessionID)
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+		byIP := map[string]bool{}
+		for _, s := range sessions {
+			byIP[s.IP+" "+s.UserAgent] = s.Current
+		}
+		assert.Equal(t, map[string]bool{"10.0.0.1 Firefox": true, "10.0.0.2 Safari": false}, byIP)
+	})
+
+	t.Run("tokens of revoked sessions are rejected", func(t *testing.T) {
+		authService, _ := newSessionService(t, service.DefaultSessionConfig())
+		laptopToken, laptopUser, err := authService.LoginUserFrom("alice", "password123", laptop)
+		require.NoError(t, err)
+		phoneToken, phoneUser, err := authService.LoginUserFrom("alice", "password123", phone)
+		require.NoError(t, err)
+		otherToken, _, err := authService.LoginUserFrom("alice", "password123", phone)
+		require.NoError(t, err)
+
+		require.NoError(t, authService.RevokeSession(phoneUser.ID, phoneUser.SessionID))
+		_, err = authService.ValidateToken(phoneToken)
+		assert.Error(t, err)
+		assert.ErrorIs(t, authService.RevokeSession(phoneUser.ID, phoneUser.SessionID), service.ErrSessionNotFound)
+		assert.ErrorIs(t, authService.RevokeSession(phoneUser.ID+1, laptopUser.SessionID), service.ErrSessionNotFound)
+
+		revoked, err := authService.RevokeOtherSessions(laptopUser.ID, laptopUser.SessionID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, revoked)
+		_, err = authService.ValidateToken(otherToken)
+		assert.Error(t, err)
+		_, err = authService.ValidateToken(laptopToken)
+		assert.NoError(t, err, "the current session survives")
+	})
+
+	t.Run("tokens without a session are rejected", func(t *testing.T) {
+		authService, _ := newSessionService(t, service.DefaultSessionConfig())
+		token, err := jwt.GenerateToken(&domain.User{ID: 1, Login: "alice"})
+		require.NoError(t, err)
+
+		_, err = authService.ValidateToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("last seen is written at most once per interval", func(t *testing.T) {
+		config := service.DefaultSessionConfig()
+		config.LastSeenInterval = time.Hour
+		authService, sessions := newSessionService(t, config)
+		token, _, err := authService.LoginUserFrom("alice", "password123", laptop)
+		require.NoError(t, err)
+
+		for i := 0; i < 5; i++ {
+			_, err := authService.ValidateToken(token)
+			require.NoError(t, err)
+		}
+		assert.Zero(t, sessions.lastSeenWrites)
+
+		config.LastSeenInterval = 0
+		authService, sessions = newSessionService(t, config)
+		token, _, err = authService.LoginUserFrom("alice", "password123", laptop)
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			_, err := authService.ValidateToken(token)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 3, sessions.lastSeenWrites)
+	})
+
+	t.Run("needs a session store", func(t *testing.T) {
+		authService := service.NewAuthService(memory.NewInMemoryUserRepository())
+		_, err := authService.GetSessions(1, 0)
+		assert.ErrorIs(t, err, service.ErrSessionsDisabled)
+	})
+}
@@ -0,0 +1,133 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/dto"
+	"vk/ecom/internal/mocks"
+	"vk/ecom/internal/pkg/money"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listingIDs(listings []*dto.ListingDTO) []int64 {
+	ids := make([]int64, 0, len(listings))
+	for _, listing := range listings {
+		ids = append(ids, listing.ID)
+	}
+	return ids
+}
+
+func TestListingService_GetSimilarListings(t *testing.T) {
+	t.Run("should rank similar listings of other sellers", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		cache := service.NewSimilarListingsCache(time.Minute, 100)
+		listingRepo := cache.Watch(memory.NewInMemoryListingRepository())
+		listingService := service.NewListingService(listingRepo, mockUserRepo, service.WithSimilarListingsCache(cache))
+
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "bob"}, nil)
+		mockUserRepo.On("GetByID", 3).Return(&domain.User{ID: 3, Login: "carol"}, nil)
+		source := &domain.Listing{Title: "Red mountain bicycle", Description: "Red mountain bicycle", Price: 20000, AuthorID: 1, Category: "bicycles"}
+		ownListing := &domain.Listing{Title: "Blue mountain bicycle", Description: "Blue mountain bicycle", Price: 20000, AuthorID: 1, Category: "bicycles"}
+		closest := &domain.Listing{Title: "Red mountain bicycle", Description: "Red mountain bicycle", Price: 19000, AuthorID: 2, Category: "bicycles"}
+		sameCategory := &domain.Listing{Title: "Kids scooter", Description: "Kids scooter", Price: 5000, AuthorID: 3, Category: "bicycles"}
+		sharedWord := &domain.Listing{Title: "Red kettle", Description: "Red kettle", Price: 1500, AuthorID: 3, Category: "kitchen"}
+		unrelated := &domain.Listing{Title: "Leather sofa", Description: "Leather sofa", Price: 30000, AuthorID: 2, Category: "furniture"}
+		for _, listing := range []*domain.Listing{source, ownListing, closest, sameCategory, sharedWord, unrelated} {
+			require.NoError(t, listingRepo.Create(listing))
+		}
+
+		similar, err := listingService.GetSimilarListings(source.ID, "", nil)
+
+		require.NoError(t, err)
+		ids := listingIDs(similar)
+		assert.Equal(t, []int64{closest.ID, sameCategory.ID, sharedWord.ID}, ids)
+		assert.NotContains(t, ids, ownListing.ID)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("should drop listings that are no longer active", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		cache := service.NewSimilarListingsCache(time.Minute, 100)
+		listingRepo := cache.Watch(memory.NewInMemoryListingRepository())
+		listingService := service.NewListingService(listingRepo, mockUserRepo, service.WithSimilarListingsCache(cache))
+
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "bob"}, nil)
+		source := &domain.Listing{Title: "Red mountain bicycle", Description: "Red mountain bicycle", Price: 20000, AuthorID: 1, Category: "bicycles"}
+		other := &domain.Listing{Title: "Red mountain bicycle", Description: "Red mountain bicycle", Price: 19000, AuthorID: 2, Category: "bicycles"}
+		require.NoError(t, listingRepo.Create(source))
+		require.NoError(t, listingRepo.Create(other))
+
+		similar, err := listingService.GetSimilarListings(source.ID, "", nil)
+		require.NoError(t, err)
+		require.Equal(t, []int64{other.ID}, listingIDs(similar))
+
+		require.NoError(t, listingRepo.UpdateStatus(other.ID, domain.ListingStatusActive, domain.ListingStatusSold))
+
+		similar, err = listingService.GetSimilarListings(source.ID, "", nil)
+		require.NoError(t, err)
+		assert.Empty(t, similar)
+	})
+
+	t.Run("should serve cached results until a listing changes", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		cache := service.NewSimilarListingsCache(time.Minute, 100)
+		listingRepo := cache.Watch(memory.NewInMemoryListingRepository())
+		listingService := service.NewListingService(listingRepo, mockUserRepo, service.WithSimilarListingsCache(cache))
+
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "bob"}, nil)
+		source := &domain.Listing{Title: "Red mountain bicycle", Description: "Red mountain bicycle", Price: 20000, AuthorID: 1, Category: "bicycles"}
+		require.NoError(t, listingRepo.Create(source))
+		_, err := listingService.GetSimilarListings(source.ID, "", nil)
+		require.NoError(t, err)
+
+		later := &domain.Listing{Title: "Red mountain bicycle", Description: "Red mountain bicycle", Price: 19000, AuthorID: 2, Category: "bicycles"}
+		require.NoError(t, listingRepo.Create(later))
+		similar, err := listingService.GetSimilarListings(source.ID, "", nil)
+		require.NoError(t, err)
+		assert.Empty(t, similar)
+
+		source.Price = 19500
+		require.NoError(t, listingRepo.Update(source))
+		similar, err = listingService.GetSimilarListings(source.ID, "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{later.ID}, listingIDs(similar))
+	})
+
+	t.Run("should convert prices into the display currency", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		listingRepo := memory.NewInMemoryListingRepository()
+		rates, err := money.NewStaticRates("RUB", map[string]float64{"USD": 0.0125})
+		require.NoError(t, err)
+		listingService := service.NewListingService(listingRepo, mockUserRepo, service.WithExchangeRates(rates))
+
+		mockUserRepo.On("GetByID", 2).Return(&domain.User{ID: 2, Login: "bob"}, nil)
+		source := &domain.Listing{Title: "Red mountain bicycle", Description: "Red mountain bicycle", Price: 20000, AuthorID: 1, Category: "bicycles"}
+		other := &domain.Listing{Title: "Red mountain bicycle", Description: "Red mountain bicycle", Price: 25000, Currency: "USD", AuthorID: 2, Category: "bicycles"}
+		require.NoError(t, listingRepo.Create(source))
+		require.NoError(t, listingRepo.Create(other))
+
+		similar, err := listingService.GetSimilarListings(source.ID, "RUB", nil)
+
+		require.NoError(t, err)
+		require.Len(t, similar, 1)
+		require.NotNil(t, similar[0].DisplayPrice)
+		assert.Equal(t, money.New(2000000, "RUB"), *similar[0].DisplayPrice)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("should fail for unknown listings", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		cache := service.NewSimilarListingsCache(time.Minute, 100)
+		listingRepo := cache.Watch(memory.NewInMemoryListingRepository())
+		listingService := service.NewListingService(listingRepo, mockUserRepo, service.WithSimilarListingsCache(cache))
+
+		_, err := listingService.GetSimilarListings(42, "", nil)
+
+		assert.ErrorIs(t, err, service.ErrListingNotFound)
+	})
+}
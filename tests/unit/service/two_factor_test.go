@@ -0,0 +1,127 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+	"vk/ecom/internal/domain"
+	"vk/ecom/internal/pkg/totp"
+	"vk/ecom/internal/repository/memory"
+	"vk/ecom/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func totpCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	code, err := totp.Code(secret, totp.Step(time.Now())+offset)
+	require.NoError(t, err)
+	return code
+}
+
+// enrollTwoFactor registers alice and turns two-factor auth on for her.
+func enrollTwoFactor(t *testing.T, opts ...service.AuthServiceOption) (*service.AuthService, int, string, []string) {
+	t.Helper()
+	opts = append(opts, service.WithTwoFactor(memory.NewInMemoryTwoFactorRepository(), service.DefaultTwoFactorConfig()))
+	authService := service.NewAuthService(memory.NewInMemoryUserRepository(), opts...)
+	user, err := authService.RegisterUser("alice", "password123")
+	require.NoError(t, err)
+
+	enrollment, err := authService.EnrollTwoFactor(user.ID)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/ecom:alice?")
+
+	_, err = authService.ConfirmTwoFactor(user.ID, "000000")
+	assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode)
+	codes, err := authService.ConfirmTwoFactor(user.ID, totpCode(t, enrollment.Secret, 0))
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+
+	return authService, user.ID, enrollment.Secret, codes
+}
+
+func loginChallenge(t *testing.T, authService *service.AuthService) string {
+	t.Helper()
+	_, _, err := authService.LoginUserFrom("alice", "password123", domain.ClientInfo{IP: "10.0.0.1"})
+	var required *service.TwoFactorRequiredError
+	require.ErrorAs(t, err, &required)
+	return required.ChallengeToken
+}
+
+func TestAuthService_TwoFactorLogin(t *testing.T) {
+	t.Run("exchanges the challenge and a fresh code for a token", func(t *testing.T) {
+		authService, userID, secret, _ := enrollTwoFactor(t)
+		challenge := loginChallenge(t, authService)
+
+		_, err := authService.ValidateToken(challenge)
+		assert.Error(t, err, "a challenge is not an access token")
+
+		_, _, err = authService.CompleteTwoFactorLogin(challenge, totpCode(t, secret, 0), domain.ClientInfo{IP: "10.0.0.1"})
+		assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode, "the code used for confirmation cannot be replayed")
+
+		next := totpCode(t, secret, 1)
+		token, user, err := authService.CompleteTwoFactorLogin(challenge, next, domain.ClientInfo{IP: "10.0.0.1"})
+		require.NoError(t, err)
+		assert.Equal(t, userID, user.ID)
+		_, err = authService.ValidateToken(token)
+		assert.NoError(t, err)
+
+		_, _, err = authService.CompleteTwoFactorLogin(challenge, next, domain.ClientInfo{IP: "10.0.0.1"})
+		assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode, "codes are single-use")
+	})
+
+	t.Run("recovery codes work once", func(t *testing.T) {
+		authService, userID, _, codes := enrollTwoFactor(t)
+		challenge := loginChallenge(t, authService)
+
+		_, _, err := authService.CompleteTwoFactorLogin(challenge, " "+codes[3]+" ", domain.ClientInfo{IP: "10.0.0.1"})
+		require.NoError(t, err)
+		_, _, err = authService.CompleteTwoFactorLogin(challenge, codes[3], domain.ClientInfo{IP: "10.0.0.1"})
+		assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode)
+
+		status, err := authService.GetTwoFactorStatus(userID)
+		require.NoError(t, err)
+		assert.True(t, status.Enabled)
+		assert.Equal(t, 9, status.RecoveryCodesLeft)
+	})
+
+	t.Run("wrong codes count as failed logins", func(t *testing.T) {
+		throttleConfig := service.DefaultLoginThrottleConfig()
+		throttleConfig.FreeAttempts = 10
+		throttleConfig.LockoutThreshold = 3
+		throttle := service.NewLoginThrottle(memory.NewInMemoryLoginAttemptRepository(), throttleConfig)
+		authService, _, _, _ := enrollTwoFactor(t, service.WithLoginThrottle(throttle))
+
+		for i := 0; i < 3; i++ {
+			// The password step does not reset the failures.
+			challenge := loginChallenge(t, authService)
+			_, _, err := authService.CompleteTwoFactorLogin(challenge, "abcd-efgh", domain.ClientInfo{IP: "10.0.0.1"})
+			assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode)
+		}
+
+		_, _, err := authService.LoginUserFrom("alice", "password123", domain.ClientInfo{IP: "10.0.0.1"})
+		assert.ErrorIs(t, err, service.ErrTooManyLoginAttempts)
+	})
+
+	t.Run("rejects forged challenges", func(t *testing.T) {
+		authService, _, secret, _ := enrollTwoFactor(t)
+		_, _, err := authService.CompleteTwoFactorLogin("not-a-token", totpCode(t, secret, 1), domain.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidLoginChallenge)
+	})
+}
+
+func TestAuthService_DisableTwoFactor(t *testing.T) {
+	authService, userID, secret, _ := enrollTwoFactor(t)
+
+	_, err := authService.EnrollTwoFactor(userID)
+	assert.ErrorIs(t, err, service.ErrTwoFactorAlreadyEnabled)
+
+	assert.ErrorIs(t, authService.DisableTwoFactor(userID, "wrong", totpCode(t, secret, 1)), service.ErrWrongPassword)
+	assert.ErrorIs(t, authService.DisableTwoFactor(userID, "password123", "000000"), service.ErrInvalidTwoFactorCode)
+	require.NoError(t, authService.DisableTwoFactor(userID, "password123", totpCode(t, secret, 1)))
+
+	token, _, err := authService.LoginUser("alice", "password123")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.ErrorIs(t, authService.DisableTwoFactor(userID, "password123", "000000"), service.ErrTwoFactorNotEnabled)
+}
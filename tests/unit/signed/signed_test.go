@@ -0,0 +1,52 @@
+package signed_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"vk/ecom/internal/pkg/signed"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner_Token(t *testing.T) {
+	signer := signed.NewSigner([]byte("0123456789abcdef0123456789abcdef"))
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	token := signer.Token("verify-email", "7|alice@example.com", now.Add(time.Hour))
+
+	payload, err := signer.Parse(token, "verify-email", now)
+	require.NoError(t, err)
+	assert.Equal(t, "7|alice@example.com", payload)
+
+	_, err = signer.Parse(token, "verify-email", now.Add(time.Hour))
+	assert.ErrorIs(t, err, signed.ErrExpired)
+	_, err = signer.Parse(token, "reset-password", now)
+	assert.ErrorIs(t, err, signed.ErrInvalid, "tokens are bound to their purpose")
+	_, err = signed.NewSigner([]byte("another key")).Parse(token, "verify-email", now)
+	assert.ErrorIs(t, err, signed.ErrInvalid)
+
+	// Another payload under this token's signature.
+	body, _, _ := strings.Cut(signer.Token("verify-email", "8|alice@example.com", now.Add(time.Hour)), ".")
+	_, sig, _ := strings.Cut(token, ".")
+	_, err = signer.Parse(body+"."+sig, "verify-email", now)
+	assert.ErrorIs(t, err, signed.ErrInvalid)
+	for _, bad := range []string{"", "abc", "abc.def", "!!.!!"} {
+		_, err = signer.Parse(bad, "verify-email", now)
+		assert.ErrorIs(t, err, signed.ErrInvalid, bad)
+	}
+}
+
+func TestSigner_Code(t *testing.T) {
+	signer := signed.NewSigner([]byte("0123456789abcdef0123456789abcdef"))
+	ttl := 10 * time.Minute
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	code := signer.Code("verify-phone", "7|+4915112345678", now, ttl)
+	assert.Regexp(t, `^\d{6}$`, code)
+
+	assert.True(t, signer.MatchCode("verify-phone", "7|+4915112345678", code, now.Add(ttl+time.Minute), ttl), "the previous period is accepted")
+	assert.False(t, signer.MatchCode("verify-phone", "7|+4915112345678", code, now.Add(2*ttl), ttl))
+	assert.False(t, signer.MatchCode("verify-phone", "7|+4915199999999", code, now, ttl), "codes are bound to the number")
+	assert.False(t, signer.MatchCode("verify-email", "7|+4915112345678", code, now, ttl))
+}
@@ -0,0 +1,26 @@
+package sms_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"vk/ecom/internal/pkg/sms"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSender_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	sender := sms.FileSender{Dir: dir}
+
+	require.NoError(t, sender.Send(&sms.Message{To: "+4915112345678", Body: "Your code is 123456"}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, "To: +4915112345678\n\nYour code is 123456", string(data))
+}
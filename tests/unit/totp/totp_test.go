@@ -0,0 +1,61 @@
+package totp_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+	"vk/ecom/internal/pkg/totp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range vectors {
+		code, err := totp.Code(rfcSecret, totp.Step(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, want, code, "at %d", unix)
+	}
+}
+
+func TestMatch_ToleratesSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := totp.Step(now)
+	previous, err := totp.Code(rfcSecret, step-1)
+	require.NoError(t, err)
+
+	matched, ok, err := totp.Match(rfcSecret, previous, now, 1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, step-1, matched)
+
+	_, ok, err = totp.Match(rfcSecret, previous, now, 0)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, err = totp.Match("not base32!", "123456", now, 1)
+	assert.Error(t, err)
+}
+
+func TestURI(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	uri, err := url.Parse(totp.URI("ecom", "alice smith", secret))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/ecom:alice smith", uri.Path)
+	assert.Equal(t, secret, uri.Query().Get("secret"))
+	assert.Equal(t, "ecom", uri.Query().Get("issuer"))
+}